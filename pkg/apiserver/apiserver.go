@@ -17,18 +17,31 @@ limitations under the License.
 package apiserver
 
 import (
+	"fmt"
+	"time"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
-	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/apiserver/pkg/endpoints/discovery"
 	genericapiserver "k8s.io/apiserver/pkg/server"
+	"k8s.io/apiserver/pkg/server/healthz"
 	"k8s.io/client-go/informers"
+	openapicommon "k8s.io/kube-openapi/pkg/common"
+	"k8s.io/kube-openapi/pkg/validation/spec"
 	cminstall "k8s.io/metrics/pkg/apis/custom_metrics/install"
 	eminstall "k8s.io/metrics/pkg/apis/external_metrics/install"
 
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/cachecontrol"
 	"sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/installer"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/logsampling"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/metrics"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/ratelimit"
 	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
 )
 
@@ -59,8 +72,110 @@ func init() {
 	)
 }
 
+// ExtraConfig holds the configuration specific to the metrics APIs.
+type ExtraConfig struct {
+	// MetricRateLimits limits the QPS of queries for individual metrics.
+	MetricRateLimits ratelimit.Limits
+	// AllowedNamespaces restricts the namespaces for which metrics are served.
+	// When empty, metrics are served for all namespaces.
+	AllowedNamespaces []string
+	// DefaultNamespace is the namespace assumed for custom metrics requested
+	// without namespace, when they are only served for namespaced objects.
+	DefaultNamespace string
+	// MetricMaxAges sets how long responses for individual metrics may be cached
+	// by clients.  Responses for other metrics must not be cached.
+	MetricMaxAges cachecontrol.MaxAges
+	// DefaultMetricWindow is the window set on custom metric values for which the
+	// provider leaves it unset.  When zero, such values are returned without window.
+	DefaultMetricWindow time.Duration
+	// MetricValueSignificantDigits is the number of significant digits custom metric
+	// values are rounded to.  When zero, values are not rounded.
+	MetricValueSignificantDigits int
+	// CoalesceWindow is how long the result of a provider query is shared with
+	// identical requests of the same user after it succeeded.  When zero, only concurrent requests
+	// share it.
+	CoalesceWindow time.Duration
+	// OpenAPIServerURL is the external URL of the server in the OpenAPI v3 documents.
+	// It defaults to the root of the main API server, under which the APIs are aggregated.
+	OpenAPIServerURL string
+	// BasePath is the path prefix under which a reverse proxy exposes the server.
+	// When set, the OpenAPI server URL defaults to it, and the paths of the OpenAPI
+	// v2 document are relative to it.  Requests are not stripped of it here, but by
+	// filters.WithBasePath, in the handler chain.
+	BasePath string
+	// EnableDebugEndpoints enables the debug endpoint dumping the metric values
+	// cached by the providers, at /debug/metrics-dump, the one serving the
+	// diagnostics of the providers implementing provider.DiagnosticsProvider, at
+	// /debug/metrics-diagnostics, and, when RESTMapper is a RefreshableRESTMapper,
+	// the one refreshing it, at /debug/refresh-restmapper.
+	EnableDebugEndpoints bool
+	// AuthorizeDiscovery restricts the metrics listed in the discovery documents to
+	// the ones the caller is authorized to get, without namespace, with the
+	// authorizer of the generic configuration.
+	AuthorizeDiscovery bool
+	// EnableOpenMetrics serves the metrics of the server in the OpenMetrics format
+	// to the clients asking for it, when metrics are enabled.
+	EnableOpenMetrics bool
+	// EnableMetricsCatalog enables the catalog documenting the custom metrics, at
+	// /apis/custom.metrics.k8s.io/catalog, with the custom metrics API.
+	EnableMetricsCatalog bool
+	// MaxCountedMetricNames caps the number of distinct metric names recorded by
+	// the counter of requests for each custom metric, and by the gauge of the age
+	// of their values.  When zero, it is not capped.
+	MaxCountedMetricNames int
+	// RequestLogSampleRate makes the successful queries to the providers logged for
+	// 1 in RequestLogSampleRate requests, at random.  When 1 or less, they are logged
+	// for all requests.  Failed queries are always logged.
+	RequestLogSampleRate int
+	// EnableValueAgeMetric records the age of the most recent value served for each
+	// metric in a gauge, capped at MaxCountedMetricNames distinct metric names.
+	EnableValueAgeMetric bool
+	// MaxSelectorRequirements limits the number of requirements of each selector of
+	// the queries passed to the providers, rejecting the queries above it with a
+	// 400 Bad Request.  When zero, selectors are not limited.
+	MaxSelectorRequirements int
+
+	// RESTMapper maps the resources of the objects described by custom metrics to
+	// their kinds, for providers implementing provider.ObjectCustomMetricsProvider.
+	// It may be nil, in which case they are passed names, like other providers.
+	// When it is a RefreshableRESTMapper, the debug endpoints can refresh it.
+	RESTMapper apimeta.RESTMapper
+
+	// DiscoveryResources pins the resources listed in discovery for the metrics API
+	// groups it has, by group, e.g. for deterministic tests: they are listed as is,
+	// for all the versions of the group, instead of the metrics of its provider,
+	// which still serves their values.
+	DiscoveryResources map[string][]metav1.APIResource
+
+	// GitVersion and GitCommit are the version and commit of the adapter, reported
+	// by /version along with its name.
+	GitVersion string
+	GitCommit  string
+
+	// CustomMetricTransform is applied to each custom metric value before it is returned.
+	CustomMetricTransform provider.CustomMetricTransformFunc
+	// ExternalMetricTransform is applied to each external metric value before it is returned.
+	ExternalMetricTransform provider.ExternalMetricTransformFunc
+	// CustomMetricQueryValidator validates each custom metric query before it is
+	// passed to the provider.
+	CustomMetricQueryValidator provider.CustomMetricQueryValidatorFunc
+	// ExternalMetricQueryValidator validates each external metric query before it
+	// is passed to the provider.
+	ExternalMetricQueryValidator provider.ExternalMetricQueryValidatorFunc
+	// CustomMetricDiscoveryFilter filters the custom metrics listed in discovery,
+	// which are still served when dropped.
+	CustomMetricDiscoveryFilter provider.CustomMetricDiscoveryFilterFunc
+	// ExternalMetricDiscoveryFilter filters the external metrics listed in discovery,
+	// which are still served when dropped.
+	ExternalMetricDiscoveryFilter provider.ExternalMetricDiscoveryFilterFunc
+	// StartupChecks are checked by /startupz, in addition to the completion of the
+	// post-start hooks, for instance to wait for providers to warm up.
+	StartupChecks []healthz.HealthChecker
+}
+
 type Config struct {
 	GenericConfig *genericapiserver.Config
+	ExtraConfig   ExtraConfig
 }
 
 // CustomMetricsAdapterServer contains state for a Kubernetes cluster master/api server.
@@ -68,19 +183,58 @@ type CustomMetricsAdapterServer struct {
 	GenericAPIServer        *genericapiserver.GenericAPIServer
 	customMetricsProvider   provider.CustomMetricsProvider
 	externalMetricsProvider provider.ExternalMetricsProvider
+
+	rateLimiter             *ratelimit.MetricRateLimiter
+	requestCounter          metrics.RequestCounter
+	valueAgeRecorder        metrics.ValueAgeRecorder
+	logSampler              logsampling.Sampler
+	maxSelectorRequirements int
+	allowedNamespaces       sets.Set[string]
+	defaultNamespace        string
+	metricMaxAges           cachecontrol.MaxAges
+	defaultMetricWindow     time.Duration
+	significantDigits       int
+	coalesceWindow          time.Duration
+	discoveryAuthorizer     authorizer.Authorizer
+	restMapper              apimeta.RESTMapper
+	customMetricTransform   provider.CustomMetricTransformFunc
+	externalMetricTransform provider.ExternalMetricTransformFunc
+	customMetricValidator   provider.CustomMetricQueryValidatorFunc
+	externalMetricValidator provider.ExternalMetricQueryValidatorFunc
+	customMetricFilter      provider.CustomMetricDiscoveryFilterFunc
+	externalMetricFilter    provider.ExternalMetricDiscoveryFilterFunc
+	enableMetricsCatalog    bool
+	discoveryRefresher      discoveryRefresher
+	discoveryResources      map[string][]metav1.APIResource
+	// metricListers are the listers of the custom metrics groups, by group
+	metricListers map[string]discovery.APIResourceLister
+	// groupProviders are the providers of the groups installed with
+	// InstallCustomMetricsGroup, by group
+	groupProviders map[string]provider.CustomMetricsProvider
+	metricsChanges []metricsChanges
+
+	openAPIConfig    *openapicommon.Config
+	openAPIV3Config  *openapicommon.Config
+	openAPIServerURL string
 }
 
 type CompletedConfig struct {
 	genericapiserver.CompletedConfig
+	ExtraConfig *ExtraConfig
 }
 
 // Complete fills in any fields not set that are required to have valid data. It's mutating the receiver.
 func (c *Config) Complete(informers informers.SharedInformerFactory) CompletedConfig {
-	c.GenericConfig.Version = &version.Info{
-		Major: "1",
-		Minor: "0",
+	c.GenericConfig.Version = versionInfo(c.ExtraConfig.GitVersion, c.ExtraConfig.GitCommit)
+	if c.GenericConfig.OpenAPIConfig != nil {
+		// copied, so that the server can post-process the document built with it
+		openAPIConfig := *c.GenericConfig.OpenAPIConfig
+		c.GenericConfig.OpenAPIConfig = &openAPIConfig
+	}
+	return CompletedConfig{
+		CompletedConfig: c.GenericConfig.Complete(informers),
+		ExtraConfig:     &c.ExtraConfig,
 	}
-	return CompletedConfig{c.GenericConfig.Complete(informers)}
 }
 
 // New returns a new instance of CustomMetricsAdapterServer from the given config.
@@ -97,6 +251,60 @@ func (c CompletedConfig) New(name string, customMetricsProvider provider.CustomM
 		GenericAPIServer:        genericServer,
 		customMetricsProvider:   customMetricsProvider,
 		externalMetricsProvider: externalMetricsProvider,
+		rateLimiter:             ratelimit.NewMetricRateLimiter(c.ExtraConfig.MetricRateLimits),
+		requestCounter:          metrics.NewRequestCounter(c.ExtraConfig.MaxCountedMetricNames),
+		logSampler:              logsampling.NewSampler(c.ExtraConfig.RequestLogSampleRate),
+		maxSelectorRequirements: c.ExtraConfig.MaxSelectorRequirements,
+		allowedNamespaces:       sets.New(c.ExtraConfig.AllowedNamespaces...),
+		defaultNamespace:        c.ExtraConfig.DefaultNamespace,
+		metricMaxAges:           c.ExtraConfig.MetricMaxAges,
+		defaultMetricWindow:     c.ExtraConfig.DefaultMetricWindow,
+		significantDigits:       c.ExtraConfig.MetricValueSignificantDigits,
+		coalesceWindow:          c.ExtraConfig.CoalesceWindow,
+		restMapper:              c.ExtraConfig.RESTMapper,
+		customMetricTransform:   c.ExtraConfig.CustomMetricTransform,
+		externalMetricTransform: c.ExtraConfig.ExternalMetricTransform,
+		customMetricValidator:   c.ExtraConfig.CustomMetricQueryValidator,
+		externalMetricValidator: c.ExtraConfig.ExternalMetricQueryValidator,
+		customMetricFilter:      c.ExtraConfig.CustomMetricDiscoveryFilter,
+		externalMetricFilter:    c.ExtraConfig.ExternalMetricDiscoveryFilter,
+		enableMetricsCatalog:    c.ExtraConfig.EnableMetricsCatalog,
+		openAPIConfig:           c.OpenAPIConfig,
+		openAPIV3Config:         c.OpenAPIV3Config,
+		openAPIServerURL:        c.ExtraConfig.OpenAPIServerURL,
+		discoveryResources:      c.ExtraConfig.DiscoveryResources,
+		metricListers:           make(map[string]discovery.APIResourceLister),
+		groupProviders:          make(map[string]provider.CustomMetricsProvider),
+	}
+	if c.ExtraConfig.EnableValueAgeMetric {
+		s.valueAgeRecorder = metrics.NewValueAgeRecorder(c.ExtraConfig.MaxCountedMetricNames)
+	}
+	if s.openAPIServerURL == "" {
+		s.openAPIServerURL = defaultOpenAPIServerURL
+		if c.ExtraConfig.BasePath != "" {
+			s.openAPIServerURL = c.ExtraConfig.BasePath
+		}
+	}
+	if c.ExtraConfig.AuthorizeDiscovery {
+		if c.Authorization.Authorizer == nil {
+			return nil, fmt.Errorf("authorizing discovery requires an authorizer")
+		}
+		s.discoveryAuthorizer = c.Authorization.Authorizer
+	}
+	if s.openAPIConfig != nil {
+		postProcessSpec := s.openAPIConfig.PostProcessSpec
+		s.openAPIConfig.PostProcessSpec = func(swagger *spec.Swagger) (*spec.Swagger, error) {
+			if postProcessSpec != nil {
+				var err error
+				if swagger, err = postProcessSpec(swagger); err != nil {
+					return nil, err
+				}
+			}
+			return s.postProcessOpenAPIV2(swagger, c.ExtraConfig.BasePath), nil
+		}
+	}
+	if s.servesOpenAPI() {
+		s.discoveryRefresher.refreshed = s.refreshOpenAPI
 	}
 
 	if customMetricsProvider != nil {
@@ -110,5 +318,23 @@ func (c CompletedConfig) New(name string, customMetricsProvider provider.CustomM
 		}
 	}
 
+	s.installStartupz(c.ExtraConfig.StartupChecks)
+	if c.Version != nil {
+		s.installVersion(name, c.Version)
+	}
+	if c.ExtraConfig.EnableDebugEndpoints {
+		s.installDebugEndpoints()
+	}
+	if c.ExtraConfig.EnableOpenMetrics && c.EnableMetrics {
+		s.installOpenMetrics(c.EnableProfiling)
+	}
+
+	if err := s.GenericAPIServer.AddPostStartHook("openapi-v3-servers", s.installOpenAPIV3Servers); err != nil {
+		return nil, err
+	}
+	if err := s.GenericAPIServer.AddPostStartHook("metrics-changes", s.relayMetricsChanges); err != nil {
+		return nil, err
+	}
+
 	return s, nil
 }
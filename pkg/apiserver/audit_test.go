@@ -0,0 +1,97 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	auditinternal "k8s.io/apiserver/pkg/apis/audit"
+	"k8s.io/apiserver/pkg/audit/policy"
+	genericapiserver "k8s.io/apiserver/pkg/server"
+	"k8s.io/apiserver/plugin/pkg/audit/fake"
+	"k8s.io/client-go/rest"
+
+	fakeprovider "sigs.k8s.io/custom-metrics-apiserver/pkg/provider/fake"
+)
+
+func TestAuditSelectors(t *testing.T) {
+	var mu sync.Mutex
+	events := map[string]*auditinternal.Event{}
+
+	genericConfig := genericapiserver.NewConfig(Codecs)
+	genericConfig.ExternalAddress = "localhost:443"
+	genericConfig.LoopbackClientConfig = &rest.Config{}
+	genericConfig.AuditBackend = &fake.Backend{OnRequest: func(batch []*auditinternal.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, event := range batch {
+			if event.Stage == auditinternal.StageResponseComplete {
+				events[event.RequestURI] = event
+			}
+		}
+	}}
+	// the metrics APIs can be targeted by their groups, with a level of their own
+	genericConfig.AuditPolicyRuleEvaluator = policy.NewPolicyRuleEvaluator(&auditinternal.Policy{
+		OmitStages: []auditinternal.Stage{auditinternal.StageRequestReceived},
+		Rules: []auditinternal.PolicyRule{
+			{
+				Level:     auditinternal.LevelRequest,
+				Resources: []auditinternal.GroupResources{{Group: "custom.metrics.k8s.io"}, {Group: "external.metrics.k8s.io"}},
+			},
+			{Level: auditinternal.LevelMetadata},
+		},
+	})
+	config := &Config{GenericConfig: genericConfig}
+	server, err := config.Complete(nil).New("test", fakeprovider.NewProvider(), fakeprovider.NewProvider())
+	require.NoError(t, err, "should have been able to create the server")
+
+	for _, tc := range []struct {
+		name     string
+		path     string
+		selector string
+	}{
+		{
+			name:     "custom metrics",
+			path:     "/apis/custom.metrics.k8s.io/v1beta2/namespaces/ns/pods/*/some-metric?labelSelector=app%3Dweb&metricLabelSelector=verb%3DGET",
+			selector: `{"kind":"MetricListOptions","apiVersion":"custom.metrics.k8s.io/v1beta2","labelSelector":"app=web","metricLabelSelector":"verb=GET"}`,
+		},
+		{
+			name:     "external metrics",
+			path:     "/apis/external.metrics.k8s.io/v1beta1/namespaces/ns/some-metric?labelSelector=queue%3Djobs",
+			selector: `"labelSelector":"queue=jobs"`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			response := httptest.NewRecorder()
+			server.GenericAPIServer.Handler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			mu.Lock()
+			defer mu.Unlock()
+			event, ok := events[tc.path]
+			require.True(t, ok, "should have audited the request")
+			assert.Equal(t, auditinternal.LevelRequest, event.Level)
+			require.NotNil(t, event.RequestObject, "should have recorded the options of the request")
+			assert.Contains(t, string(event.RequestObject.Raw), tc.selector, "should have recorded the selectors of the request")
+		})
+	}
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package authorization provides authorizers decorating the delegated
+// authorization of the metrics API server.
+package authorization
+
+import (
+	"context"
+
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/klog/v2"
+)
+
+// ErrorPolicy is how requests are authorized when the authorizer fails, e.g.
+// when the authorization webhook of the main API server is unreachable.
+type ErrorPolicy string
+
+const (
+	// ErrorPolicyFailClosed keeps the generic behavior: the request is rejected
+	// with a 500 Internal Server Error.
+	ErrorPolicyFailClosed ErrorPolicy = "fail-closed"
+	// ErrorPolicyFailOpen allows the request, so that autoscaling keeps working
+	// while the authorizer is unavailable.
+	ErrorPolicyFailOpen ErrorPolicy = "fail-open"
+)
+
+// ErrorPolicies are the valid error policies.
+var ErrorPolicies = []ErrorPolicy{ErrorPolicyFailClosed, ErrorPolicyFailOpen}
+
+// WithErrorPolicy wraps the authorizer to apply the given policy to its errors.
+// Explicit denies are kept under every policy: only the requests the authorizer
+// could not decide on because of an error are allowed by ErrorPolicyFailOpen.
+// With ErrorPolicyFailClosed, the authorizer is returned unchanged.
+func WithErrorPolicy(a authorizer.Authorizer, policy ErrorPolicy) authorizer.Authorizer {
+	if policy != ErrorPolicyFailOpen {
+		return a
+	}
+
+	return authorizer.AuthorizerFunc(func(ctx context.Context, attrs authorizer.Attributes) (authorizer.Decision, string, error) {
+		decision, reason, err := a.Authorize(ctx, attrs)
+		if err == nil || decision == authorizer.DecisionDeny {
+			return decision, reason, err
+		}
+		var userName string
+		if attrs.GetUser() != nil {
+			userName = attrs.GetUser().GetName()
+		}
+		klog.FromContext(ctx).Error(err, "Allowing the request despite the authorization error, as the authorization error policy is fail-open",
+			"user", userName, "verb", attrs.GetVerb(), "resource", attrs.GetResource(), "subresource", attrs.GetSubresource(), "path", attrs.GetPath())
+		return authorizer.DecisionAllow, "allowed despite an authorization error by the fail-open policy", nil
+	})
+}
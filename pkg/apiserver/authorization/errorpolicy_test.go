@@ -0,0 +1,78 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+)
+
+// webhookAuthorizer fails like a delegated authorizer whose webhook is unreachable,
+// except for the users it explicitly allows or denies.
+var webhookAuthorizer = authorizer.AuthorizerFunc(func(_ context.Context, a authorizer.Attributes) (authorizer.Decision, string, error) {
+	switch a.GetUser().GetName() {
+	case "allowed":
+		return authorizer.DecisionAllow, "", nil
+	case "denied":
+		return authorizer.DecisionDeny, "forbidden", nil
+	case "denied-with-error":
+		return authorizer.DecisionDeny, "forbidden", fmt.Errorf("partial failure")
+	}
+	return authorizer.DecisionNoOpinion, "", fmt.Errorf("webhook unreachable")
+})
+
+func TestWithErrorPolicy(t *testing.T) {
+	cases := []struct {
+		policy   ErrorPolicy
+		user     string
+		decision authorizer.Decision
+		err      bool
+	}{
+		{policy: ErrorPolicyFailClosed, user: "someone", decision: authorizer.DecisionNoOpinion, err: true},
+		{policy: ErrorPolicyFailClosed, user: "allowed", decision: authorizer.DecisionAllow},
+		{policy: ErrorPolicyFailClosed, user: "denied", decision: authorizer.DecisionDeny},
+		{policy: ErrorPolicyFailOpen, user: "someone", decision: authorizer.DecisionAllow},
+		{policy: ErrorPolicyFailOpen, user: "allowed", decision: authorizer.DecisionAllow},
+		{policy: ErrorPolicyFailOpen, user: "denied", decision: authorizer.DecisionDeny},
+		{policy: ErrorPolicyFailOpen, user: "denied-with-error", decision: authorizer.DecisionDeny, err: true},
+	}
+
+	for _, c := range cases {
+		t.Run(string(c.policy)+"/"+c.user, func(t *testing.T) {
+			a := WithErrorPolicy(webhookAuthorizer, c.policy)
+			decision, _, err := a.Authorize(context.Background(), authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: c.user},
+				Verb:            "get",
+				Resource:        "pods",
+				Subresource:     "some-metric",
+				ResourceRequest: true,
+			})
+			assert.Equal(t, c.decision, decision)
+			if c.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cachecontrol provides per-metric HTTP caching directives for the
+// metrics API server.
+package cachecontrol
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NoCache is the Cache-Control directive of responses which must not be cached.
+const NoCache = "no-cache"
+
+// MaxAges maps metric names to how long responses for each of them may be cached
+// by clients and intermediaries.  It can be used as a flag, in the form
+// "metric=duration,...".
+type MaxAges map[string]time.Duration
+
+// String implements pflag.Value.
+func (m *MaxAges) String() string {
+	pairs := make([]string, 0, len(*m))
+	for metric, maxAge := range *m {
+		pairs = append(pairs, metric+"="+maxAge.String())
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// Set implements pflag.Value.
+func (m *MaxAges) Set(value string) error {
+	maxAges := MaxAges{}
+	for _, pair := range strings.Split(value, ",") {
+		if len(pair) == 0 {
+			continue
+		}
+		metric, rawMaxAge, found := strings.Cut(pair, "=")
+		if !found || len(metric) == 0 {
+			return fmt.Errorf("invalid metric max age %q, expected metric=duration", pair)
+		}
+		maxAge, err := time.ParseDuration(rawMaxAge)
+		if err != nil {
+			return fmt.Errorf("invalid max age for metric %s: %v", metric, err)
+		}
+		maxAges[metric] = maxAge
+	}
+	*m = maxAges
+	return nil
+}
+
+// Type implements pflag.Value.
+func (m *MaxAges) Type() string {
+	return "mapStringDuration"
+}
+
+// Validate checks that all the max ages are positive.
+func (m MaxAges) Validate() []error {
+	errors := []error{}
+	for metric, maxAge := range m {
+		if maxAge <= 0 {
+			errors = append(errors, fmt.Errorf("max age for metric %s must be positive, got %v", metric, maxAge))
+		}
+	}
+	return errors
+}
+
+// Header returns the Cache-Control header value for responses for the given metric.
+// Metrics without a max age must not be cached.  Max ages are rounded down to the
+// second, as required by the header.
+func (m MaxAges) Header(metric string) string {
+	seconds := int64(m[metric] / time.Second)
+	if seconds <= 0 {
+		return NoCache
+	}
+	return "max-age=" + strconv.FormatInt(seconds, 10)
+}
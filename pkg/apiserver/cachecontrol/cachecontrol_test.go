@@ -0,0 +1,51 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cachecontrol
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxAgesFlag(t *testing.T) {
+	var maxAges MaxAges
+	require.NoError(t, maxAges.Set("foo=30s,bar=2m"))
+	assert.Equal(t, MaxAges{"foo": 30 * time.Second, "bar": 2 * time.Minute}, maxAges)
+	assert.Equal(t, "bar=2m0s,foo=30s", maxAges.String())
+	assert.Empty(t, maxAges.Validate())
+
+	assert.Error(t, maxAges.Set("foo"), "a max age without duration should be rejected")
+	assert.Error(t, maxAges.Set("foo=30"), "a max age without unit should be rejected")
+
+	require.NoError(t, maxAges.Set("foo=-1s"))
+	assert.Len(t, maxAges.Validate(), 1, "a negative max age should not be valid")
+}
+
+func TestMaxAgesHeader(t *testing.T) {
+	maxAges := MaxAges{"foo": 30 * time.Second, "bar": 1500 * time.Millisecond, "baz": 100 * time.Millisecond}
+
+	assert.Equal(t, "max-age=30", maxAges.Header("foo"))
+	assert.Equal(t, "max-age=1", maxAges.Header("bar"), "max ages should be rounded down to the second")
+	assert.Equal(t, NoCache, maxAges.Header("baz"), "max ages below a second should not allow caching")
+	assert.Equal(t, NoCache, maxAges.Header("other"), "metrics without a max age should not be cached")
+
+	var none MaxAges
+	assert.Equal(t, NoCache, none.Header("foo"), "no max ages should not allow caching")
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/emicklei/go-restful/v3"
+
+	"k8s.io/klog/v2"
+	"k8s.io/metrics/pkg/apis/custom_metrics"
+
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
+)
+
+// metricsCatalogPath is the path of the catalog documenting the custom metrics.
+// Like other non-resource paths, it is only served to authorized users.
+const metricsCatalogPath = "/apis/" + custom_metrics.GroupName + metricsCatalogSubPath
+
+// metricsCatalogSubPath is the path of the catalog under the web service of the
+// custom metrics group, which serves all the paths under the group.
+const metricsCatalogSubPath = "/catalog"
+
+// metricsCatalog is the document served by the catalog endpoint.
+type metricsCatalog struct {
+	Metrics []catalogEntry `json:"metrics"`
+}
+
+// catalogEntry documents a custom metric.  The fields from the description are
+// empty when the provider does not implement provider.DescribedCustomMetricsProvider.
+type catalogEntry struct {
+	Name            string              `json:"name"`
+	Resource        string              `json:"resource"`
+	Namespaced      bool                `json:"namespaced"`
+	Type            provider.MetricType `json:"type,omitempty"`
+	Description     string              `json:"description,omitempty"`
+	Unit            string              `json:"unit,omitempty"`
+	ExampleSelector string              `json:"exampleSelector,omitempty"`
+}
+
+func (s *CustomMetricsAdapterServer) addMetricsCatalogRoute(ws *restful.WebService) {
+	ws.Route(ws.GET(metricsCatalogSubPath).To(func(req *restful.Request, resp *restful.Response) {
+		s.serveMetricsCatalog(resp.ResponseWriter, req.Request)
+	}).
+		Doc("get the catalog of the custom metrics").
+		Operation("getMetricsCatalog").
+		Produces(restful.MIME_JSON))
+}
+
+func (s *CustomMetricsAdapterServer) serveMetricsCatalog(w http.ResponseWriter, _ *http.Request) {
+	typed, _ := provider.As[provider.TypedCustomMetricsProvider](s.customMetricsProvider)
+	described, _ := provider.As[provider.DescribedCustomMetricsProvider](s.customMetricsProvider)
+
+	catalog := metricsCatalog{Metrics: []catalogEntry{}}
+	for _, info := range s.customMetricsProvider.ListAllMetrics() {
+		entry := catalogEntry{
+			Name:       info.Metric,
+			Resource:   info.GroupResource.String(),
+			Namespaced: info.Namespaced,
+		}
+		if typed != nil {
+			entry.Type = typed.MetricType(info)
+		}
+		if described != nil {
+			description := described.DescribeMetric(info)
+			entry.Description = description.Description
+			entry.Unit = description.Unit
+			entry.ExampleSelector = description.ExampleSelector
+		}
+		catalog.Metrics = append(catalog.Metrics, entry)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(catalog); err != nil {
+		klog.ErrorS(err, "Unable to write the metrics catalog")
+	}
+}
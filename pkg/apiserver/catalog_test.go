@@ -0,0 +1,128 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	genericapiserver "k8s.io/apiserver/pkg/server"
+	"k8s.io/client-go/rest"
+
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider/fake"
+)
+
+var (
+	requestsInfo = provider.CustomMetricInfo{GroupResource: schema.GroupResource{Resource: "pods"}, Namespaced: true, Metric: "http_requests"}
+	bytesInfo    = provider.CustomMetricInfo{GroupResource: schema.GroupResource{Group: "apps", Resource: "deployments"}, Namespaced: true, Metric: "bytes_sent"}
+)
+
+// describedProvider documents the requests metric only.
+type describedProvider struct {
+	provider.MetricsProvider
+}
+
+func (p *describedProvider) ListAllMetrics() []provider.CustomMetricInfo {
+	return []provider.CustomMetricInfo{requestsInfo, bytesInfo}
+}
+
+func (p *describedProvider) MetricType(info provider.CustomMetricInfo) provider.MetricType {
+	if info == requestsInfo {
+		return provider.MetricTypeCounter
+	}
+	return provider.MetricTypeUnspecified
+}
+
+func (p *describedProvider) DescribeMetric(info provider.CustomMetricInfo) provider.MetricDescription {
+	if info != requestsInfo {
+		return provider.MetricDescription{}
+	}
+	return provider.MetricDescription{
+		Description:     "HTTP requests served by the pod",
+		Unit:            "requests",
+		ExampleSelector: "code=200",
+	}
+}
+
+func catalogServer(t *testing.T, enabled bool, allowed bool) http.Handler {
+	genericConfig := genericapiserver.NewConfig(Codecs)
+	genericConfig.ExternalAddress = "localhost:443"
+	genericConfig.LoopbackClientConfig = &rest.Config{}
+	genericConfig.Authorization.Authorizer = authorizer.AuthorizerFunc(func(_ context.Context, attributes authorizer.Attributes) (authorizer.Decision, string, error) {
+		if allowed || attributes.GetPath() != metricsCatalogPath {
+			return authorizer.DecisionAllow, "", nil
+		}
+		return authorizer.DecisionDeny, "", nil
+	})
+	config := &Config{
+		GenericConfig: genericConfig,
+		ExtraConfig:   ExtraConfig{EnableMetricsCatalog: enabled},
+	}
+
+	server, err := config.Complete(nil).New("test", &describedProvider{fake.NewProvider()}, nil)
+	require.NoError(t, err, "should have been able to create the server")
+	return server.GenericAPIServer.Handler
+}
+
+func TestMetricsCatalog(t *testing.T) {
+	t.Run("enabled", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		catalogServer(t, true, true).ServeHTTP(response, httptest.NewRequest(http.MethodGet, metricsCatalogPath, nil))
+		require.Equal(t, http.StatusOK, response.Code, "should have served the catalog: %s", response.Body.String())
+		assert.Equal(t, "application/json", response.Header().Get("Content-Type"))
+
+		catalog := metricsCatalog{}
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &catalog), "should have served a valid catalog")
+		assert.Equal(t, []catalogEntry{
+			{
+				Name:            "http_requests",
+				Resource:        "pods",
+				Namespaced:      true,
+				Type:            provider.MetricTypeCounter,
+				Description:     "HTTP requests served by the pod",
+				Unit:            "requests",
+				ExampleSelector: "code=200",
+			},
+			{
+				Name:       "bytes_sent",
+				Resource:   "deployments.apps",
+				Namespaced: true,
+			},
+		}, catalog.Metrics)
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		catalogServer(t, true, false).ServeHTTP(response, httptest.NewRequest(http.MethodGet, metricsCatalogPath, nil))
+		assert.Equal(t, http.StatusForbidden, response.Code, "should not have served the catalog to unauthorized users")
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		catalogServer(t, false, true).ServeHTTP(response, httptest.NewRequest(http.MethodGet, metricsCatalogPath, nil))
+		assert.Equal(t, http.StatusNotFound, response.Code, "should not have served the catalog")
+	})
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package apiserver
 
 import (
+	"fmt"
+
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -25,6 +27,9 @@ import (
 	"k8s.io/apiserver/pkg/endpoints/discovery"
 	genericapiserver "k8s.io/apiserver/pkg/server"
 	"k8s.io/metrics/pkg/apis/custom_metrics"
+	cmv1beta1 "k8s.io/metrics/pkg/apis/custom_metrics/v1beta1"
+	cmv1beta2 "k8s.io/metrics/pkg/apis/custom_metrics/v1beta2"
+	"k8s.io/metrics/pkg/apis/external_metrics"
 
 	specificapi "sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/installer"
 	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
@@ -32,9 +37,82 @@ import (
 )
 
 func (s *CustomMetricsAdapterServer) InstallCustomMetricsAPI() error {
-	groupInfo := genericapiserver.NewDefaultAPIGroupInfo(custom_metrics.GroupName, Scheme, runtime.NewParameterCodec(Scheme), Codecs)
+	return s.installCustomMetricsGroup(custom_metrics.GroupName, s.customMetricsProvider)
+}
+
+// InstallCustomMetricsGroup serves the custom metrics API under the given group,
+// such as a vendor-specific one, in addition to custom.metrics.k8s.io, with the
+// metrics of the given provider.  The group serves the same versions and types as
+// custom.metrics.k8s.io, and the same options apply to it.  It must be installed
+// before the server is run.
+func (s *CustomMetricsAdapterServer) InstallCustomMetricsGroup(group string, customMetricsProvider provider.CustomMetricsProvider) error {
+	if group == custom_metrics.GroupName || group == external_metrics.GroupName {
+		return fmt.Errorf("API group %s is already served", group)
+	}
+	if err := registerCustomMetricsGroup(Scheme, group); err != nil {
+		return err
+	}
+	if err := s.installCustomMetricsGroup(group, customMetricsProvider); err != nil {
+		return err
+	}
+	s.groupProviders[group] = customMetricsProvider
+	return nil
+}
+
+// registerCustomMetricsGroup registers the custom metrics types in the scheme under
+// the given group, unless another server already did.
+func registerCustomMetricsGroup(scheme *runtime.Scheme, group string) error {
+	if scheme.IsGroupRegistered(group) {
+		return nil
+	}
+
+	scheme.AddKnownTypes(schema.GroupVersion{Group: group, Version: runtime.APIVersionInternal},
+		&custom_metrics.MetricValue{},
+		&custom_metrics.MetricValueList{},
+		&custom_metrics.MetricListOptions{},
+	)
+	v1beta2 := schema.GroupVersion{Group: group, Version: cmv1beta2.SchemeGroupVersion.Version}
+	scheme.AddKnownTypes(v1beta2,
+		&cmv1beta2.MetricValue{},
+		&cmv1beta2.MetricValueList{},
+		&cmv1beta2.MetricListOptions{},
+	)
+	metav1.AddToGroupVersion(scheme, v1beta2)
+	v1beta1 := schema.GroupVersion{Group: group, Version: cmv1beta1.SchemeGroupVersion.Version}
+	scheme.AddKnownTypes(v1beta1,
+		&cmv1beta1.MetricValue{},
+		&cmv1beta1.MetricValueList{},
+		&cmv1beta1.MetricListOptions{},
+	)
+	metav1.AddToGroupVersion(scheme, v1beta1)
+
+	// same priority as custom.metrics.k8s.io
+	return scheme.SetVersionPriority(v1beta1, v1beta2)
+}
+
+func (s *CustomMetricsAdapterServer) installCustomMetricsGroup(group string, customMetricsProvider provider.CustomMetricsProvider) error {
+	groupInfo := genericapiserver.NewDefaultAPIGroupInfo(group, Scheme, runtime.NewParameterCodec(Scheme), Codecs)
 	container := s.GenericAPIServer.Handler.GoRestfulContainer
 
+	// the listed metrics do not depend on the version, so all versions share the
+	// lister, and the metrics it caches
+	lister, pinned := s.pinnedResourceLister(group)
+	if !pinned {
+		if notifying, ok := provider.As[provider.NotifyingCustomMetricsProvider](customMetricsProvider); ok && s.servesOpenAPI() {
+			// the metrics are listed in the OpenAPI documents, which are refreshed with discovery
+			changes := metricsChanges{provider: notifying.MetricsChanged(), lister: make(chan struct{})}
+			s.metricsChanges = append(s.metricsChanges, changes)
+			lister = provider.NewCustomMetricResourceListerWithChanges(customMetricsProvider, changes.lister)
+		} else {
+			lister = provider.NewCustomMetricResourceLister(customMetricsProvider)
+		}
+		s.discoveryRefresher.add(lister)
+		if s.customMetricFilter != nil {
+			lister = provider.NewFilteredCustomMetricResourceLister(lister, s.customMetricFilter)
+		}
+	}
+	s.metricListers[group] = lister
+
 	// Register custom metrics REST handler for all supported API versions.
 	for versionIndex, mainGroupVer := range groupInfo.PrioritizedVersions {
 		preferredVersionForDiscovery := metav1.GroupVersionForDiscovery{
@@ -51,21 +129,39 @@ func (s *CustomMetricsAdapterServer) InstallCustomMetricsAPI() error {
 			PreferredVersion: preferredVersionForDiscovery,
 		}
 
-		cmAPI := s.cmAPI(&groupInfo, mainGroupVer)
+		cmAPI := s.cmAPI(&groupInfo, mainGroupVer, customMetricsProvider, lister)
 		if err := cmAPI.InstallREST(container); err != nil {
 			return err
 		}
 
 		if versionIndex == 0 {
 			s.GenericAPIServer.DiscoveryGroupManager.AddGroup(apiGroup)
-			container.Add(discovery.NewAPIGroupHandler(s.GenericAPIServer.Serializer, apiGroup).WebService())
+			ws := discovery.NewAPIGroupHandler(s.GenericAPIServer.Serializer, apiGroup).WebService()
+			if group == custom_metrics.GroupName && s.enableMetricsCatalog {
+				s.addMetricsCatalogRoute(ws)
+			}
+			container.Add(ws)
 		}
 	}
 	return nil
 }
 
-func (s *CustomMetricsAdapterServer) cmAPI(groupInfo *genericapiserver.APIGroupInfo, groupVersion schema.GroupVersion) *specificapi.MetricsAPIGroupVersion {
-	resourceStorage := metricstorage.NewREST(s.customMetricsProvider)
+func (s *CustomMetricsAdapterServer) cmAPI(groupInfo *genericapiserver.APIGroupInfo, groupVersion schema.GroupVersion, customMetricsProvider provider.CustomMetricsProvider, lister discovery.APIResourceLister) *specificapi.MetricsAPIGroupVersion {
+	resourceStorage := metricstorage.NewREST(customMetricsProvider)
+	resourceStorage.RateLimiter = s.rateLimiter
+	resourceStorage.RequestCounter = s.requestCounter
+	resourceStorage.ValueAgeRecorder = s.valueAgeRecorder
+	resourceStorage.AllowedNamespaces = s.allowedNamespaces
+	resourceStorage.DefaultNamespace = s.defaultNamespace
+	resourceStorage.MaxAges = s.metricMaxAges
+	resourceStorage.DefaultWindow = s.defaultMetricWindow
+	resourceStorage.SignificantDigits = s.significantDigits
+	resourceStorage.CoalesceWindow = s.coalesceWindow
+	resourceStorage.Transform = s.customMetricTransform
+	resourceStorage.Validator = s.customMetricValidator
+	resourceStorage.LogSampler = s.logSampler
+	resourceStorage.MaxSelectorRequirements = s.maxSelectorRequirements
+	resourceStorage.RESTMapper = s.restMapper
 
 	return &specificapi.MetricsAPIGroupVersion{
 		DynamicStorage: resourceStorage,
@@ -83,7 +179,9 @@ func (s *CustomMetricsAdapterServer) cmAPI(groupInfo *genericapiserver.APIGroupI
 			Namer:           runtime.Namer(meta.NewAccessor()),
 		},
 
-		ResourceLister: provider.NewCustomMetricResourceLister(s.customMetricsProvider),
-		Handlers:       &specificapi.CMHandlers{},
+		ResourceLister:      lister,
+		DiscoveryAuthorizer: s.discoveryAuthorizer,
+		DiscoveryVerb:       "get",
+		Handlers:            &specificapi.CMHandlers{},
 	}
 }
@@ -0,0 +1,189 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	genericapiserver "k8s.io/apiserver/pkg/server"
+	"k8s.io/client-go/rest"
+	"k8s.io/metrics/pkg/apis/custom_metrics"
+	cmv1beta2 "k8s.io/metrics/pkg/apis/custom_metrics/v1beta2"
+	"k8s.io/metrics/pkg/apis/external_metrics"
+
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider/fake"
+)
+
+func TestInstallCustomMetricsGroup(t *testing.T) {
+	genericConfig := genericapiserver.NewConfig(Codecs)
+	genericConfig.ExternalAddress = "localhost:443"
+	genericConfig.LoopbackClientConfig = &rest.Config{}
+	config := &Config{GenericConfig: genericConfig}
+	server, err := config.Complete(nil).New("test", fake.NewProvider(), nil)
+	require.NoError(t, err, "should have been able to create the server")
+
+	vendorProvider := provider.NewStaticMetricsProvider(provider.StaticMetricsSpec{
+		CustomMetrics: []provider.StaticCustomMetric{{
+			Info:       provider.CustomMetricInfo{GroupResource: schema.GroupResource{Resource: "pods"}, Namespaced: true, Metric: "vendor-metric"},
+			APIVersion: "v1",
+			Kind:       "Pod",
+			Value:      resource.MustParse("42"),
+		}},
+	})
+	require.NoError(t, server.InstallCustomMetricsGroup("metrics.example.com", vendorProvider), "should have installed the vendor group")
+	assert.Error(t, server.InstallCustomMetricsGroup(custom_metrics.GroupName, vendorProvider), "should not have installed the custom metrics group again")
+
+	get := func(path string) []byte {
+		response := httptest.NewRecorder()
+		server.GenericAPIServer.Handler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, http.StatusOK, response.Code, "should have served %s: %s", path, response.Body.String())
+		return response.Body.Bytes()
+	}
+
+	resources := &metav1.APIResourceList{}
+	require.NoError(t, json.Unmarshal(get("/apis/metrics.example.com/v1beta2"), resources))
+	if assert.Len(t, resources.APIResources, 1, "should have listed the vendor metrics in discovery") {
+		assert.Equal(t, "pods/vendor-metric", resources.APIResources[0].Name)
+	}
+
+	values := &cmv1beta2.MetricValueList{}
+	require.NoError(t, json.Unmarshal(get("/apis/metrics.example.com/v1beta2/namespaces/default/pods/foo/vendor-metric"), values))
+	assert.Equal(t, "metrics.example.com/v1beta2", values.APIVersion, "should have served the values in the vendor group")
+	if assert.Len(t, values.Items, 1) {
+		assert.Equal(t, "foo", values.Items[0].DescribedObject.Name)
+		assert.Equal(t, "42", values.Items[0].Value.String())
+	}
+
+	groups := &metav1.APIGroupList{}
+	require.NoError(t, json.Unmarshal(get("/apis"), groups))
+	names := []string{}
+	for _, group := range groups.Groups {
+		names = append(names, group.Name)
+	}
+	assert.Contains(t, names, "metrics.example.com", "should have listed the vendor group in discovery")
+}
+
+func TestCustomMetricDiscoveryFilter(t *testing.T) {
+	metric := func(name string) provider.StaticCustomMetric {
+		return provider.StaticCustomMetric{
+			Info:       provider.CustomMetricInfo{GroupResource: schema.GroupResource{Resource: "pods"}, Namespaced: true, Metric: name},
+			APIVersion: "v1",
+			Kind:       "Pod",
+			Value:      resource.MustParse("42"),
+		}
+	}
+	staticProvider := provider.NewStaticMetricsProvider(provider.StaticMetricsSpec{
+		CustomMetrics: []provider.StaticCustomMetric{metric("current-metric"), metric("deprecated-metric")},
+	})
+
+	genericConfig := genericapiserver.NewConfig(Codecs)
+	genericConfig.ExternalAddress = "localhost:443"
+	genericConfig.LoopbackClientConfig = &rest.Config{}
+	config := &Config{
+		GenericConfig: genericConfig,
+		ExtraConfig: ExtraConfig{
+			CustomMetricDiscoveryFilter: func(info provider.CustomMetricInfo, resource *metav1.APIResource) bool {
+				resource.Categories = append(resource.Categories, "supported")
+				return info.Metric != "deprecated-metric"
+			},
+		},
+	}
+	server, err := config.Complete(nil).New("test", staticProvider, nil)
+	require.NoError(t, err, "should have been able to create the server")
+
+	get := func(path string) []byte {
+		response := httptest.NewRecorder()
+		server.GenericAPIServer.Handler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, http.StatusOK, response.Code, "should have served %s: %s", path, response.Body.String())
+		return response.Body.Bytes()
+	}
+
+	resources := &metav1.APIResourceList{}
+	require.NoError(t, json.Unmarshal(get("/apis/custom.metrics.k8s.io/v1beta2"), resources))
+	if assert.Len(t, resources.APIResources, 1, "should have dropped the deprecated metric from discovery") {
+		assert.Equal(t, "pods/current-metric", resources.APIResources[0].Name)
+		assert.Equal(t, []string{"supported"}, resources.APIResources[0].Categories, "should have annotated the listed metric")
+	}
+
+	values := &cmv1beta2.MetricValueList{}
+	require.NoError(t, json.Unmarshal(get("/apis/custom.metrics.k8s.io/v1beta2/namespaces/default/pods/foo/deprecated-metric"), values))
+	if assert.Len(t, values.Items, 1, "should still have served the deprecated metric") {
+		assert.Equal(t, "42", values.Items[0].Value.String())
+	}
+}
+
+func TestDiscoveryResources(t *testing.T) {
+	staticProvider := provider.NewStaticMetricsProvider(provider.StaticMetricsSpec{
+		CustomMetrics: []provider.StaticCustomMetric{{
+			Info:       provider.CustomMetricInfo{GroupResource: schema.GroupResource{Resource: "pods"}, Namespaced: true, Metric: "live-metric"},
+			APIVersion: "v1",
+			Kind:       "Pod",
+			Value:      resource.MustParse("42"),
+		}},
+		ExternalMetrics: []provider.StaticExternalMetric{{Name: "live-external-metric", Value: resource.MustParse("7")}},
+	})
+
+	genericConfig := genericapiserver.NewConfig(Codecs)
+	genericConfig.ExternalAddress = "localhost:443"
+	genericConfig.LoopbackClientConfig = &rest.Config{}
+	config := &Config{
+		GenericConfig: genericConfig,
+		ExtraConfig: ExtraConfig{
+			DiscoveryResources: map[string][]metav1.APIResource{
+				custom_metrics.GroupName:   {{Name: "pods/pinned-metric", Namespaced: true, Kind: "MetricValueList", Verbs: metav1.Verbs{"get"}}},
+				external_metrics.GroupName: {},
+			},
+		},
+	}
+	server, err := config.Complete(nil).New("test", staticProvider, staticProvider)
+	require.NoError(t, err, "should have been able to create the server")
+
+	get := func(path string) []byte {
+		response := httptest.NewRecorder()
+		server.GenericAPIServer.Handler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, http.StatusOK, response.Code, "should have served %s: %s", path, response.Body.String())
+		return response.Body.Bytes()
+	}
+
+	for _, version := range []string{"v1beta1", "v1beta2"} {
+		resources := &metav1.APIResourceList{}
+		require.NoError(t, json.Unmarshal(get("/apis/custom.metrics.k8s.io/"+version), resources))
+		if assert.Len(t, resources.APIResources, 1, "should have listed the pinned resources in %s", version) {
+			assert.Equal(t, "pods/pinned-metric", resources.APIResources[0].Name)
+		}
+	}
+	resources := &metav1.APIResourceList{}
+	require.NoError(t, json.Unmarshal(get("/apis/external.metrics.k8s.io/v1beta1"), resources))
+	assert.Empty(t, resources.APIResources, "should have pinned the empty list of external metrics")
+	assert.Contains(t, string(get("/apis/external.metrics.k8s.io/v1beta1/namespaces/default/live-external-metric")), `"value":"7"`, "should still have served the external values of the provider")
+
+	values := &cmv1beta2.MetricValueList{}
+	require.NoError(t, json.Unmarshal(get("/apis/custom.metrics.k8s.io/v1beta2/namespaces/default/pods/foo/live-metric"), values))
+	if assert.Len(t, values.Items, 1, "should still have served the values of the provider") {
+		assert.Equal(t, "42", values.Items[0].Value.String())
+	}
+}
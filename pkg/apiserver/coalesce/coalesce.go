@@ -0,0 +1,141 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package coalesce collapses identical queries to metrics providers.
+package coalesce
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	"k8s.io/utils/clock"
+)
+
+type result struct {
+	value   interface{}
+	expires time.Time
+}
+
+// Group collapses identical queries, identified by their key, into a single call:
+// concurrent queries share its result, as do the queries made within a window after
+// it succeeded.  Errors are only shared with concurrent queries.  The zero value is
+// ready to use.
+type Group struct {
+	inflight singleflight.Group
+	// clock is nil for the real clock.
+	clock clock.PassiveClock
+
+	mu      sync.Mutex
+	results map[string]result
+}
+
+func (g *Group) now() time.Time {
+	if g.clock == nil {
+		return time.Now()
+	}
+	return g.clock.Now()
+}
+
+// Do calls fn for the given key, unless a call for it is in flight, or succeeded
+// less than window ago, in which case it returns the result of that call and true.
+// A zero window only collapses concurrent calls.
+//
+// Since the call is shared, fn is passed a context which is not canceled with ctx,
+// so that the caller making the call does not fail the others by going away, but
+// which keeps its values and deadline, so that the call still times out.  Each
+// caller waits for the call until its own ctx is done, in which case it returns the
+// error of ctx.  If fn panics, the panic is raised again in each caller.
+func (g *Group) Do(ctx context.Context, key string, window time.Duration, fn func(context.Context) (interface{}, error)) (interface{}, error, bool) {
+	if window > 0 {
+		if value, ok := g.recent(key); ok {
+			return value, nil, true
+		}
+	}
+
+	results := g.inflight.DoChan(key, func() (value interface{}, err error) {
+		// the call runs in its own goroutine, where a panic would crash the process
+		// instead of being recovered by the handler of the request
+		defer func() {
+			if p := recover(); p != nil {
+				value, err = nil, &panicError{value: p}
+			}
+		}()
+
+		callCtx := context.WithoutCancel(ctx)
+		if deadline, ok := ctx.Deadline(); ok {
+			var cancel context.CancelFunc
+			callCtx, cancel = context.WithDeadline(callCtx, deadline)
+			defer cancel()
+		}
+		value, err = fn(callCtx)
+		if err == nil && window > 0 {
+			g.store(key, value, window)
+		}
+		return value, err
+	})
+
+	select {
+	case res := <-results:
+		var panicked *panicError
+		if errors.As(res.Err, &panicked) {
+			panic(panicked.value)
+		}
+		return res.Val, res.Err, res.Shared
+	case <-ctx.Done():
+		return nil, ctx.Err(), false
+	}
+}
+
+// panicError is the value a shared call panicked with.
+type panicError struct {
+	value interface{}
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("coalesced call panicked: %v", e.value)
+}
+
+func (g *Group) recent(key string) (interface{}, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	r, ok := g.results[key]
+	if !ok || !g.now().Before(r.expires) {
+		return nil, false
+	}
+	return r.value, true
+}
+
+func (g *Group) store(key string, value interface{}, window time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	if g.results == nil {
+		g.results = make(map[string]result)
+	}
+	// drop the expired results, so that they do not accumulate
+	for k, r := range g.results {
+		if !now.Before(r.expires) {
+			delete(g.results, k)
+		}
+	}
+	g.results[key] = result{value: value, expires: now.Add(window)}
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package coalesce
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+const window = 200 * time.Millisecond
+
+// counter returns a function returning the number of times it was called.
+func counter(calls *int) func(context.Context) (interface{}, error) {
+	return func(context.Context) (interface{}, error) {
+		*calls++
+		return *calls, nil
+	}
+}
+
+func TestGroupWindow(t *testing.T) {
+	clock := testingclock.NewFakePassiveClock(time.Now())
+	g := &Group{clock: clock}
+	calls := 0
+
+	value, err, shared := g.Do(context.Background(), "key", window, counter(&calls))
+	require.NoError(t, err)
+	assert.Equal(t, 1, value)
+	assert.False(t, shared)
+
+	clock.SetTime(clock.Now().Add(window - time.Millisecond))
+	value, err, shared = g.Do(context.Background(), "key", window, counter(&calls))
+	require.NoError(t, err)
+	assert.Equal(t, 1, value, "should have shared the result within the window")
+	assert.True(t, shared)
+
+	value, _, _ = g.Do(context.Background(), "other-key", window, counter(&calls))
+	assert.Equal(t, 2, value, "should not have shared the result of another key")
+
+	clock.SetTime(clock.Now().Add(time.Millisecond))
+	value, err, shared = g.Do(context.Background(), "key", window, counter(&calls))
+	require.NoError(t, err)
+	assert.Equal(t, 3, value, "should have called again at the end of the window")
+	assert.False(t, shared)
+}
+
+func TestGroupWithoutWindow(t *testing.T) {
+	g := &Group{clock: testingclock.NewFakePassiveClock(time.Now())}
+	calls := 0
+
+	g.Do(context.Background(), "key", 0, counter(&calls))
+	value, _, shared := g.Do(context.Background(), "key", 0, counter(&calls))
+	assert.Equal(t, 2, value, "should not have shared the results of calls which are not concurrent")
+	assert.False(t, shared)
+}
+
+func TestGroupErrors(t *testing.T) {
+	g := &Group{clock: testingclock.NewFakePassiveClock(time.Now())}
+	calls := 0
+	failure := errors.New("backend unavailable")
+
+	_, err, _ := g.Do(context.Background(), "key", window, func(context.Context) (interface{}, error) {
+		calls++
+		return nil, failure
+	})
+	assert.ErrorIs(t, err, failure)
+
+	value, err, _ := g.Do(context.Background(), "key", window, counter(&calls))
+	require.NoError(t, err)
+	assert.Equal(t, 2, value, "should not have shared the error within the window")
+}
+
+func TestGroupCanceledCaller(t *testing.T) {
+	g := &Group{}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	query := func(ctx context.Context) (interface{}, error) {
+		close(started)
+		select {
+		case <-release:
+			return "value", nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error)
+	go func() {
+		_, err, _ := g.Do(ctx, "key", 0, query)
+		first <- err
+	}()
+	<-started
+	cancel()
+	assert.ErrorIs(t, <-first, context.Canceled, "should have stopped waiting for the call once canceled")
+
+	// the call started by the canceled caller is still in flight
+	time.AfterFunc(100*time.Millisecond, func() { close(release) })
+	value, err, shared := g.Do(context.Background(), "key", 0, func(context.Context) (interface{}, error) {
+		return nil, errors.New("should have joined the call in flight")
+	})
+	require.NoError(t, err, "should not have canceled the call shared with others")
+	assert.Equal(t, "value", value)
+	assert.True(t, shared)
+}
+
+func TestGroupDeadline(t *testing.T) {
+	g := &Group{}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	expected, _ := ctx.Deadline()
+
+	_, err, _ := g.Do(ctx, "key", 0, func(callCtx context.Context) (interface{}, error) {
+		deadline, ok := callCtx.Deadline()
+		assert.True(t, ok, "should have kept the deadline of the caller")
+		assert.Equal(t, expected, deadline)
+		return nil, nil
+	})
+	require.NoError(t, err)
+}
+
+func TestGroupPanic(t *testing.T) {
+	g := &Group{}
+	assert.PanicsWithValue(t, "provider bug", func() {
+		g.Do(context.Background(), "key", window, func(context.Context) (interface{}, error) {
+			panic("provider bug")
+		})
+	}, "should have raised the panic of the call in the caller")
+
+	calls := 0
+	value, _, _ := g.Do(context.Background(), "key", window, counter(&calls))
+	assert.Equal(t, 1, value, "should not have shared the result of a call which panicked")
+}
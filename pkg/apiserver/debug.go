@@ -0,0 +1,151 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider/caching"
+)
+
+// metricsDumpPath is the path of the debug endpoint dumping the cached metric values.
+// Like other non-resource paths, it is only served to authorized users.
+const metricsDumpPath = "/debug/metrics-dump"
+
+// metricsDiagnosticsPath is the path of the debug endpoint serving the diagnostics
+// of the collection of the metrics, for providers implementing
+// provider.DiagnosticsProvider.  It's authorized like other non-resource paths.
+const metricsDiagnosticsPath = "/debug/metrics-diagnostics"
+
+// restMapperRefreshPath is the path of the debug endpoint refreshing the RESTMapper
+// from discovery, e.g. right after a CRD is installed, rather than at the next
+// discovery interval.  It only accepts POST requests, authorized like other
+// non-resource paths.
+const restMapperRefreshPath = "/debug/refresh-restmapper"
+
+// RefreshableRESTMapper is implemented by RESTMappers populated from discovery, such
+// as the one of the dynamicmapper package.  They are refreshed by the debug endpoint.
+type RefreshableRESTMapper interface {
+	RegenerateMappings() error
+}
+
+// CachingProvider is implemented by providers caching metric values, such as the
+// ones of the caching package.  Their cached values are served by the debug endpoint,
+// including when they are wrapped by decorators following the provider.Unwrap
+// convention.
+type CachingProvider interface {
+	CachedQueries() []caching.CachedQuery
+}
+
+// metricsDump is the document served by the debug endpoint.  The values of each API
+// are null when its provider does not cache them.  The values of the groups installed
+// with InstallCustomMetricsGroup are listed by group, for the providers caching them.
+type metricsDump struct {
+	CustomMetrics       []caching.CachedQuery            `json:"customMetrics"`
+	ExternalMetrics     []caching.CachedQuery            `json:"externalMetrics"`
+	CustomMetricsGroups map[string][]caching.CachedQuery `json:"customMetricsGroups,omitempty"`
+}
+
+// metricsDiagnostics is the document served by the diagnostics endpoint.  The
+// diagnostics of each API are null when its provider does not track them.  The
+// diagnostics of the groups installed with InstallCustomMetricsGroup are listed by
+// group, for the providers tracking them.
+type metricsDiagnostics struct {
+	CustomMetrics       []provider.MetricDiagnostics            `json:"customMetrics"`
+	ExternalMetrics     []provider.MetricDiagnostics            `json:"externalMetrics"`
+	CustomMetricsGroups map[string][]provider.MetricDiagnostics `json:"customMetricsGroups,omitempty"`
+}
+
+func (s *CustomMetricsAdapterServer) installDebugEndpoints() {
+	s.GenericAPIServer.Handler.NonGoRestfulMux.HandleFunc(metricsDumpPath, s.serveMetricsDump)
+	s.GenericAPIServer.Handler.NonGoRestfulMux.HandleFunc(metricsDiagnosticsPath, s.serveMetricsDiagnostics)
+	if mapper, ok := s.restMapper.(RefreshableRESTMapper); ok {
+		s.GenericAPIServer.Handler.NonGoRestfulMux.HandleFunc(restMapperRefreshPath, serveRESTMapperRefresh(mapper))
+	}
+}
+
+func serveRESTMapperRefresh(mapper RefreshableRESTMapper) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "the RESTMapper is refreshed with POST requests", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := mapper.RegenerateMappings(); err != nil {
+			klog.ErrorS(err, "Unable to refresh the RESTMapper on demand")
+			http.Error(w, fmt.Sprintf("unable to refresh the RESTMapper: %v", err), http.StatusInternalServerError)
+			return
+		}
+		klog.V(2).InfoS("Refreshed the RESTMapper on demand")
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *CustomMetricsAdapterServer) serveMetricsDump(w http.ResponseWriter, _ *http.Request) {
+	dump := metricsDump{}
+	if cachingProvider, ok := provider.As[CachingProvider](s.customMetricsProvider); ok {
+		dump.CustomMetrics = cachingProvider.CachedQueries()
+	}
+	if cachingProvider, ok := provider.As[CachingProvider](s.externalMetricsProvider); ok {
+		dump.ExternalMetrics = cachingProvider.CachedQueries()
+	}
+	for group, groupProvider := range s.groupProviders {
+		if cachingProvider, ok := provider.As[CachingProvider](groupProvider); ok {
+			if dump.CustomMetricsGroups == nil {
+				dump.CustomMetricsGroups = make(map[string][]caching.CachedQuery)
+			}
+			dump.CustomMetricsGroups[group] = cachingProvider.CachedQueries()
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(dump); err != nil {
+		klog.ErrorS(err, "Unable to write the metrics dump")
+	}
+}
+
+func (s *CustomMetricsAdapterServer) serveMetricsDiagnostics(w http.ResponseWriter, _ *http.Request) {
+	diagnostics := metricsDiagnostics{}
+	if diagnosticsProvider, ok := provider.As[provider.DiagnosticsProvider](s.customMetricsProvider); ok {
+		diagnostics.CustomMetrics = diagnosticsProvider.MetricDiagnostics()
+	}
+	if diagnosticsProvider, ok := provider.As[provider.DiagnosticsProvider](s.externalMetricsProvider); ok {
+		diagnostics.ExternalMetrics = diagnosticsProvider.MetricDiagnostics()
+	}
+	for group, groupProvider := range s.groupProviders {
+		if diagnosticsProvider, ok := provider.As[provider.DiagnosticsProvider](groupProvider); ok {
+			if diagnostics.CustomMetricsGroups == nil {
+				diagnostics.CustomMetricsGroups = make(map[string][]provider.MetricDiagnostics)
+			}
+			diagnostics.CustomMetricsGroups[group] = diagnosticsProvider.MetricDiagnostics()
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(diagnostics); err != nil {
+		klog.ErrorS(err, "Unable to write the metrics diagnostics")
+	}
+}
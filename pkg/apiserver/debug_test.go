@@ -0,0 +1,271 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	genericapiserver "k8s.io/apiserver/pkg/server"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/rest"
+	core "k8s.io/client-go/testing"
+	"k8s.io/metrics/pkg/apis/custom_metrics"
+
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/dynamicmapper"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider/caching"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider/fake"
+)
+
+// podProvider returns a value of 42 for any pod.
+type podProvider struct {
+	provider.MetricsProvider
+}
+
+func (p *podProvider) GetMetricByName(_ context.Context, name types.NamespacedName, info provider.CustomMetricInfo, _ labels.Selector) (*custom_metrics.MetricValue, error) {
+	return &custom_metrics.MetricValue{
+		DescribedObject: custom_metrics.ObjectReference{Kind: "Pod", Namespace: name.Namespace, Name: name.Name},
+		Metric:          custom_metrics.MetricIdentifier{Name: info.Metric},
+		Value:           resource.MustParse("42"),
+	}, nil
+}
+
+func debugServer(t *testing.T, enabled bool, cmProvider provider.CustomMetricsProvider) http.Handler {
+	return newDebugServer(t, enabled, cmProvider).GenericAPIServer.Handler
+}
+
+// newDebugServer returns the server serving the debug endpoints when enabled, to
+// install other groups on.
+func newDebugServer(t *testing.T, enabled bool, cmProvider provider.CustomMetricsProvider) *CustomMetricsAdapterServer {
+	genericConfig := genericapiserver.NewConfig(Codecs)
+	genericConfig.ExternalAddress = "localhost:443"
+	genericConfig.LoopbackClientConfig = &rest.Config{}
+	config := &Config{
+		GenericConfig: genericConfig,
+		ExtraConfig:   ExtraConfig{EnableDebugEndpoints: enabled},
+	}
+
+	server, err := config.Complete(nil).New("test", cmProvider, nil)
+	require.NoError(t, err, "should have been able to create the server")
+	return server
+}
+
+func TestMetricsDump(t *testing.T) {
+	cmProvider := caching.NewCustomMetricsProvider(&podProvider{fake.NewProvider()}, time.Minute)
+	info := provider.CustomMetricInfo{GroupResource: schema.GroupResource{Resource: "pods"}, Namespaced: true, Metric: "some-metric"}
+	_, err := cmProvider.GetMetricByName(context.Background(), types.NamespacedName{Namespace: "default", Name: "foo"}, info, labels.Everything())
+	require.NoError(t, err)
+
+	t.Run("enabled", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		debugServer(t, true, cmProvider).ServeHTTP(response, httptest.NewRequest(http.MethodGet, metricsDumpPath, nil))
+		require.Equal(t, http.StatusOK, response.Code, "should have served the dump: %s", response.Body.String())
+
+		dump := metricsDump{}
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &dump), "should have served a valid dump")
+		assert.Nil(t, dump.ExternalMetrics, "should not have dumped values for the disabled external metrics API")
+		require.Len(t, dump.CustomMetrics, 1, "should have dumped the cached query")
+		require.Len(t, dump.CustomMetrics[0].Values, 1)
+		value := dump.CustomMetrics[0].Values[0]
+		assert.Equal(t, "default/Pod/foo", value.Object)
+		assert.Equal(t, "some-metric", value.Metric)
+		assert.Equal(t, "42", value.Value)
+	})
+
+	t.Run("wrapped", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		debugServer(t, true, provider.NewRetryingProvider(cmProvider, 1, wait.Backoff{})).ServeHTTP(response, httptest.NewRequest(http.MethodGet, metricsDumpPath, nil))
+		require.Equal(t, http.StatusOK, response.Code, "should have served the dump: %s", response.Body.String())
+
+		dump := metricsDump{}
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &dump), "should have served a valid dump")
+		assert.Len(t, dump.CustomMetrics, 1, "should have dumped the cached query of the wrapped provider")
+	})
+
+	t.Run("group", func(t *testing.T) {
+		server := newDebugServer(t, true, &podProvider{fake.NewProvider()})
+		require.NoError(t, server.InstallCustomMetricsGroup("metrics.example.com", cmProvider), "should have installed the vendor group")
+		response := httptest.NewRecorder()
+		server.GenericAPIServer.Handler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, metricsDumpPath, nil))
+		require.Equal(t, http.StatusOK, response.Code, "should have served the dump: %s", response.Body.String())
+
+		dump := metricsDump{}
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &dump), "should have served a valid dump")
+		assert.Nil(t, dump.CustomMetrics, "should not have dumped values for the non-caching provider")
+		assert.Len(t, dump.CustomMetricsGroups["metrics.example.com"], 1, "should have dumped the cached query of the vendor group")
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		debugServer(t, false, cmProvider).ServeHTTP(response, httptest.NewRequest(http.MethodGet, metricsDumpPath, nil))
+		assert.Equal(t, http.StatusNotFound, response.Code, "should not have served the dump")
+	})
+}
+
+// diagnosedProvider returns a value of 42 for any pod, except for the failing
+// metric, recording the collections of the metrics.
+type diagnosedProvider struct {
+	podProvider
+	*provider.DiagnosticsRecorder
+}
+
+func (p *diagnosedProvider) GetMetricByName(ctx context.Context, name types.NamespacedName, info provider.CustomMetricInfo, metricSelector labels.Selector) (*custom_metrics.MetricValue, error) {
+	if info.Metric == "failing-metric" {
+		err := fmt.Errorf("backend unavailable")
+		p.Record(info.Metric, err, time.Now())
+		return nil, err
+	}
+	p.Record(info.Metric, nil, time.Now())
+	return p.podProvider.GetMetricByName(ctx, name, info, metricSelector)
+}
+
+func TestMetricsDiagnostics(t *testing.T) {
+	cmProvider := &diagnosedProvider{podProvider{fake.NewProvider()}, provider.NewDiagnosticsRecorder()}
+	handler := debugServer(t, true, cmProvider)
+	for metric, code := range map[string]int{"some-metric": http.StatusOK, "failing-metric": http.StatusInternalServerError} {
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/apis/custom.metrics.k8s.io/v1beta2/namespaces/default/pods/foo/"+metric, nil))
+		require.Equal(t, code, response.Code, "unexpected response for %s: %s", metric, response.Body.String())
+	}
+
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, metricsDiagnosticsPath, nil))
+	require.Equal(t, http.StatusOK, response.Code, "should have served the diagnostics: %s", response.Body.String())
+
+	diagnostics := metricsDiagnostics{}
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &diagnostics), "should have served valid diagnostics")
+	assert.Nil(t, diagnostics.ExternalMetrics, "should not have diagnostics for the disabled external metrics API")
+	require.Len(t, diagnostics.CustomMetrics, 2)
+	failing, succeeding := diagnostics.CustomMetrics[0], diagnostics.CustomMetrics[1]
+	assert.Equal(t, "failing-metric", failing.Metric)
+	assert.Equal(t, "backend unavailable", failing.LastError)
+	assert.NotNil(t, failing.LastErrorTime)
+	assert.Nil(t, failing.LastSuccess, "should not have reported a success for the failing metric")
+	assert.Equal(t, "some-metric", succeeding.Metric)
+	assert.NotNil(t, succeeding.LastSuccess)
+	assert.Empty(t, succeeding.LastError)
+
+	t.Run("wrapped", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		debugServer(t, true, provider.NewRetryingProvider(cmProvider, 1, wait.Backoff{})).ServeHTTP(response, httptest.NewRequest(http.MethodGet, metricsDiagnosticsPath, nil))
+		require.Equal(t, http.StatusOK, response.Code, "should have served the diagnostics: %s", response.Body.String())
+
+		diagnostics := metricsDiagnostics{}
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &diagnostics), "should have served valid diagnostics")
+		assert.Len(t, diagnostics.CustomMetrics, 2, "should have served the diagnostics of the wrapped provider")
+	})
+
+	t.Run("group", func(t *testing.T) {
+		server := newDebugServer(t, true, &podProvider{fake.NewProvider()})
+		require.NoError(t, server.InstallCustomMetricsGroup("metrics.example.com", cmProvider), "should have installed the vendor group")
+		response := httptest.NewRecorder()
+		server.GenericAPIServer.Handler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, metricsDiagnosticsPath, nil))
+		require.Equal(t, http.StatusOK, response.Code, "should have served the diagnostics: %s", response.Body.String())
+
+		diagnostics := metricsDiagnostics{}
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &diagnostics), "should have served valid diagnostics")
+		assert.Nil(t, diagnostics.CustomMetrics, "should not have diagnostics for the provider not tracking them")
+		assert.Len(t, diagnostics.CustomMetricsGroups["metrics.example.com"], 2, "should have served the diagnostics of the vendor group")
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		genericConfig := genericapiserver.NewConfig(Codecs)
+		genericConfig.ExternalAddress = "localhost:443"
+		genericConfig.LoopbackClientConfig = &rest.Config{}
+		genericConfig.Authorization.Authorizer = authorizer.AuthorizerFunc(func(_ context.Context, attributes authorizer.Attributes) (authorizer.Decision, string, error) {
+			if attributes.GetPath() == metricsDiagnosticsPath {
+				return authorizer.DecisionDeny, "", nil
+			}
+			return authorizer.DecisionAllow, "", nil
+		})
+		config := &Config{
+			GenericConfig: genericConfig,
+			ExtraConfig:   ExtraConfig{EnableDebugEndpoints: true},
+		}
+		server, err := config.Complete(nil).New("test", cmProvider, nil)
+		require.NoError(t, err, "should have been able to create the server")
+
+		response := httptest.NewRecorder()
+		server.GenericAPIServer.Handler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, metricsDiagnosticsPath, nil))
+		assert.Equal(t, http.StatusForbidden, response.Code, "should not have served the diagnostics to unauthorized users")
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		debugServer(t, false, cmProvider).ServeHTTP(response, httptest.NewRequest(http.MethodGet, metricsDiagnosticsPath, nil))
+		assert.Equal(t, http.StatusNotFound, response.Code, "should not have served the diagnostics")
+	})
+}
+
+func TestRESTMapperRefresh(t *testing.T) {
+	discovery := &fakediscovery.FakeDiscovery{Fake: &core.Fake{}}
+	discovery.Resources = []*metav1.APIResourceList{
+		{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "pods", Namespaced: true, Kind: "Pod"}}},
+	}
+	// the mapper is only refreshed on demand
+	mapper, err := dynamicmapper.NewRESTMapper(discovery, time.Hour)
+	require.NoError(t, err)
+
+	genericConfig := genericapiserver.NewConfig(Codecs)
+	genericConfig.ExternalAddress = "localhost:443"
+	genericConfig.LoopbackClientConfig = &rest.Config{}
+	config := &Config{
+		GenericConfig: genericConfig,
+		ExtraConfig:   ExtraConfig{EnableDebugEndpoints: true, RESTMapper: mapper},
+	}
+	server, err := config.Complete(nil).New("test", fake.NewProvider(), nil)
+	require.NoError(t, err, "should have been able to create the server")
+	handler := server.GenericAPIServer.Handler
+
+	// a CRD is installed
+	widgets := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+	discovery.Resources = append(discovery.Resources, &metav1.APIResourceList{
+		GroupVersion: "example.com/v1",
+		APIResources: []metav1.APIResource{{Name: "widgets", Namespaced: true, Kind: "Widget"}},
+	})
+	_, err = mapper.KindFor(widgets)
+	require.Error(t, err, "should not have mapped the new resource before the refresh")
+
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, restMapperRefreshPath, nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, response.Code, "should only have refreshed the mapper on POST requests")
+	assert.Equal(t, http.MethodPost, response.Header().Get("Allow"))
+
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, httptest.NewRequest(http.MethodPost, restMapperRefreshPath, nil))
+	require.Equal(t, http.StatusNoContent, response.Code, "should have refreshed the mapper: %s", response.Body.String())
+
+	kind, err := mapper.KindFor(widgets)
+	require.NoError(t, err, "should have mapped the new resource after the refresh")
+	assert.Equal(t, "Widget", kind.Kind)
+}
@@ -0,0 +1,39 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/endpoints/discovery"
+)
+
+// pinnedResourceLister returns a lister of the resources pinned for the given group,
+// if any.  They are copied, so that callers mutating the listed resources do not
+// change the pinned ones.
+func (s *CustomMetricsAdapterServer) pinnedResourceLister(group string) (discovery.APIResourceLister, bool) {
+	resources, ok := s.discoveryResources[group]
+	if !ok {
+		return nil, false
+	}
+	return discovery.APIResourceListerFunc(func() []metav1.APIResource {
+		listed := make([]metav1.APIResource, len(resources))
+		for i := range resources {
+			resources[i].DeepCopyInto(&listed[i])
+		}
+		return listed
+	}), true
+}
@@ -63,6 +63,23 @@ func (s *CustomMetricsAdapterServer) InstallExternalMetricsAPI() error {
 
 func (s *CustomMetricsAdapterServer) emAPI(groupInfo *genericapiserver.APIGroupInfo, groupVersion schema.GroupVersion) *specificapi.MetricsAPIGroupVersion {
 	resourceStorage := metricstorage.NewREST(s.externalMetricsProvider)
+	resourceStorage.RateLimiter = s.rateLimiter
+	resourceStorage.ValueAgeRecorder = s.valueAgeRecorder
+	resourceStorage.AllowedNamespaces = s.allowedNamespaces
+	resourceStorage.MaxAges = s.metricMaxAges
+	resourceStorage.CoalesceWindow = s.coalesceWindow
+	resourceStorage.Transform = s.externalMetricTransform
+	resourceStorage.Validator = s.externalMetricValidator
+	resourceStorage.LogSampler = s.logSampler
+	resourceStorage.MaxSelectorRequirements = s.maxSelectorRequirements
+
+	lister, pinned := s.pinnedResourceLister(groupVersion.Group)
+	if !pinned {
+		lister = provider.NewExternalMetricResourceLister(s.externalMetricsProvider)
+		if s.externalMetricFilter != nil {
+			lister = provider.NewFilteredExternalMetricResourceLister(lister, s.externalMetricFilter)
+		}
+	}
 
 	return &specificapi.MetricsAPIGroupVersion{
 		DynamicStorage: resourceStorage,
@@ -79,7 +96,9 @@ func (s *CustomMetricsAdapterServer) emAPI(groupInfo *genericapiserver.APIGroupI
 			Typer:           groupInfo.Scheme,
 			Namer:           runtime.Namer(meta.NewAccessor()),
 		},
-		ResourceLister: provider.NewExternalMetricResourceLister(s.externalMetricsProvider),
-		Handlers:       &specificapi.EMHandlers{},
+		ResourceLister:      lister,
+		DiscoveryAuthorizer: s.discoveryAuthorizer,
+		DiscoveryVerb:       "list",
+		Handlers:            &specificapi.EMHandlers{},
 	}
 }
@@ -17,6 +17,7 @@ limitations under the License.
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 	"net/url"
 	"strings"
@@ -27,13 +28,16 @@ import (
 	metainternalversionscheme "k8s.io/apimachinery/pkg/apis/meta/internalversion/scheme"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apiserver/pkg/audit"
 	"k8s.io/apiserver/pkg/endpoints/handlers"
 	"k8s.io/apiserver/pkg/endpoints/handlers/negotiation"
-	"k8s.io/apiserver/pkg/endpoints/handlers/responsewriters"
 	"k8s.io/apiserver/pkg/endpoints/request"
 	utiltrace "k8s.io/utils/trace"
 
 	cm_rest "sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/registry/rest"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
 )
 
 func ListResourceWithOptions(r cm_rest.ListerWithOptions, scope handlers.RequestScope) http.HandlerFunc {
@@ -64,6 +68,12 @@ func ListResourceWithOptions(r cm_rest.ListerWithOptions, scope handlers.Request
 			return
 		}
 
+		outputMediaType, _, err := negotiation.NegotiateOutputMediaType(req, scope.Serializer, &scope)
+		if err != nil {
+			writeError(&scope, err, w, req)
+			return
+		}
+
 		namespace, err := scope.Namer.Namespace(req)
 		if err != nil {
 			writeError(&scope, err, w, req)
@@ -88,6 +98,19 @@ func ListResourceWithOptions(r cm_rest.ListerWithOptions, scope handlers.Request
 			return
 		}
 
+		// the UID of the described object is not a field of metric values: it is
+		// taken out of the field selector, and passed to the provider in the context
+		if opts.FieldSelector != nil {
+			var uid types.UID
+			if opts.FieldSelector, uid, err = extractObjectUID(opts.FieldSelector); err != nil {
+				writeError(&scope, errors.NewBadRequest(err.Error()), w, req)
+				return
+			}
+			if uid != "" {
+				ctx = provider.WithObjectUID(ctx, uid)
+			}
+		}
+
 		// transform fields
 		// TODO: DecodeParametersInto should do this.
 		if opts.FieldSelector != nil {
@@ -134,6 +157,11 @@ func ListResourceWithOptions(r cm_rest.ListerWithOptions, scope handlers.Request
 			writeError(&scope, err, w, req)
 			return
 		}
+		if extraOpts != nil {
+			// the options hold the selectors of the request, so that audit events at the
+			// Request level record them along with the request URI
+			audit.LogRequestObject(ctx, extraOpts, scope.Kind.GroupVersion(), scope.Resource, scope.Subresource, scope.Serializer)
+		}
 		result, err := r.List(ctx, &opts, extraOpts)
 		if err != nil {
 			writeError(&scope, err, w, req)
@@ -141,11 +169,39 @@ func ListResourceWithOptions(r cm_rest.ListerWithOptions, scope handlers.Request
 		}
 		trace.Step("Listing from storage done")
 
-		responsewriters.WriteObjectNegotiated(scope.Serializer, negotiation.DefaultEndpointRestrictions, scope.Kind.GroupVersion(), w, req, http.StatusOK, result, false)
+		transformResponseObject(ctx, scope, req, w, http.StatusOK, outputMediaType, result)
 		trace.Step("Writing http response done")
 	}
 }
 
+// extractObjectUID removes the metadata.uid requirement from the field selector,
+// returning the UID it requires, if any.  Only equality is supported.
+func extractObjectUID(selector fields.Selector) (fields.Selector, types.UID, error) {
+	var uid types.UID
+	remaining := []fields.Selector{}
+	for _, requirement := range selector.Requirements() {
+		if requirement.Field != "metadata.uid" {
+			if requirement.Operator == selection.NotEquals {
+				remaining = append(remaining, fields.OneTermNotEqualSelector(requirement.Field, requirement.Value))
+			} else {
+				remaining = append(remaining, fields.OneTermEqualSelector(requirement.Field, requirement.Value))
+			}
+			continue
+		}
+		if requirement.Operator == selection.NotEquals {
+			return nil, "", fmt.Errorf("fieldSelector metadata.uid only supports equality")
+		}
+		uid = types.UID(requirement.Value)
+	}
+	if uid == "" {
+		return selector, "", nil
+	}
+	if len(remaining) == 0 {
+		return fields.Everything(), uid, nil
+	}
+	return fields.AndSelectors(remaining...), uid, nil
+}
+
 // getRequestOptions parses out options and can include path information.  The path information shouldn't include the subresource.
 func getRequestOptions(req *http.Request, scope handlers.RequestScope, into runtime.Object, hasSubpath bool, subpathKey string, isSubresource bool) error {
 	if into == nil {
@@ -0,0 +1,122 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filters
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"k8s.io/klog/v2"
+	"k8s.io/kube-openapi/pkg/handler3"
+)
+
+// openAPIV3DiscoveryPath is the path of the document linking to the OpenAPI v3
+// document of each group version.
+const openAPIV3DiscoveryPath = "/openapi/v3"
+
+// WithBasePath serves the handler under the given path prefix, for servers exposed
+// by a reverse proxy under a sub-path.  The prefix is stripped from the requests
+// received with it, so that they are routed like the ones received from proxies
+// stripping it themselves, which are served as is.  In both cases, it is added to
+// the links of the OpenAPI v3 discovery document, which are relative to the root
+// of the server.
+func WithBasePath(handler http.Handler, basePath string) http.Handler {
+	basePath = strings.TrimSuffix(basePath, "/")
+	if basePath == "" {
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if path, ok := stripBasePath(req.URL.Path, basePath); ok {
+			req = req.Clone(req.Context())
+			req.URL.Path = path
+			req.URL.RawPath = ""
+		}
+		if req.URL.Path != openAPIV3DiscoveryPath {
+			handler.ServeHTTP(w, req)
+			return
+		}
+
+		recorder := &discoveryRecorder{header: http.Header{}, status: http.StatusOK}
+		handler.ServeHTTP(recorder, req)
+		recorder.writeWithBasePath(w, basePath)
+	})
+}
+
+// stripBasePath returns the given path without the prefix, and whether it had it.
+func stripBasePath(path, basePath string) (string, bool) {
+	if path == basePath {
+		return "/", true
+	}
+	if rest := strings.TrimPrefix(path, basePath); len(rest) < len(path) && strings.HasPrefix(rest, "/") {
+		return rest, true
+	}
+	return path, false
+}
+
+// discoveryRecorder buffers the response of the OpenAPI v3 discovery handler,
+// which is small, so that its links can be rewritten.
+type discoveryRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *discoveryRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *discoveryRecorder) WriteHeader(status int) {
+	r.status = status
+}
+
+func (r *discoveryRecorder) Write(data []byte) (int, error) {
+	return r.body.Write(data)
+}
+
+// writeWithBasePath writes the recorded response, with the base path added to the
+// links of successful responses.  Other responses, such as 304 Not Modified ones,
+// are written as is.
+func (r *discoveryRecorder) writeWithBasePath(w http.ResponseWriter, basePath string) {
+	body := r.body.Bytes()
+	if r.status == http.StatusOK && len(body) > 0 {
+		discovery := &handler3.OpenAPIV3Discovery{}
+		if err := json.Unmarshal(body, discovery); err != nil {
+			klog.ErrorS(err, "Unable to add the base path to the OpenAPI v3 discovery document")
+		} else {
+			for gv, path := range discovery.Paths {
+				path.ServerRelativeURL = basePath + path.ServerRelativeURL
+				discovery.Paths[gv] = path
+			}
+			if rewritten, err := json.Marshal(discovery); err == nil {
+				body = rewritten
+			}
+		}
+	}
+
+	for key, values := range r.header {
+		w.Header()[key] = values
+	}
+	if w.Header().Get("Content-Length") != "" {
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	}
+	w.WriteHeader(r.status)
+	_, _ = w.Write(body)
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filters
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/kube-openapi/pkg/handler3"
+)
+
+func TestWithBasePath(t *testing.T) {
+	cases := []struct {
+		name         string
+		path         string
+		expectedPath string
+	}{
+		{name: "prefixed", path: "/metrics-adapter/apis/custom.metrics.k8s.io/v1beta2", expectedPath: "/apis/custom.metrics.k8s.io/v1beta2"},
+		{name: "prefix only", path: "/metrics-adapter", expectedPath: "/"},
+		{name: "stripped by the proxy", path: "/apis/custom.metrics.k8s.io/v1beta2", expectedPath: "/apis/custom.metrics.k8s.io/v1beta2"},
+		{name: "other prefix", path: "/metrics-adapters/apis", expectedPath: "/metrics-adapters/apis"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var path string
+			handler := WithBasePath(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				path = req.URL.Path
+			}), "/metrics-adapter")
+
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, c.path, nil))
+			assert.Equal(t, c.expectedPath, path, "should have routed the request to the path without prefix")
+		})
+	}
+}
+
+func TestWithBasePathOpenAPIV3Discovery(t *testing.T) {
+	handler := WithBasePath(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(handler3.OpenAPIV3Discovery{Paths: map[string]handler3.OpenAPIV3DiscoveryGroupVersion{
+			"apis/custom.metrics.k8s.io/v1beta2": {ServerRelativeURL: "/openapi/v3/apis/custom.metrics.k8s.io/v1beta2?hash=0123"},
+		}})
+	}), "/metrics-adapter")
+
+	for _, path := range []string{"/metrics-adapter/openapi/v3", "/openapi/v3"} {
+		t.Run(path, func(t *testing.T) {
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, path, nil))
+			require.Equal(t, http.StatusOK, response.Code)
+			assert.Equal(t, "application/json", response.Header().Get("Content-Type"))
+
+			discovery := &handler3.OpenAPIV3Discovery{}
+			require.NoError(t, json.Unmarshal(response.Body.Bytes(), discovery), "should have served a valid discovery document")
+			assert.Equal(t, "/metrics-adapter/openapi/v3/apis/custom.metrics.k8s.io/v1beta2?hash=0123",
+				discovery.Paths["apis/custom.metrics.k8s.io/v1beta2"].ServerRelativeURL, "should have added the base path to the links")
+		})
+	}
+}
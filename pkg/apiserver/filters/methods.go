@@ -0,0 +1,60 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filters
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/endpoints/handlers/responsewriters"
+)
+
+// apisPath is the root of the paths of the APIs, including their discovery.
+const apisPath = "/apis"
+
+// WithAllowedMethods rejects the requests for the APIs, under /apis, with other
+// methods than the given ones, with 405 Method Not Allowed and an Allow header
+// listing them, before they are authenticated nor routed.  Other paths, such as
+// the health or debug endpoints, are passed to the handler whatever their method.
+func WithAllowedMethods(handler http.Handler, methods []string, s runtime.NegotiatedSerializer) http.Handler {
+	allowed := make(map[string]bool, len(methods))
+	for _, method := range methods {
+		allowed[method] = true
+	}
+	allow := strings.Join(methods, ", ")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if allowed[req.Method] || (req.URL.Path != apisPath && !strings.HasPrefix(req.URL.Path, apisPath+"/")) {
+			handler.ServeHTTP(w, req)
+			return
+		}
+
+		w.Header().Set("Allow", allow)
+		err := &apierr.StatusError{ErrStatus: metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    http.StatusMethodNotAllowed,
+			Reason:  metav1.StatusReasonMethodNotAllowed,
+			Message: fmt.Sprintf("method %s is not allowed on %s, only %s", req.Method, req.URL.Path, allow),
+		}}
+		responsewriters.ErrorNegotiated(err, s, schema.GroupVersion{}, w, req)
+	})
+}
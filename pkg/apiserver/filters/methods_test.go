@@ -0,0 +1,69 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filters
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+)
+
+func TestWithAllowedMethods(t *testing.T) {
+	scheme := runtime.NewScheme()
+	scheme.AddUnversionedTypes(schema.GroupVersion{Version: "v1"}, &metav1.Status{})
+	codecs := serializer.NewCodecFactory(scheme)
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := WithAllowedMethods(ok, []string{http.MethodGet}, codecs)
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		status int
+	}{
+		{name: "get metric", method: http.MethodGet, path: "/apis/custom.metrics.k8s.io/v1beta2/namespaces/default/pods/*/foo", status: http.StatusOK},
+		{name: "get discovery", method: http.MethodGet, path: "/apis", status: http.StatusOK},
+		{name: "post metric", method: http.MethodPost, path: "/apis/custom.metrics.k8s.io/v1beta2/namespaces/default/pods/*/foo", status: http.StatusMethodNotAllowed},
+		{name: "put metric", method: http.MethodPut, path: "/apis/custom.metrics.k8s.io/v1beta2/namespaces/default/pods/foo/foo", status: http.StatusMethodNotAllowed},
+		{name: "patch metric", method: http.MethodPatch, path: "/apis/custom.metrics.k8s.io/v1beta2/namespaces/default/pods/foo/foo", status: http.StatusMethodNotAllowed},
+		{name: "delete external metric", method: http.MethodDelete, path: "/apis/external.metrics.k8s.io/v1beta1/namespaces/default/foo", status: http.StatusMethodNotAllowed},
+		{name: "post discovery", method: http.MethodPost, path: "/apis", status: http.StatusMethodNotAllowed},
+		{name: "post other path", method: http.MethodPost, path: "/debug/refresh-restmapper", status: http.StatusOK},
+		{name: "post path with the same prefix", method: http.MethodPost, path: "/apisx", status: http.StatusOK},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, httptest.NewRequest(c.method, c.path, nil))
+			assert.Equal(t, c.status, response.Code)
+			if c.status == http.StatusMethodNotAllowed {
+				assert.Equal(t, http.MethodGet, response.Header().Get("Allow"), "should have listed the allowed methods")
+				assert.Contains(t, response.Body.String(), `"reason":"MethodNotAllowed"`, "should have answered with a status")
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filters
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/apiserver/pkg/endpoints/handlers/responsewriters"
+	"k8s.io/klog/v2"
+)
+
+// WithMaxConnectionsPerIP rejects with 429 Too Many Requests the requests of
+// clients already having max requests in flight, so that a single client cannot
+// exhaust the connections of the server.  Clients are identified by the IP of the
+// peer, or, when trustForwarded is set, by the client IP reported by proxies, in
+// which case the headers reporting it must be sanitized with WithTrustedProxies.
+func WithMaxConnectionsPerIP(handler http.Handler, max int, trustForwarded bool, s runtime.NegotiatedSerializer) http.Handler {
+	if max <= 0 {
+		return handler
+	}
+
+	limiter := &ipLimiter{max: max, inflight: make(map[string]int)}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ip := sourceIP(req, trustForwarded)
+		if !limiter.acquire(ip) {
+			klog.V(2).InfoS("Rejected request above the limit of concurrent requests per IP", "ip", ip, "limit", max)
+			err := apierr.NewTooManyRequests(fmt.Sprintf("too many concurrent requests from %s, limit is %d", ip, max), 1)
+			responsewriters.ErrorNegotiated(err, s, schema.GroupVersion{}, w, req)
+			return
+		}
+		defer limiter.release(ip)
+		handler.ServeHTTP(w, req)
+	})
+}
+
+func sourceIP(req *http.Request, trustForwarded bool) string {
+	if trustForwarded {
+		if ip := utilnet.GetClientIP(req); ip != nil {
+			return ip.String()
+		}
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// ipLimiter counts the requests in flight for each IP.
+type ipLimiter struct {
+	max int
+
+	mu       sync.Mutex
+	inflight map[string]int
+}
+
+func (l *ipLimiter) acquire(ip string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.inflight[ip] >= l.max {
+		return false
+	}
+	l.inflight[ip]++
+	return true
+}
+
+func (l *ipLimiter) release(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// IPs without requests in flight are forgotten, so that the map does not grow
+	if l.inflight[ip]--; l.inflight[ip] <= 0 {
+		delete(l.inflight, ip)
+	}
+}
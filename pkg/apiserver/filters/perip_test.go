@@ -0,0 +1,107 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filters
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+)
+
+// blockingServer serves requests once release is closed, and counts the ones it is serving.
+func blockingServer(t *testing.T, max int, trustForwarded bool) (*httptest.Server, *sync.WaitGroup, chan struct{}) {
+	scheme := runtime.NewScheme()
+	scheme.AddUnversionedTypes(schema.GroupVersion{Version: "v1"}, &metav1.Status{})
+	codecs := serializer.NewCodecFactory(scheme)
+	serving := &sync.WaitGroup{}
+	release := make(chan struct{})
+	blocking := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		serving.Done()
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})
+	server := httptest.NewServer(WithMaxConnectionsPerIP(blocking, max, trustForwarded, codecs))
+	t.Cleanup(server.Close)
+	return server, serving, release
+}
+
+func get(t *testing.T, url string, forwardedFor string) int {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	if forwardedFor != "" {
+		req.Header.Set(forwardedForHeader, forwardedFor)
+	}
+	// each request opens its own connection
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	response, err := client.Do(req)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	return response.StatusCode
+}
+
+func TestWithMaxConnectionsPerIP(t *testing.T) {
+	server, serving, release := blockingServer(t, 3, false)
+
+	statuses := make(chan int, 3)
+	serving.Add(3)
+	for i := 0; i < 3; i++ {
+		go func() { statuses <- get(t, server.URL, "") }()
+	}
+	serving.Wait()
+
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusTooManyRequests, get(t, server.URL, ""), "should have rejected the requests above the limit")
+	}
+	// the client IP reported by untrusted peers is ignored
+	assert.Equal(t, http.StatusTooManyRequests, get(t, server.URL, "10.0.0.1"), "should have identified the client by the peer IP")
+
+	close(release)
+	for i := 0; i < 3; i++ {
+		select {
+		case status := <-statuses:
+			assert.Equal(t, http.StatusOK, status, "should have served the requests within the limit")
+		case <-time.After(5 * time.Second):
+			t.Fatal("should have served the requests within the limit")
+		}
+	}
+	serving.Add(1)
+	assert.Equal(t, http.StatusOK, get(t, server.URL, ""), "should have served requests again once the others completed")
+}
+
+func TestWithMaxConnectionsPerIPForwarded(t *testing.T) {
+	server, serving, release := blockingServer(t, 1, true)
+	defer close(release)
+
+	serving.Add(1)
+	go get(t, server.URL, "10.0.0.1")
+	serving.Wait()
+
+	assert.Equal(t, http.StatusTooManyRequests, get(t, server.URL, "10.0.0.1"), "should have rejected the requests of the same client")
+	serving.Add(1)
+	go get(t, server.URL, "10.0.0.2")
+	serving.Wait()
+}
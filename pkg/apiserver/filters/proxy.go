@@ -0,0 +1,94 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filters
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+const (
+	forwardedForHeader = "X-Forwarded-For"
+	realIPHeader       = "X-Real-Ip"
+)
+
+// WithTrustedProxies only lets the handler see the client IP reported by proxies
+// in the given CIDRs.  The X-Forwarded-For and X-Real-IP headers, from which the
+// API server takes the client IP recorded in audit events, are dropped from requests
+// received from other peers, so that clients cannot spoof their IP.  For requests
+// received from trusted proxies, X-Forwarded-For is reduced to the client IP, which
+// is the last address not belonging to a trusted proxy.
+func WithTrustedProxies(handler http.Handler, trusted []*net.IPNet) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get(forwardedForHeader) == "" && req.Header.Get(realIPHeader) == "" {
+			handler.ServeHTTP(w, req)
+			return
+		}
+
+		req = req.Clone(req.Context())
+		if clientIP := forwardedClientIP(req, trusted); clientIP != nil {
+			req.Header.Set(forwardedForHeader, clientIP.String())
+		} else {
+			req.Header.Del(forwardedForHeader)
+		}
+		req.Header.Del(realIPHeader)
+		handler.ServeHTTP(w, req)
+	})
+}
+
+// forwardedClientIP returns the client IP reported in the headers of the request,
+// or nil if it was not received from a trusted proxy.
+func forwardedClientIP(req *http.Request, trusted []*net.IPNet) net.IP {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	peer := net.ParseIP(host)
+	if peer == nil || !isTrusted(peer, trusted) {
+		return nil
+	}
+
+	// each proxy appends the address it received the request from, so the
+	// addresses before the last untrusted one may have been forged
+	var forwarded []string
+	for _, values := range req.Header.Values(forwardedForHeader) {
+		forwarded = append(forwarded, strings.Split(values, ",")...)
+	}
+	if realIP := req.Header.Get(realIPHeader); len(forwarded) == 0 && realIP != "" {
+		forwarded = []string{realIP}
+	}
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(forwarded[i]))
+		if ip == nil {
+			return nil
+		}
+		if !isTrusted(ip, trusted) || i == 0 {
+			return ip
+		}
+	}
+	return nil
+}
+
+func isTrusted(ip net.IP, trusted []*net.IPNet) bool {
+	for _, cidr := range trusted {
+		if cidr.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
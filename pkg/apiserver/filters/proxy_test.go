@@ -0,0 +1,95 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filters
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apiutilnet "k8s.io/apimachinery/pkg/util/net"
+	utilnet "k8s.io/utils/net"
+)
+
+func TestWithTrustedProxies(t *testing.T) {
+	trusted, err := utilnet.ParseCIDRs([]string{"10.0.0.0/8", "fd00::/8"})
+	require.NoError(t, err)
+
+	cases := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		expectedIP string
+	}{
+		{
+			name:       "no proxy",
+			remoteAddr: "192.0.2.1:1234",
+			expectedIP: "192.0.2.1",
+		},
+		{
+			name:       "trusted proxy",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string]string{"X-Forwarded-For": "192.0.2.1"},
+			expectedIP: "192.0.2.1",
+		},
+		{
+			name:       "trusted proxies chain",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1, 192.0.2.1, 10.0.0.2"},
+			expectedIP: "192.0.2.1",
+		},
+		{
+			name:       "trusted IPv6 proxy with X-Real-IP",
+			remoteAddr: "[fd00::1]:1234",
+			headers:    map[string]string{"X-Real-Ip": "192.0.2.1"},
+			expectedIP: "192.0.2.1",
+		},
+		{
+			name:       "untrusted proxy",
+			remoteAddr: "192.0.2.2:1234",
+			headers:    map[string]string{"X-Forwarded-For": "192.0.2.1", "X-Real-Ip": "192.0.2.1"},
+			expectedIP: "192.0.2.2",
+		},
+		{
+			name:       "invalid forwarded address",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string]string{"X-Forwarded-For": "not-an-ip"},
+			expectedIP: "10.0.0.1",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var clientIP string
+			handler := WithTrustedProxies(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+				clientIP = apiutilnet.GetClientIP(req).String()
+			}), trusted)
+
+			req := httptest.NewRequest(http.MethodGet, "/apis/custom.metrics.k8s.io/v1beta2", nil)
+			req.RemoteAddr = c.remoteAddr
+			for name, value := range c.headers {
+				req.Header.Set(name, value)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, c.expectedIP, clientIP, "should have extracted the client IP")
+		})
+	}
+}
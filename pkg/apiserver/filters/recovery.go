@@ -0,0 +1,84 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filters
+
+import (
+	"fmt"
+	"net/http"
+
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/audit"
+	"k8s.io/apiserver/pkg/endpoints/handlers/responsewriters"
+	"k8s.io/klog/v2"
+)
+
+// PanicResponseDetail is how much of a panic is reported to the client.
+type PanicResponseDetail string
+
+const (
+	// PanicDetailNone keeps the generic response of the API server.
+	PanicDetailNone PanicResponseDetail = "none"
+	// PanicDetailReference reports the audit ID of the request, so that it can
+	// be correlated with the logs.
+	PanicDetailReference PanicResponseDetail = "reference"
+	// PanicDetailFull also reports the value of the panic.
+	PanicDetailFull PanicResponseDetail = "full"
+)
+
+// PanicResponseDetails are the valid levels of detail.
+var PanicResponseDetails = []PanicResponseDetail{PanicDetailNone, PanicDetailReference, PanicDetailFull}
+
+// WithPanicResponse recovers the panics of the handler, answering with a 500
+// Internal Server Error reporting them with the given detail.  It must be
+// installed inside the audit filters for the audit ID to be known; the panics
+// it recovers are logged here rather than by the recovery filter of the API
+// server.  With PanicDetailNone, the handler is returned unchanged.
+func WithPanicResponse(handler http.Handler, detail PanicResponseDetail, s runtime.NegotiatedSerializer) http.Handler {
+	if detail == PanicDetailNone || detail == "" {
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			if r == http.ErrAbortHandler {
+				// aborted requests are left to the API server
+				panic(r)
+			}
+
+			auditID := audit.GetAuditIDTruncated(req.Context())
+			klog.ErrorS(nil, "Recovered from a panic while serving the request", "method", req.Method, "URI", req.RequestURI, "auditID", auditID, "panic", r)
+			responsewriters.ErrorNegotiated(panicError(detail, auditID, r), s, schema.GroupVersion{}, w, req)
+		}()
+		handler.ServeHTTP(w, req)
+	})
+}
+
+func panicError(detail PanicResponseDetail, auditID string, r interface{}) error {
+	if auditID == "" {
+		auditID = "unknown"
+	}
+	if detail == PanicDetailFull {
+		return apierr.NewInternalError(fmt.Errorf("the request caused a panic (audit ID %s): %v", auditID, r))
+	}
+	return apierr.NewInternalError(fmt.Errorf("the request caused a panic, look in the logs for audit ID %s", auditID))
+}
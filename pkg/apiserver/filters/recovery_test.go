@@ -0,0 +1,87 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filters
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apiserver/pkg/audit"
+)
+
+func panicResponse(detail PanicResponseDetail) *httptest.ResponseRecorder {
+	scheme := runtime.NewScheme()
+	scheme.AddUnversionedTypes(schema.GroupVersion{Version: "v1"}, &metav1.Status{})
+	codecs := serializer.NewCodecFactory(scheme)
+	panicking := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("provider secret: hunter2")
+	})
+	handler := WithPanicResponse(panicking, detail, codecs)
+
+	req := httptest.NewRequest(http.MethodGet, "/apis/custom.metrics.k8s.io/v1beta2/namespaces/default/pods/*/qps", nil)
+	ctx := audit.WithAuditContext(req.Context())
+	audit.WithAuditID(ctx, types.UID("4d3c2b1a"))
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req.WithContext(ctx))
+	return response
+}
+
+func panicStatus(t *testing.T, response *httptest.ResponseRecorder) *metav1.Status {
+	require.Equal(t, http.StatusInternalServerError, response.Code)
+	status := &metav1.Status{}
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), status), "should have answered with a status: %s", response.Body.String())
+	return status
+}
+
+func TestWithPanicResponse(t *testing.T) {
+	t.Run("none", func(t *testing.T) {
+		assert.Panics(t, func() { panicResponse(PanicDetailNone) }, "should have left the panic to the API server")
+	})
+
+	t.Run("reference", func(t *testing.T) {
+		status := panicStatus(t, panicResponse(PanicDetailReference))
+		assert.Contains(t, status.Message, "4d3c2b1a", "should have reported the audit ID")
+		assert.NotContains(t, status.Message, "hunter2", "should not have reported the panic")
+	})
+
+	t.Run("full", func(t *testing.T) {
+		status := panicStatus(t, panicResponse(PanicDetailFull))
+		assert.Contains(t, status.Message, "4d3c2b1a", "should have reported the audit ID")
+		assert.Contains(t, status.Message, "hunter2", "should have reported the panic")
+	})
+}
+
+func TestWithPanicResponseAbort(t *testing.T) {
+	aborting := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic(http.ErrAbortHandler)
+	})
+	handler := WithPanicResponse(aborting, PanicDetailFull, serializer.NewCodecFactory(runtime.NewScheme()))
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}, "should have left aborted requests to the API server")
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package filters provides HTTP filters protecting the metrics API server.
+package filters
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/endpoints/handlers/responsewriters"
+)
+
+// selectorParameters are the query parameters holding selectors.
+var selectorParameters = []string{"labelSelector", "fieldSelector", "metricLabelSelector"}
+
+// RequestSizeLimits bounds the size of requests.  Zero values disable the
+// corresponding limit.
+type RequestSizeLimits struct {
+	// MaxRequestBytes limits the size of the request URI plus the request body.
+	MaxRequestBytes int64
+	// MaxSelectorLength limits the length of each selector query parameter.
+	MaxSelectorLength int
+}
+
+// WithRequestSizeLimits rejects requests above the given limits before passing
+// them to the handler: oversized requests are rejected with 413 Request Entity
+// Too Large, and oversized selectors with 400 Bad Request, before being parsed.
+func WithRequestSizeLimits(handler http.Handler, limits RequestSizeLimits, s runtime.NegotiatedSerializer) http.Handler {
+	if limits.MaxRequestBytes <= 0 && limits.MaxSelectorLength <= 0 {
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if err := checkRequestSize(req, limits); err != nil {
+			responsewriters.ErrorNegotiated(err, s, schema.GroupVersion{}, w, req)
+			return
+		}
+		if limits.MaxRequestBytes > 0 && req.Body != nil {
+			// the content length may be unknown, so also guard the body itself
+			req.Body = http.MaxBytesReader(w, req.Body, limits.MaxRequestBytes)
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
+
+func checkRequestSize(req *http.Request, limits RequestSizeLimits) error {
+	if limits.MaxRequestBytes > 0 {
+		size := int64(len(req.URL.RequestURI()))
+		if req.ContentLength > 0 {
+			size += req.ContentLength
+		}
+		if size > limits.MaxRequestBytes {
+			return apierr.NewRequestEntityTooLargeError(fmt.Sprintf("limit is %d bytes", limits.MaxRequestBytes))
+		}
+	}
+
+	if limits.MaxSelectorLength > 0 {
+		query, err := url.ParseQuery(req.URL.RawQuery)
+		if err != nil {
+			return apierr.NewBadRequest(fmt.Sprintf("invalid query: %v", err))
+		}
+		for _, param := range selectorParameters {
+			for _, selector := range query[param] {
+				if len(selector) > limits.MaxSelectorLength {
+					return apierr.NewBadRequest(fmt.Sprintf("%s is longer than %d characters", param, limits.MaxSelectorLength))
+				}
+			}
+		}
+	}
+	return nil
+}
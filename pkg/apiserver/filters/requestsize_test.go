@@ -0,0 +1,92 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filters
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+)
+
+func TestWithRequestSizeLimits(t *testing.T) {
+	codecs := serializer.NewCodecFactory(runtime.NewScheme())
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := WithRequestSizeLimits(ok, RequestSizeLimits{MaxRequestBytes: 256, MaxSelectorLength: 32}, codecs)
+	path := "/apis/custom.metrics.k8s.io/v1beta2/namespaces/default/pods/*/foo"
+
+	cases := []struct {
+		name   string
+		query  url.Values
+		status int
+	}{
+		{
+			name:   "no selector",
+			status: http.StatusOK,
+		},
+		{
+			name:   "short selector",
+			query:  url.Values{"labelSelector": {"app=web"}},
+			status: http.StatusOK,
+		},
+		{
+			name:   "oversized label selector",
+			query:  url.Values{"labelSelector": {"app=" + strings.Repeat("a", 32)}},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "oversized metric label selector",
+			query:  url.Values{"metricLabelSelector": {"app=" + strings.Repeat("a", 32)}},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "oversized request",
+			query:  url.Values{"foo": {strings.Repeat("a", 256)}},
+			status: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, path+"?"+c.query.Encode(), nil)
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			assert.Equal(t, c.status, response.Code, "unexpected status: %s", response.Body.String())
+		})
+	}
+}
+
+func TestWithoutRequestSizeLimits(t *testing.T) {
+	codecs := serializer.NewCodecFactory(runtime.NewScheme())
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := WithRequestSizeLimits(ok, RequestSizeLimits{}, codecs)
+
+	req := httptest.NewRequest(http.MethodGet, "/apis?labelSelector=app="+strings.Repeat("a", 4096), nil)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+	assert.Equal(t, http.StatusOK, response.Code, "should not have limited requests without limits")
+}
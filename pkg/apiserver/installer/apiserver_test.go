@@ -18,15 +18,24 @@ package installer
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/emicklei/go-restful/v3"
+	"github.com/go-logr/logr/funcr"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -34,17 +43,29 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
 	genericapi "k8s.io/apiserver/pkg/endpoints"
 	genericapifilters "k8s.io/apiserver/pkg/endpoints/filters"
+	"k8s.io/apiserver/pkg/endpoints/request"
 	genericapiserver "k8s.io/apiserver/pkg/server"
+	"k8s.io/klog/v2"
 	"k8s.io/metrics/pkg/apis/custom_metrics"
 	installcm "k8s.io/metrics/pkg/apis/custom_metrics/install"
 	cmv1beta1 "k8s.io/metrics/pkg/apis/custom_metrics/v1beta1"
 	"k8s.io/metrics/pkg/apis/external_metrics"
 	installem "k8s.io/metrics/pkg/apis/external_metrics/install"
 	emv1beta1 "k8s.io/metrics/pkg/apis/external_metrics/v1beta1"
+	testingclock "k8s.io/utils/clock/testing"
 
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/cachecontrol"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/overrides"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/ratelimit"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/servertiming"
 	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider/caching"
 	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider/defaults"
 	custommetricstorage "sigs.k8s.io/custom-metrics-apiserver/pkg/registry/custom_metrics"
 	externalmetricstorage "sigs.k8s.io/custom-metrics-apiserver/pkg/registry/external_metrics"
@@ -131,10 +152,13 @@ func apiGroupVersion(gv schema.GroupVersion, groupInfo genericapiserver.APIGroup
 }
 
 func handleCustomMetrics(prov provider.CustomMetricsProvider) http.Handler {
+	return handleCustomMetricsStorage(prov, custommetricstorage.NewREST(prov))
+}
+
+func handleCustomMetricsStorage(prov provider.CustomMetricsProvider, resourceStorage *custommetricstorage.REST) http.Handler {
 	container := restful.NewContainer()
 	container.Router(restful.CurlyRouter{})
 	mux := container.ServeMux
-	resourceStorage := custommetricstorage.NewREST(prov)
 	group := &MetricsAPIGroupVersion{
 		DynamicStorage:  resourceStorage,
 		APIGroupVersion: apiGroupVersion(customMetricsGroupVersion, customMetricsGroupInfo),
@@ -153,10 +177,13 @@ func handleCustomMetrics(prov provider.CustomMetricsProvider) http.Handler {
 }
 
 func handleExternalMetrics(prov provider.ExternalMetricsProvider) http.Handler {
+	return handleExternalMetricsStorage(prov, externalmetricstorage.NewREST(prov))
+}
+
+func handleExternalMetricsStorage(prov provider.ExternalMetricsProvider, resourceStorage *externalmetricstorage.REST) http.Handler {
 	container := restful.NewContainer()
 	container.Router(restful.CurlyRouter{})
 	mux := container.ServeMux
-	resourceStorage := externalmetricstorage.NewREST(prov)
 
 	group := &MetricsAPIGroupVersion{
 		DynamicStorage:  resourceStorage,
@@ -175,15 +202,50 @@ func handleExternalMetrics(prov provider.ExternalMetricsProvider) http.Handler {
 	return handler
 }
 
+// fakeCMProvider serves the given values, keyed on the namespace, resource, name
+// and metric of the queries, the name being "*" for queries by selector.  Metrics
+// without values are served as neither a value nor an error, violating the
+// contract of providers.  It lists the given metrics, of the given types, and
+// reports its queries to onQuery, before failing them with err, if set.
 type fakeCMProvider struct {
 	rootValues             map[string][]custom_metrics.MetricValue
 	namespacedValues       map[string][]custom_metrics.MetricValue
 	rootSubsetCounts       map[string]int
 	namespacedSubsetCounts map[string]int
 
+	metrics      []provider.CustomMetricInfo
+	metricTypes  map[string]provider.MetricType
+	capabilities map[string]provider.MetricCapabilities
+	changed      chan struct{}
+	onQuery      func(ctx context.Context, query cmQuery)
+	err          error
+
+	mu    sync.Mutex
+	lists int
+
 	defaults.DefaultCustomMetricsProvider
 }
 
+// cmQuery is a query served by fakeCMProvider.
+type cmQuery struct {
+	method    string
+	namespace string
+	name      string
+	uid       types.UID
+	object    custom_metrics.ObjectReference
+	infos     []provider.CustomMetricInfo
+}
+
+func (p *fakeCMProvider) query(ctx context.Context, query cmQuery) error {
+	if p.onQuery != nil {
+		p.onQuery(ctx, query)
+	}
+	if p.err != nil {
+		return fmt.Errorf("querying backend: %w", p.err)
+	}
+	return nil
+}
+
 func (p *fakeCMProvider) valuesFor(name types.NamespacedName, info provider.CustomMetricInfo) (string, []custom_metrics.MetricValue, bool) {
 	if info.Namespaced {
 		metricID := name.Namespace + "/" + info.GroupResource.String() + "/" + name.Name + "/" + info.Metric
@@ -195,20 +257,26 @@ func (p *fakeCMProvider) valuesFor(name types.NamespacedName, info provider.Cust
 	return metricID, values, ok
 }
 
-func (p *fakeCMProvider) GetMetricByName(_ context.Context, name types.NamespacedName, info provider.CustomMetricInfo, _ labels.Selector) (*custom_metrics.MetricValue, error) {
+func (p *fakeCMProvider) valueFor(name types.NamespacedName, info provider.CustomMetricInfo) (*custom_metrics.MetricValue, error) {
 	metricID, values, ok := p.valuesFor(name, info)
 	if !ok {
 		return nil, fmt.Errorf("non-existent metric requested (id: %s)", metricID)
 	}
+	if len(values) == 0 {
+		return nil, nil
+	}
 
 	return &values[0], nil
 }
 
-func (p *fakeCMProvider) GetMetricBySelector(_ context.Context, namespace string, selector labels.Selector, info provider.CustomMetricInfo, _ labels.Selector) (*custom_metrics.MetricValueList, error) {
+func (p *fakeCMProvider) selectedValuesFor(namespace string, selector labels.Selector, info provider.CustomMetricInfo) (*custom_metrics.MetricValueList, error) {
 	metricID, values, ok := p.valuesFor(types.NamespacedName{Namespace: namespace, Name: "*"}, info)
 	if !ok {
 		return nil, fmt.Errorf("non-existent metric requested (id: %s)", metricID)
 	}
+	if len(values) == 0 {
+		return nil, nil
+	}
 
 	var trimmedValues custom_metrics.MetricValueList
 
@@ -243,6 +311,192 @@ func (p *fakeCMProvider) GetMetricBySelector(_ context.Context, namespace string
 	return &trimmedValues, nil
 }
 
+func (p *fakeCMProvider) GetMetricByName(ctx context.Context, name types.NamespacedName, info provider.CustomMetricInfo, _ labels.Selector) (*custom_metrics.MetricValue, error) {
+	if err := p.query(ctx, cmQuery{method: "GetMetricByName", namespace: name.Namespace, name: name.Name, infos: []provider.CustomMetricInfo{info}}); err != nil {
+		return nil, err
+	}
+	return p.valueFor(name, info)
+}
+
+func (p *fakeCMProvider) GetMetricBySelector(ctx context.Context, namespace string, selector labels.Selector, info provider.CustomMetricInfo, _ labels.Selector) (*custom_metrics.MetricValueList, error) {
+	if err := p.query(ctx, cmQuery{method: "GetMetricBySelector", namespace: namespace, name: "*", infos: []provider.CustomMetricInfo{info}}); err != nil {
+		return nil, err
+	}
+	return p.selectedValuesFor(namespace, selector, info)
+}
+
+func (p *fakeCMProvider) ListAllMetrics() []provider.CustomMetricInfo {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.lists++
+	return append([]provider.CustomMetricInfo(nil), p.metrics...)
+}
+
+func (p *fakeCMProvider) MetricType(info provider.CustomMetricInfo) provider.MetricType {
+	return p.metricTypes[info.Metric]
+}
+
+// addMetric lists the given metric, and signals the change on changed.
+func (p *fakeCMProvider) addMetric(info provider.CustomMetricInfo) {
+	p.mu.Lock()
+	p.metrics = append(p.metrics, info)
+	p.mu.Unlock()
+
+	select {
+	case p.changed <- struct{}{}:
+	default:
+	}
+}
+
+// The following extensions change how the provider is queried, or how its metrics
+// are listed, by their mere presence, so the fake only implements them when wrapped
+// in these types.
+
+// capableFakeCMProvider supports the queries of the capabilities of each metric.
+type capableFakeCMProvider struct {
+	*fakeCMProvider
+}
+
+func (p capableFakeCMProvider) Capabilities(info provider.CustomMetricInfo) provider.MetricCapabilities {
+	return p.capabilities[info.Metric]
+}
+
+// notifyingFakeCMProvider signals the changes of its metrics on changed.
+type notifyingFakeCMProvider struct {
+	*fakeCMProvider
+}
+
+func (p notifyingFakeCMProvider) MetricsChanged() <-chan struct{} {
+	return p.changed
+}
+
+// uidFakeCMProvider serves the values of the objects of the given names to the
+// queries by UID.
+type uidFakeCMProvider struct {
+	*fakeCMProvider
+}
+
+func (p uidFakeCMProvider) GetMetricByUID(ctx context.Context, name types.NamespacedName, uid types.UID, info provider.CustomMetricInfo, _ labels.Selector) (*custom_metrics.MetricValue, error) {
+	if err := p.query(ctx, cmQuery{method: "GetMetricByUID", namespace: name.Namespace, name: name.Name, uid: uid, infos: []provider.CustomMetricInfo{info}}); err != nil {
+		return nil, err
+	}
+	return p.valueFor(name, info)
+}
+
+// objectFakeCMProvider serves the values of the referenced objects, describing them
+// with their references.
+type objectFakeCMProvider struct {
+	*fakeCMProvider
+}
+
+func (p objectFakeCMProvider) GetMetricByObject(ctx context.Context, object custom_metrics.ObjectReference, info provider.CustomMetricInfo, _ labels.Selector) (*custom_metrics.MetricValue, error) {
+	if err := p.query(ctx, cmQuery{method: "GetMetricByObject", namespace: object.Namespace, name: object.Name, object: object, infos: []provider.CustomMetricInfo{info}}); err != nil {
+		return nil, err
+	}
+	value, err := p.valueFor(types.NamespacedName{Namespace: object.Namespace, Name: object.Name}, info)
+	if value == nil || err != nil {
+		return value, err
+	}
+	described := *value
+	described.DescribedObject = object
+	return &described, nil
+}
+
+// multiMetricFakeCMProvider serves the values of several metrics in one query.
+type multiMetricFakeCMProvider struct {
+	*fakeCMProvider
+}
+
+func (p multiMetricFakeCMProvider) GetMetricsByName(ctx context.Context, name types.NamespacedName, infos []provider.CustomMetricInfo, _ labels.Selector) (*custom_metrics.MetricValueList, error) {
+	if err := p.query(ctx, cmQuery{method: "GetMetricsByName", namespace: name.Namespace, name: name.Name, infos: infos}); err != nil {
+		return nil, err
+	}
+	res := &custom_metrics.MetricValueList{}
+	for _, info := range infos {
+		value, err := p.valueFor(name, info)
+		if err != nil {
+			return nil, err
+		}
+		if value != nil {
+			res.Items = append(res.Items, *value)
+		}
+	}
+	return res, nil
+}
+
+func (p multiMetricFakeCMProvider) GetMetricsBySelector(ctx context.Context, namespace string, selector labels.Selector, infos []provider.CustomMetricInfo, _ labels.Selector) (*custom_metrics.MetricValueList, error) {
+	if err := p.query(ctx, cmQuery{method: "GetMetricsBySelector", namespace: namespace, name: "*", infos: infos}); err != nil {
+		return nil, err
+	}
+	res := &custom_metrics.MetricValueList{}
+	for _, info := range infos {
+		values, err := p.selectedValuesFor(namespace, selector, info)
+		if err != nil {
+			return nil, err
+		}
+		if values != nil {
+			res.Items = append(res.Items, values.Items...)
+		}
+	}
+	return res, nil
+}
+
+// fakeEMProvider serves the given values of each external metric, and lists these
+// metrics.  Like fakeCMProvider, it reports its queries to onQuery, before failing
+// them with err, if set.
+type fakeEMProvider struct {
+	values  map[string][]external_metrics.ExternalMetricValue
+	stream  chan external_metrics.ExternalMetricValue
+	onQuery func(ctx context.Context, namespace string, info provider.ExternalMetricInfo)
+	err     error
+}
+
+func (p *fakeEMProvider) query(ctx context.Context, namespace string, info provider.ExternalMetricInfo) error {
+	if p.onQuery != nil {
+		p.onQuery(ctx, namespace, info)
+	}
+	if p.err != nil {
+		return fmt.Errorf("querying backend: %w", p.err)
+	}
+	return nil
+}
+
+func (p *fakeEMProvider) GetExternalMetric(ctx context.Context, namespace string, _ labels.Selector, info provider.ExternalMetricInfo) (*external_metrics.ExternalMetricValueList, error) {
+	if err := p.query(ctx, namespace, info); err != nil {
+		return nil, err
+	}
+	// a nil list is treated as an empty one
+	if len(p.values[info.Metric]) == 0 {
+		return nil, nil
+	}
+	return &external_metrics.ExternalMetricValueList{Items: p.values[info.Metric]}, nil
+}
+
+func (p *fakeEMProvider) ListAllExternalMetrics() []provider.ExternalMetricInfo {
+	infos := make([]provider.ExternalMetricInfo, 0, len(p.values))
+	for _, metric := range sets.List(sets.KeySet(p.values)) {
+		infos = append(infos, provider.ExternalMetricInfo{Metric: metric})
+	}
+	return infos
+}
+
+// streamingFakeEMProvider streams the values sent on stream.
+type streamingFakeEMProvider struct {
+	*fakeEMProvider
+}
+
+func (p streamingFakeEMProvider) GetExternalMetric(_ context.Context, _ string, _ labels.Selector, _ provider.ExternalMetricInfo) (*external_metrics.ExternalMetricValueList, error) {
+	return nil, fmt.Errorf("values should have been streamed")
+}
+
+func (p streamingFakeEMProvider) StreamExternalMetric(ctx context.Context, namespace string, _ labels.Selector, info provider.ExternalMetricInfo) (<-chan external_metrics.ExternalMetricValue, error) {
+	if err := p.query(ctx, namespace, info); err != nil {
+		return nil, err
+	}
+	return p.stream, nil
+}
+
 type T struct {
 	Method        string
 	Path          string
@@ -317,46 +571,1736 @@ func TestCustomMetricsAPI(t *testing.T) {
 	}
 }
 
-func TestExternalMetricsAPI(t *testing.T) {
-	cases := map[string]T{
-		// checks which should fail
+func TestCustomMetricsAPIRateLimit(t *testing.T) {
+	prov := &fakeCMProvider{
+		rootValues: map[string][]custom_metrics.MetricValue{
+			"nodes/foo/some-metric": make([]custom_metrics.MetricValue, 1),
+		},
+		namespacedValues: map[string][]custom_metrics.MetricValue{
+			"ns/pods/foo/some-metric":  make([]custom_metrics.MetricValue, 1),
+			"ns/pods/foo/other-metric": make([]custom_metrics.MetricValue, 1),
+		},
+	}
+	storage := custommetricstorage.NewREST(prov)
+	storage.RateLimiter = ratelimit.NewMetricRateLimiter(ratelimit.Limits{"some-metric": 0.1, "other-metric": 0.1})
 
-		"GET long prefix":             {"GET", "/" + prefix + "/", http.StatusNotFound, 0},
-		"GET at root scope":           {"GET", "/" + prefix + "/" + externalMetricsGroupVersion.Group + "/" + externalMetricsGroupVersion.Version + "/nonexistent-metric", http.StatusNotFound, 0},
-		"GET without metric name":     {"GET", "/" + prefix + "/" + externalMetricsGroupVersion.Group + "/" + externalMetricsGroupVersion.Version + "/namespaces/foo", http.StatusNotFound, 0},
-		"GET for metric with slashes": {"GET", "/" + prefix + "/" + externalMetricsGroupVersion.Group + "/" + externalMetricsGroupVersion.Version + "/namespaces/foo/group/metric", http.StatusNotFound, 0},
+	server := httptest.NewServer(handleCustomMetricsStorage(prov, storage))
+	defer server.Close()
+	client := http.Client{}
+	basePath := "/" + prefix + "/" + customMetricsGroupVersion.Group + "/" + customMetricsGroupVersion.Version
 
-		// Positive checks to make sure everything is wired correctly
-		"GET for external metric":               {"GET", "/" + prefix + "/" + externalMetricsGroupVersion.Group + "/" + externalMetricsGroupVersion.Version + "/namespaces/default/my-external-metric", http.StatusOK, 2},
-		"GET for external metric with selector": {"GET", "/" + prefix + "/" + externalMetricsGroupVersion.Group + "/" + externalMetricsGroupVersion.Version + "/namespaces/default/my-external-metric?labelSelector=foo%3Dbar", http.StatusOK, 1},
-		"GET for nonexistent metric":            {"GET", "/" + prefix + "/" + externalMetricsGroupVersion.Group + "/" + externalMetricsGroupVersion.Version + "/namespaces/foo/nonexistent-metric", http.StatusOK, 0},
+	if _, err := executeRequest(t, "first pod request", T{"GET", basePath + "/namespaces/ns/pods/foo/some-metric", http.StatusOK, 0}, server, &client); err != nil {
+		t.Fatalf(err.Error())
+	}
+	response, err := executeRequest(t, "second pod request", T{"GET", basePath + "/namespaces/ns/pods/foo/some-metric", http.StatusTooManyRequests, 0}, server, &client)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if retryAfter := response.Header.Get("Retry-After"); retryAfter != "10" {
+		t.Errorf("Expected Retry-After 10, got %q", retryAfter)
 	}
 
-	// "real" fake provider implementation can be used in test, because it doesn't have any dependencies.
-	// Note: this provider has a hardcoded list of external metrics.
-	prov, _ := sampleprovider.NewFakeProvider(nil, nil)
+	// other metrics have their own limit
+	if _, err := executeRequest(t, "other metric request", T{"GET", basePath + "/namespaces/ns/pods/foo/other-metric", http.StatusOK, 0}, server, &client); err != nil {
+		t.Errorf(err.Error())
+	}
+	if _, err := executeRequest(t, "node request", T{"GET", basePath + "/nodes/foo/some-metric", http.StatusOK, 0}, server, &client); err != nil {
+		t.Errorf(err.Error())
+	}
+}
 
-	server := httptest.NewServer(handleExternalMetrics(prov))
+func TestMetricsAPIAllowedNamespaces(t *testing.T) {
+	cmProv := &fakeCMProvider{
+		rootValues: map[string][]custom_metrics.MetricValue{
+			"nodes/foo/some-metric": make([]custom_metrics.MetricValue, 1),
+		},
+		namespacedValues: map[string][]custom_metrics.MetricValue{
+			"allowed/pods/foo/some-metric": make([]custom_metrics.MetricValue, 1),
+			"denied/pods/foo/some-metric":  make([]custom_metrics.MetricValue, 1),
+		},
+	}
+	cmStorage := custommetricstorage.NewREST(cmProv)
+	cmStorage.AllowedNamespaces = sets.New("allowed", "default")
+	cmServer := httptest.NewServer(handleCustomMetricsStorage(cmProv, cmStorage))
+	defer cmServer.Close()
+
+	emProv, _ := sampleprovider.NewFakeProvider(nil, nil)
+	emStorage := externalmetricstorage.NewREST(emProv)
+	emStorage.AllowedNamespaces = sets.New("allowed", "default")
+	emServer := httptest.NewServer(handleExternalMetricsStorage(emProv, emStorage))
+	defer emServer.Close()
+
+	client := http.Client{}
+	cmPath := "/" + prefix + "/" + customMetricsGroupVersion.Group + "/" + customMetricsGroupVersion.Version
+	emPath := "/" + prefix + "/" + externalMetricsGroupVersion.Group + "/" + externalMetricsGroupVersion.Version
+	for k, v := range map[string]struct {
+		server *httptest.Server
+		T
+	}{
+		"allowed custom namespace":   {cmServer, T{"GET", cmPath + "/namespaces/allowed/pods/foo/some-metric", http.StatusOK, 1}},
+		"denied custom namespace":    {cmServer, T{"GET", cmPath + "/namespaces/denied/pods/foo/some-metric", http.StatusForbidden, 0}},
+		"root-scoped resource":       {cmServer, T{"GET", cmPath + "/nodes/foo/some-metric", http.StatusOK, 1}},
+		"allowed external namespace": {emServer, T{"GET", emPath + "/namespaces/default/my-external-metric", http.StatusOK, 2}},
+		"denied external namespace":  {emServer, T{"GET", emPath + "/namespaces/denied/my-external-metric", http.StatusForbidden, 0}},
+	} {
+		if _, err := executeRequest(t, k, v.T, v.server, &client); err != nil {
+			t.Errorf(err.Error())
+		}
+	}
+}
+
+func TestCustomMetricsAPIDefaultNamespace(t *testing.T) {
+	cmProv := &fakeCMProvider{
+		rootValues: map[string][]custom_metrics.MetricValue{
+			"nodes/foo/some-metric": make([]custom_metrics.MetricValue, 1),
+		},
+		namespacedValues: map[string][]custom_metrics.MetricValue{
+			"legacy/pods/foo/some-metric": make([]custom_metrics.MetricValue, 1),
+			"legacy/pods/*/some-metric":   make([]custom_metrics.MetricValue, 3),
+		},
+		metrics: []provider.CustomMetricInfo{
+			{GroupResource: schema.GroupResource{Resource: "pods"}, Namespaced: true, Metric: "some-metric"},
+			{GroupResource: schema.GroupResource{Resource: "nodes"}, Namespaced: false, Metric: "some-metric"},
+		},
+	}
+	cmStorage := custommetricstorage.NewREST(cmProv)
+	cmStorage.DefaultNamespace = "legacy"
+	server := httptest.NewServer(genericapifilters.WithWarningRecorder(handleCustomMetricsStorage(cmProv, cmStorage)))
 	defer server.Close()
+
 	client := http.Client{}
-	for k, v := range cases {
-		response, err := executeRequest(t, k, v, server, &client)
+	cmPath := "/" + prefix + "/" + customMetricsGroupVersion.Group + "/" + customMetricsGroupVersion.Version
+	for k, v := range map[string]struct {
+		T
+		warned bool
+	}{
+		"default namespace by name":     {T{"GET", cmPath + "/pods/foo/some-metric", http.StatusOK, 1}, true},
+		"default namespace by selector": {T{"GET", cmPath + "/pods/*/some-metric", http.StatusOK, 3}, true},
+		"explicit namespace":            {T{"GET", cmPath + "/namespaces/legacy/pods/foo/some-metric", http.StatusOK, 1}, false},
+		"root-scoped resource":          {T{"GET", cmPath + "/nodes/foo/some-metric", http.StatusOK, 1}, false},
+	} {
+		response, err := executeRequest(t, k, v.T, server, &client)
 		if err != nil {
 			t.Errorf(err.Error())
 			continue
 		}
-		if v.ExpectedCount > 0 {
-			lst := &emv1beta1.ExternalMetricValueList{}
-			if err := extractBody(response, lst); err != nil {
-				t.Errorf("unexpected error (%s): %v", k, err)
-				continue
+		list := &cmv1beta1.MetricValueList{}
+		if err := extractBody(response, list); err != nil {
+			t.Errorf("unexpected error (%s): %v", k, err)
+			continue
+		}
+		if len(list.Items) != v.ExpectedCount {
+			t.Errorf("expected %d items for %s, got %d", v.ExpectedCount, k, len(list.Items))
+		}
+		if warned := response.Header.Get("Warning") != ""; warned != v.warned {
+			t.Errorf("expected a deprecation warning for %s: %t, got %q", k, v.warned, response.Header.Get("Warning"))
+		}
+	}
+}
+
+func TestMetricsAPITestOverrides(t *testing.T) {
+	prov := &fakeCMProvider{
+		namespacedValues: map[string][]custom_metrics.MetricValue{
+			"ns/pods/foo/some-metric": {{Value: resource.MustParse("1")}},
+		},
+	}
+	emProv, _ := sampleprovider.NewFakeProvider(nil, nil)
+	serve := func(enabled bool) (*httptest.Server, *httptest.Server) {
+		cmServer := httptest.NewServer(genericapifilters.WithWarningRecorder(overrides.WithOverrides(handleCustomMetrics(prov), enabled, Codecs)))
+		emServer := httptest.NewServer(genericapifilters.WithWarningRecorder(overrides.WithOverrides(handleExternalMetrics(emProv), enabled, Codecs)))
+		return cmServer, emServer
+	}
+	enabledCM, enabledEM := serve(true)
+	defer enabledCM.Close()
+	defer enabledEM.Close()
+	disabledCM, disabledEM := serve(false)
+	defer disabledCM.Close()
+	defer disabledEM.Close()
+
+	cmPath := "/" + prefix + "/" + customMetricsGroupVersion.Group + "/" + customMetricsGroupVersion.Version + "/namespaces/ns/pods"
+	emPath := "/" + prefix + "/" + externalMetricsGroupVersion.Group + "/" + externalMetricsGroupVersion.Version + "/namespaces/default/my-external-metric"
+	get := func(server *httptest.Server, path, override string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if override != "" {
+			req.Header.Set(overrides.HeaderMetricOverride, override)
+		}
+		response, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return response
+	}
+
+	for _, tc := range []struct {
+		name     string
+		server   *httptest.Server
+		path     string
+		override string
+		code     int
+		values   map[string]string
+	}{
+		{name: "named object", server: enabledCM, path: cmPath + "/foo/some-metric", override: "42", code: http.StatusOK, values: map[string]string{"foo": "42"}},
+		{name: "listed named object", server: enabledCM, path: cmPath + "/bar/some-metric", override: "foo=1,bar=2", code: http.StatusOK, values: map[string]string{"bar": "2"}},
+		{name: "unlisted named object", server: enabledCM, path: cmPath + "/baz/some-metric", override: "foo=1,bar=2", code: http.StatusNotFound},
+		{name: "selected objects", server: enabledCM, path: cmPath + "/*/some-metric", override: "foo=1,bar=500m", code: http.StatusOK, values: map[string]string{"foo": "1", "bar": "500m"}},
+		{name: "selected objects without names", server: enabledCM, path: cmPath + "/*/some-metric", override: "42", code: http.StatusBadRequest},
+		{name: "malformed override", server: enabledCM, path: cmPath + "/foo/some-metric", override: "fast", code: http.StatusBadRequest},
+		{name: "without override", server: enabledCM, path: cmPath + "/foo/some-metric", code: http.StatusOK, values: map[string]string{"": "1"}},
+		{name: "disabled", server: disabledCM, path: cmPath + "/foo/some-metric", override: "42", code: http.StatusBadRequest},
+		{name: "disabled without override", server: disabledCM, path: cmPath + "/foo/some-metric", code: http.StatusOK, values: map[string]string{"": "1"}},
+	} {
+		response := get(tc.server, tc.path, tc.override)
+		if response.StatusCode != tc.code {
+			body, _ := extractBodyString(response)
+			t.Errorf("expected status %d for %s, got %d: %s", tc.code, tc.name, response.StatusCode, body)
+			continue
+		}
+		if tc.values == nil {
+			response.Body.Close()
+			continue
+		}
+		if warned := strings.Contains(response.Header.Get("Warning"), overrides.HeaderMetricOverride); warned != (tc.override != "") {
+			t.Errorf("expected a warning about the override for %s: %t, got %q", tc.name, tc.override != "", response.Header.Get("Warning"))
+		}
+		list := &cmv1beta1.MetricValueList{}
+		if err := extractBody(response, list); err != nil {
+			t.Errorf("unexpected error (%s): %v", tc.name, err)
+			continue
+		}
+		values := map[string]string{}
+		for _, item := range list.Items {
+			values[item.DescribedObject.Name] = item.Value.String()
+		}
+		if !reflect.DeepEqual(values, tc.values) {
+			t.Errorf("expected values %v for %s, got %v", tc.values, tc.name, values)
+		}
+	}
+
+	response := get(enabledEM, emPath, "7")
+	list := &emv1beta1.ExternalMetricValueList{}
+	if err := extractBody(response, list); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(list.Items) != 1 || list.Items[0].Value.String() != "7" || list.Items[0].MetricName != "my-external-metric" {
+		t.Errorf("expected the overridden external metric value 7, got %v", list.Items)
+	}
+	for name, tc := range map[string]struct {
+		server   *httptest.Server
+		override string
+	}{
+		"external override with names": {enabledEM, "foo=1"},
+		"disabled external override":   {disabledEM, "7"},
+	} {
+		response := get(tc.server, emPath, tc.override)
+		response.Body.Close()
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("expected status %d for %s, got %d", http.StatusBadRequest, name, response.StatusCode)
+		}
+	}
+}
+
+func TestMetricsAPICacheControl(t *testing.T) {
+	cmProv := &fakeCMProvider{
+		rootValues: map[string][]custom_metrics.MetricValue{
+			"namespaces/default/some-metric": make([]custom_metrics.MetricValue, 1),
+		},
+		namespacedValues: map[string][]custom_metrics.MetricValue{
+			"default/pods/foo/some-metric":  make([]custom_metrics.MetricValue, 1),
+			"default/pods/foo/other-metric": make([]custom_metrics.MetricValue, 1),
+		},
+	}
+	cmStorage := custommetricstorage.NewREST(cmProv)
+	cmStorage.MaxAges = cachecontrol.MaxAges{"some-metric": 30 * time.Second}
+	cmServer := httptest.NewServer(handleCustomMetricsStorage(cmProv, cmStorage))
+	defer cmServer.Close()
+
+	emProv, _ := sampleprovider.NewFakeProvider(nil, nil)
+	emStorage := externalmetricstorage.NewREST(emProv)
+	emStorage.MaxAges = cachecontrol.MaxAges{"my-external-metric": time.Minute}
+	emServer := httptest.NewServer(handleExternalMetricsStorage(emProv, emStorage))
+	defer emServer.Close()
+
+	client := http.Client{}
+	cmPath := "/" + prefix + "/" + customMetricsGroupVersion.Group + "/" + customMetricsGroupVersion.Version
+	emPath := "/" + prefix + "/" + externalMetricsGroupVersion.Group + "/" + externalMetricsGroupVersion.Version
+	for k, v := range map[string]struct {
+		server       *httptest.Server
+		cacheControl string
+		T
+	}{
+		"custom metric with max age":    {cmServer, "max-age=30", T{"GET", cmPath + "/namespaces/default/pods/foo/some-metric", http.StatusOK, 1}},
+		"custom metric without max age": {cmServer, "no-cache", T{"GET", cmPath + "/namespaces/default/pods/foo/other-metric", http.StatusOK, 1}},
+		"failed custom metric query":    {cmServer, "", T{"GET", cmPath + "/namespaces/default/pods/bar/some-metric", http.StatusInternalServerError, 0}},
+		// the request info is rewritten by the handler for metrics describing namespaces
+		"namespace metric with max age": {cmServer, "max-age=30", T{"GET", cmPath + "/namespaces/default/metrics/some-metric", http.StatusOK, 1}},
+		"external metric with max age":  {emServer, "max-age=60", T{"GET", emPath + "/namespaces/default/my-external-metric", http.StatusOK, 2}},
+	} {
+		response, err := executeRequest(t, k, v.T, v.server, &client)
+		if err != nil {
+			t.Errorf(err.Error())
+			continue
+		}
+		if cacheControl := response.Header.Get("Cache-Control"); cacheControl != v.cacheControl {
+			t.Errorf("%s: expected Cache-Control %q, got %q", k, v.cacheControl, cacheControl)
+		}
+	}
+}
+
+func TestMetricsAPIProvenance(t *testing.T) {
+	cmProv := &fakeCMProvider{
+		namespacedValues: map[string][]custom_metrics.MetricValue{
+			"default/pods/foo/some-metric": make([]custom_metrics.MetricValue, 1),
+		},
+	}
+	plainServer := httptest.NewServer(handleCustomMetrics(cmProv))
+	defer plainServer.Close()
+	cachingServer := httptest.NewServer(handleCustomMetrics(caching.NewCustomMetricsProvider(cmProv, time.Minute)))
+	defer cachingServer.Close()
+
+	// the values are served as fallback ones
+	emProv := &fakeEMProvider{
+		values: map[string][]external_metrics.ExternalMetricValue{
+			"my-external-metric": make([]external_metrics.ExternalMetricValue, 2),
+		},
+		onQuery: func(ctx context.Context, _ string, _ provider.ExternalMetricInfo) {
+			provider.SetProvenance(ctx, provider.ProvenanceFallback)
+		},
+	}
+	fallbackServer := httptest.NewServer(handleExternalMetrics(caching.NewExternalMetricsProvider(emProv, time.Minute)))
+	defer fallbackServer.Close()
+
+	client := http.Client{}
+	cmPath := "/" + prefix + "/" + customMetricsGroupVersion.Group + "/" + customMetricsGroupVersion.Version + "/namespaces/default/pods/foo/some-metric"
+	emPath := "/" + prefix + "/" + externalMetricsGroupVersion.Group + "/" + externalMetricsGroupVersion.Version + "/namespaces/default/my-external-metric"
+	// the requests are ordered, since the caching decorators serve the second ones from their cache
+	for _, c := range []struct {
+		name   string
+		server *httptest.Server
+		T
+		provenance string
+	}{
+		{"not recorded", plainServer, T{"GET", cmPath, http.StatusOK, 1}, ""},
+		{"backend", cachingServer, T{"GET", cmPath, http.StatusOK, 1}, "backend"},
+		{"cache", cachingServer, T{"GET", cmPath, http.StatusOK, 1}, "cache"},
+		{"fallback", fallbackServer, T{"GET", emPath, http.StatusOK, 2}, "fallback"},
+		{"cached fallback", fallbackServer, T{"GET", emPath, http.StatusOK, 2}, "cache"},
+	} {
+		response, err := executeRequest(t, c.name, c.T, c.server, &client)
+		if err != nil {
+			t.Errorf(err.Error())
+			continue
+		}
+		if provenance := response.Header.Get(ProvenanceHeader); provenance != c.provenance {
+			t.Errorf("Expected provenance %q, got %q (%s)", c.provenance, provenance, c.name)
+		}
+	}
+}
+
+func TestCustomMetricsAPIConcurrentRequests(t *testing.T) {
+	var calls atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	prov := &fakeCMProvider{
+		namespacedValues: map[string][]custom_metrics.MetricValue{
+			"ns/pods/foo/some-metric": make([]custom_metrics.MetricValue, 1),
+		},
+		onQuery: func(context.Context, cmQuery) {
+			if calls.Add(1) == 1 {
+				close(started)
 			}
-			if len(lst.Items) != v.ExpectedCount {
-				t.Errorf("Expected %d items, got %d (%s): %#v", v.ExpectedCount, len(lst.Items), k, lst.Items)
-				continue
+			<-release
+		},
+	}
+	server := httptest.NewServer(handleCustomMetrics(prov))
+	defer server.Close()
+	client := http.Client{}
+
+	path := "/" + prefix + "/" + customMetricsGroupVersion.Group + "/" + customMetricsGroupVersion.Version + "/namespaces/ns/pods/foo/some-metric"
+	const requests = 5
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := executeRequest(t, fmt.Sprintf("request %d", i), T{"GET", path, http.StatusOK, 1}, server, &client); err != nil {
+				t.Errorf(err.Error())
 			}
-		}
+		}(i)
+	}
+
+	select {
+	case <-started:
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatalf("timed out waiting for the provider to be queried")
+	}
+	// give the other requests time to reach the storage while the first one is in flight
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls := calls.Load(); calls != 1 {
+		t.Errorf("Expected identical concurrent requests to query the provider once, got %d queries", calls)
+	}
+}
+
+func TestMetricsAPIZeroMatchSelectors(t *testing.T) {
+	cmServer := httptest.NewServer(handleCustomMetrics(&fakeCMProvider{
+		namespacedValues: map[string][]custom_metrics.MetricValue{
+			"ns/pods/*/some-metric": {},
+		},
+	}))
+	defer cmServer.Close()
+	emServer := httptest.NewServer(handleExternalMetrics(&fakeEMProvider{}))
+	defer emServer.Close()
+
+	cmPath := "/" + prefix + "/" + customMetricsGroupVersion.Group + "/" + customMetricsGroupVersion.Version + "/namespaces/ns/pods/*/some-metric?labelSelector=app%3Dnone"
+	cmList := &cmv1beta1.MetricValueList{}
+	getMetricValues(t, "custom metrics", T{"GET", cmPath, http.StatusOK, 0}, cmServer, cmList)
+	if len(cmList.Items) != 0 {
+		t.Errorf("Expected no custom metric values, got %#v", cmList.Items)
+	}
+
+	emPath := "/" + prefix + "/" + externalMetricsGroupVersion.Group + "/" + externalMetricsGroupVersion.Version + "/namespaces/default/my-external-metric?labelSelector=app%3Dnone"
+	emList := &emv1beta1.ExternalMetricValueList{}
+	getMetricValues(t, "external metrics", T{"GET", emPath, http.StatusOK, 0}, emServer, emList)
+	if len(emList.Items) != 0 {
+		t.Errorf("Expected no external metric values, got %#v", emList.Items)
+	}
+}
+
+func TestCustomMetricsAPIScopes(t *testing.T) {
+	queries := make(chan cmQuery, 1)
+	prov := &fakeCMProvider{
+		rootValues: map[string][]custom_metrics.MetricValue{
+			"nodes/node-1/cpu_usage": make([]custom_metrics.MetricValue, 1),
+			"nodes/*/cpu_usage":      make([]custom_metrics.MetricValue, 3),
+		},
+		namespacedValues: map[string][]custom_metrics.MetricValue{
+			"ns/pods/foo/cpu_usage": make([]custom_metrics.MetricValue, 1),
+			"ns/pods/*/cpu_usage":   make([]custom_metrics.MetricValue, 2),
+		},
+		onQuery: func(_ context.Context, query cmQuery) {
+			queries <- query
+		},
+	}
+	server := httptest.NewServer(handleCustomMetrics(prov))
+	defer server.Close()
+
+	client := http.Client{}
+	basePath := "/" + prefix + "/" + customMetricsGroupVersion.Group + "/" + customMetricsGroupVersion.Version
+	for k, v := range map[string]struct {
+		T
+		namespace string
+		info      provider.CustomMetricInfo
+	}{
+		"single node": {
+			T{"GET", basePath + "/nodes/node-1/cpu_usage", http.StatusOK, 1},
+			"", provider.CustomMetricInfo{GroupResource: schema.GroupResource{Resource: "nodes"}, Namespaced: false, Metric: "cpu_usage"},
+		},
+		"all nodes": {
+			T{"GET", basePath + "/nodes/*/cpu_usage", http.StatusOK, 3},
+			"", provider.CustomMetricInfo{GroupResource: schema.GroupResource{Resource: "nodes"}, Namespaced: false, Metric: "cpu_usage"},
+		},
+		"single pod": {
+			T{"GET", basePath + "/namespaces/ns/pods/foo/cpu_usage", http.StatusOK, 1},
+			"ns", provider.CustomMetricInfo{GroupResource: schema.GroupResource{Resource: "pods"}, Namespaced: true, Metric: "cpu_usage"},
+		},
+		"all pods": {
+			T{"GET", basePath + "/namespaces/ns/pods/*/cpu_usage", http.StatusOK, 2},
+			"ns", provider.CustomMetricInfo{GroupResource: schema.GroupResource{Resource: "pods"}, Namespaced: true, Metric: "cpu_usage"},
+		},
+	} {
+		response, err := executeRequest(t, k, v.T, server, &client)
+		if err != nil {
+			t.Errorf(err.Error())
+			continue
+		}
+		list := &cmv1beta1.MetricValueList{}
+		if err := extractBody(response, list); err != nil {
+			t.Errorf("unexpected error (%s): %v", k, err)
+		} else if len(list.Items) != v.ExpectedCount {
+			t.Errorf("Expected %d values for %s, got %d", v.ExpectedCount, k, len(list.Items))
+		}
+
+		query := <-queries
+		if query.namespace != v.namespace {
+			t.Errorf("Expected the provider to be queried for %s with namespace %q, got %q", k, v.namespace, query.namespace)
+		}
+		if info := query.infos[0]; info != v.info {
+			t.Errorf("Expected the provider to be queried for %s with %#v, got %#v", k, v.info, info)
+		}
+	}
+}
+
+func TestCustomMetricsAPIObjectReferences(t *testing.T) {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Node"}, meta.RESTScopeRoot)
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, meta.RESTScopeNamespace)
+
+	queries := make(chan cmQuery, 1)
+	prov := objectFakeCMProvider{&fakeCMProvider{
+		rootValues: map[string][]custom_metrics.MetricValue{
+			"nodes/node-1/some-metric": make([]custom_metrics.MetricValue, 1),
+		},
+		namespacedValues: map[string][]custom_metrics.MetricValue{
+			"ns/pods/foo/some-metric":             make([]custom_metrics.MetricValue, 1),
+			"ns/deployments.apps/foo/some-metric": make([]custom_metrics.MetricValue, 1),
+		},
+		onQuery: func(_ context.Context, query cmQuery) {
+			queries <- query
+		},
+	}}
+	storage := custommetricstorage.NewREST(prov)
+	storage.RESTMapper = mapper
+	server := httptest.NewServer(handleCustomMetricsStorage(prov, storage))
+	defer server.Close()
+
+	client := http.Client{}
+	basePath := "/" + prefix + "/" + customMetricsGroupVersion.Group + "/" + customMetricsGroupVersion.Version
+	for k, v := range map[string]struct {
+		T
+		object custom_metrics.ObjectReference
+	}{
+		"pod": {
+			T{"GET", basePath + "/namespaces/ns/pods/foo/some-metric", http.StatusOK, 1},
+			custom_metrics.ObjectReference{APIVersion: "v1", Kind: "Pod", Namespace: "ns", Name: "foo"},
+		},
+		"deployment": {
+			T{"GET", basePath + "/namespaces/ns/deployments.apps/foo/some-metric", http.StatusOK, 1},
+			custom_metrics.ObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "ns", Name: "foo"},
+		},
+		"node": {
+			T{"GET", basePath + "/nodes/node-1/some-metric", http.StatusOK, 1},
+			custom_metrics.ObjectReference{APIVersion: "v1", Kind: "Node", Name: "node-1"},
+		},
+	} {
+		response, err := executeRequest(t, k, v.T, server, &client)
+		if err != nil {
+			t.Errorf(err.Error())
+			continue
+		}
+		if query := <-queries; query.method != "GetMetricByObject" || query.object != v.object {
+			t.Errorf("Expected the provider to be queried for %s with %#v, got %s with %#v", k, v.object, query.method, query.object)
+		}
+		list := &cmv1beta1.MetricValueList{}
+		if err := extractBody(response, list); err != nil {
+			t.Errorf("unexpected error (%s): %v", k, err)
+		} else if len(list.Items) != 1 || list.Items[0].DescribedObject.APIVersion != v.object.APIVersion || list.Items[0].DescribedObject.Kind != v.object.Kind {
+			t.Errorf("Expected the value for %s to describe %#v, got %#v", k, v.object, list.Items)
+		}
+	}
+
+	if _, err := executeRequest(t, "unknown resource", T{"GET", basePath + "/namespaces/ns/widgets/foo/some-metric", http.StatusNotFound, 0}, server, &client); err != nil {
+		t.Errorf(err.Error())
+	}
+	if len(queries) != 0 {
+		t.Errorf("Expected the provider not to be queried for objects of unknown resources")
+	}
+}
+
+func TestCustomMetricsAPIObjectReferencesWithoutRESTMapper(t *testing.T) {
+	queries := make(chan cmQuery, 1)
+	prov := objectFakeCMProvider{&fakeCMProvider{
+		namespacedValues: map[string][]custom_metrics.MetricValue{
+			"ns/pods/foo/some-metric": make([]custom_metrics.MetricValue, 1),
+		},
+		onQuery: func(_ context.Context, query cmQuery) {
+			queries <- query
+		},
+	}}
+	server := httptest.NewServer(handleCustomMetrics(prov))
+	defer server.Close()
+
+	path := "/" + prefix + "/" + customMetricsGroupVersion.Group + "/" + customMetricsGroupVersion.Version + "/namespaces/ns/pods/foo/some-metric"
+	if _, err := executeRequest(t, "pod", T{"GET", path, http.StatusOK, 1}, server, &http.Client{}); err != nil {
+		t.Errorf(err.Error())
+	}
+	if query := <-queries; query.method != "GetMetricByName" {
+		t.Errorf("Expected the provider to be queried by name without RESTMapper, got %s", query.method)
+	}
+}
+
+func TestCustomMetricsAPIObjectUID(t *testing.T) {
+	described := func(name string, uid types.UID) custom_metrics.MetricValue {
+		return custom_metrics.MetricValue{DescribedObject: custom_metrics.ObjectReference{Kind: "Pod", Namespace: "ns", Name: name, UID: uid}}
+	}
+	prov := &fakeCMProvider{
+		namespacedValues: map[string][]custom_metrics.MetricValue{
+			// the value of foo is for a former pod of the same name
+			"ns/pods/foo/some-metric": {described("foo", "old-uid")},
+			"ns/pods/*/some-metric":   {described("foo", "old-uid"), described("bar", "bar-uid"), described("baz", "")},
+		},
+	}
+	server := httptest.NewServer(handleCustomMetrics(prov))
+	defer server.Close()
+
+	client := http.Client{}
+	basePath := "/" + prefix + "/" + customMetricsGroupVersion.Group + "/" + customMetricsGroupVersion.Version + "/namespaces/ns/pods"
+	for k, v := range map[string]T{
+		"by name without UID":         {"GET", basePath + "/foo/some-metric", http.StatusOK, 1},
+		"by name with the same UID":   {"GET", basePath + "/foo/some-metric?fieldSelector=metadata.uid%3Dold-uid", http.StatusOK, 1},
+		"by name with a reused name":  {"GET", basePath + "/foo/some-metric?fieldSelector=metadata.uid%3Dnew-uid", http.StatusNotFound, 0},
+		"by selector with UID":        {"GET", basePath + "/*/some-metric?fieldSelector=metadata.uid%3Dold-uid", http.StatusOK, 2},
+		"by name with UID inequality": {"GET", basePath + "/foo/some-metric?fieldSelector=metadata.uid!%3Dold-uid", http.StatusBadRequest, 0},
+	} {
+		response, err := executeRequest(t, k, v, server, &client)
+		if err != nil {
+			t.Errorf(err.Error())
+			continue
+		}
+		if v.Status != http.StatusOK {
+			continue
+		}
+		list := &cmv1beta1.MetricValueList{}
+		if err := extractBody(response, list); err != nil {
+			t.Errorf("unexpected error (%s): %v", k, err)
+		} else if len(list.Items) != v.ExpectedCount {
+			t.Errorf("Expected %d items for %s, got %d", v.ExpectedCount, k, len(list.Items))
+		}
+	}
+
+	// providers keyed on UIDs are queried with the selected one
+	uids := make(chan types.UID, 1)
+	uidServer := httptest.NewServer(handleCustomMetrics(uidFakeCMProvider{&fakeCMProvider{
+		namespacedValues: map[string][]custom_metrics.MetricValue{
+			"ns/pods/foo/some-metric": {described("foo", "")},
+		},
+		onQuery: func(_ context.Context, query cmQuery) {
+			uids <- query.uid
+		},
+	}}))
+	defer uidServer.Close()
+	if _, err := executeRequest(t, "by UID", T{"GET", basePath + "/foo/some-metric?fieldSelector=metadata.uid%3Dnew-uid", http.StatusOK, 1}, uidServer, &client); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if uid := <-uids; uid != "new-uid" {
+		t.Errorf("Expected the provider to be queried for UID new-uid, got %q", uid)
+	}
+}
+
+func TestCustomMetricsAPIServerTiming(t *testing.T) {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, meta.RESTScopeNamespace)
+
+	prov := objectFakeCMProvider{&fakeCMProvider{
+		namespacedValues: map[string][]custom_metrics.MetricValue{
+			"ns/pods/foo/some-metric": make([]custom_metrics.MetricValue, 1),
+		},
+	}}
+	storage := custommetricstorage.NewREST(prov)
+	storage.RESTMapper = mapper
+	server := httptest.NewServer(servertiming.WithServerTiming(handleCustomMetricsStorage(prov, storage)))
+	defer server.Close()
+
+	client := http.Client{}
+	basePath := "/" + prefix + "/" + customMetricsGroupVersion.Group + "/" + customMetricsGroupVersion.Version
+	response, err := executeRequest(t, "pod", T{"GET", basePath + "/namespaces/ns/pods/foo/some-metric", http.StatusOK, 1}, server, &client)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	header := response.Header.Get(servertiming.HeaderServerTiming)
+	for _, phase := range []string{servertiming.PhaseMapper, servertiming.PhaseProvider} {
+		if !strings.Contains(header, phase+";dur=") {
+			t.Errorf("Expected the %s header to report the %s phase, got %q", servertiming.HeaderServerTiming, phase, header)
+		}
+	}
+
+	response, err = executeRequest(t, "unknown path", T{"GET", "/unknown", http.StatusNotFound, 0}, server, &client)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if header := response.Header.Get(servertiming.HeaderServerTiming); header != "" {
+		t.Errorf("Expected no %s header for requests not reaching the storage, got %q", servertiming.HeaderServerTiming, header)
+	}
+}
+
+func TestCustomMetricsAPIMultipleMetrics(t *testing.T) {
+	queries := make(chan cmQuery, 2)
+	prov := &fakeCMProvider{
+		namespacedValues: map[string][]custom_metrics.MetricValue{
+			"ns/pods/foo/metric-a": make([]custom_metrics.MetricValue, 1),
+			"ns/pods/foo/metric-b": make([]custom_metrics.MetricValue, 1),
+			"ns/pods/*/metric-a":   make([]custom_metrics.MetricValue, 2),
+			"ns/pods/*/metric-b":   make([]custom_metrics.MetricValue, 3),
+		},
+		onQuery: func(_ context.Context, query cmQuery) {
+			queries <- query
+		},
+	}
+	batchedServer := httptest.NewServer(handleCustomMetrics(multiMetricFakeCMProvider{prov}))
+	defer batchedServer.Close()
+	fallbackServer := httptest.NewServer(handleCustomMetrics(prov))
+	defer fallbackServer.Close()
+
+	client := http.Client{}
+	basePath := "/" + prefix + "/" + customMetricsGroupVersion.Group + "/" + customMetricsGroupVersion.Version + "/namespaces/ns/pods"
+	for k, v := range map[string]struct {
+		server *httptest.Server
+		T
+		queries []string
+	}{
+		"batched by name":       {batchedServer, T{"GET", basePath + "/foo/metric-a,metric-b", http.StatusOK, 2}, []string{"GetMetricsByName metric-a,metric-b"}},
+		"batched by selector":   {batchedServer, T{"GET", basePath + "/*/metric-a,metric-b", http.StatusOK, 5}, []string{"GetMetricsBySelector metric-a,metric-b"}},
+		"batched single metric": {batchedServer, T{"GET", basePath + "/foo/metric-a", http.StatusOK, 1}, []string{"GetMetricByName metric-a"}},
+		"fallback by name":      {fallbackServer, T{"GET", basePath + "/foo/metric-a,metric-b", http.StatusOK, 2}, []string{"GetMetricByName metric-a", "GetMetricByName metric-b"}},
+		"fallback by selector":  {fallbackServer, T{"GET", basePath + "/*/metric-a,metric-b", http.StatusOK, 5}, []string{"GetMetricBySelector metric-a", "GetMetricBySelector metric-b"}},
+		"empty metric":          {fallbackServer, T{"GET", basePath + "/foo/metric-a,", http.StatusBadRequest, 0}, []string{}},
+	} {
+		response, err := executeRequest(t, k, v.T, v.server, &client)
+		if err != nil {
+			t.Errorf(err.Error())
+			continue
+		}
+		served := []string{}
+		for len(queries) > 0 {
+			query := <-queries
+			metrics := []string{}
+			for _, info := range query.infos {
+				metrics = append(metrics, info.Metric)
+			}
+			served = append(served, query.method+" "+strings.Join(metrics, ","))
+		}
+		if !reflect.DeepEqual(served, v.queries) {
+			t.Errorf("Expected the provider to serve the queries %v (%s), got %v", v.queries, k, served)
+		}
+		if v.Status != http.StatusOK {
+			continue
+		}
+		list := &cmv1beta1.MetricValueList{}
+		if err := extractBody(response, list); err != nil {
+			t.Errorf("unexpected error (%s): %v", k, err)
+		} else if len(list.Items) != v.ExpectedCount {
+			t.Errorf("Expected %d values for %s, got %d", v.ExpectedCount, k, len(list.Items))
+		}
+	}
+}
+
+func TestCustomMetricsAPIAuditID(t *testing.T) {
+	auditIDs := make(chan string, 1)
+	prov := &fakeCMProvider{
+		namespacedValues: map[string][]custom_metrics.MetricValue{
+			"ns/pods/foo/some-metric": make([]custom_metrics.MetricValue, 1),
+		},
+		onQuery: func(ctx context.Context, _ cmQuery) {
+			auditID, _ := provider.AuditIDFromContext(ctx)
+			auditIDs <- auditID
+		},
+	}
+	server := httptest.NewServer(genericapifilters.WithAuditInit(handleCustomMetrics(prov)))
+	defer server.Close()
+
+	path := "/" + prefix + "/" + customMetricsGroupVersion.Group + "/" + customMetricsGroupVersion.Version + "/namespaces/ns/pods/foo/some-metric"
+	request, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	request.Header.Set("Audit-ID", "test-audit-id")
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected %d, got %d", http.StatusOK, response.StatusCode)
+	}
+
+	if auditID := <-auditIDs; auditID != "test-audit-id" {
+		t.Errorf("Expected the provider to be queried with audit ID test-audit-id, got %q", auditID)
+	}
+}
+
+func TestMetricsAPIProviderErrors(t *testing.T) {
+	cmPath := "/" + prefix + "/" + customMetricsGroupVersion.Group + "/" + customMetricsGroupVersion.Version + "/namespaces/ns/pods/foo/some-metric"
+	emPath := "/" + prefix + "/" + externalMetricsGroupVersion.Group + "/" + externalMetricsGroupVersion.Version + "/namespaces/default/my-external-metric"
+
+	for _, tc := range []struct {
+		name       string
+		err        error
+		expected   int
+		retryAfter string
+	}{
+		{name: "retryable", err: provider.NewRetryableError(fmt.Errorf("backend is starting"), 5*time.Second), expected: http.StatusServiceUnavailable, retryAfter: "5"},
+		// partial seconds are rounded up
+		{name: "retryable after partial seconds", err: provider.NewRetryableError(fmt.Errorf("backend is starting"), 1500*time.Millisecond), expected: http.StatusServiceUnavailable, retryAfter: "2"},
+		{name: "deadline exceeded", err: context.DeadlineExceeded, expected: http.StatusGatewayTimeout},
+		{name: "canceled", err: context.Canceled, expected: 499},
+		{name: "other", err: fmt.Errorf("backend failure"), expected: http.StatusInternalServerError},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cmServer := httptest.NewServer(handleCustomMetrics(&fakeCMProvider{err: tc.err}))
+			defer cmServer.Close()
+			emServer := httptest.NewServer(handleExternalMetrics(&fakeEMProvider{err: tc.err}))
+			defer emServer.Close()
+			client := http.Client{}
+
+			for k, v := range map[string]struct {
+				server *httptest.Server
+				path   string
+			}{
+				"custom metrics":   {cmServer, cmPath},
+				"external metrics": {emServer, emPath},
+			} {
+				response, err := executeRequest(t, k, T{"GET", v.path, tc.expected, 0}, v.server, &client)
+				if err != nil {
+					t.Error(err)
+					continue
+				}
+				response.Body.Close()
+				if retryAfter := response.Header.Get("Retry-After"); retryAfter != tc.retryAfter {
+					t.Errorf("Expected Retry-After %q for %s, got %q", tc.retryAfter, k, retryAfter)
+				}
+			}
+		})
+	}
+}
+
+func TestCustomMetricsAPINilValue(t *testing.T) {
+	server := httptest.NewServer(handleCustomMetrics(&fakeCMProvider{
+		namespacedValues: map[string][]custom_metrics.MetricValue{
+			"ns/pods/foo/some-metric": {},
+		},
+	}))
+	defer server.Close()
+	client := http.Client{}
+
+	base := "/" + prefix + "/" + customMetricsGroupVersion.Group + "/" + customMetricsGroupVersion.Version + "/namespaces/ns/pods/foo/"
+	for _, path := range []string{base + "some-metric", base + "some-metric,other-metric"} {
+		response, err := executeRequest(t, path, T{"GET", path, http.StatusInternalServerError, 0}, server, &client)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		body, err := extractBodyString(response)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.Contains(body, "returned neither a value nor an error for metric some-metric of pods foo") {
+			t.Errorf("%s: expected the error to explain the provider returned no value, got %s", path, body)
+		}
+	}
+}
+
+func TestMetricsAPINoData(t *testing.T) {
+	cmProv := &fakeCMProvider{err: provider.NewNoDataError("some-metric", "nothing reported in the last 5m")}
+	cmServer := httptest.NewServer(genericapifilters.WithWarningRecorder(handleCustomMetrics(cmProv)))
+	defer cmServer.Close()
+	emProv := &fakeEMProvider{err: provider.NewNoDataError("my-external-metric", "")}
+	emServer := httptest.NewServer(genericapifilters.WithWarningRecorder(handleExternalMetrics(emProv)))
+	defer emServer.Close()
+
+	cmPath := "/" + prefix + "/" + customMetricsGroupVersion.Group + "/" + customMetricsGroupVersion.Version + "/namespaces/ns/pods/*/some-metric"
+	cmList := &cmv1beta1.MetricValueList{}
+	response := getMetricValues(t, "custom metrics", T{"GET", cmPath, http.StatusOK, 0}, cmServer, cmList)
+	if len(cmList.Items) != 0 {
+		t.Errorf("Expected no custom metric values, got %d", len(cmList.Items))
+	}
+	if warning := response.Header.Get("Warning"); !strings.Contains(warning, "the metric some-metric has no data: nothing reported in the last 5m") {
+		t.Errorf("Expected a no data warning for the custom metric, got %q", warning)
+	}
+
+	emPath := "/" + prefix + "/" + externalMetricsGroupVersion.Group + "/" + externalMetricsGroupVersion.Version + "/namespaces/default/my-external-metric"
+	emList := &emv1beta1.ExternalMetricValueList{}
+	response = getMetricValues(t, "external metrics", T{"GET", emPath, http.StatusOK, 0}, emServer, emList)
+	if len(emList.Items) != 0 {
+		t.Errorf("Expected no external metric values, got %d", len(emList.Items))
+	}
+	if warning := response.Header.Get("Warning"); !strings.Contains(warning, "the metric my-external-metric has no data") {
+		t.Errorf("Expected a no data warning for the external metric, got %q", warning)
+	}
+}
+
+func TestMetricsAPINamedLoggers(t *testing.T) {
+	var mu sync.Mutex
+	names := map[string]int{}
+	klog.SetLoggerWithOptions(funcr.New(func(prefix, _ string) {
+		mu.Lock()
+		defer mu.Unlock()
+		names[prefix]++
+	}, funcr.Options{Verbosity: 5}), klog.ContextualLogger(true))
+	defer klog.ClearLogger()
+
+	prov, _ := sampleprovider.NewFakeProvider(nil, nil)
+	server := httptest.NewServer(handleExternalMetrics(prov))
+	defer server.Close()
+
+	path := "/" + prefix + "/" + externalMetricsGroupVersion.Group + "/" + externalMetricsGroupVersion.Version + "/namespaces/default/my-external-metric"
+	if _, err := executeRequest(t, "external metrics", T{"GET", path, http.StatusOK, 2}, server, &http.Client{}); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, name := range []string{"installer", "provider"} {
+		if names[name] == 0 {
+			t.Errorf("Expected logs from the %s logger, got logs from %v", name, names)
+		}
+	}
+}
+
+func TestExternalMetricsAPIRequestLogSampling(t *testing.T) {
+	var mu sync.Mutex
+	messages := map[string]int{}
+	klog.SetLoggerWithOptions(funcr.New(func(_, args string) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, message := range []string{"querying external metrics provider", "external metrics provider returned an error"} {
+			if strings.Contains(args, message) {
+				messages[message]++
+			}
+		}
+	}, funcr.Options{Verbosity: 5}), klog.ContextualLogger(true))
+	defer klog.ClearLogger()
+
+	// 1 in 4 requests is sampled
+	var requests atomic.Int32
+	sampler := func() bool {
+		return requests.Add(1)%4 == 0
+	}
+	prov, _ := sampleprovider.NewFakeProvider(nil, nil)
+	storage := externalmetricstorage.NewREST(prov)
+	storage.LogSampler = sampler
+	server := httptest.NewServer(handleExternalMetricsStorage(prov, storage))
+	defer server.Close()
+	failingProv := &fakeEMProvider{err: fmt.Errorf("backend failure")}
+	failingStorage := externalmetricstorage.NewREST(failingProv)
+	failingStorage.LogSampler = sampler
+	failingServer := httptest.NewServer(handleExternalMetricsStorage(failingProv, failingStorage))
+	defer failingServer.Close()
+	client := http.Client{}
+
+	path := "/" + prefix + "/" + externalMetricsGroupVersion.Group + "/" + externalMetricsGroupVersion.Version + "/namespaces/default/my-external-metric"
+	for i := 0; i < 20; i++ {
+		if _, err := executeRequest(t, "external metrics", T{"GET", path, http.StatusOK, 2}, server, &client); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+	mu.Lock()
+	if queries := messages["querying external metrics provider"]; queries != 5 {
+		t.Errorf("Expected the queries of 5 of the 20 requests to be logged, got %d", queries)
+	}
+	mu.Unlock()
+
+	// failed queries are logged for all requests
+	for i := 0; i < 4; i++ {
+		if _, err := executeRequest(t, "failing external metrics", T{"GET", path, http.StatusInternalServerError, 0}, failingServer, &client); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if failures := messages["external metrics provider returned an error"]; failures != 4 {
+		t.Errorf("Expected the failures of all 4 requests to be logged, got %d", failures)
+	}
+}
+
+func scaleQuantity(q resource.Quantity, factor int64) resource.Quantity {
+	return *resource.NewMilliQuantity(q.MilliValue()*factor, q.Format)
+}
+
+func TestCustomMetricsAPITransform(t *testing.T) {
+	prov := &fakeCMProvider{
+		namespacedValues: map[string][]custom_metrics.MetricValue{
+			"ns/pods/foo/some-metric": {{Value: resource.MustParse("3")}},
+		},
+	}
+	storage := custommetricstorage.NewREST(prov)
+	storage.Transform = func(info provider.CustomMetricInfo, value custom_metrics.MetricValue) (custom_metrics.MetricValue, error) {
+		if info.Metric != "some-metric" || info.GroupResource.Resource != "pods" {
+			return value, fmt.Errorf("unexpected metric info %v", info)
+		}
+		value.Value = scaleQuantity(value.Value, 1000)
+		return value, nil
+	}
+
+	server := httptest.NewServer(handleCustomMetricsStorage(prov, storage))
+	defer server.Close()
+
+	path := "/" + prefix + "/" + customMetricsGroupVersion.Group + "/" + customMetricsGroupVersion.Version + "/namespaces/ns/pods/foo/some-metric"
+	lst := &cmv1beta1.MetricValueList{}
+	getMetricValues(t, "transformed", T{"GET", path, http.StatusOK, 1}, server, lst)
+	if len(lst.Items) != 1 || lst.Items[0].Value.Cmp(resource.MustParse("3k")) != 0 {
+		t.Errorf("Expected a single value of 3k, got %#v", lst.Items)
+	}
+}
+
+func TestCustomMetricsAPIDefaultWindow(t *testing.T) {
+	window := int64(30)
+	prov := &fakeCMProvider{
+		namespacedValues: map[string][]custom_metrics.MetricValue{
+			"ns/pods/*/some-metric": {
+				{DescribedObject: custom_metrics.ObjectReference{Name: "foo"}},
+				{DescribedObject: custom_metrics.ObjectReference{Name: "bar"}, WindowSeconds: &window},
+			},
+		},
+	}
+	storage := custommetricstorage.NewREST(prov)
+	storage.DefaultWindow = time.Minute
+
+	server := httptest.NewServer(handleCustomMetricsStorage(prov, storage))
+	defer server.Close()
+
+	path := "/" + prefix + "/" + customMetricsGroupVersion.Group + "/" + customMetricsGroupVersion.Version + "/namespaces/ns/pods/*/some-metric"
+	lst := &cmv1beta1.MetricValueList{}
+	getMetricValues(t, "default window", T{"GET", path, http.StatusOK, 2}, server, lst)
+	if len(lst.Items) != 2 {
+		t.Fatalf("Expected 2 values, got %#v", lst.Items)
+	}
+	if lst.Items[0].WindowSeconds == nil || *lst.Items[0].WindowSeconds != 60 {
+		t.Errorf("Expected the default window of 60s for a value without window, got %v", lst.Items[0].WindowSeconds)
+	}
+	if lst.Items[1].WindowSeconds == nil || *lst.Items[1].WindowSeconds != 30 {
+		t.Errorf("Expected the window of 30s set by the provider to be preserved, got %v", lst.Items[1].WindowSeconds)
+	}
+}
+
+func TestCustomMetricsAPISignificantDigits(t *testing.T) {
+	values := []string{"1234567m", "1235", "-1235", "999.5", "12", "0"}
+	for _, tc := range []struct {
+		digits   int
+		expected []string
+	}{
+		{digits: 1, expected: []string{"1k", "1k", "-1k", "1k", "10", "0"}},
+		{digits: 2, expected: []string{"1200", "1200", "-1200", "1k", "12", "0"}},
+		{digits: 3, expected: []string{"1230", "1240", "-1240", "1k", "12", "0"}},
+		{digits: 4, expected: []string{"1235", "1235", "-1235", "999500m", "12", "0"}},
+		{digits: 10, expected: []string{"1234567m", "1235", "-1235", "999500m", "12", "0"}},
+	} {
+		prov := &fakeCMProvider{namespacedValues: map[string][]custom_metrics.MetricValue{}}
+		for _, value := range values {
+			prov.namespacedValues["ns/pods/*/some-metric"] = append(prov.namespacedValues["ns/pods/*/some-metric"], custom_metrics.MetricValue{Value: resource.MustParse(value)})
+		}
+		storage := custommetricstorage.NewREST(prov)
+		storage.SignificantDigits = tc.digits
+
+		server := httptest.NewServer(handleCustomMetricsStorage(prov, storage))
+		path := "/" + prefix + "/" + customMetricsGroupVersion.Group + "/" + customMetricsGroupVersion.Version + "/namespaces/ns/pods/*/some-metric"
+		k := fmt.Sprintf("%d digits", tc.digits)
+		response, err := executeRequest(t, k, T{"GET", path, http.StatusOK, len(values)}, server, &http.Client{})
+		if err != nil {
+			server.Close()
+			t.Fatalf(err.Error())
+		}
+		lst := &cmv1beta1.MetricValueList{}
+		if err := extractBody(response, lst); err != nil {
+			t.Errorf("unexpected error (%s): %v", k, err)
+		}
+		server.Close()
+
+		rounded := []string{}
+		for _, value := range lst.Items {
+			rounded = append(rounded, value.Value.String())
+		}
+		if !reflect.DeepEqual(rounded, tc.expected) {
+			t.Errorf("Expected the values rounded to %s to be %v, got %v", k, tc.expected, rounded)
+		}
+	}
+}
+
+// fakeRequestCounter records the requests counted for each metric.
+type fakeRequestCounter struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (c *fakeRequestCounter) Count(metric, groupResource, verb string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[verb+" "+groupResource+"/"+metric]++
+}
+
+func TestCustomMetricsAPIRequestCounter(t *testing.T) {
+	prov := &fakeCMProvider{
+		namespacedValues: map[string][]custom_metrics.MetricValue{
+			"ns/pods/foo/metric-a":           make([]custom_metrics.MetricValue, 1),
+			"ns/pods/foo/metric-b":           make([]custom_metrics.MetricValue, 1),
+			"ns/deployments.apps/*/metric-a": make([]custom_metrics.MetricValue, 1),
+		},
+	}
+	counter := &fakeRequestCounter{counts: map[string]int{}}
+	storage := custommetricstorage.NewREST(prov)
+	storage.RequestCounter = counter
+	server := httptest.NewServer(handleCustomMetricsStorage(prov, storage))
+	defer server.Close()
+
+	basePath := "/" + prefix + "/" + customMetricsGroupVersion.Group + "/" + customMetricsGroupVersion.Version + "/namespaces/ns"
+	for k, v := range map[string]T{
+		"first by name":   {"GET", basePath + "/pods/foo/metric-a", http.StatusOK, 1},
+		"second by name":  {"GET", basePath + "/pods/foo/metric-a", http.StatusOK, 1},
+		"several metrics": {"GET", basePath + "/pods/foo/metric-a,metric-b", http.StatusOK, 2},
+		"by selector":     {"GET", basePath + "/deployments.apps/*/metric-a", http.StatusOK, 1},
+		"invalid metric":  {"GET", basePath + "/pods/foo/metric-a,", http.StatusBadRequest, 0},
+		"provider error":  {"GET", basePath + "/deployments.apps/foo/metric-b", http.StatusInternalServerError, 0},
+	} {
+		if _, err := executeRequest(t, k, v, server, &http.Client{}); err != nil {
+			t.Errorf(err.Error())
+		}
+	}
+
+	expected := map[string]int{
+		"get pods/metric-a":              3,
+		"get pods/metric-b":              1,
+		"list deployments.apps/metric-a": 1,
+		"get deployments.apps/metric-b":  1,
+	}
+	if !reflect.DeepEqual(counter.counts, expected) {
+		t.Errorf("Expected the requests to be counted per metric as %v, got %v", expected, counter.counts)
+	}
+}
+
+// fakeValueAgeRecorder records the timestamps recorded for each metric.
+type fakeValueAgeRecorder struct {
+	mu         sync.Mutex
+	timestamps map[string][]metav1.Time
+}
+
+func (r *fakeValueAgeRecorder) Record(apiGroup, metric string, timestamp metav1.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.timestamps[apiGroup+"/"+metric] = append(r.timestamps[apiGroup+"/"+metric], timestamp)
+}
+
+func TestMetricsAPIValueAgeRecorder(t *testing.T) {
+	older := metav1.NewTime(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	newer := metav1.NewTime(older.Add(30 * time.Second))
+	recorder := &fakeValueAgeRecorder{timestamps: map[string][]metav1.Time{}}
+
+	cmProv := &fakeCMProvider{
+		namespacedValues: map[string][]custom_metrics.MetricValue{
+			"ns/pods/*/some-metric":    {{Timestamp: older, Value: resource.MustParse("1")}, {Timestamp: newer, Value: resource.MustParse("2")}},
+			"ns/pods/foo/other-metric": {{Timestamp: older, Value: resource.MustParse("3")}},
+		},
+	}
+	cmStorage := custommetricstorage.NewREST(cmProv)
+	cmStorage.ValueAgeRecorder = recorder
+	cmServer := httptest.NewServer(handleCustomMetricsStorage(cmProv, cmStorage))
+	defer cmServer.Close()
+	emProv := &fakeEMProvider{
+		values: map[string][]external_metrics.ExternalMetricValue{
+			"my-external-metric": {
+				{MetricName: "my-external-metric", Timestamp: newer, Value: resource.MustParse("1")},
+				{MetricName: "my-external-metric", Timestamp: older, Value: resource.MustParse("1")},
+			},
+		},
+	}
+	emStorage := externalmetricstorage.NewREST(emProv)
+	emStorage.ValueAgeRecorder = recorder
+	emServer := httptest.NewServer(handleExternalMetricsStorage(emProv, emStorage))
+	defer emServer.Close()
+	client := http.Client{}
+
+	cmBase := "/" + prefix + "/" + customMetricsGroupVersion.Group + "/" + customMetricsGroupVersion.Version + "/namespaces/ns/pods/"
+	emPath := "/" + prefix + "/" + externalMetricsGroupVersion.Group + "/" + externalMetricsGroupVersion.Version + "/namespaces/default/my-external-metric"
+	for _, tc := range []struct {
+		server  *httptest.Server
+		request T
+	}{
+		{cmServer, T{"GET", cmBase + "*/some-metric", http.StatusOK, 0}},
+		{cmServer, T{"GET", cmBase + "foo/other-metric", http.StatusOK, 0}},
+		{emServer, T{"GET", emPath, http.StatusOK, 2}},
+	} {
+		if _, err := executeRequest(t, tc.request.Path, tc.request, tc.server, &client); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	// only the most recent value of each response is recorded
+	expected := map[string][]metav1.Time{
+		"custom.metrics.k8s.io/some-metric":          {newer},
+		"custom.metrics.k8s.io/other-metric":         {older},
+		"external.metrics.k8s.io/my-external-metric": {newer},
+	}
+	if !reflect.DeepEqual(recorder.timestamps, expected) {
+		t.Errorf("Expected the recorded timestamps to be %v, got %v", expected, recorder.timestamps)
+	}
+}
+
+func TestExternalMetricsAPITransform(t *testing.T) {
+	prov, _ := sampleprovider.NewFakeProvider(nil, nil)
+	storage := externalmetricstorage.NewREST(prov)
+	storage.Transform = func(info provider.ExternalMetricInfo, value external_metrics.ExternalMetricValue) (external_metrics.ExternalMetricValue, error) {
+		if info.Metric != "my-external-metric" {
+			return value, fmt.Errorf("unexpected metric info %v", info)
+		}
+		value.Value = scaleQuantity(value.Value, 1000)
+		return value, nil
+	}
+
+	server := httptest.NewServer(handleExternalMetricsStorage(prov, storage))
+	defer server.Close()
+
+	path := "/" + prefix + "/" + externalMetricsGroupVersion.Group + "/" + externalMetricsGroupVersion.Version + "/namespaces/default/my-external-metric?labelSelector=foo%3Dbar"
+	lst := &emv1beta1.ExternalMetricValueList{}
+	getMetricValues(t, "transformed", T{"GET", path, http.StatusOK, 1}, server, lst)
+	if len(lst.Items) != 1 || lst.Items[0].Value.Cmp(resource.MustParse("42k")) != 0 {
+		t.Errorf("Expected a single value of 42k, got %#v", lst.Items)
+	}
+}
+
+func TestCustomMetricsAPIQueryValidator(t *testing.T) {
+	prov := &fakeCMProvider{
+		namespacedValues: map[string][]custom_metrics.MetricValue{
+			"ns/pods/*/some-metric":   {{Value: resource.MustParse("3")}},
+			"ns/pods/*/other-metric":  {{Value: resource.MustParse("3")}},
+			"ns/pods/foo/some-metric": {{Value: resource.MustParse("3")}},
+		},
+	}
+	storage := custommetricstorage.NewREST(prov)
+	storage.Validator = func(_ context.Context, query provider.CustomMetricQuery) error {
+		if query.Info.Metric == "other-metric" {
+			return fmt.Errorf("other-metric is not served")
+		}
+		// some-metric is too expensive to compute for all the pods of a namespace
+		if query.Name == "*" && query.Selector.Empty() {
+			return apierrors.NewForbidden(query.Info.GroupResource, query.Info.Metric, fmt.Errorf("a label selector is required"))
+		}
+		return nil
+	}
+	server := httptest.NewServer(handleCustomMetricsStorage(prov, storage))
+	defer server.Close()
+	client := http.Client{}
+
+	base := "/" + prefix + "/" + customMetricsGroupVersion.Group + "/" + customMetricsGroupVersion.Version + "/namespaces/ns/pods/"
+	for _, tc := range []T{
+		{"GET", base + "*/some-metric", http.StatusForbidden, 0},
+		{"GET", base + "*/some-metric?labelSelector=app%3Dweb", http.StatusOK, 0},
+		{"GET", base + "foo/some-metric", http.StatusOK, 0},
+		{"GET", base + "*/other-metric?labelSelector=app%3Dweb", http.StatusBadRequest, 0},
+	} {
+		if _, err := executeRequest(t, tc.Path, tc, server, &client); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestExternalMetricsAPIQueryValidator(t *testing.T) {
+	prov, _ := sampleprovider.NewFakeProvider(nil, nil)
+	storage := externalmetricstorage.NewREST(prov)
+	storage.Validator = func(_ context.Context, query provider.ExternalMetricQuery) error {
+		if query.MetricSelector.Empty() {
+			return apierrors.NewForbidden(external_metrics.Resource(query.Info.Metric), "", fmt.Errorf("a label selector is required"))
+		}
+		return nil
+	}
+	server := httptest.NewServer(handleExternalMetricsStorage(prov, storage))
+	defer server.Close()
+	client := http.Client{}
+
+	base := "/" + prefix + "/" + externalMetricsGroupVersion.Group + "/" + externalMetricsGroupVersion.Version + "/namespaces/default/my-external-metric"
+	for _, tc := range []T{
+		{"GET", base, http.StatusForbidden, 0},
+		{"GET", base + "?labelSelector=foo%3Dbar", http.StatusOK, 0},
+	} {
+		if _, err := executeRequest(t, tc.Path, tc, server, &client); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestMetricsAPIMaxSelectorRequirements(t *testing.T) {
+	var queries atomic.Int32
+	cmProv := &fakeCMProvider{
+		namespacedValues: map[string][]custom_metrics.MetricValue{
+			"ns/pods/*/some-metric": {},
+		},
+		onQuery: func(context.Context, cmQuery) {
+			queries.Add(1)
+		},
+	}
+	cmStorage := custommetricstorage.NewREST(cmProv)
+	cmStorage.MaxSelectorRequirements = 2
+	cmServer := httptest.NewServer(handleCustomMetricsStorage(cmProv, cmStorage))
+	defer cmServer.Close()
+	emProv := &fakeEMProvider{
+		onQuery: func(context.Context, string, provider.ExternalMetricInfo) {
+			queries.Add(1)
+		},
+	}
+	emStorage := externalmetricstorage.NewREST(emProv)
+	emStorage.MaxSelectorRequirements = 2
+	emServer := httptest.NewServer(handleExternalMetricsStorage(emProv, emStorage))
+	defer emServer.Close()
+	client := http.Client{}
+
+	cmBase := "/" + prefix + "/" + customMetricsGroupVersion.Group + "/" + customMetricsGroupVersion.Version + "/namespaces/ns/pods/*/some-metric"
+	emBase := "/" + prefix + "/" + externalMetricsGroupVersion.Group + "/" + externalMetricsGroupVersion.Version + "/namespaces/default/my-external-metric"
+	for _, tc := range []struct {
+		server   *httptest.Server
+		request  T
+		provided bool
+	}{
+		{cmServer, T{"GET", cmBase + "?labelSelector=app%3Dweb,tier!%3Dcache", http.StatusOK, 0}, true},
+		{cmServer, T{"GET", cmBase + "?labelSelector=app%3Dweb,tier!%3Dcache,track", http.StatusBadRequest, 0}, false},
+		{cmServer, T{"GET", cmBase + "?metricLabelSelector=app%3Dweb,tier!%3Dcache", http.StatusOK, 0}, true},
+		{cmServer, T{"GET", cmBase + "?metricLabelSelector=app%3Dweb,tier!%3Dcache,track", http.StatusBadRequest, 0}, false},
+		{emServer, T{"GET", emBase + "?labelSelector=app%3Dweb,tier!%3Dcache", http.StatusOK, 0}, true},
+		{emServer, T{"GET", emBase + "?labelSelector=app%3Dweb,tier!%3Dcache,track", http.StatusBadRequest, 0}, false},
+	} {
+		served := queries.Load()
+		if _, err := executeRequest(t, tc.request.Path, tc.request, tc.server, &client); err != nil {
+			t.Error(err)
+		}
+		if provided := queries.Load() > served; provided != tc.provided {
+			t.Errorf("%s: expected the provider to be queried: %v, got %v", tc.request.Path, tc.provided, provided)
+		}
+	}
+}
+
+func getTable(t *testing.T, url string) *metav1.Table {
+	request, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	request.Header.Set("Accept", "application/json;as=Table;v=v1;g=meta.k8s.io")
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body, err := extractBodyString(response)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected %d, got %d -- %s", http.StatusOK, response.StatusCode, body)
+	}
+
+	table := &metav1.Table{}
+	if err := json.Unmarshal([]byte(body), table); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return table
+}
+
+func assertColumn(t *testing.T, table *metav1.Table, expected metav1.TableColumnDefinition) {
+	for _, column := range table.ColumnDefinitions {
+		if column.Name == expected.Name {
+			if column.Type != expected.Type || column.Format != expected.Format || column.Priority != expected.Priority {
+				t.Errorf("Expected column %s to have type %q, format %q and priority %d, got %#v", expected.Name, expected.Type, expected.Format, expected.Priority, column)
+			}
+			return
+		}
+	}
+	t.Errorf("Expected a column named %s, got %#v", expected.Name, table.ColumnDefinitions)
+}
+
+func TestCustomMetricsAPITable(t *testing.T) {
+	prov := &fakeCMProvider{
+		namespacedValues: map[string][]custom_metrics.MetricValue{
+			"ns/pods/*/some-metric": {
+				{DescribedObject: custom_metrics.ObjectReference{Kind: "Pod", Name: "foo"}, Metric: custom_metrics.MetricIdentifier{Name: "some-metric"}, Value: resource.MustParse("3")},
+				{DescribedObject: custom_metrics.ObjectReference{Kind: "Pod", Name: "bar"}, Metric: custom_metrics.MetricIdentifier{Name: "some-metric"}, Value: resource.MustParse("4")},
+			},
+		},
+	}
+	server := httptest.NewServer(handleCustomMetrics(prov))
+	defer server.Close()
+
+	table := getTable(t, server.URL+"/"+prefix+"/"+customMetricsGroupVersion.Group+"/"+customMetricsGroupVersion.Version+"/namespaces/ns/pods/*/some-metric")
+
+	assertColumn(t, table, metav1.TableColumnDefinition{Name: "Name", Type: "string", Format: "name"})
+	assertColumn(t, table, metav1.TableColumnDefinition{Name: "Value", Type: "string", Format: "quantity"})
+	assertColumn(t, table, metav1.TableColumnDefinition{Name: "Age", Type: "date"})
+	assertColumn(t, table, metav1.TableColumnDefinition{Name: "Kind", Type: "string", Priority: 1})
+	assertColumn(t, table, metav1.TableColumnDefinition{Name: "Window", Type: "string", Priority: 1})
+
+	if len(table.Rows) != 2 {
+		t.Fatalf("Expected 2 rows, got %d", len(table.Rows))
+	}
+	for _, row := range table.Rows {
+		if len(row.Cells) != len(table.ColumnDefinitions) {
+			t.Errorf("Expected %d cells, got %#v", len(table.ColumnDefinitions), row.Cells)
+		}
+	}
+	if table.Rows[0].Cells[0] != "foo" || table.Rows[0].Cells[2] != "3" {
+		t.Errorf("Expected the first row to describe foo with value 3, got %#v", table.Rows[0].Cells)
+	}
+}
+
+func TestCustomMetricsAPITableAge(t *testing.T) {
+	collected := time.Date(2026, time.January, 1, 12, 0, 0, 0, time.UTC)
+	prov := &fakeCMProvider{
+		namespacedValues: map[string][]custom_metrics.MetricValue{
+			"ns/pods/foo/some-metric": {
+				{DescribedObject: custom_metrics.ObjectReference{Kind: "Pod", Name: "foo"}, Metric: custom_metrics.MetricIdentifier{Name: "some-metric"}, Timestamp: metav1.NewTime(collected), Value: resource.MustParse("3")},
+			},
+		},
+	}
+	storage := custommetricstorage.NewREST(prov)
+	storage.Clock = testingclock.NewFakePassiveClock(collected.Add(90 * time.Second))
+	server := httptest.NewServer(handleCustomMetricsStorage(prov, storage))
+	defer server.Close()
+
+	table := getTable(t, server.URL+"/"+prefix+"/"+customMetricsGroupVersion.Group+"/"+customMetricsGroupVersion.Version+"/namespaces/ns/pods/foo/some-metric")
+	if len(table.Rows) != 1 {
+		t.Fatalf("Expected 1 row, got %d", len(table.Rows))
+	}
+	if age := table.Rows[0].Cells[3]; age != "90s" {
+		t.Errorf("Expected the value to be 90s old according to the storage clock, got %v", age)
+	}
+}
+
+func TestExternalMetricsAPITable(t *testing.T) {
+	prov, _ := sampleprovider.NewFakeProvider(nil, nil)
+	server := httptest.NewServer(handleExternalMetrics(prov))
+	defer server.Close()
+
+	table := getTable(t, server.URL+"/"+prefix+"/"+externalMetricsGroupVersion.Group+"/"+externalMetricsGroupVersion.Version+"/namespaces/default/my-external-metric")
+
+	assertColumn(t, table, metav1.TableColumnDefinition{Name: "Metric", Type: "string", Format: "name"})
+	assertColumn(t, table, metav1.TableColumnDefinition{Name: "Value", Type: "string", Format: "quantity"})
+	assertColumn(t, table, metav1.TableColumnDefinition{Name: "Age", Type: "date"})
+	assertColumn(t, table, metav1.TableColumnDefinition{Name: "Labels", Type: "string", Priority: 1})
+
+	if len(table.Rows) != 2 {
+		t.Fatalf("Expected 2 rows, got %d", len(table.Rows))
+	}
+	if table.Rows[0].Cells[1] != "42" || table.Rows[0].Cells[4] != "foo=bar" {
+		t.Errorf("Expected the first row to have value 42 and labels foo=bar, got %#v", table.Rows[0].Cells)
+	}
+}
+
+// discover returns the resources of the discovery document of the custom metrics
+// API served by the server.
+func discover(t *testing.T, server *httptest.Server) []metav1.APIResource {
+	response, err := executeRequest(t, "discovery", T{"GET", "/" + prefix + "/" + customMetricsGroupVersion.Group + "/" + customMetricsGroupVersion.Version, http.StatusOK, 0}, server, &http.Client{})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	lst := &metav1.APIResourceList{}
+	if err := extractBody(response, lst); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return lst.APIResources
+}
+
+func TestCustomMetricsAPIDiscovery(t *testing.T) {
+	podMetric := func(metric string) provider.CustomMetricInfo {
+		return provider.CustomMetricInfo{GroupResource: schema.GroupResource{Resource: "pods"}, Namespaced: true, Metric: metric}
+	}
+	rootScoped := podMetric("some-metric")
+	rootScoped.Namespaced = false
+	resource := func(name string, namespaced bool, verbs metav1.Verbs, categories ...string) metav1.APIResource {
+		return metav1.APIResource{Name: name, Namespaced: namespaced, Kind: "MetricValueList", Verbs: verbs, Categories: categories}
+	}
+
+	for _, tc := range []struct {
+		name     string
+		provider provider.CustomMetricsProvider
+		expected []metav1.APIResource
+	}{
+		{
+			name:     "duplicate metrics",
+			provider: &fakeCMProvider{metrics: []provider.CustomMetricInfo{podMetric("some-metric"), podMetric("some-metric"), rootScoped}},
+			expected: []metav1.APIResource{resource("pods/some-metric", true, metav1.Verbs{"get"}), resource("pods/some-metric", false, metav1.Verbs{"get"})},
+		},
+		{
+			name: "capabilities",
+			provider: capableFakeCMProvider{&fakeCMProvider{
+				metrics:      []provider.CustomMetricInfo{podMetric("by-name"), podMetric("by-selector")},
+				capabilities: map[string]provider.MetricCapabilities{"by-name": {ByName: true}, "by-selector": {BySelector: true}},
+			}},
+			expected: []metav1.APIResource{resource("pods/by-name", true, metav1.Verbs{"get"}), resource("pods/by-selector", true, metav1.Verbs{"list"})},
+		},
+		{
+			name: "metric types",
+			provider: &fakeCMProvider{
+				metrics:     []provider.CustomMetricInfo{podMetric("some-counter"), podMetric("some-gauge"), podMetric("some-metric")},
+				metricTypes: map[string]provider.MetricType{"some-counter": provider.MetricTypeCounter, "some-gauge": provider.MetricTypeGauge},
+			},
+			expected: []metav1.APIResource{
+				resource("pods/some-counter", true, metav1.Verbs{"get"}, "counter"),
+				resource("pods/some-gauge", true, metav1.Verbs{"get"}, "gauge"),
+				resource("pods/some-metric", true, metav1.Verbs{"get"}),
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(handleCustomMetrics(tc.provider))
+			defer server.Close()
+
+			if resources := discover(t, server); !reflect.DeepEqual(resources, tc.expected) {
+				t.Errorf("Expected discovery to list %#v, got %#v", tc.expected, resources)
+			}
+		})
+	}
+}
+
+func TestCustomMetricsAPIDiscoveryRefresh(t *testing.T) {
+	podMetric := func(metric string) provider.CustomMetricInfo {
+		return provider.CustomMetricInfo{GroupResource: schema.GroupResource{Resource: "pods"}, Namespaced: true, Metric: metric}
+	}
+	prov := &fakeCMProvider{metrics: []provider.CustomMetricInfo{podMetric("some-metric")}, changed: make(chan struct{}, 1)}
+	server := httptest.NewServer(handleCustomMetrics(notifyingFakeCMProvider{prov}))
+	defer server.Close()
+
+	names := func() []string {
+		names := []string{}
+		for _, resource := range discover(t, server) {
+			names = append(names, resource.Name)
+		}
+		return names
+	}
+
+	if names := names(); !reflect.DeepEqual(names, []string{"pods/some-metric"}) {
+		t.Fatalf("Expected discovery to list pods/some-metric, got %v", names)
+	}
+	names()
+	prov.mu.Lock()
+	lists := prov.lists
+	prov.mu.Unlock()
+	if lists != 1 {
+		t.Errorf("Expected discovery to be cached until the provider signals a change, but metrics were listed %d times", lists)
+	}
+
+	prov.addMetric(podMetric("other-metric"))
+	if names := names(); !reflect.DeepEqual(names, []string{"pods/some-metric", "pods/other-metric"}) {
+		t.Errorf("Expected discovery to reflect the new metric, got %v", names)
+	}
+}
+
+// authorizedDiscovery installs the group, and returns a function listing the
+// resources its discovery document lists for the given user.
+func authorizedDiscovery(t *testing.T, group *MetricsAPIGroupVersion) func(userName string) []string {
+	container := restful.NewContainer()
+	container.Router(restful.CurlyRouter{})
+	if err := group.InstallREST(container); err != nil {
+		t.Fatalf("unable to install container %s: %v", group.GroupVersion, err)
+	}
+
+	var handler http.Handler = &defaultAPIServer{container.ServeMux, container}
+	handler = genericapifilters.WithRequestInfo(handler, genericapiserver.NewRequestInfoResolver(&genericapiserver.Config{}))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if name := req.Header.Get("X-Test-User"); name != "" {
+			req = req.WithContext(request.WithUser(req.Context(), &user.DefaultInfo{Name: name}))
+		}
+		handler.ServeHTTP(w, req)
+	}))
+	t.Cleanup(server.Close)
+
+	return func(userName string) []string {
+		req, err := http.NewRequest("GET", server.URL+"/"+prefix+"/"+group.GroupVersion.Group+"/"+group.GroupVersion.Version, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if userName != "" {
+			req.Header.Set("X-Test-User", userName)
+		}
+		response, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected %d for discovery, got %d", http.StatusOK, response.StatusCode)
+		}
+		lst := &metav1.APIResourceList{}
+		if err := extractBody(response, lst); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		names := make([]string, 0, len(lst.APIResources))
+		for _, resource := range lst.APIResources {
+			names = append(names, resource.Name)
+		}
+		return names
+	}
+}
+
+func TestCustomMetricsAPIAuthorizedDiscovery(t *testing.T) {
+	prov := &fakeCMProvider{metrics: []provider.CustomMetricInfo{
+		{GroupResource: schema.GroupResource{Resource: "pods"}, Namespaced: true, Metric: "public-metric"},
+		{GroupResource: schema.GroupResource{Resource: "pods"}, Namespaced: true, Metric: "secret-metric"},
+	}}
+	discover := authorizedDiscovery(t, &MetricsAPIGroupVersion{
+		DynamicStorage:  custommetricstorage.NewREST(prov),
+		APIGroupVersion: apiGroupVersion(customMetricsGroupVersion, customMetricsGroupInfo),
+		ResourceLister:  provider.NewCustomMetricResourceLister(prov),
+		Handlers:        &CMHandlers{},
+		DiscoveryAuthorizer: authorizer.AuthorizerFunc(func(_ context.Context, a authorizer.Attributes) (authorizer.Decision, string, error) {
+			if a.GetVerb() != "get" {
+				return authorizer.DecisionNoOpinion, "", nil
+			}
+			if a.GetUser().GetName() == "admin" || (a.GetResource() == "pods" && a.GetSubresource() == "public-metric") {
+				return authorizer.DecisionAllow, "", nil
+			}
+			return authorizer.DecisionNoOpinion, "", nil
+		}),
+	})
+
+	if names := discover("admin"); !reflect.DeepEqual(names, []string{"pods/public-metric", "pods/secret-metric"}) {
+		t.Errorf("Expected discovery to list all metrics for an authorized user, got %v", names)
+	}
+	if names := discover("someone"); !reflect.DeepEqual(names, []string{"pods/public-metric"}) {
+		t.Errorf("Expected discovery to only list the metrics the user may get, got %v", names)
+	}
+	if names := discover(""); len(names) != 0 {
+		t.Errorf("Expected discovery to list no metrics without a user, got %v", names)
+	}
+}
+
+func TestExternalMetricsAPIAuthorizedDiscovery(t *testing.T) {
+	prov := &fakeEMProvider{values: map[string][]external_metrics.ExternalMetricValue{"public-metric": nil, "secret-metric": nil}}
+	discover := authorizedDiscovery(t, &MetricsAPIGroupVersion{
+		DynamicStorage:  externalmetricstorage.NewREST(prov),
+		APIGroupVersion: apiGroupVersion(externalMetricsGroupVersion, externalMetricsGroupInfo),
+		ResourceLister:  provider.NewExternalMetricResourceLister(prov),
+		Handlers:        &EMHandlers{},
+		DiscoveryVerb:   "list",
+		DiscoveryAuthorizer: authorizer.AuthorizerFunc(func(_ context.Context, a authorizer.Attributes) (authorizer.Decision, string, error) {
+			// external metrics are queried with list, so get alone does not allow seeing them
+			if a.GetVerb() != "list" {
+				return authorizer.DecisionNoOpinion, "", nil
+			}
+			if a.GetUser().GetName() == "admin" || a.GetResource() == "public-metric" {
+				return authorizer.DecisionAllow, "", nil
+			}
+			return authorizer.DecisionNoOpinion, "", nil
+		}),
+	})
+
+	if names := discover("admin"); !reflect.DeepEqual(names, []string{"public-metric", "secret-metric"}) {
+		t.Errorf("Expected discovery to list all metrics for an authorized user, got %v", names)
+	}
+	if names := discover("someone"); !reflect.DeepEqual(names, []string{"public-metric"}) {
+		t.Errorf("Expected discovery to only list the metrics the user may list, got %v", names)
+	}
+}
+
+func TestCustomMetricsAPICapabilities(t *testing.T) {
+	prov := capableFakeCMProvider{&fakeCMProvider{
+		namespacedValues: map[string][]custom_metrics.MetricValue{
+			"ns/pods/foo/by-name":     make([]custom_metrics.MetricValue, 1),
+			"ns/pods/*/by-name":       make([]custom_metrics.MetricValue, 2),
+			"ns/pods/foo/by-selector": make([]custom_metrics.MetricValue, 1),
+			"ns/pods/*/by-selector":   make([]custom_metrics.MetricValue, 2),
+		},
+		capabilities: map[string]provider.MetricCapabilities{"by-name": {ByName: true}, "by-selector": {BySelector: true}},
+	}}
+	server := httptest.NewServer(handleCustomMetrics(prov))
+	defer server.Close()
+	client := http.Client{}
+	path := "/" + prefix + "/" + customMetricsGroupVersion.Group + "/" + customMetricsGroupVersion.Version
+
+	for k, v := range map[string]T{
+		"supported query by name":       {"GET", path + "/namespaces/ns/pods/foo/by-name", http.StatusOK, 1},
+		"unsupported query by selector": {"GET", path + "/namespaces/ns/pods/*/by-name", http.StatusMethodNotAllowed, 0},
+		"supported query by selector":   {"GET", path + "/namespaces/ns/pods/*/by-selector", http.StatusOK, 2},
+		"unsupported query by name":     {"GET", path + "/namespaces/ns/pods/foo/by-selector", http.StatusMethodNotAllowed, 0},
+	} {
+		if _, err := executeRequest(t, k, v, server, &client); err != nil {
+			t.Errorf(err.Error())
+		}
+	}
+}
+
+func TestExternalMetricsAPI(t *testing.T) {
+	cases := map[string]T{
+		// checks which should fail
+
+		"GET long prefix":             {"GET", "/" + prefix + "/", http.StatusNotFound, 0},
+		"GET at root scope":           {"GET", "/" + prefix + "/" + externalMetricsGroupVersion.Group + "/" + externalMetricsGroupVersion.Version + "/nonexistent-metric", http.StatusNotFound, 0},
+		"GET without metric name":     {"GET", "/" + prefix + "/" + externalMetricsGroupVersion.Group + "/" + externalMetricsGroupVersion.Version + "/namespaces/foo", http.StatusNotFound, 0},
+		"GET for metric with slashes": {"GET", "/" + prefix + "/" + externalMetricsGroupVersion.Group + "/" + externalMetricsGroupVersion.Version + "/namespaces/foo/group/metric", http.StatusNotFound, 0},
+
+		// Positive checks to make sure everything is wired correctly
+		"GET for external metric":               {"GET", "/" + prefix + "/" + externalMetricsGroupVersion.Group + "/" + externalMetricsGroupVersion.Version + "/namespaces/default/my-external-metric", http.StatusOK, 2},
+		"GET for external metric with selector": {"GET", "/" + prefix + "/" + externalMetricsGroupVersion.Group + "/" + externalMetricsGroupVersion.Version + "/namespaces/default/my-external-metric?labelSelector=foo%3Dbar", http.StatusOK, 1},
+		"GET for nonexistent metric":            {"GET", "/" + prefix + "/" + externalMetricsGroupVersion.Group + "/" + externalMetricsGroupVersion.Version + "/namespaces/foo/nonexistent-metric", http.StatusOK, 0},
+	}
+
+	// "real" fake provider implementation can be used in test, because it doesn't have any dependencies.
+	// Note: this provider has a hardcoded list of external metrics.
+	prov, _ := sampleprovider.NewFakeProvider(nil, nil)
+
+	server := httptest.NewServer(handleExternalMetrics(prov))
+	defer server.Close()
+	client := http.Client{}
+	for k, v := range cases {
+		response, err := executeRequest(t, k, v, server, &client)
+		if err != nil {
+			t.Errorf(err.Error())
+			continue
+		}
+		if v.ExpectedCount > 0 {
+			lst := &emv1beta1.ExternalMetricValueList{}
+			if err := extractBody(response, lst); err != nil {
+				t.Errorf("unexpected error (%s): %v", k, err)
+				continue
+			}
+			if len(lst.Items) != v.ExpectedCount {
+				t.Errorf("Expected %d items, got %d (%s): %#v", v.ExpectedCount, len(lst.Items), k, lst.Items)
+				continue
+			}
+		}
+	}
+}
+
+func TestExternalMetricsAPIStreaming(t *testing.T) {
+	prov := streamingFakeEMProvider{&fakeEMProvider{stream: make(chan external_metrics.ExternalMetricValue)}}
+
+	server := httptest.NewServer(handleExternalMetrics(prov))
+	defer server.Close()
+
+	path := "/" + prefix + "/" + externalMetricsGroupVersion.Group + "/" + externalMetricsGroupVersion.Version + "/namespaces/default/my-external-metric"
+	response, err := executeRequest(t, "streaming", T{"GET", path, http.StatusOK, 0}, server, &http.Client{})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer response.Body.Close()
+
+	prov.stream <- external_metrics.ExternalMetricValue{MetricName: "first"}
+
+	// the first value must reach the client before the provider is done
+	received := make(chan []byte)
+	go func() {
+		var body []byte
+		buf := make([]byte, 1024)
+		for !strings.Contains(string(body), `"metricName":"first"`) {
+			n, err := response.Body.Read(buf)
+			body = append(body, buf[:n]...)
+			if err != nil {
+				break
+			}
+		}
+		received <- body
+	}()
+
+	var body []byte
+	select {
+	case body = <-received:
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatalf("timed out waiting for the first streamed value")
+	}
+
+	prov.stream <- external_metrics.ExternalMetricValue{MetricName: "second"}
+	close(prov.stream)
+
+	rest, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lst := &emv1beta1.ExternalMetricValueList{}
+	if err := runtime.DecodeInto(codec, append(body, rest...), lst); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(lst.Items) != 2 || lst.Items[0].MetricName != "first" || lst.Items[1].MetricName != "second" {
+		t.Errorf("Expected streamed values first and second, got %#v", lst.Items)
+	}
+}
+
+// getMetricValues executes the request, expecting it to succeed, and decodes the
+// metric values of its response into the given list.
+func getMetricValues(t *testing.T, k string, v T, server *httptest.Server, list runtime.Object) *http.Response {
+	response, err := executeRequest(t, k, v, server, &http.Client{})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := extractBody(response, list); err != nil {
+		t.Fatalf("unexpected error (%s): %v", k, err)
 	}
+	return response
 }
 
 func executeRequest(t *testing.T, k string, v T, server *httptest.Server, client *http.Client) (*http.Response, error) {
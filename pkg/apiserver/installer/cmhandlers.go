@@ -25,6 +25,7 @@ import (
 	"k8s.io/apiserver/pkg/endpoints/handlers"
 	"k8s.io/apiserver/pkg/endpoints/handlers/negotiation"
 	"k8s.io/apiserver/pkg/endpoints/metrics"
+	genericrest "k8s.io/apiserver/pkg/registry/rest"
 
 	"sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/registry/rest"
 )
@@ -118,8 +119,6 @@ func (ch *CMHandlers) registerResourceHandlers(a *MetricsAPIInstaller, ws *restf
 		Typer:           a.group.Typer,
 		UnsafeConvertor: a.group.UnsafeConvertor,
 
-		// TODO: support TableConvertor?
-
 		// TODO: This seems wrong for cross-group subresources. It makes an assumption that a subresource and its parent are in the same group version. Revisit this.
 		Resource:    a.group.GroupVersion.WithResource("*"),
 		Subresource: "*",
@@ -130,9 +129,13 @@ func (ch *CMHandlers) registerResourceHandlers(a *MetricsAPIInstaller, ws *restf
 	if a.group.MetaGroupVersion != nil {
 		reqScope.MetaGroupVersion = *a.group.MetaGroupVersion
 	}
+	if tableConvertor, ok := a.group.DynamicStorage.(genericrest.TableConvertor); ok {
+		reqScope.TableConvertor = tableConvertor
+	}
 
 	// we need one path for namespaced resources, one for non-namespaced resources
-	doc := "list custom metrics describing an object or objects"
+	doc := "list custom metrics describing an object or objects. The type of each metric, gauge or counter, " +
+		"is advertised as its category in discovery when the provider knows it."
 	reqScope.Namer = MetricsNaming{
 		handlers.ContextBasedNaming{
 			Namer:         a.group.Namer,
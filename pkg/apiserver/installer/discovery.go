@@ -0,0 +1,98 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package installer
+
+import (
+	"context"
+	"strings"
+
+	"github.com/emicklei/go-restful/v3"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/apiserver/pkg/endpoints/discovery"
+	"k8s.io/apiserver/pkg/endpoints/handlers/negotiation"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/klog/v2"
+)
+
+// authorizedVersionHandler serves the discovery document of a metrics API group
+// version, listing only the metrics the caller is authorized to query, so that
+// unauthorized users cannot enumerate metric names.
+type authorizedVersionHandler struct {
+	serializer   runtime.NegotiatedSerializer
+	groupVersion schema.GroupVersion
+	lister       discovery.APIResourceLister
+	authorizer   authorizer.Authorizer
+	// verb is the verb the metrics are queried with: get for custom metrics, and list
+	// for external metrics.
+	verb string
+}
+
+// AddToWebService mirrors discovery.APIVersionHandler.AddToWebService.
+func (h *authorizedVersionHandler) AddToWebService(ws *restful.WebService) {
+	mediaTypes, _ := negotiation.MediaTypesForSerializer(h.serializer)
+	ws.Route(ws.GET("/").To(h.handle).
+		Doc("get available resources").
+		Operation("getAPIResources").
+		Produces(mediaTypes...).
+		Consumes(mediaTypes...).
+		Writes(metav1.APIResourceList{}))
+}
+
+func (h *authorizedVersionHandler) handle(req *restful.Request, resp *restful.Response) {
+	resources := h.authorizedResources(req.Request.Context(), h.lister.ListAPIResources())
+	discovery.NewAPIVersionHandler(h.serializer, h.groupVersion, discovery.APIResourceListerFunc(func() []metav1.APIResource {
+		return resources
+	})).ServeHTTP(resp.ResponseWriter, req.Request)
+}
+
+// authorizedResources filters the resources the user of the request is authorized
+// to query with the verb of the handler.  Resources are named after the path of the metrics they describe, e.g.
+// pods/cpu_usage for a custom metric, which is authorized as the cpu_usage
+// subresource of pods.  Since discovery is not namespaced, so are the checks:
+// users only authorized in some namespaces do not see the namespaced metrics.
+func (h *authorizedVersionHandler) authorizedResources(ctx context.Context, resources []metav1.APIResource) []metav1.APIResource {
+	user, ok := request.UserFrom(ctx)
+	if !ok {
+		return []metav1.APIResource{}
+	}
+
+	authorized := make([]metav1.APIResource, 0, len(resources))
+	for _, resource := range resources {
+		name, subresource, _ := strings.Cut(resource.Name, "/")
+		decision, _, err := h.authorizer.Authorize(ctx, authorizer.AttributesRecord{
+			User:            user,
+			Verb:            h.verb,
+			APIGroup:        h.groupVersion.Group,
+			APIVersion:      h.groupVersion.Version,
+			Resource:        name,
+			Subresource:     subresource,
+			ResourceRequest: true,
+		})
+		if err != nil {
+			klog.FromContext(ctx).WithName(loggerName).V(4).Info("unable to authorize metric discovery, omitting it", "resource", resource.Name, "err", err)
+			continue
+		}
+		if decision == authorizer.DecisionAllow {
+			authorized = append(authorized, resource)
+		}
+	}
+	return authorized
+}
@@ -83,8 +83,6 @@ func (ch *EMHandlers) registerResourceHandlers(a *MetricsAPIInstaller, ws *restf
 		Typer:           a.group.Typer,
 		UnsafeConvertor: a.group.UnsafeConvertor,
 
-		// TODO: support TableConvertor?
-
 		// TODO: This seems wrong for cross-group subresources. It makes an assumption that a subresource and its parent are in the same group version. Revisit this.
 		Resource:    a.group.GroupVersion.WithResource("*"),
 		Subresource: "*",
@@ -95,6 +93,9 @@ func (ch *EMHandlers) registerResourceHandlers(a *MetricsAPIInstaller, ws *restf
 	if a.group.MetaGroupVersion != nil {
 		reqScope.MetaGroupVersion = *a.group.MetaGroupVersion
 	}
+	if tableConvertor, ok := a.group.DynamicStorage.(rest.TableConvertor); ok {
+		reqScope.TableConvertor = tableConvertor
+	}
 
 	doc := "list external metrics"
 	reqScope.Namer = MetricsNaming{
@@ -17,7 +17,9 @@ limitations under the License.
 package installer
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	gpath "path"
 	"reflect"
 	"strings"
@@ -25,17 +27,23 @@ import (
 
 	"github.com/emicklei/go-restful/v3"
 
+	metainternalversion "k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	metainternalversionscheme "k8s.io/apimachinery/pkg/apis/meta/internalversion/scheme"
 	"k8s.io/apimachinery/pkg/conversion"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apiserver/pkg/audit"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
 	"k8s.io/apiserver/pkg/endpoints"
 	"k8s.io/apiserver/pkg/endpoints/discovery"
 	"k8s.io/apiserver/pkg/endpoints/handlers"
 	"k8s.io/apiserver/pkg/endpoints/handlers/negotiation"
 	"k8s.io/apiserver/pkg/registry/rest"
+	"k8s.io/klog/v2"
 
 	cm_handlers "sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/endpoints/handlers"
 	cm_rest "sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/registry/rest"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
 )
 
 // NB: the contents of this file should mostly be a subset of the functionality
@@ -55,6 +63,12 @@ type MetricsAPIGroupVersion struct {
 	*endpoints.APIGroupVersion
 
 	ResourceLister discovery.APIResourceLister
+	// DiscoveryAuthorizer, when set, restricts the metrics listed in the discovery
+	// document to the ones the caller is authorized to query with DiscoveryVerb.
+	DiscoveryAuthorizer authorizer.Authorizer
+	// DiscoveryVerb is the verb the metrics of the group are queried with, which
+	// DiscoveryAuthorizer checks.  It defaults to get.
+	DiscoveryVerb string
 
 	Handlers apiHandlers
 }
@@ -75,8 +89,23 @@ func (g *MetricsAPIGroupVersion) InstallREST(container *restful.Container) error
 	if lister == nil {
 		return fmt.Errorf("must provide a dynamic lister for dynamic API groups")
 	}
-	versionDiscoveryHandler := discovery.NewAPIVersionHandler(g.Serializer, g.GroupVersion, lister)
-	versionDiscoveryHandler.AddToWebService(ws)
+	if g.DiscoveryAuthorizer != nil {
+		verb := g.DiscoveryVerb
+		if verb == "" {
+			verb = "get"
+		}
+		versionDiscoveryHandler := &authorizedVersionHandler{
+			serializer:   g.Serializer,
+			groupVersion: g.GroupVersion,
+			lister:       lister,
+			authorizer:   g.DiscoveryAuthorizer,
+			verb:         verb,
+		}
+		versionDiscoveryHandler.AddToWebService(ws)
+	} else {
+		versionDiscoveryHandler := discovery.NewAPIVersionHandler(g.Serializer, g.GroupVersion, lister)
+		versionDiscoveryHandler.AddToWebService(ws)
+	}
 	container.Add(ws)
 	return utilerrors.NewAggregate(registrationErrors)
 }
@@ -95,6 +124,10 @@ func (g *MetricsAPIGroupVersion) newDynamicInstaller() *MetricsAPIInstaller {
 	return installer
 }
 
+// loggerName is the name of the logger used by the installer.
+// Its verbosity can be raised on its own with --vmodule=installer=4.
+const loggerName = "installer"
+
 // MetricsAPIInstaller is a specialized API installer for the metrics API.
 // It is intended to be fully compliant with the Kubernetes API server conventions,
 // but serves wildcard resource/subresource routes instead of hard-coded resources
@@ -118,6 +151,11 @@ func (a *MetricsAPIInstaller) Install(ws *restful.WebService) (errors []error) {
 		errors = append(errors, fmt.Errorf("error in registering custom metrics resource: %v", err))
 	}
 
+	logger := klog.Background().WithName(loggerName)
+	for _, route := range ws.Routes() {
+		logger.V(4).Info("registered metrics API route", "method", route.Method, "path", route.Path)
+	}
+
 	return errors
 }
 
@@ -271,12 +309,118 @@ type MetricsNaming struct {
 
 func restfulListResource(r rest.Lister, rw rest.Watcher, scope handlers.RequestScope, forceWatch bool, minRequestTimeout time.Duration) restful.RouteFunction {
 	return func(req *restful.Request, res *restful.Response) {
-		handlers.ListResource(r, rw, &scope, forceWatch, minRequestTimeout)(res.ResponseWriter, req.Request)
+		w, httpReq := withProvenance(res.ResponseWriter, req.Request)
+		auditListOptions(httpReq, scope)
+		handlers.ListResource(r, rw, &scope, forceWatch, minRequestTimeout)(withCacheControl(w, httpReq, r), httpReq)
 	}
 }
 
+// auditListOptions records the list options of the request, holding its selectors,
+// in its audit event, for events at the Request level.  The generic list handler
+// only records the request URI.  Options which can't be decoded are left for the
+// handler to reject.
+func auditListOptions(req *http.Request, scope handlers.RequestScope) {
+	opts := &metainternalversion.ListOptions{}
+	if err := metainternalversionscheme.ParameterCodec.DecodeParameters(req.URL.Query(), scope.MetaGroupVersion, opts); err != nil {
+		return
+	}
+	audit.LogRequestObject(req.Context(), opts, scope.MetaGroupVersion, scope.Resource, scope.Subresource, metainternalversionscheme.Codecs)
+}
+
 func restfulListResourceWithOptions(r cm_rest.ListerWithOptions, scope handlers.RequestScope) restful.RouteFunction {
 	return func(req *restful.Request, res *restful.Response) {
-		cm_handlers.ListResourceWithOptions(r, scope)(res.ResponseWriter, req.Request)
+		w, httpReq := withProvenance(res.ResponseWriter, req.Request)
+		cm_handlers.ListResourceWithOptions(r, scope)(withCacheControl(w, httpReq, r), httpReq)
+	}
+}
+
+// ProvenanceHeader is the header of successful responses reporting the provenance
+// of the metric values, when the provider recorded it.
+const ProvenanceHeader = "X-Metrics-Provenance"
+
+// withProvenance sets the provenance header of successful responses to the
+// provenance recorded by the provider while serving the request, if any.
+func withProvenance(w http.ResponseWriter, req *http.Request) (http.ResponseWriter, *http.Request) {
+	ctx, provenance := provider.WithProvenanceRecorder(req.Context())
+	return &provenanceResponseWriter{ResponseWriter: w, provenance: provenance}, req.WithContext(ctx)
+}
+
+type provenanceResponseWriter struct {
+	http.ResponseWriter
+	provenance  func() provider.Provenance
+	wroteHeader bool
+}
+
+func (w *provenanceResponseWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if provenance := w.provenance(); code == http.StatusOK && provenance != "" {
+			w.Header().Set(ProvenanceHeader, string(provenance))
+		}
 	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *provenanceResponseWriter) Write(data []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *provenanceResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *provenanceResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// withCacheControl sets the Cache-Control header of successful responses to the
+// value returned by the storage, if it is a CacheControlled.  The value is only
+// computed once the response is written, since handlers may rewrite the request
+// info first, e.g. for metrics describing namespaces: they do so in place, so the
+// context of the request is enough to see the rewritten info.
+func withCacheControl(w http.ResponseWriter, req *http.Request, storage interface{}) http.ResponseWriter {
+	cacheControlled, ok := storage.(cm_rest.CacheControlled)
+	if !ok {
+		return w
+	}
+	return &cacheControlResponseWriter{ResponseWriter: w, ctx: req.Context(), storage: cacheControlled}
+}
+
+type cacheControlResponseWriter struct {
+	http.ResponseWriter
+	ctx         context.Context
+	storage     cm_rest.CacheControlled
+	wroteHeader bool
+}
+
+func (w *cacheControlResponseWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if code == http.StatusOK {
+			w.Header().Set("Cache-Control", w.storage.CacheControl(w.ctx))
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *cacheControlResponseWriter) Write(data []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *cacheControlResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *cacheControlResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
 }
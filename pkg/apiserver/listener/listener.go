@@ -0,0 +1,118 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package listener configures the connections accepted by the listener of the
+// secure server, for network setups where idle connections linger.
+package listener
+
+import (
+	"net"
+	"time"
+)
+
+// WithTimeouts wraps the listener to set, when positive, the TCP keep-alive period
+// of the accepted connections, and to close them after being idle, without read
+// or write, for idleTimeout.  Connections of requests waiting on a provider for
+// longer than idleTimeout are closed too.
+//
+// The accepted connections are wrapped, so that the generic server does not
+// override their keep-alive period with its default of 3 minutes.  Since it then
+// skips them, that default is set here when keepAlivePeriod is not positive.
+func WithTimeouts(ln net.Listener, keepAlivePeriod, idleTimeout time.Duration) net.Listener {
+	if keepAlivePeriod <= 0 && idleTimeout <= 0 {
+		return ln
+	}
+	return &timeoutListener{
+		Listener:        ln,
+		keepAlivePeriod: keepAlivePeriod,
+		idleTimeout:     idleTimeout,
+	}
+}
+
+// defaultKeepAlivePeriod is the keep-alive period the generic server sets on the
+// connections it accepts.
+const defaultKeepAlivePeriod = 3 * time.Minute
+
+type timeoutListener struct {
+	net.Listener
+	keepAlivePeriod time.Duration
+	idleTimeout     time.Duration
+}
+
+func (ln *timeoutListener) Accept() (net.Conn, error) {
+	c, err := ln.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if tc, ok := c.(*net.TCPConn); ok {
+		keepAlivePeriod := ln.keepAlivePeriod
+		if keepAlivePeriod <= 0 {
+			keepAlivePeriod = defaultKeepAlivePeriod
+		}
+		if err := tc.SetKeepAlive(true); err != nil {
+			c.Close()
+			return nil, err
+		}
+		if err := tc.SetKeepAlivePeriod(keepAlivePeriod); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+
+	conn := &timeoutConn{Conn: c, idleTimeout: ln.idleTimeout}
+	if ln.idleTimeout > 0 {
+		conn.idle = time.AfterFunc(ln.idleTimeout, func() {
+			c.Close()
+		})
+	}
+	return conn, nil
+}
+
+// timeoutConn closes the connection once idle for idleTimeout, if positive.
+type timeoutConn struct {
+	net.Conn
+	idleTimeout time.Duration
+	idle        *time.Timer
+}
+
+func (c *timeoutConn) active() {
+	if c.idle != nil {
+		c.idle.Reset(c.idleTimeout)
+	}
+}
+
+func (c *timeoutConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		c.active()
+	}
+	return n, err
+}
+
+func (c *timeoutConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	if n > 0 {
+		c.active()
+	}
+	return n, err
+}
+
+func (c *timeoutConn) Close() error {
+	if c.idle != nil {
+		c.idle.Stop()
+	}
+	return c.Conn.Close()
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package listener
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// connect returns both ends of a connection accepted by the listener wrapped with
+// the given timeouts.
+func connect(t *testing.T, keepAlivePeriod, idleTimeout time.Duration) (client, server net.Conn) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ln = WithTimeouts(ln, keepAlivePeriod, idleTimeout)
+	t.Cleanup(func() { ln.Close() })
+
+	client, err = net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	server, err = ln.Accept()
+	require.NoError(t, err, "should have accepted the connection")
+	t.Cleanup(func() { server.Close() })
+	return client, server
+}
+
+func TestWithTimeoutsDisabled(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	assert.Equal(t, ln, WithTimeouts(ln, 0, 0), "should not have wrapped the listener without timeouts")
+}
+
+func TestKeepAlivePeriod(t *testing.T) {
+	client, server := connect(t, time.Minute, 0)
+	_, isTCP := server.(*net.TCPConn)
+	assert.False(t, isTCP, "should have hidden the TCP connection from the keep-alive defaults of the generic server")
+
+	_, err := client.Write([]byte("ping"))
+	require.NoError(t, err)
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(server, buf)
+	require.NoError(t, err, "should have served the connection")
+	assert.Equal(t, "ping", string(buf))
+}
+
+func TestIdleTimeout(t *testing.T) {
+	idleTimeout := 100 * time.Millisecond
+	client, server := connect(t, 0, idleTimeout)
+
+	// the connection is kept open while active
+	buf := make([]byte, 1)
+	for i := 0; i < 5; i++ {
+		time.Sleep(idleTimeout / 2)
+		_, err := client.Write([]byte{'x'})
+		require.NoError(t, err)
+		_, err = server.Read(buf)
+		require.NoError(t, err, "should have kept the active connection open")
+	}
+
+	require.NoError(t, client.SetReadDeadline(time.Now().Add(10*idleTimeout)))
+	_, err := client.Read(buf)
+	assert.ErrorIs(t, err, io.EOF, "should have closed the idle connection")
+}
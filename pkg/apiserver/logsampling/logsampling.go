@@ -0,0 +1,61 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package logsampling samples the requests whose queries to the providers are
+// logged by the metrics API server.
+package logsampling
+
+import (
+	"math/rand"
+
+	"github.com/go-logr/logr"
+)
+
+// Sampler returns whether the queries of a request are logged.  A nil Sampler logs
+// all of them.
+type Sampler func() bool
+
+// NewSampler returns a Sampler logging 1 in rate requests, at random, or nil, logging
+// all of them, when rate is 1 or less.
+func NewSampler(rate int) Sampler {
+	return newSampler(rate, rand.Intn)
+}
+
+// newSampler returns a Sampler logging 1 in rate requests, picked with intn, which
+// returns a number in [0, n).
+func newSampler(rate int, intn func(n int) int) Sampler {
+	if rate <= 1 {
+		return nil
+	}
+	return func() bool {
+		return intn(rate) == 0
+	}
+}
+
+// Sample returns whether the queries of a request are logged.
+func (s Sampler) Sample() bool {
+	return s == nil || s()
+}
+
+// Logger returns the logger of the successful queries of a request: the given
+// logger when it is sampled, or one discarding everything.  Failed queries are
+// always logged, with the given logger.
+func (s Sampler) Logger(logger logr.Logger) logr.Logger {
+	if s.Sample() {
+		return logger
+	}
+	return logr.Discard()
+}
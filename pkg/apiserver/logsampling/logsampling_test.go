@@ -0,0 +1,39 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logsampling
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampler(t *testing.T) {
+	sampler := newSampler(10, rand.New(rand.NewSource(1)).Intn)
+	sampled := 0
+	for i := 0; i < 10000; i++ {
+		if sampler.Sample() {
+			sampled++
+		}
+	}
+	assert.InDelta(t, 1000, sampled, 100, "should have sampled about 1 in 10 requests")
+
+	assert.Nil(t, NewSampler(1), "should log all requests with a rate of 1")
+	assert.Nil(t, NewSampler(0), "should log all requests with a rate of 0")
+	assert.True(t, Sampler(nil).Sample(), "a nil sampler should log all requests")
+}
@@ -19,7 +19,10 @@ limitations under the License.
 package metrics
 
 import (
+	"sync"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/component-base/metrics"
 	"k8s.io/utils/clock"
 )
@@ -32,11 +35,124 @@ var (
 		StabilityLevel: metrics.ALPHA,
 		Buckets:        metrics.ExponentialBuckets(1, 1.364, 20),
 	}, []string{"group"})
+
+	customMetricsRequests = metrics.NewCounterVec(&metrics.CounterOpts{
+		Name:           "custom_metrics_requests_total",
+		Help:           "Number of requests for each custom metric",
+		StabilityLevel: metrics.ALPHA,
+	}, []string{"metric", "group_resource", "verb"})
+
+	metricValueAge = metrics.NewGaugeVec(&metrics.GaugeOpts{
+		Name:           "custom_metrics_value_age_seconds",
+		Help:           "Age of the most recent value of each metric, when it was last served",
+		StabilityLevel: metrics.ALPHA,
+	}, []string{"group", "metric"})
 )
 
+// otherMetricsLabel is the metric label of the requests for the custom metrics
+// above the limit of a RequestCounter.
+const otherMetricsLabel = "other"
+
 // RegisterMetrics registers API server metrics, given a registration function.
 func RegisterMetrics(registrationFunc func(metrics.Registerable) error) error {
-	return registrationFunc(metricFreshness)
+	if err := registrationFunc(metricFreshness); err != nil {
+		return err
+	}
+	if err := registrationFunc(customMetricsRequests); err != nil {
+		return err
+	}
+	return registrationFunc(metricValueAge)
+}
+
+// RequestCounter counts the requests for each custom metric.
+type RequestCounter interface {
+	// Count counts a request for the given metric of the given group resource,
+	// with the verb of the request, get or list.
+	Count(metric, groupResource, verb string)
+}
+
+// NewRequestCounter creates a RequestCounter recording at most maxMetrics distinct
+// metric names, so that clients requesting arbitrary metrics cannot grow the
+// cardinality of the counter without bound.  Requests for metrics above the limit
+// are recorded as "other".  Zero means no limit.
+func NewRequestCounter(maxMetrics int) RequestCounter {
+	return &requestCounter{
+		metrics: newMetricNames(maxMetrics),
+	}
+}
+
+type requestCounter struct {
+	metrics *metricNames
+}
+
+func (c *requestCounter) Count(metric, groupResource, verb string) {
+	label := metric
+	if !c.metrics.record(metric) {
+		label = otherMetricsLabel
+	}
+	customMetricsRequests.WithLabelValues(label, groupResource, verb).Inc()
+}
+
+// metricNames records at most limit distinct metric names.  Zero means no limit.
+type metricNames struct {
+	limit int
+
+	mu    sync.Mutex
+	names sets.Set[string]
+}
+
+func newMetricNames(limit int) *metricNames {
+	return &metricNames{limit: limit, names: sets.New[string]()}
+}
+
+// record records the metric, and returns whether it is recorded, i.e. whether
+// it was recorded before or is below the limit.
+func (m *metricNames) record(metric string) bool {
+	if m.limit <= 0 {
+		return true
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.names.Has(metric) {
+		if m.names.Len() >= m.limit {
+			return false
+		}
+		m.names.Insert(metric)
+	}
+	return true
+}
+
+// ValueAgeRecorder records the age of the values served for each metric, so that
+// stale metrics can be alerted on.
+type ValueAgeRecorder interface {
+	// Record records the timestamp of the most recent value served for the given
+	// metric of the given metrics API group.  Zero timestamps are not recorded.
+	Record(apiGroup, metric string, timestamp metav1.Time)
+}
+
+// NewValueAgeRecorder creates a ValueAgeRecorder recording at most maxMetrics
+// distinct metric names, so that clients requesting arbitrary metrics cannot grow
+// the cardinality of the gauge without bound.  The values of metrics above the
+// limit are not recorded, since their ages cannot be told apart.  Zero means no
+// limit.
+func NewValueAgeRecorder(maxMetrics int) ValueAgeRecorder {
+	return &valueAgeRecorder{
+		metrics: newMetricNames(maxMetrics),
+		clock:   clock.RealClock{},
+	}
+}
+
+type valueAgeRecorder struct {
+	metrics *metricNames
+	clock   clock.PassiveClock
+}
+
+func (r *valueAgeRecorder) Record(apiGroup, metric string, timestamp metav1.Time) {
+	if timestamp.IsZero() || !r.metrics.record(metric) {
+		return
+	}
+	metricValueAge.WithLabelValues(apiGroup, metric).Set(r.clock.Since(timestamp.Time).Seconds())
 }
 
 // FreshnessObserver captures individual observations of the timestamp of
@@ -114,3 +114,54 @@ func TestFreshness(t *testing.T) {
 		t.Errorf("unexpected error: %v", err)
 	}
 }
+
+func TestRequestCounter(t *testing.T) {
+	customMetricsRequests.Create(nil)
+	customMetricsRequests.Reset()
+
+	counter := NewRequestCounter(2)
+	for _, metric := range []string{"first", "second", "first", "third", "fourth"} {
+		counter.Count(metric, "pods", "get")
+	}
+	counter.Count("first", "pods", "list")
+
+	err := testutil.CollectAndCompare(customMetricsRequests, strings.NewReader(`
+	# HELP custom_metrics_requests_total [ALPHA] Number of requests for each custom metric
+	# TYPE custom_metrics_requests_total counter
+	custom_metrics_requests_total{group_resource="pods",metric="first",verb="get"} 2
+	custom_metrics_requests_total{group_resource="pods",metric="first",verb="list"} 1
+	custom_metrics_requests_total{group_resource="pods",metric="other",verb="get"} 2
+	custom_metrics_requests_total{group_resource="pods",metric="second",verb="get"} 1
+	`))
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestValueAgeRecorder(t *testing.T) {
+	now := time.Now()
+
+	metricValueAge.Create(nil)
+	metricValueAge.Reset()
+
+	recorder := NewValueAgeRecorder(2)
+	recorder.(*valueAgeRecorder).clock = clocktesting.NewFakeClock(now)
+	recorder.Record("custom.metrics.k8s.io", "first", metav1.NewTime(now.Add(-30*time.Second)))
+	recorder.Record("custom.metrics.k8s.io", "second", metav1.NewTime(now.Add(-5*time.Second)))
+	// the age is the one of the value served last
+	recorder.Record("custom.metrics.k8s.io", "first", metav1.NewTime(now.Add(-12*time.Second)))
+	// metrics above the limit are not recorded
+	recorder.Record("external.metrics.k8s.io", "third", metav1.NewTime(now.Add(-90*time.Second)))
+	// nor are values without timestamp
+	recorder.Record("custom.metrics.k8s.io", "second", metav1.Time{})
+
+	err := testutil.CollectAndCompare(metricValueAge, strings.NewReader(`
+	# HELP custom_metrics_value_age_seconds [ALPHA] Age of the most recent value of each metric, when it was last served
+	# TYPE custom_metrics_value_age_seconds gauge
+	custom_metrics_value_age_seconds{group="custom.metrics.k8s.io",metric="first"} 12
+	custom_metrics_value_age_seconds{group="custom.metrics.k8s.io",metric="second"} 5
+	`))
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,227 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/emicklei/go-restful/v3"
+
+	genericapiserver "k8s.io/apiserver/pkg/server"
+	"k8s.io/klog/v2"
+	"k8s.io/kube-openapi/pkg/builder"
+	"k8s.io/kube-openapi/pkg/builder3"
+	"k8s.io/kube-openapi/pkg/common/restfuladapter"
+	"k8s.io/kube-openapi/pkg/handler"
+	"k8s.io/kube-openapi/pkg/spec3"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// defaultOpenAPIServerURL is the URL of the server in the OpenAPI v3 documents
+// when none is configured.  The paths in the documents are the ones under which
+// the APIs are aggregated in the main API server, so they are relative to its root.
+const defaultOpenAPIServerURL = "/"
+
+// metricParameter is the path parameter of the custom metrics routes holding the
+// name of the metric.
+const metricParameter = "subresource"
+
+// installOpenAPIV3Servers rebuilds the OpenAPI v3 documents served by the
+// server, setting their servers field to the configured external URL.
+// The generic API server builds the documents in PrepareRun, without any servers,
+// so this must be called afterwards.
+func (s *CustomMetricsAdapterServer) installOpenAPIV3Servers(genericapiserver.PostStartHookContext) error {
+	if s.openAPIV3Config == nil || s.GenericAPIServer.OpenAPIV3VersionedService == nil {
+		return nil
+	}
+
+	for _, ws := range s.GenericAPIServer.Handler.GoRestfulContainer.RegisteredWebServices() {
+		spec, err := s.buildOpenAPIV3(ws)
+		if err != nil {
+			return err
+		}
+		// strip the "/" prefix from the path, as done by the generic API server
+		s.GenericAPIServer.OpenAPIV3VersionedService.UpdateGroupVersion(ws.RootPath()[1:], spec)
+	}
+	return nil
+}
+
+// buildOpenAPIV3 builds the OpenAPI v3 document of the given web service, with
+// the configured server, and the metrics listed in discovery.
+func (s *CustomMetricsAdapterServer) buildOpenAPIV3(ws *restful.WebService) (*spec3.OpenAPI, error) {
+	doc, err := builder3.BuildOpenAPISpecFromRoutes(restfuladapter.AdaptWebServices([]*restful.WebService{ws}), s.openAPIV3Config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to build OpenAPI v3 document for %s: %v", ws.RootPath(), err)
+	}
+	doc.Servers = []*spec3.Server{{ServerProps: spec3.ServerProps{URL: s.openAPIServerURL}}}
+
+	if doc.Paths == nil {
+		return doc, nil
+	}
+	for path, item := range doc.Paths.Paths {
+		metrics := s.documentedMetrics(path)
+		if metrics == nil || item == nil {
+			continue
+		}
+		parameters := make([]*spec3.Parameter, len(item.Parameters))
+		for i, parameter := range item.Parameters {
+			parameters[i] = parameter
+			if parameter != nil && parameter.Name == metricParameter && parameter.In == "path" && parameter.Schema != nil {
+				withEnum, schema := *parameter, *parameter.Schema
+				schema.Enum = metrics
+				withEnum.Schema = &schema
+				parameters[i] = &withEnum
+			}
+		}
+		item.Parameters = parameters
+	}
+	return doc, nil
+}
+
+// postProcessOpenAPIV2 sets the base path of the OpenAPI v2 document, and lists
+// the metrics listed in discovery in it.
+func (s *CustomMetricsAdapterServer) postProcessOpenAPIV2(swagger *spec.Swagger, basePath string) *spec.Swagger {
+	if basePath != "" {
+		swagger.BasePath = basePath
+	}
+	if swagger.Paths == nil {
+		return swagger
+	}
+
+	for path, item := range swagger.Paths.Paths {
+		metrics := s.documentedMetrics(path)
+		if metrics == nil {
+			continue
+		}
+		// parameters are only shared between paths once post-processed, so the
+		// parameters of each path can be changed
+		parameters := make([]spec.Parameter, len(item.Parameters))
+		for i, parameter := range item.Parameters {
+			if parameter.Name == metricParameter && parameter.In == "path" {
+				parameter.Enum = metrics
+			}
+			parameters[i] = parameter
+		}
+		item.Parameters = parameters
+		swagger.Paths.Paths[path] = item
+	}
+	return swagger
+}
+
+// documentedMetrics returns the names of the metrics of the custom metrics group
+// of the given path, or nil for paths of other groups, and paths without metric
+// parameter.  Metrics are not documented when discovery is only authorized per
+// metric, since all the users get the same documents.
+func (s *CustomMetricsAdapterServer) documentedMetrics(path string) []interface{} {
+	if s.discoveryAuthorizer != nil || !strings.Contains(path, "{"+metricParameter+"}") {
+		return nil
+	}
+	// paths are of the form /apis/<group>/<version>/...
+	segments := strings.SplitN(strings.TrimPrefix(path, "/apis/"), "/", 2)
+	lister, ok := s.metricListers[segments[0]]
+	if !ok {
+		return nil
+	}
+
+	seen := map[string]bool{}
+	names := []string{}
+	for _, resource := range lister.ListAPIResources() {
+		// resources are named <resource>/<metric>
+		_, metric, _ := strings.Cut(resource.Name, "/")
+		if metric != "" && !seen[metric] {
+			seen[metric] = true
+			names = append(names, metric)
+		}
+	}
+	sort.Strings(names)
+	metrics := make([]interface{}, len(names))
+	for i, name := range names {
+		metrics[i] = name
+	}
+	return metrics
+}
+
+// servesOpenAPI returns whether the server serves OpenAPI documents.
+func (s *CustomMetricsAdapterServer) servesOpenAPI() bool {
+	return s.openAPIConfig != nil || s.openAPIV3Config != nil
+}
+
+// refreshOpenAPI rebuilds the OpenAPI documents served by the server, for the
+// metrics they list to match the ones listed in discovery.  The documents are only
+// served once the server is prepared to run, which builds them in the first place.
+func (s *CustomMetricsAdapterServer) refreshOpenAPI() {
+	klog.V(4).InfoS("Refreshing the OpenAPI documents of custom metrics")
+	if s.openAPIConfig != nil && s.GenericAPIServer.OpenAPIVersionedService != nil {
+		webServices := s.GenericAPIServer.Handler.GoRestfulContainer.RegisteredWebServices()
+		swagger, err := builder.BuildOpenAPISpecFromRoutes(restfuladapter.AdaptWebServices(webServices), s.openAPIConfig)
+		if err == nil {
+			// pruned like the document built by the generic API server
+			swagger.Definitions = handler.PruneDefaults(swagger.Definitions)
+			err = s.GenericAPIServer.OpenAPIVersionedService.UpdateSpec(swagger)
+		}
+		if err != nil {
+			klog.ErrorS(err, "Unable to refresh the OpenAPI v2 document")
+		}
+	}
+	if err := s.installOpenAPIV3Servers(genericapiserver.PostStartHookContext{}); err != nil {
+		klog.ErrorS(err, "Unable to refresh the OpenAPI v3 documents")
+	}
+}
+
+// WriteOpenAPI writes, as JSON, the OpenAPI document of the given version ("v2" or
+// "v3") describing the APIs installed in the server.  For v3, it writes an object
+// holding the document of each group version, keyed by its path.  The documents
+// are built from the installed APIs, so the server does not need to be running.
+func (s *CustomMetricsAdapterServer) WriteOpenAPI(w io.Writer, version string) error {
+	webServices := s.GenericAPIServer.Handler.GoRestfulContainer.RegisteredWebServices()
+
+	var doc interface{}
+	switch version {
+	case "v2":
+		if s.openAPIConfig == nil {
+			return fmt.Errorf("OpenAPI v2 is not configured")
+		}
+		spec, err := builder.BuildOpenAPISpecFromRoutes(restfuladapter.AdaptWebServices(webServices), s.openAPIConfig)
+		if err != nil {
+			return fmt.Errorf("unable to build OpenAPI v2 document: %v", err)
+		}
+		doc = spec
+	case "v3":
+		if s.openAPIV3Config == nil {
+			return fmt.Errorf("OpenAPI v3 is not configured")
+		}
+		specs := make(map[string]*spec3.OpenAPI, len(webServices))
+		for _, ws := range webServices {
+			spec, err := s.buildOpenAPIV3(ws)
+			if err != nil {
+				return err
+			}
+			specs[ws.RootPath()[1:]] = spec
+		}
+		doc = specs
+	default:
+		return fmt.Errorf("unknown OpenAPI version %q, expected v2 or v3", version)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
@@ -0,0 +1,227 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	openapinamer "k8s.io/apiserver/pkg/endpoints/openapi"
+	genericapiserver "k8s.io/apiserver/pkg/server"
+	"k8s.io/client-go/rest"
+	openapicommon "k8s.io/kube-openapi/pkg/common"
+	"k8s.io/kube-openapi/pkg/handler3"
+	"k8s.io/kube-openapi/pkg/spec3"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/filters"
+	generatedcore "sigs.k8s.io/custom-metrics-apiserver/pkg/generated/openapi/core"
+	generatedcustommetrics "sigs.k8s.io/custom-metrics-apiserver/pkg/generated/openapi/custommetrics"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider/fake"
+)
+
+func openAPIV3Servers(t *testing.T, serverURL string) []*spec3.Server {
+	genericConfig := genericapiserver.NewConfig(Codecs)
+	genericConfig.ExternalAddress = "localhost:443"
+	genericConfig.LoopbackClientConfig = &rest.Config{}
+	getDefinitions := func(ref openapicommon.ReferenceCallback) map[string]openapicommon.OpenAPIDefinition {
+		definitions := generatedcore.GetOpenAPIDefinitions(ref)
+		for k, v := range generatedcustommetrics.GetOpenAPIDefinitions(ref) {
+			definitions[k] = v
+		}
+		return definitions
+	}
+	genericConfig.OpenAPIV3Config = genericapiserver.DefaultOpenAPIV3Config(getDefinitions, openapinamer.NewDefinitionNamer(Scheme))
+	config := &Config{
+		GenericConfig: genericConfig,
+		ExtraConfig:   ExtraConfig{OpenAPIServerURL: serverURL},
+	}
+
+	server, err := config.Complete(nil).New("test", fake.NewProvider(), nil)
+	require.NoError(t, err, "should have been able to create the server")
+	server.GenericAPIServer.PrepareRun()
+	require.NoError(t, server.installOpenAPIV3Servers(genericapiserver.PostStartHookContext{}))
+
+	response := httptest.NewRecorder()
+	server.GenericAPIServer.Handler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/openapi/v3/apis/custom.metrics.k8s.io/v1beta2", nil))
+	require.Equal(t, http.StatusOK, response.Code, "should have served the OpenAPI v3 document: %s", response.Body.String())
+
+	doc := &spec3.OpenAPI{}
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), doc), "should have served a valid OpenAPI v3 document")
+	return doc.Servers
+}
+
+func TestOpenAPIV3Servers(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		servers := openAPIV3Servers(t, "")
+		if assert.Len(t, servers, 1) {
+			assert.Equal(t, "/", servers[0].URL, "should have defaulted to the root of the main API server")
+		}
+	})
+
+	t.Run("external URL", func(t *testing.T) {
+		servers := openAPIV3Servers(t, "https://metrics.example.com")
+		if assert.Len(t, servers, 1) {
+			assert.Equal(t, "https://metrics.example.com", servers[0].URL, "should have used the configured URL")
+		}
+	})
+}
+
+func TestBasePath(t *testing.T) {
+	genericConfig := genericapiserver.NewConfig(Codecs)
+	genericConfig.ExternalAddress = "localhost:443"
+	genericConfig.LoopbackClientConfig = &rest.Config{}
+	getDefinitions := func(ref openapicommon.ReferenceCallback) map[string]openapicommon.OpenAPIDefinition {
+		definitions := generatedcore.GetOpenAPIDefinitions(ref)
+		for k, v := range generatedcustommetrics.GetOpenAPIDefinitions(ref) {
+			definitions[k] = v
+		}
+		return definitions
+	}
+	genericConfig.OpenAPIConfig = genericapiserver.DefaultOpenAPIConfig(getDefinitions, openapinamer.NewDefinitionNamer(Scheme))
+	genericConfig.OpenAPIV3Config = genericapiserver.DefaultOpenAPIV3Config(getDefinitions, openapinamer.NewDefinitionNamer(Scheme))
+	buildHandlerChain := genericConfig.BuildHandlerChainFunc
+	genericConfig.BuildHandlerChainFunc = func(apiHandler http.Handler, c *genericapiserver.Config) http.Handler {
+		return filters.WithBasePath(buildHandlerChain(apiHandler, c), "/metrics-adapter")
+	}
+	config := &Config{
+		GenericConfig: genericConfig,
+		ExtraConfig:   ExtraConfig{BasePath: "/metrics-adapter"},
+	}
+
+	server, err := config.Complete(nil).New("test", fake.NewProvider(), nil)
+	require.NoError(t, err, "should have been able to create the server")
+	server.GenericAPIServer.PrepareRun()
+	require.NoError(t, server.installOpenAPIV3Servers(genericapiserver.PostStartHookContext{}))
+	get := func(path string) []byte {
+		response := httptest.NewRecorder()
+		server.GenericAPIServer.Handler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, http.StatusOK, response.Code, "should have served %s: %s", path, response.Body.String())
+		return response.Body.Bytes()
+	}
+
+	resources := &metav1.APIResourceList{}
+	require.NoError(t, json.Unmarshal(get("/metrics-adapter/apis/custom.metrics.k8s.io/v1beta2"), resources))
+	assert.Equal(t, "custom.metrics.k8s.io/v1beta2", resources.GroupVersion, "should have routed the request under the base path")
+
+	discovery := &handler3.OpenAPIV3Discovery{}
+	require.NoError(t, json.Unmarshal(get("/metrics-adapter/openapi/v3"), discovery))
+	link := discovery.Paths["apis/custom.metrics.k8s.io/v1beta2"].ServerRelativeURL
+	require.True(t, strings.HasPrefix(link, "/metrics-adapter/openapi/v3/apis/custom.metrics.k8s.io/v1beta2"), "should have linked to the document under the base path, got %q", link)
+
+	doc := &spec3.OpenAPI{}
+	require.NoError(t, json.Unmarshal(get(link), doc))
+	if assert.Len(t, doc.Servers, 1) {
+		assert.Equal(t, "/metrics-adapter", doc.Servers[0].URL, "should have defaulted the server URL to the base path")
+	}
+
+	swagger := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(get("/metrics-adapter/openapi/v2"), &swagger))
+	assert.Equal(t, "/metrics-adapter", swagger["basePath"], "should have set the base path of the OpenAPI v2 document")
+}
+
+// notifyingProvider serves metrics which change when signaled.
+type notifyingProvider struct {
+	*changingProvider
+	changed chan struct{}
+}
+
+func (p *notifyingProvider) MetricsChanged() <-chan struct{} {
+	return p.changed
+}
+
+func TestOpenAPIMetrics(t *testing.T) {
+	genericConfig := genericapiserver.NewConfig(Codecs)
+	genericConfig.ExternalAddress = "localhost:443"
+	genericConfig.LoopbackClientConfig = &rest.Config{}
+	getDefinitions := func(ref openapicommon.ReferenceCallback) map[string]openapicommon.OpenAPIDefinition {
+		definitions := generatedcore.GetOpenAPIDefinitions(ref)
+		for k, v := range generatedcustommetrics.GetOpenAPIDefinitions(ref) {
+			definitions[k] = v
+		}
+		return definitions
+	}
+	genericConfig.OpenAPIConfig = genericapiserver.DefaultOpenAPIConfig(getDefinitions, openapinamer.NewDefinitionNamer(Scheme))
+	genericConfig.OpenAPIV3Config = genericapiserver.DefaultOpenAPIV3Config(getDefinitions, openapinamer.NewDefinitionNamer(Scheme))
+	config := &Config{GenericConfig: genericConfig}
+
+	prov := &notifyingProvider{
+		changingProvider: &changingProvider{MetricsProvider: fake.NewProvider(), metrics: []string{"old-metric"}},
+		changed:          make(chan struct{}, 1),
+	}
+	server, err := config.Complete(nil).New("test", prov, nil)
+	require.NoError(t, err, "should have been able to create the server")
+	server.GenericAPIServer.PrepareRun()
+	require.NoError(t, server.installOpenAPIV3Servers(genericapiserver.PostStartHookContext{}))
+	stop := make(chan struct{})
+	defer close(stop)
+	require.NoError(t, server.relayMetricsChanges(genericapiserver.PostStartHookContext{StopCh: stop}))
+
+	get := func(path string) []byte {
+		response := httptest.NewRecorder()
+		server.GenericAPIServer.Handler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, http.StatusOK, response.Code, "should have served %s: %s", path, response.Body.String())
+		return response.Body.Bytes()
+	}
+	v3Metrics := func() []interface{} {
+		doc := &spec3.OpenAPI{}
+		require.NoError(t, json.Unmarshal(get("/openapi/v3/apis/custom.metrics.k8s.io/v1beta2"), doc))
+		item := doc.Paths.Paths["/apis/custom.metrics.k8s.io/v1beta2/namespaces/{namespace}/{resource}/{name}/{subresource}"]
+		require.NotNil(t, item, "should have documented the path of the metrics of named objects")
+		for _, parameter := range item.Parameters {
+			if parameter.Name == "subresource" {
+				return parameter.Schema.Enum
+			}
+		}
+		return nil
+	}
+	v2Metrics := func() []interface{} {
+		doc := &spec.Swagger{}
+		require.NoError(t, json.Unmarshal(get("/openapi/v2"), doc))
+		item := doc.Paths.Paths["/apis/custom.metrics.k8s.io/v1beta1/namespaces/{namespace}/{resource}/{name}/{subresource}"]
+		for _, parameter := range item.Parameters {
+			// parameters are shared between paths
+			if ref := parameter.Ref.String(); ref != "" {
+				parameter = doc.Parameters[strings.TrimPrefix(ref, "#/parameters/")]
+			}
+			if parameter.Name == "subresource" {
+				return parameter.Enum
+			}
+		}
+		return nil
+	}
+
+	assert.Equal(t, []interface{}{"old-metric"}, v3Metrics(), "should have listed the metrics in the OpenAPI v3 document")
+	assert.Equal(t, []interface{}{"old-metric"}, v2Metrics(), "should have listed the metrics in the OpenAPI v2 document")
+
+	prov.setMetrics("new-metric")
+	prov.changed <- struct{}{}
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]interface{}{"new-metric"}, v3Metrics())
+	}, 5*time.Second, 10*time.Millisecond, "should have refreshed the OpenAPI v3 document")
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]interface{}{"new-metric"}, v2Metrics())
+	}, 5*time.Second, 10*time.Millisecond, "should have refreshed the OpenAPI v2 document")
+}
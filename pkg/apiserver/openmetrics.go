@@ -0,0 +1,52 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"io"
+	"net/http"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+const metricsPath = "/metrics"
+
+// installOpenMetrics replaces the metrics handler of the generic API server by one
+// negotiating the OpenMetrics format.  Like the generic one, it resets the metrics
+// on DELETE requests when profiling is enabled.
+func (s *CustomMetricsAdapterServer) installOpenMetrics(withReset bool) {
+	handler := metrics.HandlerFor(legacyregistry.DefaultGatherer, metrics.HandlerOpts{EnableOpenMetrics: true})
+	if withReset {
+		handler = resetOnDelete(handler)
+	}
+
+	mux := s.GenericAPIServer.Handler.NonGoRestfulMux
+	mux.Unregister(metricsPath)
+	mux.Handle(metricsPath, handler)
+}
+
+func resetOnDelete(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodDelete {
+			legacyregistry.Reset()
+			_, _ = io.WriteString(w, "metrics reset\n")
+			return
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	genericapiserver "k8s.io/apiserver/pkg/server"
+	"k8s.io/client-go/rest"
+
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider/fake"
+)
+
+const openMetricsAccept = "application/openmetrics-text;version=1.0.0,text/plain;version=0.0.4;q=0.5"
+
+func scrapeMetrics(t *testing.T, openMetrics bool, accept string) *httptest.ResponseRecorder {
+	genericConfig := genericapiserver.NewConfig(Codecs)
+	genericConfig.ExternalAddress = "localhost:443"
+	genericConfig.LoopbackClientConfig = &rest.Config{}
+	config := &Config{
+		GenericConfig: genericConfig,
+		ExtraConfig:   ExtraConfig{EnableOpenMetrics: openMetrics},
+	}
+
+	server, err := config.Complete(nil).New("test", fake.NewProvider(), nil)
+	require.NoError(t, err, "should have been able to create the server")
+
+	request := httptest.NewRequest(http.MethodGet, metricsPath, nil)
+	request.Header.Set("Accept", accept)
+	response := httptest.NewRecorder()
+	server.GenericAPIServer.Handler.ServeHTTP(response, request)
+	require.Equal(t, http.StatusOK, response.Code, "should have served the metrics: %s", response.Body.String())
+	return response
+}
+
+func TestOpenMetrics(t *testing.T) {
+	t.Run("enabled", func(t *testing.T) {
+		response := scrapeMetrics(t, true, openMetricsAccept)
+		assert.True(t, strings.HasPrefix(response.Header().Get("Content-Type"), "application/openmetrics-text; version=1.0.0"),
+			"should have served the OpenMetrics format, got %q", response.Header().Get("Content-Type"))
+		assert.True(t, strings.HasSuffix(response.Body.String(), "# EOF\n"), "should have terminated the exposition with an EOF marker")
+	})
+
+	t.Run("enabled without asking for it", func(t *testing.T) {
+		response := scrapeMetrics(t, true, "")
+		assert.True(t, strings.HasPrefix(response.Header().Get("Content-Type"), "text/plain; version=0.0.4"),
+			"should have served the Prometheus text format, got %q", response.Header().Get("Content-Type"))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		response := scrapeMetrics(t, false, openMetricsAccept)
+		assert.True(t, strings.HasPrefix(response.Header().Get("Content-Type"), "text/plain; version=0.0.4"),
+			"should have served the Prometheus text format, got %q", response.Header().Get("Content-Type"))
+		assert.False(t, strings.HasSuffix(response.Body.String(), "# EOF\n"))
+	})
+}
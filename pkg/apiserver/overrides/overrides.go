@@ -0,0 +1,136 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package overrides lets the clients of the metrics APIs force the values of the
+// metrics they request, bypassing the providers, for integration tests.
+package overrides
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/endpoints/handlers/responsewriters"
+)
+
+// HeaderMetricOverride is the header of the requests forcing the values of the
+// requested metrics: either a quantity, the value for any object, or a
+// comma-separated list of name=quantity pairs, the values for the listed objects.
+const HeaderMetricOverride = "X-Metric-Override"
+
+type overrideKey struct{}
+
+// Override is the values forced by a request.
+type Override struct {
+	// value is the value for any object, if set
+	value *resource.Quantity
+	// values are the values for the listed objects, by name
+	values map[string]resource.Quantity
+}
+
+// Parse parses the value of the HeaderMetricOverride header.
+func Parse(header string) (*Override, error) {
+	if !strings.Contains(header, "=") {
+		value, err := resource.ParseQuantity(strings.TrimSpace(header))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q: %v", header, err)
+		}
+		return &Override{value: &value}, nil
+	}
+
+	values := map[string]resource.Quantity{}
+	for _, pair := range strings.Split(header, ",") {
+		name, rawValue, _ := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, fmt.Errorf("invalid value %q, expected name=quantity", pair)
+		}
+		value, err := resource.ParseQuantity(strings.TrimSpace(rawValue))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %v", name, err)
+		}
+		values[name] = value
+	}
+	return &Override{values: values}, nil
+}
+
+// Value returns the value forced for any object, if any.
+func (o *Override) Value() (resource.Quantity, bool) {
+	if o.value == nil {
+		return resource.Quantity{}, false
+	}
+	return o.value.DeepCopy(), true
+}
+
+// ValueFor returns the value forced for the named object, if any.
+func (o *Override) ValueFor(name string) (resource.Quantity, bool) {
+	if o.value != nil {
+		return o.value.DeepCopy(), true
+	}
+	value, ok := o.values[name]
+	return value.DeepCopy(), ok
+}
+
+// Names returns the sorted names of the objects listed with their values.
+func (o *Override) Names() []string {
+	names := make([]string, 0, len(o.values))
+	for name := range o.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WithOverrides passes the values forced by the HeaderMetricOverride header of the
+// requests to the storages of the APIs, in their context, when enabled.  Requests
+// with a malformed header, or with the header when overrides are disabled, are
+// rejected with 400 Bad Request, rather than served the values of the providers.
+//
+// Since any client authorized to get a metric can then force its value, overrides
+// must only be enabled for tests.
+func WithOverrides(handler http.Handler, enabled bool, s runtime.NegotiatedSerializer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		header := req.Header.Get(HeaderMetricOverride)
+		if header == "" {
+			handler.ServeHTTP(w, req)
+			return
+		}
+		if !enabled {
+			err := apierr.NewBadRequest(fmt.Sprintf("the %s header is only accepted by adapters run with --enable-test-overrides", HeaderMetricOverride))
+			responsewriters.ErrorNegotiated(err, s, schema.GroupVersion{}, w, req)
+			return
+		}
+
+		override, err := Parse(header)
+		if err != nil {
+			responsewriters.ErrorNegotiated(apierr.NewBadRequest(fmt.Sprintf("invalid %s header: %v", HeaderMetricOverride, err)), s, schema.GroupVersion{}, w, req)
+			return
+		}
+		handler.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), overrideKey{}, override)))
+	})
+}
+
+// From returns the values forced by the request of the context, if any.
+func From(ctx context.Context) (*Override, bool) {
+	override, ok := ctx.Value(overrideKey{}).(*Override)
+	return override, ok
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package overrides
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestParse(t *testing.T) {
+	override, err := Parse("42")
+	require.NoError(t, err)
+	value, ok := override.ValueFor("any-object")
+	require.True(t, ok, "should have forced the value for any object")
+	assert.Equal(t, resource.MustParse("42"), value)
+	assert.Empty(t, override.Names())
+
+	override, err = Parse("web-1=1, web-0=500m")
+	require.NoError(t, err)
+	_, ok = override.Value()
+	assert.False(t, ok, "should not have forced a value for any object")
+	assert.Equal(t, []string{"web-0", "web-1"}, override.Names())
+	value, ok = override.ValueFor("web-0")
+	require.True(t, ok)
+	assert.Equal(t, resource.MustParse("500m"), value)
+	_, ok = override.ValueFor("web-2")
+	assert.False(t, ok, "should not have forced a value for an unlisted object")
+
+	for _, header := range []string{"fast", "web-0=fast", "=1", "web-0=1,"} {
+		_, err := Parse(header)
+		assert.Error(t, err, "should have rejected %q", header)
+	}
+}
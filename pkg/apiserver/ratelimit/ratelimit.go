@@ -0,0 +1,164 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ratelimit provides per-metric rate limiting for the metrics API server.
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/utils/clock"
+)
+
+// Limits maps metric names to the maximum number of queries per second allowed
+// for each of them.  It can be used as a flag, in the form "metric=qps,...".
+type Limits map[string]float64
+
+// String implements pflag.Value.
+func (l *Limits) String() string {
+	pairs := make([]string, 0, len(*l))
+	for metric, qps := range *l {
+		pairs = append(pairs, metric+"="+strconv.FormatFloat(qps, 'f', -1, 64))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// Set implements pflag.Value.
+func (l *Limits) Set(value string) error {
+	limits := Limits{}
+	for _, pair := range strings.Split(value, ",") {
+		if len(pair) == 0 {
+			continue
+		}
+		metric, rawQPS, found := strings.Cut(pair, "=")
+		if !found || len(metric) == 0 {
+			return fmt.Errorf("invalid metric rate limit %q, expected metric=qps", pair)
+		}
+		qps, err := strconv.ParseFloat(rawQPS, 64)
+		if err != nil {
+			return fmt.Errorf("invalid QPS for metric %s: %v", metric, err)
+		}
+		limits[metric] = qps
+	}
+	*l = limits
+	return nil
+}
+
+// Type implements pflag.Value.
+func (l *Limits) Type() string {
+	return "mapStringFloat64"
+}
+
+// Validate checks that all the limits are positive.
+func (l Limits) Validate() []error {
+	errors := []error{}
+	for metric, qps := range l {
+		if qps <= 0 || math.IsInf(qps, 0) || math.IsNaN(qps) {
+			errors = append(errors, fmt.Errorf("rate limit for metric %s must be a positive number, got %v", metric, qps))
+		}
+	}
+	return errors
+}
+
+// defaultMaxLimiters bounds the number of token buckets of a MetricRateLimiter.
+// Keys are chosen by clients, which could otherwise grow them without bound.
+const defaultMaxLimiters = 1000
+
+// MetricRateLimiter limits the rate of queries for individual metrics.
+// Each distinct key of a limited metric gets its own token bucket, so that
+// one hot metric does not consume the budget of another.  Once there are too
+// many buckets, the keys without one share a single bucket per metric.
+type MetricRateLimiter struct {
+	limits      Limits
+	clock       clock.PassiveClock
+	maxLimiters int
+
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+	// overflow holds the buckets shared by the keys of each metric beyond maxLimiters
+	overflow map[string]*rate.Limiter
+}
+
+// NewMetricRateLimiter creates a MetricRateLimiter enforcing the given limits.
+// It returns nil when there are no limits, which allows every query.
+func NewMetricRateLimiter(limits Limits) *MetricRateLimiter {
+	if len(limits) == 0 {
+		return nil
+	}
+	return &MetricRateLimiter{
+		limits:      limits,
+		clock:       clock.RealClock{},
+		maxLimiters: defaultMaxLimiters,
+		limiters:    make(map[string]*rate.Limiter),
+		overflow:    make(map[string]*rate.Limiter),
+	}
+}
+
+// Accept records a query of the given metric, identified by key, and reports
+// whether it may proceed.  When it may not, it also returns how long the
+// caller should wait before trying again.
+func (l *MetricRateLimiter) Accept(metric, key string) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+	qps, limited := l.limits[metric]
+	if !limited {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	limiter, ok := l.limiters[key]
+	if !ok {
+		limiters := l.limiters
+		if len(limiters) >= l.maxLimiters {
+			limiters, key = l.overflow, metric
+			limiter, ok = limiters[key]
+		}
+		if !ok {
+			limiter = rate.NewLimiter(rate.Limit(qps), int(math.Max(1, math.Ceil(qps))))
+			limiters[key] = limiter
+		}
+	}
+	l.mu.Unlock()
+
+	now := l.clock.Now()
+	reservation := limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// NewTooManyRequestsError returns an error indicating that queries for the given
+// metric are being rate limited, and should be retried after the given delay.
+func NewTooManyRequestsError(metric string, retryAfter time.Duration) error {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return apierr.NewTooManyRequests(fmt.Sprintf("too many requests for metric %s, please try again later", metric), seconds)
+}
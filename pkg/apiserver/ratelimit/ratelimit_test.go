@@ -0,0 +1,109 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func TestLimitsFlag(t *testing.T) {
+	var limits Limits
+	require.NoError(t, limits.Set("foo=2,bar=0.5"))
+	assert.Equal(t, Limits{"foo": 2, "bar": 0.5}, limits)
+	assert.Equal(t, "bar=0.5,foo=2", limits.String())
+	assert.Empty(t, limits.Validate())
+
+	assert.Error(t, limits.Set("foo"), "a limit without QPS should be rejected")
+	assert.Error(t, limits.Set("foo=fast"), "a non-numeric QPS should be rejected")
+
+	require.NoError(t, limits.Set("foo=0"))
+	assert.Len(t, limits.Validate(), 1, "a zero QPS should not be valid")
+}
+
+func TestMetricRateLimiter(t *testing.T) {
+	limiter := NewMetricRateLimiter(Limits{"foo": 1, "bar": 1})
+
+	accepted, _ := limiter.Accept("foo", "pods/foo")
+	assert.True(t, accepted, "the first query for foo should be accepted")
+	accepted, retryAfter := limiter.Accept("foo", "pods/foo")
+	assert.False(t, accepted, "the second query for foo should be limited")
+	assert.Positive(t, retryAfter, "a limited query should have a retry delay")
+
+	accepted, _ = limiter.Accept("bar", "pods/bar")
+	assert.True(t, accepted, "queries for bar should not be limited by queries for foo")
+	accepted, _ = limiter.Accept("foo", "nodes/foo")
+	assert.True(t, accepted, "queries for foo on nodes should not be limited by queries for foo on pods")
+
+	for i := 0; i < 10; i++ {
+		accepted, _ = limiter.Accept("baz", "pods/baz")
+		assert.True(t, accepted, "queries for metrics without limits should always be accepted")
+	}
+}
+
+func TestMetricRateLimiterRefill(t *testing.T) {
+	clock := testingclock.NewFakePassiveClock(time.Now())
+	limiter := NewMetricRateLimiter(Limits{"foo": 2})
+	limiter.clock = clock
+
+	for i := 0; i < 2; i++ {
+		accepted, _ := limiter.Accept("foo", "pods/foo")
+		assert.True(t, accepted, "queries within the burst should be accepted")
+	}
+	accepted, retryAfter := limiter.Accept("foo", "pods/foo")
+	assert.False(t, accepted, "queries beyond the burst should be limited")
+	assert.Equal(t, 500*time.Millisecond, retryAfter, "should have waited for the next token")
+
+	clock.SetTime(clock.Now().Add(499 * time.Millisecond))
+	accepted, retryAfter = limiter.Accept("foo", "pods/foo")
+	assert.False(t, accepted, "queries before the next token should be limited")
+	assert.Equal(t, time.Millisecond, retryAfter)
+
+	clock.SetTime(clock.Now().Add(time.Millisecond))
+	accepted, _ = limiter.Accept("foo", "pods/foo")
+	assert.True(t, accepted, "queries should be accepted once a token is available")
+}
+
+func TestMetricRateLimiterMaxLimiters(t *testing.T) {
+	limiter := NewMetricRateLimiter(Limits{"foo": 1, "bar": 1})
+	limiter.maxLimiters = 2
+
+	for _, key := range []string{"pods/foo", "nodes/foo"} {
+		accepted, _ := limiter.Accept("foo", key)
+		assert.True(t, accepted, "the first query for %s should be accepted", key)
+	}
+	accepted, _ := limiter.Accept("foo", "services/foo")
+	assert.True(t, accepted, "the first query beyond the maximum number of buckets should be accepted")
+	accepted, _ = limiter.Accept("foo", "deployments/foo")
+	assert.False(t, accepted, "queries beyond the maximum number of buckets should share a bucket")
+	accepted, _ = limiter.Accept("bar", "services/bar")
+	assert.True(t, accepted, "queries for bar should not share the bucket of foo")
+	assert.Len(t, limiter.limiters, 2, "should not have created buckets beyond the maximum")
+}
+
+func TestNilMetricRateLimiter(t *testing.T) {
+	limiter := NewMetricRateLimiter(nil)
+	assert.Nil(t, limiter)
+
+	accepted, _ := limiter.Accept("foo", "pods/foo")
+	assert.True(t, accepted, "a nil limiter should accept every query")
+}
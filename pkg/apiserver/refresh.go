@@ -0,0 +1,133 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apiserver/pkg/endpoints/discovery"
+	genericapiserver "k8s.io/apiserver/pkg/server"
+	"k8s.io/klog/v2"
+)
+
+// discoveryRefreshDelay is how long RefreshDiscovery waits for further calls before
+// rebuilding discovery, so that bursts of changes only rebuild it once.
+const discoveryRefreshDelay = 100 * time.Millisecond
+
+// refreshableLister is implemented by the resource listers caching the metrics
+// they list.
+type refreshableLister interface {
+	Refresh()
+}
+
+// discoveryRefresher refreshes the listers of the custom metrics groups.
+type discoveryRefresher struct {
+	mu      sync.Mutex
+	listers []refreshableLister
+	pending bool
+	// refreshed is called once the listers are refreshed, if set, to update the
+	// documents built from them
+	refreshed func()
+}
+
+func (r *discoveryRefresher) add(lister discovery.APIResourceLister) {
+	refreshable, ok := lister.(refreshableLister)
+	if !ok {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.listers = append(r.listers, refreshable)
+}
+
+// schedule refreshes the listers after discoveryRefreshDelay, unless a refresh is
+// already scheduled.
+func (r *discoveryRefresher) schedule() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.pending {
+		return
+	}
+	r.pending = true
+	time.AfterFunc(discoveryRefreshDelay, r.refresh)
+}
+
+func (r *discoveryRefresher) refresh() {
+	r.mu.Lock()
+	// changes made while refreshing schedule another refresh
+	r.pending = false
+	listers := r.listers
+	refreshed := r.refreshed
+	r.mu.Unlock()
+
+	klog.V(4).InfoS("Refreshing the discovery of custom metrics")
+	for _, lister := range listers {
+		lister.Refresh()
+	}
+	if refreshed != nil {
+		refreshed()
+	}
+}
+
+// metricsChanges relays the signals of a notifying provider to the refresher,
+// instead of its lister, so that the documents built from the lister are refreshed
+// along with it.
+type metricsChanges struct {
+	// provider is the channel the provider signals changes on
+	provider <-chan struct{}
+	// lister is the channel the lister is passed, which is closed when the one of
+	// the provider is, to stop caching
+	lister chan struct{}
+}
+
+// relayMetricsChanges schedules a refresh of discovery each time a notifying
+// provider signals that its metrics changed, until the server stops.
+func (s *CustomMetricsAdapterServer) relayMetricsChanges(ctx genericapiserver.PostStartHookContext) error {
+	for _, changes := range s.metricsChanges {
+		go func(changes metricsChanges) {
+			for {
+				select {
+				case _, ok := <-changes.provider:
+					if !ok {
+						close(changes.lister)
+						return
+					}
+					s.discoveryRefresher.schedule()
+				case <-ctx.StopCh:
+					return
+				}
+			}
+		}(changes)
+	}
+	return nil
+}
+
+// RefreshDiscovery rebuilds the discovery documents of the custom metrics groups,
+// as when their providers signal a change, for instance when the provider learns
+// by other means than NotifyingCustomMetricsProvider that its set of metrics
+// changed.  It returns immediately, and calls made within a short delay are
+// coalesced into a single rebuild.  It may be called concurrently.
+//
+// Discovery is only cached for providers implementing
+// provider.NotifyingCustomMetricsProvider: for others, and for external metrics,
+// the metrics are listed for each discovery request.
+func (s *CustomMetricsAdapterServer) RefreshDiscovery() {
+	s.discoveryRefresher.schedule()
+}
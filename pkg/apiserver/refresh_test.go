@@ -0,0 +1,120 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	genericapiserver "k8s.io/apiserver/pkg/server"
+	"k8s.io/client-go/rest"
+
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider/fake"
+)
+
+// changingProvider serves metrics which change without being signaled.
+type changingProvider struct {
+	provider.MetricsProvider
+
+	mu      sync.Mutex
+	metrics []string
+	lists   int
+}
+
+func (p *changingProvider) ListAllMetrics() []provider.CustomMetricInfo {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.lists++
+	infos := []provider.CustomMetricInfo{}
+	for _, metric := range p.metrics {
+		infos = append(infos, provider.CustomMetricInfo{GroupResource: schema.GroupResource{Resource: "pods"}, Namespaced: true, Metric: metric})
+	}
+	return infos
+}
+
+func (p *changingProvider) MetricsChanged() <-chan struct{} {
+	return make(chan struct{})
+}
+
+func (p *changingProvider) setMetrics(metrics ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.metrics = metrics
+}
+
+func TestRefreshDiscovery(t *testing.T) {
+	genericConfig := genericapiserver.NewConfig(Codecs)
+	genericConfig.ExternalAddress = "localhost:443"
+	genericConfig.LoopbackClientConfig = &rest.Config{}
+	config := &Config{GenericConfig: genericConfig}
+
+	prov := &changingProvider{MetricsProvider: fake.NewProvider(), metrics: []string{"old-metric"}}
+	server, err := config.Complete(nil).New("test", prov, nil)
+	require.NoError(t, err, "should have been able to create the server")
+
+	discovered := func(version string) []string {
+		response := httptest.NewRecorder()
+		server.GenericAPIServer.Handler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/apis/custom.metrics.k8s.io/"+version, nil))
+		require.Equal(t, http.StatusOK, response.Code, "should have served discovery: %s", response.Body.String())
+
+		resources := &metav1.APIResourceList{}
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), resources))
+		names := []string{}
+		for _, resource := range resources.APIResources {
+			names = append(names, resource.Name)
+		}
+		return names
+	}
+
+	for _, version := range []string{"v1beta1", "v1beta2"} {
+		assert.Equal(t, []string{"pods/old-metric"}, discovered(version))
+	}
+
+	prov.setMetrics("new-metric")
+	assert.Equal(t, []string{"pods/old-metric"}, discovered("v1beta2"), "should have cached discovery until refreshed")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			server.RefreshDiscovery()
+		}()
+	}
+	wg.Wait()
+
+	for _, version := range []string{"v1beta1", "v1beta2"} {
+		assert.Eventually(t, func() bool {
+			names := discovered(version)
+			return len(names) == 1 && names[0] == "pods/new-metric"
+		}, 5*time.Second, 10*time.Millisecond, "should have refreshed the discovery of %s", version)
+	}
+	prov.mu.Lock()
+	defer prov.mu.Unlock()
+	assert.Equal(t, 2, prov.lists, "should have coalesced the refreshes, and shared the metrics between versions")
+}
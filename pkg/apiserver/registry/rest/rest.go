@@ -43,3 +43,11 @@ type ListerWithOptions interface {
 	// passed to the converter.
 	NewListOptions() (runtime.Object, bool, string)
 }
+
+// CacheControlled is an optional interface for ListerWithOptions setting the
+// Cache-Control header of their responses.
+type CacheControlled interface {
+	// CacheControl returns the Cache-Control header value of the response to the
+	// list request described by ctx.
+	CacheControl(ctx context.Context) string
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package servertiming reports in a Server-Timing header the time spent in the
+// phases of the requests, so that clients can tell where their latency comes
+// from without tracing.
+package servertiming
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apiserver/pkg/endpoints/responsewriter"
+)
+
+// HeaderServerTiming is the header reporting the phases of a request.
+const HeaderServerTiming = "Server-Timing"
+
+const (
+	// PhaseMapper is the time spent mapping resources to kinds with the RESTMapper.
+	PhaseMapper = "mapper"
+	// PhaseProvider is the time spent in the provider.
+	PhaseProvider = "provider"
+)
+
+type timingsKey struct{}
+
+// timings accumulates the time spent in each phase of a request.
+type timings struct {
+	mu        sync.Mutex
+	phases    []string
+	durations map[string]time.Duration
+}
+
+func (t *timings) add(phase string, d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.durations[phase]; !ok {
+		t.phases = append(t.phases, phase)
+	}
+	t.durations[phase] += d
+}
+
+// header formats the phases, in milliseconds, in the order they were first entered.
+func (t *timings) header() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	metrics := make([]string, 0, len(t.phases))
+	for _, phase := range t.phases {
+		metrics = append(metrics, fmt.Sprintf("%s;dur=%.3f", phase, float64(t.durations[phase])/float64(time.Millisecond)))
+	}
+	return strings.Join(metrics, ", ")
+}
+
+// Start starts timing the given phase of the request of the context, and returns
+// the function stopping it.  The time spent in a phase entered several times is
+// summed.  It does nothing for requests not timed by WithServerTiming.
+func Start(ctx context.Context, phase string) func() {
+	t, ok := ctx.Value(timingsKey{}).(*timings)
+	if !ok {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		t.add(phase, time.Since(start))
+	}
+}
+
+// WithServerTiming times the phases of the requests, as reported with Start, and
+// sends them in the Server-Timing header of the responses.  Phases which have not
+// ended when the response is written are not reported.
+func WithServerTiming(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		t := &timings{durations: make(map[string]time.Duration)}
+		req = req.WithContext(context.WithValue(req.Context(), timingsKey{}, t))
+		handler.ServeHTTP(responsewriter.WrapForHTTP1Or2(&timingWriter{ResponseWriter: w, timings: t}), req)
+	})
+}
+
+// timingWriter adds the Server-Timing header when the response is written.
+type timingWriter struct {
+	http.ResponseWriter
+	timings *timings
+
+	once sync.Once
+}
+
+var _ responsewriter.UserProvidedDecorator = &timingWriter{}
+
+func (w *timingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *timingWriter) setHeader() {
+	w.once.Do(func() {
+		if header := w.timings.header(); header != "" {
+			w.Header().Set(HeaderServerTiming, header)
+		}
+	})
+}
+
+func (w *timingWriter) WriteHeader(code int) {
+	w.setHeader()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *timingWriter) Write(b []byte) (int, error) {
+	w.setHeader()
+	return w.ResponseWriter.Write(b)
+}
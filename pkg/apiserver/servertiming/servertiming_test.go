@@ -0,0 +1,69 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package servertiming
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithServerTiming(t *testing.T) {
+	handler := WithServerTiming(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		stop := Start(req.Context(), PhaseProvider)
+		time.Sleep(time.Millisecond)
+		stop()
+		Start(req.Context(), PhaseMapper)()
+		stop = Start(req.Context(), PhaseProvider)
+		time.Sleep(time.Millisecond)
+		stop()
+		// phases which have not ended are not reported
+		Start(req.Context(), "unfinished")
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	header := response.Header().Get(HeaderServerTiming)
+	assert.Regexp(t, regexp.MustCompile(`^provider;dur=\d+\.\d{3}, mapper;dur=\d+\.\d{3}$`), header, "should have reported the phases in the order they were first entered")
+	var duration float64
+	_, err := fmt.Sscanf(header, "provider;dur=%f", &duration)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, duration, 2.0, "should have summed the time spent in the provider phase")
+}
+
+func TestWithServerTimingWithoutPhases(t *testing.T) {
+	handler := WithServerTiming(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusNotFound, response.Code)
+	assert.Empty(t, response.Header().Get(HeaderServerTiming), "should not have sent the header without phases")
+}
+
+func TestStartWithoutServerTiming(t *testing.T) {
+	assert.NotPanics(t, Start(context.Background(), PhaseProvider), "should do nothing for requests which are not timed")
+}
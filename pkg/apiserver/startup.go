@@ -0,0 +1,85 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"k8s.io/apiserver/pkg/server/healthz"
+)
+
+// StartupzPath is the path of the startup endpoint, which fails until the
+// server finished starting, for startup probes.  Unlike /livez and /readyz, it
+// keeps succeeding once it did, so that probes only check the startup.
+const StartupzPath = "/startupz"
+
+// postStartHookCheckPrefix prefixes the names of the health checks of the
+// post-start hooks of the generic API server.
+const postStartHookCheckPrefix = "poststarthook/"
+
+// installStartupz installs the startup endpoint, checking the post-start hooks
+// and the given checks.
+func (s *CustomMetricsAdapterServer) installStartupz(checks []healthz.HealthChecker) {
+	checks = append([]healthz.HealthChecker{healthz.NamedCheck("poststarthooks", s.checkPostStartHooks)}, checks...)
+	for i := range checks {
+		checks[i] = &latchedCheck{HealthChecker: checks[i]}
+	}
+	healthz.InstallPathHandler(s.GenericAPIServer.Handler.NonGoRestfulMux, StartupzPath, checks...)
+}
+
+// checkPostStartHooks fails while any post-start hook has not completed.  The
+// hooks are looked up on each check, since they may be added until the server runs.
+func (s *CustomMetricsAdapterServer) checkPostStartHooks(r *http.Request) error {
+	var pending []string
+	for _, check := range s.GenericAPIServer.HealthzChecks() {
+		if !strings.HasPrefix(check.Name(), postStartHookCheckPrefix) {
+			continue
+		}
+		if err := check.Check(r); err != nil {
+			pending = append(pending, strings.TrimPrefix(check.Name(), postStartHookCheckPrefix))
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("post-start hooks not completed: %s", strings.Join(pending, ", "))
+	}
+	return nil
+}
+
+// latchedCheck succeeds for good once its check succeeded.
+type latchedCheck struct {
+	healthz.HealthChecker
+
+	mu     sync.Mutex
+	passed bool
+}
+
+func (c *latchedCheck) Check(r *http.Request) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.passed {
+		return nil
+	}
+	if err := c.HealthChecker.Check(r); err != nil {
+		return err
+	}
+	c.passed = true
+	return nil
+}
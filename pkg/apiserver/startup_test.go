@@ -0,0 +1,88 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	genericapiserver "k8s.io/apiserver/pkg/server"
+	"k8s.io/apiserver/pkg/server/healthz"
+	"k8s.io/client-go/rest"
+
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider/fake"
+)
+
+func startupzStatus(handler http.Handler) int {
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, StartupzPath, nil))
+	return response.Code
+}
+
+func TestStartupz(t *testing.T) {
+	warm := &atomic.Bool{}
+	warmUp := healthz.NamedCheck("warm-up", func(*http.Request) error {
+		if !warm.Load() {
+			return errors.New("still warming up")
+		}
+		return nil
+	})
+
+	genericConfig := genericapiserver.NewConfig(Codecs)
+	genericConfig.ExternalAddress = "localhost:443"
+	genericConfig.LoopbackClientConfig = &rest.Config{}
+	config := &Config{
+		GenericConfig: genericConfig,
+		ExtraConfig:   ExtraConfig{StartupChecks: []healthz.HealthChecker{warmUp}},
+	}
+	server, err := config.Complete(nil).New("test", fake.NewProvider(), nil)
+	require.NoError(t, err, "should have been able to create the server")
+
+	// a slow initialization, such as a first discovery
+	initialized := make(chan struct{})
+	require.NoError(t, server.GenericAPIServer.AddPostStartHook("slow-init", func(genericapiserver.PostStartHookContext) error {
+		<-initialized
+		return nil
+	}))
+	handler := server.GenericAPIServer.Handler
+
+	assert.Equal(t, http.StatusInternalServerError, startupzStatus(handler), "should have failed before the post-start hooks ran")
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	server.GenericAPIServer.RunPostStartHooks(stopCh)
+	assert.Never(t, func() bool { return startupzStatus(handler) == http.StatusOK }, 100*time.Millisecond, 10*time.Millisecond,
+		"should have failed while a post-start hook is running")
+
+	close(initialized)
+	assert.Never(t, func() bool { return startupzStatus(handler) == http.StatusOK }, 100*time.Millisecond, 10*time.Millisecond,
+		"should have failed while a startup check fails")
+
+	warm.Store(true)
+	assert.Eventually(t, func() bool { return startupzStatus(handler) == http.StatusOK }, time.Second, 10*time.Millisecond,
+		"should have succeeded once the hooks completed and the checks succeeded")
+
+	warm.Store(false)
+	assert.Equal(t, http.StatusOK, startupzStatus(handler), "should have kept succeeding after the startup")
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+
+	"github.com/emicklei/go-restful/v3"
+
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/apiserver/pkg/endpoints/handlers/responsewriters"
+)
+
+// versionPath is the path of the version of the adapter.  Like other non-resource
+// paths, it is only served to authorized users.
+const versionPath = "/version"
+
+// adapterVersion is the document served by the version endpoint: the version
+// information served by other API servers, with the name of the adapter, so that
+// clients decoding it as the former keep working.
+type adapterVersion struct {
+	Name string `json:"name"`
+	version.Info
+}
+
+// versionInfo returns the version information of the adapter, built with the given
+// version and commit.
+func versionInfo(gitVersion, gitCommit string) *version.Info {
+	return &version.Info{
+		Major:      "1",
+		Minor:      "0",
+		GitVersion: gitVersion,
+		GitCommit:  gitCommit,
+		GoVersion:  runtime.Version(),
+		Compiler:   runtime.Compiler,
+		Platform:   fmt.Sprintf("%s/%s", runtime.GOOS, runtime.GOARCH),
+	}
+}
+
+// installVersion replaces the version endpoint of the generic API server with one
+// also reporting the name of the adapter.
+func (s *CustomMetricsAdapterServer) installVersion(name string, info *version.Info) {
+	container := s.GenericAPIServer.Handler.GoRestfulContainer
+	for _, ws := range container.RegisteredWebServices() {
+		if ws.RootPath() == versionPath {
+			container.Remove(ws)
+		}
+	}
+
+	document := adapterVersion{Name: name, Info: *info}
+	ws := new(restful.WebService)
+	ws.Path(versionPath)
+	ws.Doc("git code version from which this is built")
+	ws.Route(ws.GET("/").To(func(_ *restful.Request, resp *restful.Response) {
+		responsewriters.WriteRawJSON(http.StatusOK, document, resp.ResponseWriter)
+	}).
+		Doc("get the code version, and the name of the adapter").
+		Operation("getCodeVersion").
+		Produces(restful.MIME_JSON).
+		Consumes(restful.MIME_JSON).
+		Writes(version.Info{}))
+	container.Add(ws)
+}
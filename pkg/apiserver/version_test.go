@@ -0,0 +1,78 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	genericapiserver "k8s.io/apiserver/pkg/server"
+	"k8s.io/client-go/rest"
+
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider/fake"
+)
+
+func versionServer(t *testing.T, allowed bool) http.Handler {
+	genericConfig := genericapiserver.NewConfig(Codecs)
+	genericConfig.ExternalAddress = "localhost:443"
+	genericConfig.LoopbackClientConfig = &rest.Config{}
+	genericConfig.Authorization.Authorizer = authorizer.AuthorizerFunc(func(_ context.Context, attributes authorizer.Attributes) (authorizer.Decision, string, error) {
+		if allowed || attributes.GetPath() != versionPath {
+			return authorizer.DecisionAllow, "", nil
+		}
+		return authorizer.DecisionDeny, "", nil
+	})
+	config := &Config{
+		GenericConfig: genericConfig,
+		ExtraConfig:   ExtraConfig{GitVersion: "v1.2.3", GitCommit: "0123456789abcdef"},
+	}
+
+	server, err := config.Complete(nil).New("test-adapter", fake.NewProvider(), nil)
+	require.NoError(t, err, "should have been able to create the server")
+	return server.GenericAPIServer.Handler
+}
+
+func TestVersion(t *testing.T) {
+	t.Run("authorized", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		versionServer(t, true).ServeHTTP(response, httptest.NewRequest(http.MethodGet, versionPath, nil))
+		require.Equal(t, http.StatusOK, response.Code, "should have served the version: %s", response.Body.String())
+		assert.Equal(t, "application/json", response.Header().Get("Content-Type"))
+
+		document := map[string]string{}
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &document), "should have served a valid version")
+		assert.Equal(t, "test-adapter", document["name"])
+		assert.Equal(t, "v1.2.3", document["gitVersion"])
+		assert.Equal(t, "0123456789abcdef", document["gitCommit"])
+		assert.Equal(t, "1", document["major"])
+		assert.Equal(t, runtime.Version(), document["goVersion"])
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		versionServer(t, false).ServeHTTP(response, httptest.NewRequest(http.MethodGet, versionPath, nil))
+		assert.Equal(t, http.StatusForbidden, response.Code, "should not have served the version to unauthorized users")
+	})
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// BackendCAPool returns the pool of the CA certificates held in BackendCAFiles, for
+// providers verifying the certificate of their backend.  The files are read again
+// when they change, so that the returned pool holds rotated CAs.  The pool is nil
+// if no CA file is set, for the system pool to be used instead.
+func (b *AdapterBase) BackendCAPool() (*x509.CertPool, error) {
+	if len(b.BackendCAFiles) == 0 {
+		return nil, nil
+	}
+	return b.backendCABundle().pool()
+}
+
+// BackendTransport returns a copy of the given transport for the backend of the
+// providers, verifying its certificate against the CAs held in BackendCAFiles.
+// Each connection trusts the CAs read last, so that clients built with the returned
+// transport trust rotated CAs without restarting; established connections are kept.
+// If no CA file is set, the transport is returned as is.
+func (b *AdapterBase) BackendTransport(transport *http.Transport) *http.Transport {
+	if len(b.BackendCAFiles) == 0 {
+		return transport
+	}
+
+	bundle := b.backendCABundle()
+	transport = transport.Clone()
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	transport.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		pool, err := bundle.pool()
+		if err != nil {
+			return nil, err
+		}
+		config := &tls.Config{MinVersion: tls.VersionTLS12}
+		if transport.TLSClientConfig != nil {
+			config = transport.TLSClientConfig.Clone()
+		}
+		config.RootCAs = pool
+		if config.ServerName == "" {
+			host, _, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, err
+			}
+			config.ServerName = host
+		}
+		return (&tls.Dialer{NetDialer: dialer, Config: config}).DialContext(ctx, network, addr)
+	}
+	return transport
+}
+
+func (b *AdapterBase) backendCABundle() *caBundle {
+	b.backendCAOnce.Do(func() {
+		b.backendCAs = &caBundle{paths: b.BackendCAFiles}
+	})
+	return b.backendCAs
+}
+
+func (b *AdapterBase) validateBackendCAFiles() []error {
+	if len(b.BackendCAFiles) == 0 {
+		return nil
+	}
+	if _, err := (&caBundle{paths: b.BackendCAFiles}).pool(); err != nil {
+		return []error{fmt.Errorf("invalid --backend-ca-file: %v", err)}
+	}
+	return nil
+}
+
+// caBundle is a pool of CA certificates read from files, which are read again when
+// the modification time or size of any of them change.
+type caBundle struct {
+	paths []string
+
+	mu      sync.Mutex
+	current *x509.CertPool
+	states  []caFileState
+}
+
+type caFileState struct {
+	modTime time.Time
+	size    int64
+}
+
+func (c *caBundle) pool() (*x509.CertPool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	states := make([]caFileState, len(c.paths))
+	changed := c.current == nil
+	for i, path := range c.paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return c.stale(fmt.Errorf("unable to read backend CA file: %v", err))
+		}
+		states[i] = caFileState{modTime: info.ModTime(), size: info.Size()}
+		changed = changed || !states[i].modTime.Equal(c.states[i].modTime) || states[i].size != c.states[i].size
+	}
+	if !changed {
+		return c.current, nil
+	}
+
+	pool := x509.NewCertPool()
+	for _, path := range c.paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return c.stale(fmt.Errorf("unable to read backend CA file: %v", err))
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return c.stale(fmt.Errorf("backend CA file %s holds no PEM certificate", path))
+		}
+	}
+	c.current, c.states = pool, states
+	return c.current, nil
+}
+
+// stale returns the CAs read last, if any, when the files can't be read again, for
+// instance while they are being replaced, and the error otherwise.
+func (c *caBundle) stale(err error) (*x509.CertPool, error) {
+	if c.current == nil {
+		return nil, err
+	}
+	klog.V(2).InfoS("Unable to reload the backend CA files, using the CAs read last", "err", err)
+	return c.current, nil
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	certutil "k8s.io/client-go/util/cert"
+)
+
+// newTLSBackend starts a backend serving a certificate for 127.0.0.1 signed by a
+// new CA, and returns it along with the CA certificate.
+func newTLSBackend(t *testing.T) (*httptest.Server, []byte) {
+	// the certificate is followed by the one of its CA
+	certPEM, keyPEM, err := certutil.GenerateSelfSignedCertKey("127.0.0.1", nil, nil)
+	require.NoError(t, err)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+	_, rest := pem.Decode(certPEM)
+	caBlock, _ := pem.Decode(rest)
+	require.NotNil(t, caBlock, "should have generated a CA certificate")
+
+	backend := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	backend.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	backend.StartTLS()
+	t.Cleanup(backend.Close)
+	return backend, pem.EncodeToMemory(caBlock)
+}
+
+func TestBackendTransport(t *testing.T) {
+	backend, caPEM := newTLSBackend(t)
+	_, otherCAPEM := newTLSBackend(t)
+
+	dir := t.TempDir()
+	caFile, otherCAFile := filepath.Join(dir, "ca.crt"), filepath.Join(dir, "other-ca.crt")
+	require.NoError(t, os.WriteFile(caFile, otherCAPEM, 0600))
+	require.NoError(t, os.WriteFile(otherCAFile, otherCAPEM, 0600))
+	adapter := &AdapterBase{BackendCAFiles: []string{otherCAFile, caFile}}
+	client := &http.Client{Transport: adapter.BackendTransport(http.DefaultTransport.(*http.Transport))}
+	defer client.CloseIdleConnections()
+
+	get := func() error {
+		response, err := client.Get(backend.URL)
+		if err == nil {
+			response.Body.Close()
+		}
+		return err
+	}
+	assert.Error(t, get(), "should not have trusted a backend signed by another CA")
+
+	// swap the file, as done when updating mounted secrets
+	rotated := filepath.Join(t.TempDir(), "ca.crt")
+	require.NoError(t, os.WriteFile(rotated, caPEM, 0600))
+	require.NoError(t, os.Rename(rotated, caFile))
+	assert.Eventually(t, func() bool {
+		return get() == nil
+	}, 10*time.Second, 100*time.Millisecond, "should have trusted the backend once its CA is configured")
+
+	pool, err := adapter.BackendCAPool()
+	require.NoError(t, err)
+	assert.NotNil(t, pool, "should have returned the pool of the configured CAs")
+}
+
+func TestBackendTransportWithoutCAFile(t *testing.T) {
+	adapter := &AdapterBase{}
+	transport := &http.Transport{}
+	assert.Same(t, transport, adapter.BackendTransport(transport), "should have returned the transport as is")
+	pool, err := adapter.BackendCAPool()
+	assert.NoError(t, err)
+	assert.Nil(t, pool, "should have left the system pool to be used")
+}
+
+func TestValidateBackendCAFiles(t *testing.T) {
+	dir := t.TempDir()
+	_, caPEM := newTLSBackend(t)
+	caFile, invalidFile := filepath.Join(dir, "ca.crt"), filepath.Join(dir, "invalid.crt")
+	require.NoError(t, os.WriteFile(caFile, caPEM, 0600))
+	require.NoError(t, os.WriteFile(invalidFile, []byte("not a certificate"), 0600))
+
+	cases := []struct {
+		testName  string
+		args      []string
+		shouldErr bool
+	}{
+		{
+			testName: "repeated",
+			args:     []string{"--backend-ca-file=" + caFile, "--backend-ca-file=" + caFile},
+		},
+		{
+			testName:  "missing",
+			args:      []string{"--backend-ca-file=" + filepath.Join(dir, "missing.crt")},
+			shouldErr: true,
+		},
+		{
+			testName:  "not-a-certificate",
+			args:      []string{"--backend-ca-file=" + invalidFile},
+			shouldErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.testName, func(t *testing.T) {
+			adapter := &AdapterBase{FlagSet: pflag.NewFlagSet("", pflag.ContinueOnError)}
+			require.NoError(t, adapter.Flags().Parse(append([]string{"--secure-port=6443"}, c.args...)))
+
+			errs := adapter.Validate()
+			if c.shouldErr {
+				assert.NotEmpty(t, errs)
+			} else {
+				assert.Empty(t, errs)
+			}
+		})
+	}
+}
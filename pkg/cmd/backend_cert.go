@@ -0,0 +1,53 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"k8s.io/client-go/rest"
+)
+
+// BackendClientConfig returns a copy of the given REST client configuration for
+// the backend of the providers, authenticating with the client certificate held
+// in BackendClientCertFile and BackendClientKeyFile.  The certificate is read from
+// the files, rather than copied into the configuration, so that client-go reloads
+// it when it is rotated, within a few minutes, and closes the connections using the
+// old one.  Transports
+// built from the returned configuration, e.g. with rest.HTTPClientFor, thus keep
+// authenticating through rotations.  If no certificate file is set, the
+// configuration is copied as is.
+func (b *AdapterBase) BackendClientConfig(config *rest.Config) *rest.Config {
+	config = rest.CopyConfig(config)
+	if b.BackendClientCertFile == "" {
+		return config
+	}
+
+	// certificate data takes precedence over files, and is never reloaded
+	config.TLSClientConfig.CertData = nil
+	config.TLSClientConfig.KeyData = nil
+	config.TLSClientConfig.CertFile = b.BackendClientCertFile
+	config.TLSClientConfig.KeyFile = b.BackendClientKeyFile
+	return config
+}
+
+func (b *AdapterBase) validateBackendClientCert() []error {
+	if (b.BackendClientCertFile == "") != (b.BackendClientKeyFile == "") {
+		return []error{fmt.Errorf("--backend-client-cert-file and --backend-client-key-file must be set together")}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/transport"
+	certutil "k8s.io/client-go/util/cert"
+)
+
+// writeClientCert writes a self-signed certificate for the given common name, and
+// its key, to the given files.
+func writeClientCert(t *testing.T, commonName, certFile, keyFile string) {
+	certPEM, keyPEM, err := certutil.GenerateSelfSignedCertKey(commonName, nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(certFile, certPEM, 0600))
+	require.NoError(t, os.WriteFile(keyFile, keyPEM, 0600))
+}
+
+func TestBackendClientConfigRotation(t *testing.T) {
+	// client-go checks for rotated certificates every few minutes
+	refreshDuration := transport.CertCallbackRefreshDuration
+	transport.CertCallbackRefreshDuration = 100 * time.Millisecond
+	defer func() { transport.CertCallbackRefreshDuration = refreshDuration }()
+
+	backend := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(req.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	backend.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	backend.StartTLS()
+	defer backend.Close()
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeClientCert(t, "first", certFile, keyFile)
+	adapter := &AdapterBase{BackendClientCertFile: certFile, BackendClientKeyFile: keyFile}
+	config := adapter.BackendClientConfig(&rest.Config{Host: backend.URL, TLSClientConfig: rest.TLSClientConfig{Insecure: true}})
+	client, err := rest.HTTPClientFor(config)
+	require.NoError(t, err)
+
+	// the common names of self-signed certificates are suffixed with their creation time
+	presented := func() string {
+		response, err := client.Get(backend.URL)
+		require.NoError(t, err)
+		defer response.Body.Close()
+		commonName, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		return strings.Split(string(commonName), "@")[0]
+	}
+
+	assert.Equal(t, "first", presented(), "should have presented the certificate from the files")
+
+	// swap the files, as cert-manager does when renewing certificates
+	rotated := t.TempDir()
+	writeClientCert(t, "second", filepath.Join(rotated, "tls.crt"), filepath.Join(rotated, "tls.key"))
+	require.NoError(t, os.Rename(filepath.Join(rotated, "tls.crt"), certFile))
+	require.NoError(t, os.Rename(filepath.Join(rotated, "tls.key"), keyFile))
+
+	assert.Eventually(t, func() bool {
+		return presented() == "second"
+	}, 10*time.Second, 100*time.Millisecond, "should have presented the rotated certificate")
+}
+
+func TestBackendClientConfigWithoutCertFile(t *testing.T) {
+	adapter := &AdapterBase{}
+	config := &rest.Config{Host: "https://backend.example.com", TLSClientConfig: rest.TLSClientConfig{CertData: []byte("cert"), KeyData: []byte("key")}}
+	assert.Equal(t, config, adapter.BackendClientConfig(config), "should have copied the configuration as is")
+}
+
+func TestValidateBackendClientCert(t *testing.T) {
+	cases := []struct {
+		testName  string
+		args      []string
+		shouldErr bool
+	}{
+		{
+			testName: "cert-and-key",
+			args:     []string{"--backend-client-cert-file=tls.crt", "--backend-client-key-file=tls.key"},
+		},
+		{
+			testName:  "cert-without-key",
+			args:      []string{"--backend-client-cert-file=tls.crt"},
+			shouldErr: true,
+		},
+		{
+			testName:  "key-without-cert",
+			args:      []string{"--backend-client-key-file=tls.key"},
+			shouldErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.testName, func(t *testing.T) {
+			adapter := &AdapterBase{FlagSet: pflag.NewFlagSet("", pflag.ContinueOnError)}
+			require.NoError(t, adapter.Flags().Parse(append([]string{"--secure-port=6443"}, c.args...)))
+
+			errs := adapter.Validate()
+			if c.shouldErr {
+				assert.NotEmpty(t, errs)
+			} else {
+				assert.Empty(t, errs)
+			}
+		})
+	}
+}
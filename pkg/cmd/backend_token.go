@@ -0,0 +1,90 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+)
+
+// BackendRoundTripper wraps the given RoundTripper, so that requests sent through it
+// to the backend of the providers are authenticated with the bearer token held in
+// BackendTokenFile.  The file is read again whenever it changes, so that rotated
+// tokens, such as projected service account tokens, are picked up.  If no token
+// file is set, the RoundTripper is returned as is.
+func (b *AdapterBase) BackendRoundTripper(rt http.RoundTripper) http.RoundTripper {
+	if b.BackendTokenFile == "" {
+		return rt
+	}
+	return &tokenFileRoundTripper{path: b.BackendTokenFile, rt: rt}
+}
+
+// tokenFileRoundTripper sets the Authorization header of requests to the token read
+// from a file, which is read again when its modification time or size change.
+type tokenFileRoundTripper struct {
+	path string
+	rt   http.RoundTripper
+
+	mu      sync.Mutex
+	token   string
+	modTime time.Time
+	size    int64
+}
+
+func (t *tokenFileRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if len(req.Header.Get("Authorization")) != 0 {
+		return t.rt.RoundTrip(req)
+	}
+
+	token, err := t.currentToken()
+	if err != nil {
+		return nil, err
+	}
+	req = utilnet.CloneRequest(req)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return t.rt.RoundTrip(req)
+}
+
+func (t *tokenFileRoundTripper) currentToken() (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	info, err := os.Stat(t.path)
+	if err != nil {
+		return "", fmt.Errorf("unable to read backend token file: %v", err)
+	}
+	if t.token != "" && info.ModTime().Equal(t.modTime) && info.Size() == t.size {
+		return t.token, nil
+	}
+
+	data, err := os.ReadFile(t.path)
+	if err != nil {
+		return "", fmt.Errorf("unable to read backend token file: %v", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("backend token file %s is empty", t.path)
+	}
+	t.token, t.modTime, t.size = token, info.ModTime(), info.Size()
+	return t.token, nil
+}
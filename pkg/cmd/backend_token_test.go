@@ -0,0 +1,68 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackendRoundTripperRotation(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(req.Header.Get("Authorization")))
+	}))
+	defer backend.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("first\n"), 0600))
+	adapter := &AdapterBase{BackendTokenFile: tokenFile}
+	client := &http.Client{Transport: adapter.BackendRoundTripper(http.DefaultTransport)}
+
+	get := func() string {
+		response, err := client.Get(backend.URL)
+		require.NoError(t, err)
+		defer response.Body.Close()
+		authorization, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		return string(authorization)
+	}
+
+	assert.Equal(t, "Bearer first", get(), "should have authenticated with the token from the file")
+	assert.Equal(t, "Bearer first", get())
+
+	// swap the file, as the kubelet does when rotating projected tokens
+	rotated := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(rotated, []byte("second\n"), 0600))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(rotated, later, later))
+	require.NoError(t, os.Rename(rotated, tokenFile))
+
+	assert.Equal(t, "Bearer second", get(), "should have authenticated with the rotated token")
+}
+
+func TestBackendRoundTripperWithoutTokenFile(t *testing.T) {
+	adapter := &AdapterBase{}
+	assert.Equal(t, http.DefaultTransport, adapter.BackendRoundTripper(http.DefaultTransport), "should not have wrapped the round tripper without a token file")
+}
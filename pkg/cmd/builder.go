@@ -17,24 +17,42 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/spf13/pflag"
 
+	corev1 "k8s.io/api/core/v1"
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
 	openapinamer "k8s.io/apiserver/pkg/endpoints/openapi"
 	"k8s.io/apiserver/pkg/features"
 	genericapiserver "k8s.io/apiserver/pkg/server"
+	"k8s.io/apiserver/pkg/server/healthz"
 	utilfeature "k8s.io/apiserver/pkg/util/feature"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/record"
+	componentbaseconfig "k8s.io/component-base/config"
+	componentbaseoptions "k8s.io/component-base/config/options"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
 	openapicommon "k8s.io/kube-openapi/pkg/common"
 
 	"sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver"
@@ -46,12 +64,22 @@ import (
 	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
 )
 
+// ProviderOptions holds the configuration of a provider set from flags.
+type ProviderOptions interface {
+	// AddFlags adds the flags of the provider to the given flagset.
+	AddFlags(fs *pflag.FlagSet)
+	// Validate validates the configuration once the flags are parsed.
+	Validate() []error
+}
+
 // AdapterBase provides a base set of functionality for any custom metrics adapter.
 // Embed it in a struct containing your options, then:
 //
+// - Use WithProviderOptions(options) to add the flags of your provider
 // - Use Flags() to add flags, then call Flags().Parse(os.Argv)
-// - Use DynamicClient and RESTMapper to fetch handles to common utilities
+// - Use DynamicClient, RESTMapper and EventRecorder to fetch handles to common utilities
 // - Use WithCustomMetrics(provider) and WithExternalMetrics(provider) to install metrics providers
+// - Use WithLeaderCallbacks(callbacks) to run components, such as pollers, on the leader only
 // - Use Run(stopChannel) to start the server
 //
 // All methods on this struct are idempotent except for Run -- they'll perform any
@@ -63,17 +91,62 @@ type AdapterBase struct {
 
 	// Name is the name of the API server.  It defaults to custom-metrics-adapter
 	Name string
+	// GitVersion and GitCommit are the version and commit of the adapter reported by
+	// /version.  They default to the ones set with -ldflags (see gitVersion).
+	GitVersion string
+	GitCommit  string
 
 	// RemoteKubeConfigFile specifies the kubeconfig to use to construct
 	// the dynamic client and RESTMapper.  It's set from a flag.
 	RemoteKubeConfigFile string
+	// WatchNamespace restricts the informers returned by Informers to the given
+	// namespace, which also restricts the permissions they need.  They watch all
+	// namespaces when it's empty.  It's set from a flag.
+	WatchNamespace string
 	// DiscoveryInterval specifies the interval at which to recheck discovery
 	// information for the discovery RESTMapper.  It's set from a flag.
 	DiscoveryInterval time.Duration
+	// RESTMapperMode selects the RESTMapper returned by RESTMapper: RESTMapperModeDynamic
+	// (the default) or RESTMapperModeStatic.  It's set from a flag.
+	RESTMapperMode string
+	// RESTMapperFile is the file the static RESTMapper is loaded from.  It's set from a flag.
+	RESTMapperFile string
+	// DiscoveryFile is a file pinning the resources listed in the discovery of the
+	// metrics API groups, instead of the metrics of the providers.  It's set from a flag.
+	DiscoveryFile string
 	// ClientQPS specifies the maximum QPS for the client-side throttle. It's set from a flag.
 	ClientQPS float32
 	// ClientBurst specifies the maximum QPS burst for client-side throttle. It's set from a flag.
 	ClientBurst int
+	// PrintOpenAPI makes Run write the OpenAPI document of the given version
+	// ("v2" or "v3") to stdout, and exit, instead of running the server.
+	// It's set from a flag.
+	PrintOpenAPI string
+	// BackendTokenFile is a file holding a bearer token used by BackendRoundTripper to
+	// authenticate to the backend of the providers, when it is another API requiring
+	// service account authentication.  It's set from a flag.
+	BackendTokenFile string
+	// BackendClientCertFile and BackendClientKeyFile hold the client certificate used
+	// by BackendClientConfig to authenticate to the backend of the providers.  They
+	// are read again when the certificate is rotated.  They're set from flags.
+	BackendClientCertFile string
+	BackendClientKeyFile  string
+	// BackendCAFiles hold the CA certificates used by BackendCAPool and BackendTransport
+	// to verify the certificate of the backend of the providers.  They are read again
+	// when they change.  They're set from a repeatable flag.
+	BackendCAFiles []string
+	// LogEffectiveConfig specifies whether to log the effective configuration, with
+	// sensitive values redacted, once it's resolved.  It's set from a flag.
+	LogEffectiveConfig bool
+	// CheckRBAC makes Run check, before starting the server, that the adapter has
+	// the permissions needed to serve requests.  It's set from a flag.
+	CheckRBAC bool
+	// LeaderElection configures the election of the replica running the components
+	// registered with WithLeaderCallbacks.  It's set from flags.
+	LeaderElection componentbaseconfig.LeaderElectionConfiguration
+	// LeaderElectionDrainTimeout bounds the time given to the components registered
+	// with WithLeaderCallbacks to stop.  It's set from a flag.
+	LeaderElectionDrainTimeout time.Duration
 
 	// FlagSet is the flagset to add flags to.
 	// It defaults to the normal CommandLine flags
@@ -82,21 +155,44 @@ type AdapterBase struct {
 
 	// OpenAPIConfig
 	OpenAPIConfig *openapicommon.Config
+	// OpenAPIServerURL is the external URL of the server in the OpenAPI v3 documents.
+	// It defaults to the root of the main API server, under which the APIs are aggregated.
+	OpenAPIServerURL string
 
 	// flagOnce controls initialization of the flags.
 	flagOnce sync.Once
 
+	backendCAOnce sync.Once
+	backendCAs    *caBundle
+
 	clientConfig    *rest.Config
 	discoveryClient discovery.DiscoveryInterface
 	restMapper      apimeta.RESTMapper
 	dynamicClient   dynamic.Interface
 	informers       informers.SharedInformerFactory
+	eventRecorder   record.EventRecorder
 
 	config *apiserver.Config
-	server *apiserver.CustomMetricsAdapterServer
+	// server is read by RefreshDiscovery, which may be called concurrently
+	server atomic.Pointer[apiserver.CustomMetricsAdapterServer]
 
 	cmProvider provider.CustomMetricsProvider
 	emProvider provider.ExternalMetricsProvider
+	cmGroups   []customMetricsGroup
+
+	cmTransform provider.CustomMetricTransformFunc
+	emTransform provider.ExternalMetricTransformFunc
+
+	cmValidator provider.CustomMetricQueryValidatorFunc
+	emValidator provider.ExternalMetricQueryValidatorFunc
+
+	cmDiscoveryFilter provider.CustomMetricDiscoveryFilterFunc
+	emDiscoveryFilter provider.ExternalMetricDiscoveryFilterFunc
+
+	startupChecks []healthz.HealthChecker
+
+	providerOptions []ProviderOptions
+	leaderCallbacks []LeaderCallbacks
 }
 
 // InstallFlags installs the minimum required set of flags into the flagset.
@@ -112,10 +208,57 @@ func (b *AdapterBase) InstallFlags() {
 		b.FlagSet.StringVar(&b.RemoteKubeConfigFile, "lister-kubeconfig", b.RemoteKubeConfigFile,
 			"kubeconfig file pointing at the 'core' kubernetes server with enough rights to list "+
 				"any described objects")
+		b.FlagSet.StringVar(&b.WatchNamespace, "watch-namespace", b.WatchNamespace,
+			"Namespace watched by the informers shared with the providers, for adapters serving a single namespace. "+
+				"All namespaces are watched when empty")
 		b.FlagSet.DurationVar(&b.DiscoveryInterval, "discovery-interval", b.DiscoveryInterval,
 			"Interval at which to refresh API discovery information")
+		if b.RESTMapperMode == "" {
+			b.RESTMapperMode = RESTMapperModeDynamic
+		}
+		b.FlagSet.StringVar(&b.RESTMapperMode, "rest-mapper-mode", b.RESTMapperMode,
+			"How to map kinds to resources: dynamic, using API discovery, or static, using the mappings of --rest-mapper-file, "+
+				"in which case the adapter needs no discovery permissions")
+		b.FlagSet.StringVar(&b.RESTMapperFile, "rest-mapper-file", b.RESTMapperFile,
+			"YAML or JSON file listing the group, version, kind, and optionally resource, singular and namespaced fields "+
+				"of the kinds mapped by the static RESTMapper")
+		b.FlagSet.StringVar(&b.DiscoveryFile, "discovery-file", b.DiscoveryFile,
+			"YAML or JSON file listing APIResourceLists of metrics API groups, e.g. captured from the adapter, whose resources are "+
+				"listed in the discovery of these groups, for all their versions, instead of the metrics of the providers, which still "+
+				"serve their values. Meant for deterministic tests and air-gapped environments")
 		b.FlagSet.Float32Var(&b.ClientQPS, "client-qps", rest.DefaultQPS, "Maximum QPS for client-side throttle")
 		b.FlagSet.IntVar(&b.ClientBurst, "client-burst", rest.DefaultBurst, "Maximum QPS burst for client-side throttle")
+		b.FlagSet.StringVar(&b.PrintOpenAPI, "print-openapi", b.PrintOpenAPI,
+			"Print the OpenAPI document of the given version (v2 or v3) to stdout and exit, instead of running the server")
+		b.FlagSet.StringVar(&b.BackendTokenFile, "backend-token-file", b.BackendTokenFile,
+			"File holding a bearer token to authenticate to the backend of the metrics providers, "+
+				"such as a projected service account token. The file is read again when the token is rotated")
+		b.FlagSet.StringVar(&b.BackendClientCertFile, "backend-client-cert-file", b.BackendClientCertFile,
+			"File holding a client certificate to authenticate to the backend of the metrics providers, "+
+				"with the key of --backend-client-key-file. The files are read again when the certificate is rotated")
+		b.FlagSet.StringVar(&b.BackendClientKeyFile, "backend-client-key-file", b.BackendClientKeyFile,
+			"File holding the key of the client certificate of --backend-client-cert-file")
+		b.FlagSet.StringSliceVar(&b.BackendCAFiles, "backend-ca-file", b.BackendCAFiles,
+			"File holding CA certificates to verify the certificate of the backend of the metrics providers with, "+
+				"instead of the system CAs. It can be repeated, and the files are read again when they change")
+		b.FlagSet.BoolVar(&b.LogEffectiveConfig, "log-effective-config", b.LogEffectiveConfig,
+			"Log the effective configuration at startup, with sensitive values redacted")
+		b.FlagSet.BoolVar(&b.CheckRBAC, "check-rbac", b.CheckRBAC,
+			"Check at startup, with self subject access reviews, that the adapter has the permissions needed to serve requests, "+
+				"and exit listing the missing ones otherwise")
+		if b.LeaderElection == (componentbaseconfig.LeaderElectionConfiguration{}) {
+			name := b.Name
+			if name == "" {
+				name = "custom-metrics-adapter"
+			}
+			b.LeaderElection = defaultLeaderElection(name)
+		}
+		componentbaseoptions.BindLeaderElectionFlags(&b.LeaderElection, b.FlagSet)
+		if b.LeaderElectionDrainTimeout == 0 {
+			b.LeaderElectionDrainTimeout = defaultDrainTimeout
+		}
+		b.FlagSet.DurationVar(&b.LeaderElectionDrainTimeout, "leader-elect-drain-timeout", b.LeaderElectionDrainTimeout,
+			"The time given to the components running only on the leader, such as provider pollers, to stop once the leadership is lost")
 	})
 }
 
@@ -137,10 +280,38 @@ func (b *AdapterBase) Flags() *pflag.FlagSet {
 	return b.FlagSet
 }
 
+// WithProviderOptions adds the flags of the given provider options to the flagset,
+// and validates them along with the adapter's own options.
+func (b *AdapterBase) WithProviderOptions(options ...ProviderOptions) {
+	fs := b.Flags()
+	for _, o := range options {
+		o.AddFlags(fs)
+	}
+	b.providerOptions = append(b.providerOptions, options...)
+}
+
+// Validate validates the adapter's options, and the provider options.
+func (b *AdapterBase) Validate() []error {
+	b.InstallFlags() // just to be sure
+
+	errors := b.CustomMetricsAdapterServerOptions.Validate()
+	errors = append(errors, b.validateRESTMapper()...)
+	errors = append(errors, b.validateDiscoveryFile()...)
+	errors = append(errors, b.validateBackendClientCert()...)
+	errors = append(errors, b.validateBackendCAFiles()...)
+	errors = append(errors, b.validateLeaderElection()...)
+	for _, o := range b.providerOptions {
+		errors = append(errors, o.Validate()...)
+	}
+	return errors
+}
+
 // ClientConfig returns the REST client configuration used to construct
 // clients for the clients and RESTMapper, and may be used for other
 // purposes as well.  If you need to mutate it, be sure to copy it with
-// rest.CopyConfig first.
+// rest.CopyConfig first.  When running in cluster, its token is read from
+// the service account token file, and read again periodically, so that
+// rotated tokens are picked up.
 func (b *AdapterBase) ClientConfig() (*rest.Config, error) {
 	if b.clientConfig == nil {
 		var clientConfig *rest.Config
@@ -187,7 +358,16 @@ func (b *AdapterBase) DiscoveryClient() (discovery.DiscoveryInterface, error) {
 
 // RESTMapper returns a RESTMapper dynamically populated with discovery information.
 // The discovery information will be periodically repopulated according to DiscoveryInterval.
+// The initial discovery is retried for up to StartupRetryTimeout when it fails.
+// With RESTMapperModeStatic, it returns a RESTMapper loaded from RESTMapperFile instead.
 func (b *AdapterBase) RESTMapper() (apimeta.RESTMapper, error) {
+	if b.restMapper == nil && b.RESTMapperMode == RESTMapperModeStatic {
+		staticMapper, err := loadStaticRESTMapper(b.RESTMapperFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load static mapper: %v", err)
+		}
+		b.restMapper = staticMapper
+	}
 	if b.restMapper == nil {
 		discoveryClient, err := b.DiscoveryClient()
 		if err != nil {
@@ -195,7 +375,15 @@ func (b *AdapterBase) RESTMapper() (apimeta.RESTMapper, error) {
 		}
 		// NB: since we never actually look at the contents of
 		// the objects we fetch (beyond ObjectMeta), unstructured should be fine
-		dynamicMapper, err := dynamicmapper.NewRESTMapper(discoveryClient, b.DiscoveryInterval)
+		var dynamicMapper *dynamicmapper.RegeneratingDiscoveryRESTMapper
+		// the API server may be briefly unavailable at startup, in which case the
+		// initial REST mappings are built again, rather than left empty
+		b.InstallFlags()
+		err = b.CustomMetricsAdapterServerOptions.RetryStartupStep("discover the API resources of the cluster", func() error {
+			var err error
+			dynamicMapper, err = dynamicmapper.NewRESTMapper(discoveryClient, b.DiscoveryInterval)
+			return err
+		})
 		if err != nil {
 			return nil, fmt.Errorf("unable to construct dynamic discovery mapper: %v", err)
 		}
@@ -225,11 +413,101 @@ func (b *AdapterBase) DynamicClient() (dynamic.Interface, error) {
 // WithCustomMetrics populates the custom metrics provider for this adapter.
 func (b *AdapterBase) WithCustomMetrics(p provider.CustomMetricsProvider) {
 	b.cmProvider = p
+	registerProviderCollectors(p)
+}
+
+// customMetricsGroup is an additional group serving the custom metrics API.
+type customMetricsGroup struct {
+	group    string
+	provider provider.CustomMetricsProvider
+}
+
+// WithCustomMetricsGroup serves the custom metrics API under the given group, such
+// as a vendor-specific one, in addition to custom.metrics.k8s.io, with the metrics of
+// the given provider.
+func (b *AdapterBase) WithCustomMetricsGroup(group string, p provider.CustomMetricsProvider) {
+	b.cmGroups = append(b.cmGroups, customMetricsGroup{group: group, provider: p})
+	registerProviderCollectors(p)
 }
 
 // WithExternalMetrics populates the external metrics provider for this adapter.
 func (b *AdapterBase) WithExternalMetrics(p provider.ExternalMetricsProvider) {
 	b.emProvider = p
+	registerProviderCollectors(p)
+}
+
+// registerProviderCollectors registers the Prometheus collectors of the given
+// provider, and of the ones it wraps, if they implement prometheus.Collector or
+// provider.InstrumentedProvider, in the registry served by the metrics endpoint.
+// Collectors already registered, for instance by a provider serving both APIs, are
+// skipped.
+func registerProviderCollectors(p interface{}) {
+	var collectors []prometheus.Collector
+	for ; p != nil; p = provider.Unwrap(p) {
+		if collector, ok := p.(prometheus.Collector); ok {
+			collectors = append(collectors, collector)
+		}
+		if instrumented, ok := p.(provider.InstrumentedProvider); ok {
+			collectors = append(collectors, instrumented.Collectors()...)
+		}
+	}
+
+	for _, collector := range collectors {
+		err := legacyregistry.Registerer().Register(collector)
+		if errors.As(err, &prometheus.AlreadyRegisteredError{}) {
+			continue
+		}
+		if err != nil {
+			klog.ErrorS(err, "Unable to register the collector of a provider")
+		}
+	}
+}
+
+// WithCustomMetricsTransform sets a function applied to each custom metric value
+// before it is returned to the client.  By default, values are returned as is.
+func (b *AdapterBase) WithCustomMetricsTransform(transform provider.CustomMetricTransformFunc) {
+	b.cmTransform = transform
+}
+
+// WithExternalMetricsTransform sets a function applied to each external metric value
+// before it is returned to the client.  By default, values are returned as is.
+func (b *AdapterBase) WithExternalMetricsTransform(transform provider.ExternalMetricTransformFunc) {
+	b.emTransform = transform
+}
+
+// WithCustomMetricsQueryValidator sets a function validating each custom metric
+// query before it is passed to the provider, to enforce query policies centrally,
+// such as forbidding selecting all the objects of a namespace for some metrics.
+// By default, all queries are passed.
+func (b *AdapterBase) WithCustomMetricsQueryValidator(validator provider.CustomMetricQueryValidatorFunc) {
+	b.cmValidator = validator
+}
+
+// WithExternalMetricsQueryValidator sets a function validating each external metric
+// query before it is passed to the provider.  By default, all queries are passed.
+func (b *AdapterBase) WithExternalMetricsQueryValidator(validator provider.ExternalMetricQueryValidatorFunc) {
+	b.emValidator = validator
+}
+
+// WithCustomMetricsDiscoveryFilter sets a function filtering the custom metrics
+// listed in discovery, for instance to hide deprecated metrics during their grace
+// period.  Dropped metrics are still served.  By default, all metrics are listed.
+func (b *AdapterBase) WithCustomMetricsDiscoveryFilter(filter provider.CustomMetricDiscoveryFilterFunc) {
+	b.cmDiscoveryFilter = filter
+}
+
+// WithExternalMetricsDiscoveryFilter sets a function filtering the external metrics
+// listed in discovery, for instance to hide deprecated metrics during their grace
+// period.  Dropped metrics are still served.  By default, all metrics are listed.
+func (b *AdapterBase) WithExternalMetricsDiscoveryFilter(filter provider.ExternalMetricDiscoveryFilterFunc) {
+	b.emDiscoveryFilter = filter
+}
+
+// WithStartupChecks adds checks to /startupz, which fails until they and the
+// post-start hooks of the server succeeded, for instance for providers to report
+// that they finished warming up.
+func (b *AdapterBase) WithStartupChecks(checks ...healthz.HealthChecker) {
+	b.startupChecks = append(b.startupChecks, checks...)
 }
 
 func mergeOpenAPIDefinitions(definitionsGetters []openapicommon.GetOpenAPIDefinitions) openapicommon.GetOpenAPIDefinitions {
@@ -288,7 +566,7 @@ func (b *AdapterBase) Config() (*apiserver.Config, error) {
 			b.OpenAPIV3Config = b.defaultOpenAPIV3Config()
 		}
 
-		if errList := b.CustomMetricsAdapterServerOptions.Validate(); len(errList) > 0 {
+		if errList := b.Validate(); len(errList) > 0 {
 			return nil, utilerrors.NewAggregate(errList)
 		}
 
@@ -297,20 +575,83 @@ func (b *AdapterBase) Config() (*apiserver.Config, error) {
 		if err != nil {
 			return nil, err
 		}
+		if b.LogEffectiveConfig {
+			logEffectiveConfig(b.FlagSet, serverConfig)
+		}
+		// the RESTMapper the providers already use can also be refreshed by the debug endpoints
+		restMapper := b.restMapper
+		if restMapper == nil && b.hasObjectProvider() {
+			if restMapper, err = b.RESTMapper(); err != nil {
+				return nil, err
+			}
+		}
+		var discoveryResources map[string][]metav1.APIResource
+		if b.DiscoveryFile != "" {
+			if discoveryResources, err = loadDiscoveryFile(b.DiscoveryFile); err != nil {
+				return nil, err
+			}
+		}
+		version, commit := b.version()
 		b.config = &apiserver.Config{
 			GenericConfig: serverConfig,
+			ExtraConfig: apiserver.ExtraConfig{
+				MetricRateLimits:        b.CustomMetricsAdapterServerOptions.MetricRateLimits,
+				AllowedNamespaces:       b.CustomMetricsAdapterServerOptions.AllowedNamespaces,
+				DefaultNamespace:        b.CustomMetricsAdapterServerOptions.DefaultNamespace,
+				MetricMaxAges:           b.CustomMetricsAdapterServerOptions.MetricMaxAges,
+				DefaultMetricWindow:     b.CustomMetricsAdapterServerOptions.DefaultMetricWindow,
+				CoalesceWindow:          b.CustomMetricsAdapterServerOptions.CoalesceWindow,
+				OpenAPIServerURL:        b.OpenAPIServerURL,
+				BasePath:                b.CustomMetricsAdapterServerOptions.BasePath,
+				EnableDebugEndpoints:    b.CustomMetricsAdapterServerOptions.EnableDebugEndpoints,
+				AuthorizeDiscovery:      b.CustomMetricsAdapterServerOptions.AuthorizeDiscovery,
+				EnableOpenMetrics:       b.CustomMetricsAdapterServerOptions.EnableOpenMetrics,
+				EnableMetricsCatalog:    b.CustomMetricsAdapterServerOptions.EnableMetricsCatalog,
+				RESTMapper:              restMapper,
+				CustomMetricTransform:   b.cmTransform,
+				ExternalMetricTransform: b.emTransform,
+				StartupChecks:           b.startupChecks,
+
+				MetricValueSignificantDigits:  b.CustomMetricsAdapterServerOptions.MetricValueSignificantDigits,
+				MaxCountedMetricNames:         b.CustomMetricsAdapterServerOptions.MaxCountedMetricNames,
+				EnableValueAgeMetric:          b.CustomMetricsAdapterServerOptions.EnableValueAgeMetric,
+				RequestLogSampleRate:          b.CustomMetricsAdapterServerOptions.RequestLogSampleRate,
+				MaxSelectorRequirements:       b.CustomMetricsAdapterServerOptions.MaxSelectorRequirements,
+				CustomMetricDiscoveryFilter:   b.cmDiscoveryFilter,
+				ExternalMetricDiscoveryFilter: b.emDiscoveryFilter,
+				CustomMetricQueryValidator:    b.cmValidator,
+				ExternalMetricQueryValidator:  b.emValidator,
+				DiscoveryResources:            discoveryResources,
+				GitVersion:                    version,
+				GitCommit:                     commit,
+			},
 		}
 	}
 
 	return b.config, nil
 }
 
+// hasObjectProvider returns whether one of the custom metrics providers is passed
+// the kinds of described objects, which are looked up with the RESTMapper.
+func (b *AdapterBase) hasObjectProvider() bool {
+	providers := []provider.CustomMetricsProvider{b.cmProvider}
+	for _, group := range b.cmGroups {
+		providers = append(providers, group.provider)
+	}
+	for _, p := range providers {
+		if _, ok := p.(provider.ObjectCustomMetricsProvider); ok {
+			return true
+		}
+	}
+	return false
+}
+
 // Server fetches API server object used to ultimately run the custom metrics adapter.
 // While this method is idempotent, it does "cement" values of some of the other
 // fields, so make sure to only call it just before `Run`.
 // Normal users should not need to call this method -- it's for advanced use cases.
 func (b *AdapterBase) Server() (*apiserver.CustomMetricsAdapterServer, error) {
-	if b.server == nil {
+	if b.server.Load() == nil {
 		config, err := b.Config()
 		if err != nil {
 			return nil, err
@@ -322,14 +663,31 @@ func (b *AdapterBase) Server() (*apiserver.CustomMetricsAdapterServer, error) {
 		if err != nil {
 			return nil, err
 		}
-		b.server = server
+		for _, cmGroup := range b.cmGroups {
+			if err := server.InstallCustomMetricsGroup(cmGroup.group, cmGroup.provider); err != nil {
+				return nil, err
+			}
+		}
+		b.server.Store(server)
 	}
 
-	return b.server, nil
+	return b.server.Load(), nil
+}
+
+// RefreshDiscovery rebuilds the discovery documents of the custom metrics, for
+// providers learning by external events, such as webhooks or file changes, that
+// their set of metrics changed.  Calls made within a short delay are coalesced.
+// It may be called concurrently, once the server has been created by Server or
+// Run: earlier calls are ignored, since discovery is built with the server.
+func (b *AdapterBase) RefreshDiscovery() {
+	if server := b.server.Load(); server != nil {
+		server.RefreshDiscovery()
+	}
 }
 
 // Informers returns a SharedInformerFactory for constructing new informers.
 // The informers will be automatically started as part of starting the adapter.
+// They only watch WatchNamespace, when set.
 func (b *AdapterBase) Informers() (informers.SharedInformerFactory, error) {
 	if b.informers == nil {
 		clientConfig, err := b.ClientConfig()
@@ -340,18 +698,125 @@ func (b *AdapterBase) Informers() (informers.SharedInformerFactory, error) {
 		if err != nil {
 			return nil, err
 		}
-		b.informers = informers.NewSharedInformerFactory(kubeClient, 0)
+		b.informers = informers.NewSharedInformerFactoryWithOptions(kubeClient, 0, informers.WithNamespace(b.WatchNamespace))
 	}
 
 	return b.informers, nil
 }
 
+// EventRecorder returns an EventRecorder, with the adapter as source, which providers can
+// use to report problems on Kubernetes objects, where operators see them with kubectl
+// describe, e.g. through events.NewCustomMetricsProvider.  The events are sent with
+// the client configuration returned by ClientConfig.
+func (b *AdapterBase) EventRecorder() (record.EventRecorder, error) {
+	if b.eventRecorder == nil {
+		clientConfig, err := b.ClientConfig()
+		if err != nil {
+			return nil, err
+		}
+		kubeClient, err := kubernetes.NewForConfig(clientConfig)
+		if err != nil {
+			return nil, err
+		}
+		name := b.Name
+		if name == "" {
+			name = "custom-metrics-adapter"
+		}
+		broadcaster := record.NewBroadcaster()
+		broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kubeClient.CoreV1().Events("")})
+		b.eventRecorder = broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: name})
+	}
+
+	return b.eventRecorder, nil
+}
+
+// WriteOpenAPI writes, as JSON, the OpenAPI document of the given version ("v2" or
+// "v3") describing the APIs of the installed providers.  It builds the APIs without
+// serving them, nor contacting the cluster, so it can be used to extract the
+// documents, for instance to generate clients.
+func (b *AdapterBase) WriteOpenAPI(w io.Writer, version string) error {
+	if b.Name == "" {
+		b.Name = "custom-metrics-adapter"
+	}
+	openAPIConfig := b.OpenAPIConfig
+	if openAPIConfig == nil {
+		openAPIConfig = b.defaultOpenAPIConfig()
+	}
+	var openAPIV3Config *openapicommon.Config
+	if b.CustomMetricsAdapterServerOptions != nil {
+		openAPIV3Config = b.OpenAPIV3Config
+	}
+	if openAPIV3Config == nil {
+		openAPIV3Config = b.defaultOpenAPIV3Config()
+	}
+
+	// the server is never run, so it only needs enough configuration to be created
+	serverConfig := genericapiserver.NewConfig(apiserver.Codecs)
+	serverConfig.ExternalAddress = "localhost:443"
+	serverConfig.LoopbackClientConfig = &rest.Config{}
+	serverConfig.OpenAPIConfig = openAPIConfig
+	serverConfig.OpenAPIV3Config = openAPIV3Config
+	config := &apiserver.Config{
+		GenericConfig: serverConfig,
+		ExtraConfig:   apiserver.ExtraConfig{OpenAPIServerURL: b.OpenAPIServerURL},
+	}
+	if b.CustomMetricsAdapterServerOptions != nil {
+		config.ExtraConfig.BasePath = b.BasePath
+	}
+	server, err := config.Complete(nil).New(b.Name, b.cmProvider, b.emProvider)
+	if err != nil {
+		return err
+	}
+	return server.WriteOpenAPI(w, version)
+}
+
 // Run runs this custom metrics adapter until the given stop channel is closed.
+// If PrintOpenAPI is set, it writes the OpenAPI document to stdout instead, and returns.
+// If CheckRBAC is set, it returns an error listing the missing permissions, if any,
+// before starting the server.  The components registered with WithLeaderCallbacks
+// run while the adapter leads, for as long as the server does without leader
+// election, and are drained before Run returns.
 func (b *AdapterBase) Run(stopCh <-chan struct{}) error {
+	if b.PrintOpenAPI != "" {
+		return b.WriteOpenAPI(os.Stdout, b.PrintOpenAPI)
+	}
+
+	if b.CheckRBAC {
+		if err := b.checkRBAC(); err != nil {
+			return err
+		}
+	}
+
 	server, err := b.Server()
 	if err != nil {
 		return err
 	}
 
-	return server.GenericAPIServer.PrepareRun().Run(stopCh)
+	lifecycle := &leaderLifecycle{callbacks: b.leaderCallbacks, drainTimeout: b.LeaderElectionDrainTimeout}
+	var elector *leaderelection.LeaderElector
+	if b.LeaderElection.LeaderElect {
+		clientConfig, err := b.ClientConfig()
+		if err != nil {
+			return err
+		}
+		client, err := kubernetes.NewForConfig(clientConfig)
+		if err != nil {
+			return fmt.Errorf("unable to construct client for leader election: %v", err)
+		}
+		if elector, err = b.leaderElector(client, lifecycle); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(wait.ContextForChannel(stopCh))
+	led := make(chan struct{})
+	go func() {
+		defer close(led)
+		lead(ctx, elector, lifecycle)
+	}()
+
+	err = server.GenericAPIServer.PrepareRun().Run(stopCh)
+	cancel()
+	<-led
+	return err
 }
@@ -17,13 +17,36 @@ limitations under the License.
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	genericapiserver "k8s.io/apiserver/pkg/server"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/component-base/metrics/legacyregistry"
 	"k8s.io/kube-openapi/pkg/builder"
+	"k8s.io/kube-openapi/pkg/spec3"
+	"k8s.io/kube-openapi/pkg/validation/spec"
 
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider/caching"
 	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider/fake"
+	sampleprovider "sigs.k8s.io/custom-metrics-apiserver/test-adapter/provider"
 )
 
 func TestDefaultOpenAPIConfig(t *testing.T) {
@@ -53,3 +76,269 @@ func TestDefaultOpenAPIConfig(t *testing.T) {
 		assert.NoError(t, err2)
 	})
 }
+
+func TestWriteOpenAPI(t *testing.T) {
+	adapter := &AdapterBase{}
+	prov, _ := sampleprovider.NewFakeProvider(nil, nil)
+	adapter.WithCustomMetrics(prov)
+	adapter.WithExternalMetrics(prov)
+
+	t.Run("v2", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		require.NoError(t, adapter.WriteOpenAPI(out, "v2"))
+
+		swagger := &spec.Swagger{}
+		require.NoError(t, json.Unmarshal(out.Bytes(), swagger), "should have written valid JSON")
+		assert.Equal(t, "2.0", swagger.Swagger)
+		assert.Contains(t, swagger.Paths.Paths, "/apis/custom.metrics.k8s.io/v1beta2/namespaces/{namespace}/{resource}/{name}/{subresource}")
+		assert.Contains(t, swagger.Paths.Paths, "/apis/external.metrics.k8s.io/v1beta1/namespaces/{namespace}/{resource}")
+	})
+
+	t.Run("v3", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		require.NoError(t, adapter.WriteOpenAPI(out, "v3"))
+
+		docs := map[string]*spec3.OpenAPI{}
+		require.NoError(t, json.Unmarshal(out.Bytes(), &docs), "should have written valid JSON")
+		require.Contains(t, docs, "apis/custom.metrics.k8s.io/v1beta2")
+		require.Contains(t, docs, "apis/external.metrics.k8s.io/v1beta1")
+		assert.Contains(t, docs["apis/custom.metrics.k8s.io/v1beta2"].Paths.Paths, "/apis/custom.metrics.k8s.io/v1beta2/namespaces/{namespace}/{resource}/{name}/{subresource}")
+	})
+
+	t.Run("unknown version", func(t *testing.T) {
+		assert.Error(t, adapter.WriteOpenAPI(io.Discard, "v4"))
+	})
+}
+
+func TestPrintOpenAPIFlag(t *testing.T) {
+	tests := map[string]struct {
+		args     []string
+		expected string
+	}{
+		"with equals": {args: []string{"--print-openapi=v2"}, expected: "v2"},
+		"separate":    {args: []string{"--print-openapi", "v3"}, expected: "v3"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			adapter := &AdapterBase{FlagSet: pflag.NewFlagSet("", pflag.ContinueOnError)}
+			require.NoError(t, adapter.Flags().Parse(test.args))
+			assert.Equal(t, test.expected, adapter.PrintOpenAPI)
+			assert.Empty(t, adapter.Flags().Args(), "should have consumed the version")
+		})
+	}
+
+	t.Run("missing version", func(t *testing.T) {
+		adapter := &AdapterBase{FlagSet: pflag.NewFlagSet("", pflag.ContinueOnError)}
+		adapter.Flags().SetOutput(io.Discard)
+		assert.Error(t, adapter.Flags().Parse([]string{"--print-openapi"}))
+	})
+}
+
+type testProviderOptions struct {
+	BackendURL string
+	Labels     []string
+}
+
+func (o *testProviderOptions) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.BackendURL, "backend-url", o.BackendURL, "URL of the backend")
+	fs.StringSliceVar(&o.Labels, "backend-labels", o.Labels, "Labels exposed by the backend")
+}
+
+func (o *testProviderOptions) Validate() []error {
+	if o.BackendURL == "" {
+		return []error{fmt.Errorf("--backend-url is required")}
+	}
+	return nil
+}
+
+func TestProviderOptions(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		adapter := &AdapterBase{FlagSet: pflag.NewFlagSet("", pflag.ContinueOnError)}
+		providerOptions := &testProviderOptions{}
+		adapter.WithProviderOptions(providerOptions)
+
+		require.NoError(t, adapter.Flags().Parse([]string{"--secure-port=6443", "--backend-url=http://backend:9090", "--backend-labels=a,b"}))
+		assert.Equal(t, "http://backend:9090", providerOptions.BackendURL)
+		assert.Equal(t, []string{"a", "b"}, providerOptions.Labels)
+		assert.Equal(t, 6443, adapter.SecureServing.BindPort, "should have kept the adapter's own flags")
+		assert.Empty(t, adapter.Validate())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		adapter := &AdapterBase{FlagSet: pflag.NewFlagSet("", pflag.ContinueOnError)}
+		adapter.WithProviderOptions(&testProviderOptions{})
+
+		require.NoError(t, adapter.Flags().Parse([]string{"--secure-port=6443"}))
+		assert.Len(t, adapter.Validate(), 1, "should have validated the provider options")
+		_, err := adapter.Config()
+		assert.Error(t, err, "should not have built a config with invalid provider options")
+	})
+}
+
+func TestEventRecorder(t *testing.T) {
+	received := make(chan *corev1.Event, 1)
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		event := &corev1.Event{}
+		if req.Method != http.MethodPost || req.URL.Path != "/api/v1/namespaces/monitoring/events" || json.NewDecoder(req.Body).Decode(event) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- event
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(event)
+	}))
+	defer apiServer.Close()
+
+	adapter := &AdapterBase{Name: "test-adapter", clientConfig: &rest.Config{Host: apiServer.URL}}
+	recorder, err := adapter.EventRecorder()
+	require.NoError(t, err)
+	again, err := adapter.EventRecorder()
+	require.NoError(t, err)
+	assert.Equal(t, recorder, again, "should have returned the same recorder")
+
+	object := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "monitoring", Name: "adapter"}}
+	recorder.Event(object, corev1.EventTypeWarning, "MetricCollectionFailed", "backend unavailable")
+
+	select {
+	case event := <-received:
+		assert.Equal(t, "adapter", event.InvolvedObject.Name)
+		assert.Equal(t, "MetricCollectionFailed", event.Reason)
+		assert.Equal(t, "test-adapter", event.Source.Component, "should have recorded the adapter as source")
+	case <-time.After(10 * time.Second):
+		t.Fatal("should have sent the event to the API server")
+	}
+}
+
+func TestInformersWatchNamespace(t *testing.T) {
+	paths := make(chan string, 10)
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		select {
+		case paths <- req.URL.Path:
+		default:
+		}
+		w.Header().Set("Content-Type", "application/json")
+		// watches get an empty stream, so that none is left open when the server is closed
+		if req.URL.Query().Get("watch") == "true" {
+			return
+		}
+		_ = json.NewEncoder(w).Encode(&corev1.PodList{ListMeta: metav1.ListMeta{ResourceVersion: "1"}})
+	}))
+	defer apiServer.Close()
+
+	adapter := &AdapterBase{Name: "test-adapter", WatchNamespace: "monitoring", clientConfig: &rest.Config{Host: apiServer.URL}}
+	factory, err := adapter.Informers()
+	require.NoError(t, err)
+	informer := factory.Core().V1().Pods().Informer()
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	factory.Start(stopCh)
+	require.True(t, cache.WaitForCacheSync(stopCh, informer.HasSynced), "should have synced the informer")
+
+	select {
+	case path := <-paths:
+		assert.Equal(t, "/api/v1/namespaces/monitoring/pods", path, "should only have listed the pods of the watched namespace")
+	case <-time.After(10 * time.Second):
+		t.Fatal("should have listed the pods")
+	}
+}
+
+// instrumentedProvider counts its queries to its backend.
+type instrumentedProvider struct {
+	provider.MetricsProvider
+
+	backendQueries prometheus.Counter
+}
+
+func (p *instrumentedProvider) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{p.backendQueries}
+}
+
+// collectingProvider reports its backend as up.
+type collectingProvider struct {
+	provider.MetricsProvider
+
+	up prometheus.Gauge
+}
+
+func (p *collectingProvider) Describe(descs chan<- *prometheus.Desc) {
+	p.up.Describe(descs)
+}
+
+func (p *collectingProvider) Collect(metrics chan<- prometheus.Metric) {
+	p.up.Collect(metrics)
+}
+
+func scrapeMetrics(t *testing.T) string {
+	server := httptest.NewServer(legacyregistry.Handler())
+	defer server.Close()
+	response, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestProviderCollectors(t *testing.T) {
+	instrumented := &instrumentedProvider{
+		MetricsProvider: fake.NewProvider(),
+		backendQueries:  prometheus.NewCounter(prometheus.CounterOpts{Name: "test_provider_backend_queries_total", Help: "Queries to the backend"}),
+	}
+	instrumented.backendQueries.Add(3)
+	collecting := &collectingProvider{
+		MetricsProvider: fake.NewProvider(),
+		up:              prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_provider_backend_up", Help: "Whether the backend is up"}),
+	}
+	collecting.up.Set(1)
+
+	adapter := &AdapterBase{}
+	adapter.WithCustomMetrics(instrumented)
+	// registering the same provider for both APIs is fine
+	adapter.WithExternalMetrics(instrumented)
+	// decorators keep the collectors of the providers they wrap
+	adapter.WithCustomMetricsGroup("metrics.example.com", caching.NewCustomMetricsProvider(collecting, time.Minute))
+
+	metrics := scrapeMetrics(t)
+	assert.Contains(t, metrics, "test_provider_backend_queries_total 3", "should have served the collectors returned by the provider")
+	assert.Contains(t, metrics, "test_provider_backend_up 1", "should have served the provider implementing prometheus.Collector")
+}
+
+func TestRefreshDiscoveryWhileCreatingServer(t *testing.T) {
+	genericConfig := genericapiserver.NewConfig(apiserver.Codecs)
+	genericConfig.ExternalAddress = "localhost:443"
+	genericConfig.LoopbackClientConfig = &rest.Config{}
+
+	adapter := &AdapterBase{}
+	adapter.WithCustomMetrics(fake.NewProvider())
+	// skip building the config from the flags, which requires a cluster
+	adapter.config = &apiserver.Config{GenericConfig: genericConfig}
+
+	// a provider learning of new metrics may refresh discovery at any time,
+	// even before the server exists
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				adapter.RefreshDiscovery()
+			}
+		}
+	}()
+
+	server, err := adapter.Server()
+	close(stop)
+	wg.Wait()
+	require.NoError(t, err, "should have been able to create the server")
+
+	again, err := adapter.Server()
+	require.NoError(t, err)
+	assert.Same(t, server, again, "should have created the server once")
+	adapter.RefreshDiscovery()
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+// loadDiscoveryFile loads the resources pinned in the discovery of the metrics API
+// groups, by group, from a YAML or JSON file listing their APIResourceLists, as
+// served by the adapter, e.g.:
+//
+//	# discovery.yaml
+//	- groupVersion: custom.metrics.k8s.io/v1beta2
+//	  resources:
+//	  - name: pods/http_requests
+//	    namespaced: true
+//	    kind: MetricValueList
+//	    verbs: [get]
+//
+// The resources of a group are listed for all its versions, so each group may
+// only be listed once.
+func loadDiscoveryFile(path string) (map[string][]metav1.APIResource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var lists []metav1.APIResourceList
+	if err := yaml.UnmarshalStrict(data, &lists); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %v", path, err)
+	}
+
+	resources := make(map[string][]metav1.APIResource, len(lists))
+	for i, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil || gv.Group == "" {
+			return nil, fmt.Errorf("invalid resource list %d in %s: the group version of a metrics API is required", i, path)
+		}
+		if _, ok := resources[gv.Group]; ok {
+			return nil, fmt.Errorf("invalid resource list %d in %s: group %s is already listed", i, path, gv.Group)
+		}
+		for j, resource := range list.APIResources {
+			if resource.Name == "" {
+				return nil, fmt.Errorf("invalid resource %d of %s in %s: name is required", j, list.GroupVersion, path)
+			}
+		}
+		// an empty list of resources is pinned too
+		resources[gv.Group] = append([]metav1.APIResource{}, list.APIResources...)
+	}
+	return resources, nil
+}
+
+// validateDiscoveryFile validates the file of the pinned discovery, if any.
+func (b *AdapterBase) validateDiscoveryFile() []error {
+	if b.DiscoveryFile == "" {
+		return nil
+	}
+	if _, err := loadDiscoveryFile(b.DiscoveryFile); err != nil {
+		return []error{fmt.Errorf("invalid --discovery-file: %v", err)}
+	}
+	return nil
+}
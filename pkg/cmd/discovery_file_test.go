@@ -0,0 +1,105 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// pinnedDiscovery is captured from an adapter, with the kind and version of the lists.
+const pinnedDiscovery = `
+- kind: APIResourceList
+  apiVersion: v1
+  groupVersion: custom.metrics.k8s.io/v1beta2
+  resources:
+  - name: pods/http_requests
+    singularName: ""
+    namespaced: true
+    kind: MetricValueList
+    verbs: [get]
+- groupVersion: external.metrics.k8s.io/v1beta1
+  resources: []
+`
+
+func TestLoadDiscoveryFile(t *testing.T) {
+	resources, err := loadDiscoveryFile(writeFile(t, pinnedDiscovery))
+	require.NoError(t, err, "should have loaded the discovery file")
+	assert.Equal(t, map[string][]metav1.APIResource{
+		"custom.metrics.k8s.io":   {{Name: "pods/http_requests", Namespaced: true, Kind: "MetricValueList", Verbs: metav1.Verbs{"get"}}},
+		"external.metrics.k8s.io": {},
+	}, resources)
+}
+
+func TestValidateDiscoveryFile(t *testing.T) {
+	cases := []struct {
+		testName  string
+		content   string
+		shouldErr bool
+	}{
+		{
+			testName: "valid",
+			content:  pinnedDiscovery,
+		},
+		{
+			testName:  "invalid-field",
+			content:   "- groupVersion: custom.metrics.k8s.io/v1beta2\n  resources:\n  - nam: pods/http_requests\n",
+			shouldErr: true,
+		},
+		{
+			testName:  "resource-without-name",
+			content:   "- groupVersion: custom.metrics.k8s.io/v1beta2\n  resources:\n  - namespaced: true\n",
+			shouldErr: true,
+		},
+		{
+			testName:  "core-group",
+			content:   "- groupVersion: v1\n  resources: []\n",
+			shouldErr: true,
+		},
+		{
+			testName:  "duplicate-group",
+			content:   "- groupVersion: custom.metrics.k8s.io/v1beta1\n- groupVersion: custom.metrics.k8s.io/v1beta2\n",
+			shouldErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.testName, func(t *testing.T) {
+			adapter := &AdapterBase{FlagSet: pflag.NewFlagSet("", pflag.ContinueOnError)}
+			require.NoError(t, adapter.Flags().Parse([]string{"--secure-port=6443", "--discovery-file=" + writeFile(t, c.content)}))
+
+			errs := adapter.Validate()
+			if c.shouldErr {
+				assert.NotEmpty(t, errs)
+			} else {
+				assert.Empty(t, errs)
+			}
+		})
+	}
+
+	t.Run("missing-file", func(t *testing.T) {
+		adapter := &AdapterBase{FlagSet: pflag.NewFlagSet("", pflag.ContinueOnError)}
+		require.NoError(t, adapter.Flags().Parse([]string{"--secure-port=6443", "--discovery-file=" + filepath.Join(t.TempDir(), "missing.yaml")}))
+		assert.NotEmpty(t, adapter.Validate())
+	})
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/pflag"
+
+	genericapiserver "k8s.io/apiserver/pkg/server"
+	"k8s.io/klog/v2"
+)
+
+// redactedValue replaces the values of sensitive flags in the effective configuration.
+const redactedValue = "<redacted>"
+
+// sensitiveFlagWords are the words identifying flags which may hold secret
+// material, rather than references to it, such as passwords and tokens.
+var sensitiveFlagWords = []string{"password", "passwd", "secret", "token", "credential", "private-key", "apikey", "api-key"}
+
+func isSensitiveFlag(name string) bool {
+	name = strings.ToLower(name)
+	for _, word := range sensitiveFlagWords {
+		if strings.Contains(name, word) && !strings.HasSuffix(name, "-file") {
+			return true
+		}
+	}
+	return false
+}
+
+// effectiveConfig returns the values of all the flags, as resolved after parsing,
+// followed by the settings derived from them in the server configuration.
+// The values of sensitive flags are redacted.  It returns alternating keys and
+// values, sorted by key, suitable for structured logging.
+func effectiveConfig(fs *pflag.FlagSet, serverConfig *genericapiserver.Config) []interface{} {
+	config := map[string]string{}
+	fs.VisitAll(func(flag *pflag.Flag) {
+		value := flag.Value.String()
+		if isSensitiveFlag(flag.Name) && value != "" {
+			value = redactedValue
+		}
+		config["flag."+flag.Name] = value
+	})
+
+	if serverConfig != nil {
+		if serverConfig.SecureServing != nil && serverConfig.SecureServing.Listener != nil {
+			config["derived.secure-serving-address"] = serverConfig.SecureServing.Listener.Addr().String()
+		}
+		config["derived.external-address"] = serverConfig.ExternalAddress
+		config["derived.authentication-enabled"] = strconv.FormatBool(serverConfig.Authentication.Authenticator != nil)
+		config["derived.authorization-enabled"] = strconv.FormatBool(serverConfig.Authorization.Authorizer != nil)
+		config["derived.openapi-v3-enabled"] = strconv.FormatBool(serverConfig.OpenAPIV3Config != nil)
+		config["derived.metrics-enabled"] = strconv.FormatBool(serverConfig.EnableMetrics)
+	}
+
+	keys := make([]string, 0, len(config))
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	keysAndValues := make([]interface{}, 0, 2*len(keys))
+	for _, key := range keys {
+		keysAndValues = append(keysAndValues, key, config[key])
+	}
+	return keysAndValues
+}
+
+func logEffectiveConfig(fs *pflag.FlagSet, serverConfig *genericapiserver.Config) {
+	klog.InfoS("Effective configuration", effectiveConfig(fs, serverConfig)...)
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	genericapiserver "k8s.io/apiserver/pkg/server"
+
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver"
+)
+
+func TestEffectiveConfig(t *testing.T) {
+	adapter := &AdapterBase{FlagSet: pflag.NewFlagSet("test", pflag.ContinueOnError)}
+	adapter.InstallFlags()
+	var password, passwordFile string
+	adapter.FlagSet.StringVar(&password, "backend-password", "", "")
+	adapter.FlagSet.StringVar(&passwordFile, "backend-password-file", "", "")
+	require.NoError(t, adapter.FlagSet.Parse([]string{
+		"--secure-port=6443",
+		"--allowed-namespaces=default",
+		"--backend-password=hunter2",
+		"--backend-password-file=/etc/backend/password",
+	}))
+
+	serverConfig := genericapiserver.NewConfig(apiserver.Codecs)
+	serverConfig.ExternalAddress = "adapter.example.com:6443"
+	keysAndValues := effectiveConfig(adapter.FlagSet, serverConfig)
+	require.Zero(t, len(keysAndValues)%2, "should have returned alternating keys and values")
+
+	config := map[string]string{}
+	for i := 0; i < len(keysAndValues); i += 2 {
+		config[keysAndValues[i].(string)] = keysAndValues[i+1].(string)
+	}
+	assert.Equal(t, "6443", config["flag.secure-port"], "should have included the serving port")
+	assert.Equal(t, "[default]", config["flag.allowed-namespaces"], "should have included the allowed namespaces")
+	assert.Equal(t, "/etc/backend/password", config["flag.backend-password-file"], "should have included the path to the password")
+	assert.Equal(t, redactedValue, config["flag.backend-password"], "should have redacted the password")
+	assert.Equal(t, "adapter.example.com:6443", config["derived.external-address"], "should have included the derived settings")
+
+	assert.NotContains(t, fmt.Sprint(keysAndValues...), "hunter2", "should not have included any secret material")
+}
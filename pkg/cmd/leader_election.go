@@ -0,0 +1,186 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	componentbaseconfig "k8s.io/component-base/config"
+	componentbasevalidation "k8s.io/component-base/config/validation"
+	"k8s.io/klog/v2"
+)
+
+const (
+	defaultLeaseDuration = 15 * time.Second
+	defaultRenewDeadline = 10 * time.Second
+	defaultRetryPeriod   = 2 * time.Second
+	defaultDrainTimeout  = 10 * time.Second
+)
+
+// LeaderCallbacks is implemented by the components which must only run on one
+// replica of the adapter at a time, such as the pollers of providers filling a
+// shared cache.  Without leader election, every replica is considered to lead
+// while it runs.
+type LeaderCallbacks interface {
+	// OnStartedLeading is called when the replica starts leading.  It must not
+	// block: the components are started in the background, and stopped when the
+	// given context is done.
+	OnStartedLeading(ctx context.Context)
+	// OnStoppedLeading is called once the replica stopped leading, after the
+	// context given to OnStartedLeading is done.  It returns once the components
+	// are stopped, and should give up when the given context, which expires after
+	// the drain timeout, is done.
+	OnStoppedLeading(ctx context.Context)
+}
+
+// WithLeaderCallbacks registers components to start when the adapter starts leading,
+// and to stop when it stops leading.  They are started in the order they are
+// registered, and stopped in the reverse order.
+func (b *AdapterBase) WithLeaderCallbacks(callbacks ...LeaderCallbacks) {
+	b.leaderCallbacks = append(b.leaderCallbacks, callbacks...)
+}
+
+// defaultLeaderElection returns the default leader election configuration, which
+// is disabled.
+func defaultLeaderElection(name string) componentbaseconfig.LeaderElectionConfiguration {
+	return componentbaseconfig.LeaderElectionConfiguration{
+		LeaseDuration:     metav1.Duration{Duration: defaultLeaseDuration},
+		RenewDeadline:     metav1.Duration{Duration: defaultRenewDeadline},
+		RetryPeriod:       metav1.Duration{Duration: defaultRetryPeriod},
+		ResourceLock:      resourcelock.LeasesResourceLock,
+		ResourceName:      name,
+		ResourceNamespace: metav1.NamespaceSystem,
+	}
+}
+
+func (b *AdapterBase) validateLeaderElection() []error {
+	var errors []error
+	for _, err := range componentbasevalidation.ValidateLeaderElectionConfiguration(&b.LeaderElection, field.NewPath("leaderElection")) {
+		errors = append(errors, err)
+	}
+	if b.LeaderElectionDrainTimeout < 0 {
+		errors = append(errors, fmt.Errorf("--leader-elect-drain-timeout must not be negative"))
+	}
+	return errors
+}
+
+// leaderElector creates the leader elector running the leader callbacks with the
+// given client.
+func (b *AdapterBase) leaderElector(client kubernetes.Interface, lifecycle *leaderLifecycle) (*leaderelection.LeaderElector, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get the hostname for the leader election identity: %v", err)
+	}
+	lock, err := resourcelock.New(b.LeaderElection.ResourceLock,
+		b.LeaderElection.ResourceNamespace,
+		b.LeaderElection.ResourceName,
+		client.CoreV1(),
+		client.CoordinationV1(),
+		resourcelock.ResourceLockConfig{Identity: hostname + "_" + string(uuid.NewUUID())})
+	if err != nil {
+		return nil, fmt.Errorf("unable to create the leader election lock: %v", err)
+	}
+
+	return leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   b.LeaderElection.LeaseDuration.Duration,
+		RenewDeadline:   b.LeaderElection.RenewDeadline.Duration,
+		RetryPeriod:     b.LeaderElection.RetryPeriod.Duration,
+		ReleaseOnCancel: true,
+		Name:            b.LeaderElection.ResourceName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: lifecycle.start,
+			OnStoppedLeading: lifecycle.stop,
+		},
+	})
+}
+
+// lead runs the leader callbacks until the given context is done.  With leader
+// election, the elector is run again after each loss of leadership, so that the
+// replica can lead again later.
+func lead(ctx context.Context, elector *leaderelection.LeaderElector, lifecycle *leaderLifecycle) {
+	if elector == nil {
+		lifecycle.start(ctx)
+		<-ctx.Done()
+		lifecycle.stop()
+		return
+	}
+	for ctx.Err() == nil {
+		elector.Run(ctx)
+	}
+}
+
+// leaderLifecycle starts and stops the leader callbacks, making sure that they
+// are only stopped after being started.
+type leaderLifecycle struct {
+	callbacks    []LeaderCallbacks
+	drainTimeout time.Duration
+
+	mu      sync.Mutex
+	leading bool
+}
+
+func (l *leaderLifecycle) start(ctx context.Context) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// the elector starts the callbacks asynchronously, possibly after losing the leadership
+	if l.leading || ctx.Err() != nil {
+		return
+	}
+	l.leading = true
+	klog.InfoS("Started leading")
+	for _, callbacks := range l.callbacks {
+		callbacks.OnStartedLeading(ctx)
+	}
+}
+
+func (l *leaderLifecycle) stop() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.leading {
+		return
+	}
+	l.leading = false
+	klog.InfoS("Stopped leading, draining")
+
+	ctx, cancel := context.WithTimeout(context.Background(), l.drainTimeout)
+	defer cancel()
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		for i := len(l.callbacks) - 1; i >= 0; i-- {
+			l.callbacks[i].OnStoppedLeading(ctx)
+		}
+	}()
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		klog.InfoS("Gave up draining after the drain timeout", "timeout", l.drainTimeout)
+	}
+}
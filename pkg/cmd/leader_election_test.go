@@ -0,0 +1,175 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+// eventLog records the calls to leader callbacks.
+type eventLog struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (l *eventLog) add(event string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, event)
+}
+
+func (l *eventLog) get() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.events...)
+}
+
+type recordingCallbacks struct {
+	name string
+	log  *eventLog
+}
+
+func (c *recordingCallbacks) OnStartedLeading(context.Context) {
+	c.log.add(c.name + " started")
+}
+
+func (c *recordingCallbacks) OnStoppedLeading(context.Context) {
+	c.log.add(c.name + " stopped")
+}
+
+func TestLeaderElectionTransitions(t *testing.T) {
+	log := &eventLog{}
+	adapter := &AdapterBase{LeaderElection: defaultLeaderElection("my-adapter")}
+	adapter.LeaderElection.LeaderElect = true
+	adapter.LeaderElection.LeaseDuration = metav1.Duration{Duration: time.Second}
+	adapter.LeaderElection.RenewDeadline = metav1.Duration{Duration: 500 * time.Millisecond}
+	adapter.LeaderElection.RetryPeriod = metav1.Duration{Duration: 100 * time.Millisecond}
+	adapter.WithLeaderCallbacks(&recordingCallbacks{name: "a", log: log}, &recordingCallbacks{name: "b", log: log})
+	require.Empty(t, adapter.validateLeaderElection())
+
+	client := fake.NewSimpleClientset()
+	lifecycle := &leaderLifecycle{callbacks: adapter.leaderCallbacks, drainTimeout: time.Second}
+	elector, err := adapter.leaderElector(client, lifecycle)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	led := make(chan struct{})
+	go func() {
+		defer close(led)
+		lead(ctx, elector, lifecycle)
+	}()
+
+	started := []string{"a started", "b started"}
+	assert.Eventually(t, func() bool { return assert.ObjectsAreEqual(started, log.get()) }, 5*time.Second, 10*time.Millisecond,
+		"should have started the callbacks in order once leading, got %v", log.get())
+
+	// another replica takes over the lease
+	leases := client.CoordinationV1().Leases("kube-system")
+	lease, err := leases.Get(context.Background(), "my-adapter", metav1.GetOptions{})
+	require.NoError(t, err)
+	lease.Spec.HolderIdentity = ptr.To("other")
+	lease.Spec.RenewTime = &metav1.MicroTime{Time: time.Now()}
+	_, err = leases.Update(context.Background(), lease, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	lost := append(started, "b stopped", "a stopped")
+	assert.Eventually(t, func() bool { return assert.ObjectsAreEqual(lost, log.get()) }, 5*time.Second, 10*time.Millisecond,
+		"should have stopped the callbacks in reverse order once the leadership was lost, got %v", log.get())
+
+	// the lease of the other replica expires without being renewed
+	regained := append(lost, started...)
+	assert.Eventually(t, func() bool { return assert.ObjectsAreEqual(regained, log.get()) }, 5*time.Second, 10*time.Millisecond,
+		"should have started the callbacks again once leading again, got %v", log.get())
+
+	cancel()
+	<-led
+	assert.Equal(t, append(regained, "b stopped", "a stopped"), log.get(), "should have stopped the callbacks when stopping")
+	lease, err = leases.Get(context.Background(), "my-adapter", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, ptr.Deref(lease.Spec.HolderIdentity, ""), "should have released the lease when stopping")
+}
+
+func TestLeaderElectionDisabled(t *testing.T) {
+	log := &eventLog{}
+	lifecycle := &leaderLifecycle{callbacks: []LeaderCallbacks{&recordingCallbacks{name: "a", log: log}}, drainTimeout: time.Second}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	led := make(chan struct{})
+	go func() {
+		defer close(led)
+		lead(ctx, nil, lifecycle)
+	}()
+
+	assert.Eventually(t, func() bool { return len(log.get()) == 1 }, 5*time.Second, 10*time.Millisecond, "should have started the callbacks right away")
+	cancel()
+	<-led
+	assert.Equal(t, []string{"a started", "a stopped"}, log.get())
+}
+
+// stuckCallbacks never stop by themselves.
+type stuckCallbacks struct {
+	stopped chan struct{}
+}
+
+func (c *stuckCallbacks) OnStartedLeading(context.Context) {}
+
+func (c *stuckCallbacks) OnStoppedLeading(ctx context.Context) {
+	<-ctx.Done()
+	close(c.stopped)
+}
+
+func TestLeaderLifecycleDrainTimeout(t *testing.T) {
+	callbacks := &stuckCallbacks{stopped: make(chan struct{})}
+	lifecycle := &leaderLifecycle{callbacks: []LeaderCallbacks{callbacks}, drainTimeout: 50 * time.Millisecond}
+
+	lifecycle.stop()
+	select {
+	case <-callbacks.stopped:
+		t.Fatal("should not have stopped callbacks which were not started")
+	default:
+	}
+
+	lifecycle.start(context.Background())
+	start := time.Now()
+	lifecycle.stop()
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond, "should have waited for the drain timeout")
+	select {
+	case <-callbacks.stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("should have expired the context given to the callbacks after the drain timeout")
+	}
+}
+
+func TestValidateLeaderElection(t *testing.T) {
+	adapter := &AdapterBase{LeaderElection: defaultLeaderElection("my-adapter")}
+	assert.Empty(t, adapter.validateLeaderElection(), "should have accepted the defaults")
+
+	adapter.LeaderElection.LeaderElect = true
+	adapter.LeaderElection.RenewDeadline = adapter.LeaderElection.LeaseDuration
+	adapter.LeaderElectionDrainTimeout = -time.Second
+	assert.Len(t, adapter.validateLeaderElection(), 2, "should have rejected a renew deadline as long as the lease, and a negative drain timeout")
+}
@@ -20,12 +20,28 @@ package options
 import (
 	"fmt"
 	"net"
+	"net/http"
+	"runtime"
+	"strings"
+	"time"
 
 	"github.com/spf13/pflag"
 
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apiserver/pkg/authorization/authorizerfactory"
 	genericapiserver "k8s.io/apiserver/pkg/server"
 	genericoptions "k8s.io/apiserver/pkg/server/options"
+	"k8s.io/klog/v2"
 	openapicommon "k8s.io/kube-openapi/pkg/common"
+	netutils "k8s.io/utils/net"
+
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/authorization"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/cachecontrol"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/filters"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/listener"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/overrides"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/ratelimit"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/servertiming"
 )
 
 // CustomMetricsAdapterServerOptions contains the of options used to configure
@@ -42,8 +58,122 @@ type CustomMetricsAdapterServerOptions struct {
 	OpenAPIConfig   *openapicommon.Config
 	OpenAPIV3Config *openapicommon.Config
 	EnableMetrics   bool
+
+	// MetricRateLimits limits the QPS of queries for individual metrics.
+	MetricRateLimits ratelimit.Limits
+	// AllowedNamespaces restricts the namespaces for which metrics are served.
+	// When empty, metrics are served for all namespaces.
+	AllowedNamespaces []string
+	// DefaultNamespace is the namespace assumed for custom metrics requested
+	// without namespace, when they are only served for namespaced objects.
+	DefaultNamespace string
+	// MetricMaxAges sets how long responses for individual metrics may be cached
+	// by clients.  Responses for other metrics must not be cached.
+	MetricMaxAges cachecontrol.MaxAges
+	// DefaultMetricWindow is the window set on custom metric values for which the
+	// provider leaves it unset.  Zero means that such values have no window.
+	DefaultMetricWindow time.Duration
+	// MetricValueSignificantDigits is the number of significant digits custom
+	// metric values are rounded to.  Zero means that values are not rounded.
+	MetricValueSignificantDigits int
+	// CoalesceWindow is how long the result of a provider query is shared with
+	// identical requests of the same user after it succeeded.  Zero means that only concurrent
+	// requests share it.
+	CoalesceWindow time.Duration
+	// StartupRetryTimeout bounds the time spent retrying the startup steps which
+	// depend on the cluster, such as looking up the authentication configuration,
+	// and building the initial REST mappings.
+	StartupRetryTimeout time.Duration
+	// SelfSignedCertOrganization is the organization of the self-signed serving
+	// certificate generated when none is provided.
+	SelfSignedCertOrganization string
+	// SelfSignedCertValidity is the validity of the self-signed serving certificate
+	// generated when none is provided.
+	SelfSignedCertValidity time.Duration
+	// MaxRequestBytes limits the size of requests (URI and body).  Zero means no limit.
+	MaxRequestBytes int64
+	// MaxSelectorLength limits the length of selectors in queries.  Zero means no limit.
+	MaxSelectorLength int
+	// MaxSelectorRequirements limits the number of requirements of each selector in
+	// queries.  Zero means no limit.
+	MaxSelectorRequirements int
+	// TrustedProxyCIDRs are the CIDRs of the proxies trusted to report the client
+	// IP of requests, through the X-Forwarded-For and X-Real-IP headers.
+	TrustedProxyCIDRs []string
+	// MaxConnectionsPerIP caps the number of requests served concurrently for a
+	// single client IP.  Zero means no cap.
+	MaxConnectionsPerIP int
+	// PanicResponseDetail is how much of the panics of the handlers is reported
+	// to clients: none, reference (the audit ID) or full.
+	PanicResponseDetail string
+	// EnableDebugEndpoints enables the debug endpoint dumping the cached metric values.
+	EnableDebugEndpoints bool
+	// RequireRequestHeaderAuthentication fails at startup when the requests relayed
+	// by the aggregation layer cannot be authenticated, for lack of front-proxy CA.
+	RequireRequestHeaderAuthentication bool
+	// AuthorizeDiscovery restricts the metrics listed in the discovery documents
+	// to the ones the caller is authorized to get.
+	AuthorizeDiscovery bool
+	// DisableAuthorization replaces the delegated authorization with one allowing
+	// all requests, for network-isolated deployments.
+	DisableAuthorization bool
+	// AllowUnauthorizedPublicServing allows DisableAuthorization on other addresses
+	// than the loopback ones.
+	AllowUnauthorizedPublicServing bool
+	// AuthorizationWebhookErrorPolicy is how requests are authorized when the
+	// delegated authorization fails: fail-closed or fail-open.
+	AuthorizationWebhookErrorPolicy string
+	// EnableOpenMetrics serves the metrics of the adapter in the OpenMetrics
+	// format to the clients asking for it.
+	EnableOpenMetrics bool
+	// EnableMetricsCatalog enables the catalog documenting the custom metrics.
+	EnableMetricsCatalog bool
+	// MaxCountedMetricNames caps the number of distinct metric names recorded by
+	// the custom_metrics_requests_total counter, and by the
+	// custom_metrics_value_age_seconds gauge.  Zero means no cap.
+	MaxCountedMetricNames int
+	// EnableValueAgeMetric records the age of the most recent value served for
+	// each metric in the custom_metrics_value_age_seconds gauge.
+	EnableValueAgeMetric bool
+	// RequestLogSampleRate makes the successful queries to the providers logged for 1
+	// in RequestLogSampleRate requests.  1 or less logs them for all requests.
+	RequestLogSampleRate int
+	// EnableServerTiming reports the time spent in the RESTMapper and in the
+	// provider in the Server-Timing header of responses.
+	EnableServerTiming bool
+	// EnableTestOverrides lets clients force the values of the metrics they request
+	// with the X-Metric-Override header, bypassing the providers, for tests.
+	EnableTestOverrides bool
+	// LoopbackClientQPS is the QPS of the loopback client of the server.  Zero
+	// keeps the default of the generic API server.
+	LoopbackClientQPS float32
+	// LoopbackClientBurst is the burst of the loopback client of the server.  Zero
+	// keeps the default of the generic API server.  It requires LoopbackClientQPS,
+	// since the loopback client is not rate limited otherwise.
+	LoopbackClientBurst int
+	// TCPKeepAlivePeriod is the TCP keep-alive period of the connections to the
+	// secure port.  Zero keeps the default of the generic API server.
+	TCPKeepAlivePeriod time.Duration
+	// ConnectionIdleTimeout is the time after which the connections to the secure
+	// port without reads or writes are closed.  Zero means no timeout.
+	ConnectionIdleTimeout time.Duration
+	// MaxRequestsInFlight caps the number of requests served concurrently.
+	// Zero keeps the default of the generic API server.
+	MaxRequestsInFlight int
+	// MaxRequestsInFlightPerCPU caps the number of requests served concurrently
+	// for each CPU available to the process, as reported by GOMAXPROCS.  Zero
+	// means no cap.
+	MaxRequestsInFlightPerCPU int
+	// BasePath is the path prefix under which a reverse proxy exposes the server.
+	// It's stripped from the requests received with it, and added to the links of
+	// the OpenAPI v3 discovery document.
+	BasePath string
 }
 
+// defaultMaxCountedMetricNames is the default number of distinct metric names
+// recorded by the request counter.
+const defaultMaxCountedMetricNames = 100
+
 // NewCustomMetricsAdapterServerOptions creates a new instance of
 // CustomMetricsAdapterServerOptions with its default values.
 func NewCustomMetricsAdapterServerOptions() *CustomMetricsAdapterServerOptions {
@@ -54,9 +184,18 @@ func NewCustomMetricsAdapterServerOptions() *CustomMetricsAdapterServerOptions {
 		Audit:          genericoptions.NewAuditOptions(),
 		Features:       genericoptions.NewFeatureOptions(),
 
-		EnableMetrics: true,
+		EnableMetrics:          true,
+		SelfSignedCertValidity: defaultSelfSignedCertValidity,
+		StartupRetryTimeout:    defaultStartupRetryTimeout,
+		PanicResponseDetail:    string(filters.PanicDetailNone),
+		MaxCountedMetricNames:  defaultMaxCountedMetricNames,
+
+		AuthorizationWebhookErrorPolicy: string(authorization.ErrorPolicyFailClosed),
 	}
 
+	// startup probes are not authorized, like the other probes
+	o.Authorization.AlwaysAllowPaths = append(o.Authorization.AlwaysAllowPaths, "/startupz")
+
 	return o
 }
 
@@ -65,9 +204,90 @@ func (o CustomMetricsAdapterServerOptions) Validate() []error {
 	errors := []error{}
 	errors = append(errors, o.SecureServing.Validate()...)
 	errors = append(errors, o.Authentication.Validate()...)
+	errors = append(errors, o.validateRequestHeader()...)
 	errors = append(errors, o.Authorization.Validate()...)
 	errors = append(errors, o.Audit.Validate()...)
 	errors = append(errors, o.Features.Validate()...)
+	errors = append(errors, o.MetricRateLimits.Validate()...)
+	errors = append(errors, o.MetricMaxAges.Validate()...)
+	if o.DefaultNamespace != "" {
+		if msgs := validation.IsDNS1123Label(o.DefaultNamespace); len(msgs) > 0 {
+			errors = append(errors, fmt.Errorf("invalid --default-namespace: %s", strings.Join(msgs, ", ")))
+		}
+	}
+	if o.DefaultMetricWindow < 0 {
+		errors = append(errors, fmt.Errorf("--default-metric-window must not be negative"))
+	} else if o.DefaultMetricWindow%time.Second != 0 {
+		// windows are served in whole seconds, so others would be silently truncated,
+		// and those below a second reported as no window at all
+		errors = append(errors, fmt.Errorf("--default-metric-window must be a whole number of seconds, got %v", o.DefaultMetricWindow))
+	}
+	if o.MetricValueSignificantDigits < 0 {
+		errors = append(errors, fmt.Errorf("--metric-value-significant-digits must not be negative"))
+	}
+	if o.CoalesceWindow < 0 {
+		errors = append(errors, fmt.Errorf("--coalesce-window must not be negative"))
+	}
+	if o.StartupRetryTimeout < 0 {
+		errors = append(errors, fmt.Errorf("--startup-retry-timeout must not be negative"))
+	}
+	if o.SelfSignedCertValidity <= 0 {
+		errors = append(errors, fmt.Errorf("--self-signed-cert-validity must be positive"))
+	}
+	if o.MaxRequestBytes < 0 {
+		errors = append(errors, fmt.Errorf("--max-request-bytes must not be negative"))
+	}
+	if o.MaxConnectionsPerIP < 0 {
+		errors = append(errors, fmt.Errorf("--max-connections-per-ip must not be negative"))
+	}
+	if o.MaxSelectorLength < 0 {
+		errors = append(errors, fmt.Errorf("--max-selector-length must not be negative"))
+	}
+	if o.MaxSelectorRequirements < 0 {
+		errors = append(errors, fmt.Errorf("--max-selector-requirements must not be negative"))
+	}
+	if o.MaxRequestsInFlight < 0 {
+		errors = append(errors, fmt.Errorf("--max-requests-inflight must not be negative"))
+	}
+	if o.MaxRequestsInFlightPerCPU < 0 {
+		errors = append(errors, fmt.Errorf("--max-requests-inflight-per-cpu must not be negative"))
+	}
+	if !validPanicResponseDetail(o.PanicResponseDetail) {
+		errors = append(errors, fmt.Errorf("--panic-response-detail must be one of %v", filters.PanicResponseDetails))
+	}
+	if o.LoopbackClientQPS < 0 {
+		errors = append(errors, fmt.Errorf("--loopback-client-qps must not be negative"))
+	}
+	if o.LoopbackClientBurst < 0 {
+		errors = append(errors, fmt.Errorf("--loopback-client-burst must not be negative"))
+	}
+	if o.LoopbackClientBurst > 0 && o.LoopbackClientQPS <= 0 {
+		errors = append(errors, fmt.Errorf("--loopback-client-burst requires --loopback-client-qps, since the loopback client is not rate limited otherwise"))
+	}
+	if !validAuthorizationErrorPolicy(o.AuthorizationWebhookErrorPolicy) {
+		errors = append(errors, fmt.Errorf("--authorization-webhook-error-policy must be one of %v", authorization.ErrorPolicies))
+	}
+	if o.DisableAuthorization && !o.AllowUnauthorizedPublicServing && !o.servesOnLoopbackOnly() {
+		errors = append(errors, fmt.Errorf("--disable-authorization requires a loopback --bind-address, unless --allow-unauthorized-public-serving is set"))
+	}
+	if o.MaxCountedMetricNames < 0 {
+		errors = append(errors, fmt.Errorf("--max-counted-metric-names must not be negative"))
+	}
+	if o.RequestLogSampleRate < 0 {
+		errors = append(errors, fmt.Errorf("--request-log-sample-rate must not be negative"))
+	}
+	if o.TCPKeepAlivePeriod < 0 {
+		errors = append(errors, fmt.Errorf("--tcp-keep-alive-period must not be negative"))
+	}
+	if o.ConnectionIdleTimeout < 0 {
+		errors = append(errors, fmt.Errorf("--connection-idle-timeout must not be negative"))
+	}
+	if o.BasePath != "" && (!strings.HasPrefix(o.BasePath, "/") || strings.HasSuffix(o.BasePath, "/") || strings.ContainsAny(o.BasePath, "?#")) {
+		errors = append(errors, fmt.Errorf("--base-path must be an absolute path, without trailing slash, query nor fragment"))
+	}
+	if _, err := netutils.ParseCIDRs(o.TrustedProxyCIDRs); err != nil {
+		errors = append(errors, fmt.Errorf("invalid --trusted-proxy-cidrs: %v", err))
+	}
 	return errors
 }
 
@@ -78,27 +298,176 @@ func (o *CustomMetricsAdapterServerOptions) AddFlags(fs *pflag.FlagSet) {
 	o.Authorization.AddFlags(fs)
 	o.Audit.AddFlags(fs)
 	o.Features.AddFlags(fs)
+
+	fs.Var(&o.MetricRateLimits, "metric-rate-limits", "A set of metric=qps pairs limiting the rate of queries passed to the provider "+
+		"for each metric. Queries above the limit are rejected with 429 Too Many Requests. Metrics not listed are not limited.")
+	fs.StringSliceVar(&o.AllowedNamespaces, "allowed-namespaces", o.AllowedNamespaces, "A list of namespaces for which metrics are served. "+
+		"Queries for other namespaces are rejected with 403 Forbidden. If empty, metrics are served for all namespaces.")
+	fs.StringVar(&o.DefaultNamespace, "default-namespace", o.DefaultNamespace, "The namespace assumed, for compatibility with "+
+		"legacy clients, when custom metrics only served for namespaced objects are requested without namespace. Such requests "+
+		"are answered with a deprecation warning. If empty, requests without namespace are for root-scoped objects only.")
+	fs.DurationVar(&o.DefaultMetricWindow, "default-metric-window", o.DefaultMetricWindow, "The window reported for custom metric values "+
+		"for which the provider does not set one, so that clients such as the HPA do not assume a wrong one. Must be a whole number of seconds. If 0, such values are reported without window.")
+	fs.IntVar(&o.MetricValueSignificantDigits, "metric-value-significant-digits", o.MetricValueSignificantDigits, "The number of "+
+		"significant digits custom metric values are rounded to, half away from zero, for clients which cannot parse precise "+
+		"quantities. The unit and format of the values are kept, e.g. 1234567m is rounded to 1230 with 3 digits. If 0, values "+
+		"are not rounded.")
+	fs.DurationVar(&o.CoalesceWindow, "coalesce-window", o.CoalesceWindow, "How long the result of a query to the provider "+
+		"is shared with identical requests of the same user after it succeeded, so that bursts of identical requests, such as those of several HPA "+
+		"controllers, cause a single query. Errors are not shared. If 0, only concurrent identical requests share a query.")
+	fs.DurationVar(&o.StartupRetryTimeout, "startup-retry-timeout", o.StartupRetryTimeout, "The maximum time spent retrying, with backoff, "+
+		"the startup steps which depend on the cluster, such as looking up the authentication configuration and discovering "+
+		"the API resources of the cluster, so that a briefly "+
+		"unavailable API server does not make the adapter exit. If 0, they are not retried.")
+	fs.StringVar(&o.SelfSignedCertOrganization, "self-signed-cert-organization", o.SelfSignedCertOrganization, "The organization of the "+
+		"self-signed serving certificate generated when no certificate is provided.")
+	fs.DurationVar(&o.SelfSignedCertValidity, "self-signed-cert-validity", o.SelfSignedCertValidity, "The validity of the "+
+		"self-signed serving certificate generated when no certificate is provided.")
+	fs.Var(&o.MetricMaxAges, "metric-max-ages", "A set of metric=duration pairs setting how long responses for each metric "+
+		"may be cached by clients and intermediaries, through a Cache-Control max-age header. Responses for metrics not listed are sent with Cache-Control: no-cache.")
+	fs.Int64Var(&o.MaxRequestBytes, "max-request-bytes", o.MaxRequestBytes, "The maximum size in bytes of a request, including its URI and body. "+
+		"Larger requests are rejected with 413 Request Entity Too Large. 0 means no limit.")
+	fs.IntVar(&o.MaxSelectorLength, "max-selector-length", o.MaxSelectorLength, "The maximum length of the selectors of a query. "+
+		"Queries with longer selectors are rejected with 400 Bad Request before the selectors are parsed. 0 means no limit.")
+	fs.IntVar(&o.MaxSelectorRequirements, "max-selector-requirements", o.MaxSelectorRequirements, "The maximum number of "+
+		"requirements of each selector of a query, e.g. 2 for app=web,tier!=cache. Queries with more requirements are rejected "+
+		"with 400 Bad Request before being passed to the providers, to protect them from pathological selectors. 0 means no limit.")
+	fs.IntVar(&o.MaxConnectionsPerIP, "max-connections-per-ip", o.MaxConnectionsPerIP, "The maximum number of requests served "+
+		"concurrently for a single client IP, above which requests are rejected with 429 Too Many Requests. Clients are identified "+
+		"by the IP reported by the proxies of --trusted-proxy-cidrs, when set, and otherwise by the IP of the peer, which is the "+
+		"main API server for requests relayed by the aggregation layer. Zero means no limit.")
+	fs.StringSliceVar(&o.TrustedProxyCIDRs, "trusted-proxy-cidrs", o.TrustedProxyCIDRs, "A comma-separated list of the CIDRs of the proxies, "+
+		"such as ingress controllers or load balancers, trusted to report the client IP of requests through the X-Forwarded-For and X-Real-IP headers. "+
+		"The client IP is recorded in audit events. When set, these headers are dropped from requests received from other peers; "+
+		"otherwise, they are trusted from all peers.")
+	fs.StringVar(&o.PanicResponseDetail, "panic-response-detail", o.PanicResponseDetail, "How much of a panic while serving a request "+
+		"is reported in the 500 Internal Server Error response: none keeps the generic message, reference adds the audit ID of the "+
+		"request to correlate it with the logs, and full also adds the panic message, which may leak internal details.")
+	fs.BoolVar(&o.EnableDebugEndpoints, "enable-debug-endpoints", o.EnableDebugEndpoints, "Enable the debug endpoint dumping the metric values "+
+		"cached by caching providers, at /debug/metrics-dump, the one serving the last successful and failed collections of the "+
+		"metrics of the providers tracking them, at /debug/metrics-diagnostics, and the one refreshing the dynamic RESTMapper from discovery on POST "+
+		"requests, at /debug/refresh-restmapper, e.g. once a CRD is installed. They are only served to users authorized for these "+
+		"non-resource URLs.")
+	fs.BoolVar(&o.RequireRequestHeaderAuthentication, "require-requestheader-authentication", o.RequireRequestHeaderAuthentication, "Fail "+
+		"at startup unless the requests relayed by the aggregation layer can be authenticated, with the front-proxy CA of "+
+		"--requestheader-client-ca-file or the one looked up in the cluster, so that a missing CA is not only noticed once "+
+		"all the requests are rejected with 401 Unauthorized. Set it when the adapter is served through the aggregation layer.")
+	fs.BoolVar(&o.AuthorizeDiscovery, "authorize-discovery", o.AuthorizeDiscovery, "List in the discovery documents only the metrics "+
+		"the caller is authorized to get, so that metric names cannot be enumerated by unauthorized users. Authorization is checked "+
+		"cluster-wide, so users only authorized in some namespaces do not see the namespaced metrics. This costs an authorization check "+
+		"per metric for each discovery request.")
+	fs.BoolVar(&o.DisableAuthorization, "disable-authorization", o.DisableAuthorization, "Allow all the requests instead of "+
+		"delegating their authorization to the main API server, so that any authenticated or anonymous user can get all the "+
+		"metrics. Only meant for debugging in network-isolated deployments. Unless --allow-unauthorized-public-serving is set, "+
+		"it requires --bind-address to be a loopback address.")
+	fs.BoolVar(&o.AllowUnauthorizedPublicServing, "allow-unauthorized-public-serving", o.AllowUnauthorizedPublicServing, "Acknowledge "+
+		"that, with --disable-authorization, all the metrics are served to anyone who can reach --bind-address, which is "+
+		"required when it is not a loopback address.")
+	fs.StringVar(&o.AuthorizationWebhookErrorPolicy, "authorization-webhook-error-policy", o.AuthorizationWebhookErrorPolicy, "How "+
+		"requests are authorized when their authorization by the main API server fails, e.g. when it is unreachable: fail-closed "+
+		"rejects them, and fail-open allows them, so that autoscaling keeps working at the cost of serving the metrics to "+
+		"unauthorized users meanwhile. Explicit denies are kept under both policies.")
+	fs.BoolVar(&o.EnableOpenMetrics, "enable-openmetrics", o.EnableOpenMetrics, "Serve the metrics of the adapter at /metrics in the "+
+		"OpenMetrics format to the clients asking for it in their Accept header. Other clients keep getting the Prometheus text format.")
+	fs.BoolVar(&o.EnableMetricsCatalog, "enable-metrics-catalog", o.EnableMetricsCatalog, "Serve at /apis/custom.metrics.k8s.io/catalog "+
+		"a JSON catalog of the custom metrics, with their type and, for providers documenting them, their description, unit and an "+
+		"example selector. It is only served to users authorized for this non-resource URL.")
+	fs.IntVar(&o.MaxCountedMetricNames, "max-counted-metric-names", o.MaxCountedMetricNames, "The maximum number of distinct "+
+		"metric names recorded by the custom_metrics_requests_total counter, which counts the requests for each custom metric. "+
+		"Requests for further metrics are recorded under the metric name \"other\", so that clients requesting arbitrary metric "+
+		"names cannot grow the cardinality of the counter without bound. It also caps the metric names recorded by the "+
+		"custom_metrics_value_age_seconds gauge, which does not record further metrics. 0 means no limit.")
+	fs.BoolVar(&o.EnableValueAgeMetric, "enable-value-age-metric", o.EnableValueAgeMetric, "Record in the "+
+		"custom_metrics_value_age_seconds gauge, labeled by API group and metric name, the age of the most recent value "+
+		"served for each metric, when it is served, to alert on metrics whose providers return stale values.")
+	fs.IntVar(&o.RequestLogSampleRate, "request-log-sample-rate", o.RequestLogSampleRate, "Log the queries passed to the "+
+		"providers, at verbosity 5, for 1 in this many requests, picked at random, so that the logs stay usable at high QPS. "+
+		"Queries failing are logged for all requests. 0 or 1 logs all of them.")
+	fs.Float32Var(&o.LoopbackClientQPS, "loopback-client-qps", o.LoopbackClientQPS, "The QPS of the client the adapter uses "+
+		"to call itself. 0 keeps the default of the API server, which does not rate limit it.")
+	fs.IntVar(&o.LoopbackClientBurst, "loopback-client-burst", o.LoopbackClientBurst, "The burst of the client the adapter uses "+
+		"to call itself. It requires --loopback-client-qps, since the client is not rate limited otherwise. "+
+		"0 keeps the default of the API server.")
+	fs.DurationVar(&o.TCPKeepAlivePeriod, "tcp-keep-alive-period", o.TCPKeepAlivePeriod, "The TCP keep-alive period of the "+
+		"connections to the secure port, so that connections dropped by the network, e.g. by load balancers, are detected. "+
+		"0 keeps the default of the API server, 3 minutes.")
+	fs.DurationVar(&o.ConnectionIdleTimeout, "connection-idle-timeout", o.ConnectionIdleTimeout, "The time after which the "+
+		"connections to the secure port without reads or writes are closed, including the ones of requests waiting for the "+
+		"provider. Unlike the HTTP idle timeout, it also applies to connections stuck before or during the TLS handshake. "+
+		"0 means no timeout.")
+	fs.BoolVar(&o.EnableServerTiming, "enable-server-timing", o.EnableServerTiming, "Report in the Server-Timing header of the "+
+		"responses the time spent mapping resources with the RESTMapper and querying the provider, to diagnose the latency of "+
+		"requests without tracing. Queries shared by identical requests are only reported to the request which made them.")
+	fs.BoolVar(&o.EnableTestOverrides, "enable-test-overrides", o.EnableTestOverrides, "FOR TESTS ONLY: let clients force the "+
+		"values of the metrics they request with the "+overrides.HeaderMetricOverride+" header, bypassing the providers: a quantity, "+
+		"for any object, or name=quantity pairs, for the listed objects. Any client authorized to get a metric can then fake its "+
+		"value, so this must never be enabled in production. When disabled, requests with the header are rejected.")
+	fs.IntVar(&o.MaxRequestsInFlight, "max-requests-inflight", o.MaxRequestsInFlight, "The maximum number of requests served "+
+		"concurrently, above which requests are rejected with 429 Too Many Requests. 0 keeps the default of the API server.")
+	fs.IntVar(&o.MaxRequestsInFlightPerCPU, "max-requests-inflight-per-cpu", o.MaxRequestsInFlightPerCPU, "The maximum number of "+
+		"requests served concurrently for each CPU available to the adapter, as reported by GOMAXPROCS, which should be set according "+
+		"to the CPU limit of the pod. If --max-requests-inflight is also set, the lowest cap applies. 0 means no cap per CPU.")
+	fs.StringVar(&o.BasePath, "base-path", o.BasePath, "The path prefix, such as /metrics-adapter, under which a reverse proxy "+
+		"exposes the adapter. Requests are served with or without it, for proxies which strip it or not, and it is added to the "+
+		"links of the OpenAPI v3 discovery document and to the default server URL of the OpenAPI documents. If empty, the adapter "+
+		"is served at the root, e.g. through the aggregation layer.")
+}
+
+// servesOnLoopbackOnly returns whether the secure port is only reachable on a
+// loopback address.
+func (o *CustomMetricsAdapterServerOptions) servesOnLoopbackOnly() bool {
+	if o.SecureServing.Listener != nil {
+		addr, ok := o.SecureServing.Listener.Addr().(*net.TCPAddr)
+		return ok && addr.IP.IsLoopback()
+	}
+	return o.SecureServing.BindAddress.IsLoopback()
 }
 
 // ApplyTo applies CustomMetricsAdapterServerOptions to the server configuration.
 func (o *CustomMetricsAdapterServerOptions) ApplyTo(serverConfig *genericapiserver.Config) error {
 	// TODO have a "real" external address (have an AdvertiseAddress?)
-	if err := o.SecureServing.MaybeDefaultWithSelfSignedCerts("localhost", nil, []net.IP{net.ParseIP("127.0.0.1")}); err != nil {
+	if err := o.maybeDefaultWithSelfSignedCerts("localhost", nil, []net.IP{net.ParseIP("127.0.0.1")}); err != nil {
 		return fmt.Errorf("error creating self-signed certificates: %v", err)
 	}
 
 	if err := o.SecureServing.ApplyTo(&serverConfig.SecureServing, &serverConfig.LoopbackClientConfig); err != nil {
 		return err
 	}
-	if err := o.Authentication.ApplyTo(&serverConfig.Authentication, serverConfig.SecureServing, nil); err != nil {
+	if serverConfig.SecureServing != nil {
+		serverConfig.SecureServing.Listener = listener.WithTimeouts(serverConfig.SecureServing.Listener, o.TCPKeepAlivePeriod, o.ConnectionIdleTimeout)
+	}
+	if serverConfig.LoopbackClientConfig != nil {
+		if o.LoopbackClientQPS > 0 {
+			serverConfig.LoopbackClientConfig.QPS = o.LoopbackClientQPS
+		}
+		if o.LoopbackClientBurst > 0 {
+			serverConfig.LoopbackClientConfig.Burst = o.LoopbackClientBurst
+		}
+	}
+	// the authentication configuration is looked up in the cluster
+	if err := retryWithBackoff(o.StartupRetryTimeout, "load the authentication configuration", func() error {
+		return o.Authentication.ApplyTo(&serverConfig.Authentication, serverConfig.SecureServing, nil)
+	}); err != nil {
 		return err
 	}
-	if err := o.Authorization.ApplyTo(&serverConfig.Authorization); err != nil {
+	if err := o.checkRequestHeaderConfig(serverConfig); err != nil {
 		return err
 	}
+	if o.DisableAuthorization {
+		klog.Warning("AUTHORIZATION IS DISABLED: all the requests are allowed, for all users, including anonymous ones. " +
+			"Only use --disable-authorization in network-isolated deployments.")
+		serverConfig.Authorization.Authorizer = authorizerfactory.NewAlwaysAllowAuthorizer()
+	} else {
+		if err := o.Authorization.ApplyTo(&serverConfig.Authorization); err != nil {
+			return err
+		}
+		serverConfig.Authorization.Authorizer = authorization.WithErrorPolicy(serverConfig.Authorization.Authorizer, authorization.ErrorPolicy(o.AuthorizationWebhookErrorPolicy))
+	}
 	if err := o.Audit.ApplyTo(serverConfig); err != nil {
 		return err
 	}
+	// the feature options only set the profiling and debugging endpoints: they
+	// create no client nor informers, so they need no extra permissions
 	if err := o.Features.ApplyTo(serverConfig); err != nil {
 		return err
 	}
@@ -112,6 +481,106 @@ func (o *CustomMetricsAdapterServerOptions) ApplyTo(serverConfig *genericapiserv
 	}
 
 	serverConfig.EnableMetrics = o.EnableMetrics
+	if maxRequestsInFlight := o.maxRequestsInFlight(runtime.GOMAXPROCS(0)); maxRequestsInFlight > 0 {
+		serverConfig.MaxRequestsInFlight = maxRequestsInFlight
+	}
+
+	// reject oversized requests before any other processing
+	limits := filters.RequestSizeLimits{MaxRequestBytes: o.MaxRequestBytes, MaxSelectorLength: o.MaxSelectorLength}
+	buildHandlerChain := serverConfig.BuildHandlerChainFunc
+	serverConfig.BuildHandlerChainFunc = func(apiHandler http.Handler, c *genericapiserver.Config) http.Handler {
+		return filters.WithRequestSizeLimits(buildHandlerChain(apiHandler, c), limits, c.Serializer)
+	}
+
+	// reject the methods of writes early, since the metrics APIs are read-only
+	buildUnfilteredHandlerChain := serverConfig.BuildHandlerChainFunc
+	serverConfig.BuildHandlerChainFunc = func(apiHandler http.Handler, c *genericapiserver.Config) http.Handler {
+		return filters.WithAllowedMethods(buildUnfilteredHandlerChain(apiHandler, c), []string{http.MethodGet}, c.Serializer)
+	}
+
+	// report panics, within the audit filters which know the audit ID
+	if detail := filters.PanicResponseDetail(o.PanicResponseDetail); detail != filters.PanicDetailNone {
+		buildUnrecoveredHandlerChain := serverConfig.BuildHandlerChainFunc
+		serverConfig.BuildHandlerChainFunc = func(apiHandler http.Handler, c *genericapiserver.Config) http.Handler {
+			return buildUnrecoveredHandlerChain(filters.WithPanicResponse(apiHandler, detail, c.Serializer), c)
+		}
+	}
+
+	// pass the values forced by tests to the APIs, once the requests are authorized
+	if o.EnableTestOverrides {
+		klog.Warning("TEST OVERRIDES ARE ENABLED: clients can force the values of any metric they are authorized to get with the " +
+			overrides.HeaderMetricOverride + " header. Only use --enable-test-overrides in tests.")
+	}
+	buildUnoverriddenHandlerChain := serverConfig.BuildHandlerChainFunc
+	serverConfig.BuildHandlerChainFunc = func(apiHandler http.Handler, c *genericapiserver.Config) http.Handler {
+		return buildUnoverriddenHandlerChain(overrides.WithOverrides(apiHandler, o.EnableTestOverrides, c.Serializer), c)
+	}
+
+	// time the phases of the requests, as they reach the APIs
+	if o.EnableServerTiming {
+		buildUntimedHandlerChain := serverConfig.BuildHandlerChainFunc
+		serverConfig.BuildHandlerChainFunc = func(apiHandler http.Handler, c *genericapiserver.Config) http.Handler {
+			return buildUntimedHandlerChain(servertiming.WithServerTiming(apiHandler), c)
+		}
+	}
+
+	// cap the requests of each client, once its IP is known
+	if o.MaxConnectionsPerIP > 0 {
+		buildSizedHandlerChain := serverConfig.BuildHandlerChainFunc
+		serverConfig.BuildHandlerChainFunc = func(apiHandler http.Handler, c *genericapiserver.Config) http.Handler {
+			return filters.WithMaxConnectionsPerIP(buildSizedHandlerChain(apiHandler, c), o.MaxConnectionsPerIP, len(o.TrustedProxyCIDRs) > 0, c.Serializer)
+		}
+	}
+
+	// only let trusted proxies report the client IP, before it is recorded
+	if len(o.TrustedProxyCIDRs) > 0 {
+		trustedProxies, err := netutils.ParseCIDRs(o.TrustedProxyCIDRs)
+		if err != nil {
+			return err
+		}
+		buildLimitedHandlerChain := serverConfig.BuildHandlerChainFunc
+		serverConfig.BuildHandlerChainFunc = func(apiHandler http.Handler, c *genericapiserver.Config) http.Handler {
+			return filters.WithTrustedProxies(buildLimitedHandlerChain(apiHandler, c), trustedProxies)
+		}
+	}
+
+	// strip the prefix of the reverse proxy before the requests are routed
+	if o.BasePath != "" {
+		buildUnprefixedHandlerChain := serverConfig.BuildHandlerChainFunc
+		serverConfig.BuildHandlerChainFunc = func(apiHandler http.Handler, c *genericapiserver.Config) http.Handler {
+			return filters.WithBasePath(buildUnprefixedHandlerChain(apiHandler, c), o.BasePath)
+		}
+	}
 
 	return nil
 }
+
+func validAuthorizationErrorPolicy(policy string) bool {
+	for _, valid := range authorization.ErrorPolicies {
+		if policy == string(valid) {
+			return true
+		}
+	}
+	return false
+}
+
+func validPanicResponseDetail(detail string) bool {
+	for _, valid := range filters.PanicResponseDetails {
+		if detail == string(valid) {
+			return true
+		}
+	}
+	return false
+}
+
+// maxRequestsInFlight returns the cap on concurrent requests for the given number
+// of CPUs, or zero if there is none.
+func (o *CustomMetricsAdapterServerOptions) maxRequestsInFlight(cpus int) int {
+	limit := o.MaxRequestsInFlight
+	if o.MaxRequestsInFlightPerCPU > 0 {
+		if perCPU := o.MaxRequestsInFlightPerCPU * cpus; limit == 0 || perCPU < limit {
+			limit = perCPU
+		}
+	}
+	return limit
+}
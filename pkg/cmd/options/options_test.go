@@ -17,13 +17,34 @@ limitations under the License.
 package options
 
 import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	cryptorand "crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
 	genericapiserver "k8s.io/apiserver/pkg/server"
+	certutil "k8s.io/client-go/util/cert"
+	"k8s.io/klog/v2"
 
 	"sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver"
 )
@@ -54,11 +75,276 @@ func TestValidate(t *testing.T) {
 			args:      []string{"--secure-port=6443", "--requestheader-username-headers=\" \""},
 			shouldErr: true,
 		},
+		{
+			testName:  "requestheader-allowed-names-without-client-ca",
+			args:      []string{"--secure-port=6443", "--requestheader-allowed-names=front-proxy-client"},
+			shouldErr: true,
+		},
+		{
+			testName:  "missing-requestheader-client-ca-file",
+			args:      []string{"--secure-port=6443", "--requestheader-client-ca-file=/nonexistent/front-proxy-ca.crt"},
+			shouldErr: true,
+		},
+		{
+			testName:  "require-requestheader-authentication",
+			args:      []string{"--secure-port=6443", "--require-requestheader-authentication"},
+			shouldErr: false,
+		},
+		{
+			testName:  "require-requestheader-authentication-without-lookup",
+			args:      []string{"--secure-port=6443", "--require-requestheader-authentication", "--authentication-skip-lookup"},
+			shouldErr: true,
+		},
 		{
 			testName:  "invalid-audit-log-format",
 			args:      []string{"--secure-port=6443", "--audit-log-path=file", "--audit-log-format=txt"},
 			shouldErr: true,
 		},
+		{
+			testName:  "metric-rate-limits",
+			args:      []string{"--secure-port=6443", "--metric-rate-limits=foo=10,bar=0.5"},
+			shouldErr: false,
+		},
+		{
+			testName:  "invalid-metric-rate-limits",
+			args:      []string{"--secure-port=6443", "--metric-rate-limits=foo=0"},
+			shouldErr: true,
+		},
+		{
+			testName:  "allowed-namespaces",
+			args:      []string{"--secure-port=6443", "--allowed-namespaces=default,kube-system"},
+			shouldErr: false,
+		},
+		{
+			testName:  "metric-max-ages",
+			args:      []string{"--secure-port=6443", "--metric-max-ages=foo=30s,bar=1m"},
+			shouldErr: false,
+		},
+		{
+			testName:  "invalid-metric-max-ages",
+			args:      []string{"--secure-port=6443", "--metric-max-ages=foo=0s"},
+			shouldErr: true,
+		},
+		{
+			testName:  "default-metric-window",
+			args:      []string{"--secure-port=6443", "--default-metric-window=1m"},
+			shouldErr: false,
+		},
+		{
+			testName:  "negative-default-metric-window",
+			args:      []string{"--secure-port=6443", "--default-metric-window=-1m"},
+			shouldErr: true,
+		},
+		{
+			testName:  "zero-default-metric-window",
+			args:      []string{"--secure-port=6443", "--default-metric-window=0"},
+			shouldErr: false,
+		},
+		{
+			testName:  "sub-second-default-metric-window",
+			args:      []string{"--secure-port=6443", "--default-metric-window=500ms"},
+			shouldErr: true,
+		},
+		{
+			testName:  "fractional-default-metric-window",
+			args:      []string{"--secure-port=6443", "--default-metric-window=1500ms"},
+			shouldErr: true,
+		},
+		{
+			testName:  "coalesce-window",
+			args:      []string{"--secure-port=6443", "--coalesce-window=500ms"},
+			shouldErr: false,
+		},
+		{
+			testName:  "negative-coalesce-window",
+			args:      []string{"--secure-port=6443", "--coalesce-window=-1s"},
+			shouldErr: true,
+		},
+		{
+			testName:  "self-signed-cert",
+			args:      []string{"--secure-port=6443", "--self-signed-cert-organization=example", "--self-signed-cert-validity=720h"},
+			shouldErr: false,
+		},
+		{
+			testName:  "invalid-self-signed-cert-validity",
+			args:      []string{"--secure-port=6443", "--self-signed-cert-validity=0s"},
+			shouldErr: true,
+		},
+		{
+			testName:  "request-size-limits",
+			args:      []string{"--secure-port=6443", "--max-request-bytes=65536", "--max-selector-length=1024"},
+			shouldErr: false,
+		},
+		{
+			testName:  "negative-max-selector-length",
+			args:      []string{"--secure-port=6443", "--max-selector-length=-1"},
+			shouldErr: true,
+		},
+		{
+			testName:  "max-connections-per-ip",
+			args:      []string{"--secure-port=6443", "--max-connections-per-ip=20"},
+			shouldErr: false,
+		},
+		{
+			testName:  "negative-max-connections-per-ip",
+			args:      []string{"--secure-port=6443", "--max-connections-per-ip=-1"},
+			shouldErr: true,
+		},
+		{
+			testName:  "panic-response-detail",
+			args:      []string{"--secure-port=6443", "--panic-response-detail=reference"},
+			shouldErr: false,
+		},
+		{
+			testName:  "invalid-panic-response-detail",
+			args:      []string{"--secure-port=6443", "--panic-response-detail=stack"},
+			shouldErr: true,
+		},
+		{
+			testName:  "loopback-client-rate",
+			args:      []string{"--secure-port=6443", "--loopback-client-qps=200", "--loopback-client-burst=400"},
+			shouldErr: false,
+		},
+		{
+			testName:  "negative-loopback-client-qps",
+			args:      []string{"--secure-port=6443", "--loopback-client-qps=-1"},
+			shouldErr: true,
+		},
+		{
+			testName:  "negative-loopback-client-burst",
+			args:      []string{"--secure-port=6443", "--loopback-client-burst=-1"},
+			shouldErr: true,
+		},
+		{
+			testName:  "loopback-client-burst-without-qps",
+			args:      []string{"--secure-port=6443", "--loopback-client-burst=400"},
+			shouldErr: true,
+		},
+		{
+			testName:  "audit-log-batch",
+			args:      []string{"--secure-port=6443", "--audit-log-path=-", "--audit-log-mode=batch", "--audit-log-batch-buffer-size=20000", "--audit-log-batch-max-size=500"},
+			shouldErr: false,
+		},
+		{
+			testName:  "invalid-audit-log-batch-buffer-size",
+			args:      []string{"--secure-port=6443", "--audit-log-path=-", "--audit-log-mode=batch", "--audit-log-batch-buffer-size=0"},
+			shouldErr: true,
+		},
+		{
+			testName:  "invalid-audit-log-batch-throttle",
+			args:      []string{"--secure-port=6443", "--audit-log-path=-", "--audit-log-mode=batch", "--audit-log-batch-throttle-enable", "--audit-log-batch-throttle-qps=0"},
+			shouldErr: true,
+		},
+		{
+			testName:  "default-namespace",
+			args:      []string{"--secure-port=6443", "--default-namespace=legacy"},
+			shouldErr: false,
+		},
+		{
+			testName:  "invalid-default-namespace",
+			args:      []string{"--secure-port=6443", "--default-namespace=Not_A_Namespace"},
+			shouldErr: true,
+		},
+		{
+			testName:  "connection-timeouts",
+			args:      []string{"--secure-port=6443", "--tcp-keep-alive-period=30s", "--connection-idle-timeout=5m"},
+			shouldErr: false,
+		},
+		{
+			testName:  "negative-tcp-keep-alive-period",
+			args:      []string{"--secure-port=6443", "--tcp-keep-alive-period=-1s"},
+			shouldErr: true,
+		},
+		{
+			testName:  "negative-connection-idle-timeout",
+			args:      []string{"--secure-port=6443", "--connection-idle-timeout=-1s"},
+			shouldErr: true,
+		},
+		{
+			testName:  "disable-authorization-on-loopback",
+			args:      []string{"--secure-port=6443", "--disable-authorization", "--bind-address=127.0.0.1"},
+			shouldErr: false,
+		},
+		{
+			testName:  "disable-authorization-on-public-address",
+			args:      []string{"--secure-port=6443", "--disable-authorization"},
+			shouldErr: true,
+		},
+		{
+			testName:  "disable-authorization-on-acknowledged-public-address",
+			args:      []string{"--secure-port=6443", "--disable-authorization", "--allow-unauthorized-public-serving"},
+			shouldErr: false,
+		},
+		{
+			testName:  "metric-value-significant-digits",
+			args:      []string{"--secure-port=6443", "--metric-value-significant-digits=3"},
+			shouldErr: false,
+		},
+		{
+			testName:  "negative-metric-value-significant-digits",
+			args:      []string{"--secure-port=6443", "--metric-value-significant-digits=-1"},
+			shouldErr: true,
+		},
+		{
+			testName:  "negative-max-counted-metric-names",
+			args:      []string{"--secure-port=6443", "--max-counted-metric-names=-1"},
+			shouldErr: true,
+		},
+		{
+			testName:  "request-log-sample-rate",
+			args:      []string{"--secure-port=6443", "--request-log-sample-rate=100"},
+			shouldErr: false,
+		},
+		{
+			testName:  "negative-request-log-sample-rate",
+			args:      []string{"--secure-port=6443", "--request-log-sample-rate=-1"},
+			shouldErr: true,
+		},
+		{
+			testName:  "authorization-webhook-error-policy",
+			args:      []string{"--secure-port=6443", "--authorization-webhook-error-policy=fail-open"},
+			shouldErr: false,
+		},
+		{
+			testName:  "invalid-authorization-webhook-error-policy",
+			args:      []string{"--secure-port=6443", "--authorization-webhook-error-policy=ignore"},
+			shouldErr: true,
+		},
+		{
+			testName:  "base-path",
+			args:      []string{"--secure-port=6443", "--base-path=/metrics-adapter"},
+			shouldErr: false,
+		},
+		{
+			testName:  "relative-base-path",
+			args:      []string{"--secure-port=6443", "--base-path=metrics-adapter"},
+			shouldErr: true,
+		},
+		{
+			testName:  "base-path-with-trailing-slash",
+			args:      []string{"--secure-port=6443", "--base-path=/metrics-adapter/"},
+			shouldErr: true,
+		},
+		{
+			testName:  "negative-startup-retry-timeout",
+			args:      []string{"--secure-port=6443", "--startup-retry-timeout=-1s"},
+			shouldErr: true,
+		},
+		{
+			testName:  "trusted-proxy-cidrs",
+			args:      []string{"--secure-port=6443", "--trusted-proxy-cidrs=10.0.0.0/8,fd00::/8"},
+			shouldErr: false,
+		},
+		{
+			testName:  "invalid-trusted-proxy-cidrs",
+			args:      []string{"--secure-port=6443", "--trusted-proxy-cidrs=10.0.0.1"},
+			shouldErr: true,
+		},
+		{
+			testName:  "negative-max-requests-inflight",
+			args:      []string{"--secure-port=6443", "--max-requests-inflight=-1"},
+			shouldErr: true,
+		},
 	}
 
 	for _, c := range cases {
@@ -105,6 +391,8 @@ func TestApplyTo(t *testing.T) {
 			// Unit tests have no Kubernetes cluster access
 			o.Authentication.RemoteKubeConfigFileOptional = true
 			o.Authorization.RemoteKubeConfigFileOptional = true
+			// the self-signed serving cert is generated outside the source tree
+			o.SecureServing.ServerCert.CertDirectory = t.TempDir()
 
 			flagSet := pflag.NewFlagSet("", pflag.PanicOnError)
 			o.AddFlags(flagSet)
@@ -126,3 +414,352 @@ func TestApplyTo(t *testing.T) {
 		})
 	}
 }
+
+func TestLoopbackClientRate(t *testing.T) {
+	cases := []struct {
+		testName      string
+		args          []string
+		expectedQPS   float32
+		expectedBurst int
+	}{
+		{
+			testName:      "default",
+			expectedQPS:   -1, // not rate limited
+			expectedBurst: 0,
+		},
+		{
+			testName:      "overridden",
+			args:          []string{"--loopback-client-qps=200", "--loopback-client-burst=400"},
+			expectedQPS:   200,
+			expectedBurst: 400,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.testName, func(t *testing.T) {
+			o := NewCustomMetricsAdapterServerOptions()
+			o.Authentication.RemoteKubeConfigFileOptional = true
+			o.Authorization.RemoteKubeConfigFileOptional = true
+			o.SecureServing.ServerCert.CertDirectory = t.TempDir()
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			o.SecureServing.Listener = listener
+			defer listener.Close()
+
+			flagSet := pflag.NewFlagSet("", pflag.PanicOnError)
+			o.AddFlags(flagSet)
+			err = flagSet.Parse(c.args)
+			assert.NoErrorf(t, err, "Error while parsing flags")
+
+			serverConfig := genericapiserver.NewConfig(apiserver.Codecs)
+			err = o.ApplyTo(serverConfig)
+			require.NoErrorf(t, err, "Error while applying options")
+			require.NotNil(t, serverConfig.LoopbackClientConfig, "should have configured the loopback client")
+			assert.Equal(t, c.expectedQPS, serverConfig.LoopbackClientConfig.QPS)
+			assert.Equal(t, c.expectedBurst, serverConfig.LoopbackClientConfig.Burst)
+		})
+	}
+}
+
+func TestConnectionIdleTimeout(t *testing.T) {
+	o := NewCustomMetricsAdapterServerOptions()
+	o.Authentication.RemoteKubeConfigFileOptional = true
+	o.Authorization.RemoteKubeConfigFileOptional = true
+	o.SecureServing.ServerCert.CertDirectory = t.TempDir()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	o.SecureServing.Listener = listener
+	defer listener.Close()
+
+	flagSet := pflag.NewFlagSet("", pflag.PanicOnError)
+	o.AddFlags(flagSet)
+	err = flagSet.Parse([]string{"--connection-idle-timeout=100ms"})
+	require.NoErrorf(t, err, "Error while parsing flags")
+
+	serverConfig := genericapiserver.NewConfig(apiserver.Codecs)
+	require.NoErrorf(t, o.ApplyTo(serverConfig), "Error while applying options")
+	require.NotNil(t, serverConfig.SecureServing, "should have configured secure serving")
+
+	client, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer client.Close()
+	conn, err := serverConfig.SecureServing.Listener.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, client.SetReadDeadline(time.Now().Add(time.Second)))
+	_, err = client.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, io.EOF, "should have closed the idle connection")
+}
+
+func TestDisableAuthorization(t *testing.T) {
+	var logs bytes.Buffer
+	klog.LogToStderr(false)
+	klog.SetOutput(&logs)
+	defer klog.LogToStderr(true)
+
+	o := NewCustomMetricsAdapterServerOptions()
+	o.Authentication.RemoteKubeConfigFileOptional = true
+	o.SecureServing.ServerCert.CertDirectory = t.TempDir()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	o.SecureServing.Listener = listener
+	defer listener.Close()
+
+	flagSet := pflag.NewFlagSet("", pflag.PanicOnError)
+	o.AddFlags(flagSet)
+	err = flagSet.Parse([]string{"--disable-authorization"})
+	require.NoErrorf(t, err, "Error while parsing flags")
+	assert.Empty(t, o.Validate(), "should have allowed disabling authorization on a loopback listener")
+
+	serverConfig := genericapiserver.NewConfig(apiserver.Codecs)
+	require.NoErrorf(t, o.ApplyTo(serverConfig), "Error while applying options, without a cluster to delegate authorization to")
+	require.NotNil(t, serverConfig.Authorization.Authorizer, "should have configured an authorizer")
+
+	decision, _, err := serverConfig.Authorization.Authorizer.Authorize(context.Background(), authorizer.AttributesRecord{
+		User:            &user.DefaultInfo{Name: "system:anonymous"},
+		Verb:            "get",
+		APIGroup:        "custom.metrics.k8s.io",
+		Resource:        "pods",
+		Subresource:     "some-metric",
+		Namespace:       "default",
+		ResourceRequest: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, authorizer.DecisionAllow, decision, "should have allowed the request")
+
+	klog.Flush()
+	assert.Contains(t, logs.String(), "AUTHORIZATION IS DISABLED", "should have warned that authorization is disabled")
+}
+
+func TestFeatureOptionsNeedNoCluster(t *testing.T) {
+	o := NewCustomMetricsAdapterServerOptions()
+	o.Authentication.RemoteKubeConfigFileOptional = true
+	o.SecureServing.ServerCert.CertDirectory = t.TempDir()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	o.SecureServing.Listener = listener
+	defer listener.Close()
+
+	flagSet := pflag.NewFlagSet("", pflag.PanicOnError)
+	o.AddFlags(flagSet)
+	require.NoError(t, flagSet.Parse([]string{"--disable-authorization", "--profiling=false", "--contention-profiling"}))
+
+	serverConfig := genericapiserver.NewConfig(apiserver.Codecs)
+	// the feature options are applied without clients nor informers, so the adapter
+	// needs no permissions to list or watch anything for them
+	require.NoErrorf(t, o.ApplyTo(serverConfig), "Error while applying options, without a cluster")
+	assert.False(t, serverConfig.EnableProfiling, "should have applied the feature options")
+	assert.True(t, serverConfig.EnableContentionProfiling, "should have applied the feature options")
+}
+
+func TestAuditBatchOptions(t *testing.T) {
+	o := NewCustomMetricsAdapterServerOptions()
+	flagSet := pflag.NewFlagSet("", pflag.PanicOnError)
+	o.AddFlags(flagSet)
+	err := flagSet.Parse([]string{
+		"--audit-log-mode=batch",
+		"--audit-log-batch-buffer-size=20000",
+		"--audit-log-batch-max-size=500",
+		"--audit-log-batch-max-wait=5s",
+		"--audit-webhook-mode=batch",
+		"--audit-webhook-batch-buffer-size=30000",
+		"--audit-webhook-batch-throttle-qps=20",
+	})
+	require.NoErrorf(t, err, "Error while parsing flags")
+
+	logBatch := o.Audit.LogOptions.BatchOptions
+	assert.Equal(t, "batch", logBatch.Mode)
+	assert.Equal(t, 20000, logBatch.BatchConfig.BufferSize)
+	assert.Equal(t, 500, logBatch.BatchConfig.MaxBatchSize)
+	assert.Equal(t, 5*time.Second, logBatch.BatchConfig.MaxBatchWait)
+
+	webhookBatch := o.Audit.WebhookOptions.BatchOptions
+	assert.Equal(t, "batch", webhookBatch.Mode)
+	assert.Equal(t, 30000, webhookBatch.BatchConfig.BufferSize)
+	assert.Equal(t, float32(20), webhookBatch.BatchConfig.ThrottleQPS)
+}
+
+func TestMaxRequestsInFlight(t *testing.T) {
+	cases := []struct {
+		testName string
+		args     []string
+		cpus     int
+		expected int
+	}{
+		{
+			testName: "default",
+			cpus:     2,
+			expected: 0,
+		},
+		{
+			testName: "cap",
+			args:     []string{"--max-requests-inflight=50"},
+			cpus:     2,
+			expected: 50,
+		},
+		{
+			testName: "cap-per-cpu",
+			args:     []string{"--max-requests-inflight-per-cpu=20"},
+			cpus:     2,
+			expected: 40,
+		},
+		{
+			testName: "lowest-cap",
+			args:     []string{"--max-requests-inflight=50", "--max-requests-inflight-per-cpu=20"},
+			cpus:     4,
+			expected: 50,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.testName, func(t *testing.T) {
+			o := NewCustomMetricsAdapterServerOptions()
+
+			flagSet := pflag.NewFlagSet("", pflag.PanicOnError)
+			o.AddFlags(flagSet)
+			err := flagSet.Parse(c.args)
+			assert.NoErrorf(t, err, "Error while parsing flags")
+
+			assert.Equal(t, c.expected, o.maxRequestsInFlight(c.cpus))
+		})
+	}
+
+	t.Run("applied", func(t *testing.T) {
+		o := NewCustomMetricsAdapterServerOptions()
+		o.Authentication.RemoteKubeConfigFileOptional = true
+		o.Authorization.RemoteKubeConfigFileOptional = true
+		o.SecureServing.BindPort = 0
+		o.SecureServing.ServerCert.CertDirectory = t.TempDir()
+		o.MaxRequestsInFlight = 50
+
+		serverConfig := genericapiserver.NewConfig(apiserver.Codecs)
+		err := o.ApplyTo(serverConfig)
+		assert.NoErrorf(t, err, "Error while applying options")
+		assert.Equal(t, 50, serverConfig.MaxRequestsInFlight, "should have applied the cap")
+	})
+}
+
+func TestSelfSignedCert(t *testing.T) {
+	o := NewCustomMetricsAdapterServerOptions()
+	certDir := t.TempDir()
+
+	flagSet := pflag.NewFlagSet("", pflag.PanicOnError)
+	o.AddFlags(flagSet)
+	err := flagSet.Parse([]string{"--cert-dir=" + certDir, "--self-signed-cert-organization=example", "--self-signed-cert-validity=720h"})
+	require.NoError(t, err, "Error while parsing flags")
+
+	require.NoError(t, o.maybeDefaultWithSelfSignedCerts("localhost", nil, nil), "Error while generating the self-signed cert")
+
+	certs, err := certutil.CertsFromFile(filepath.Join(certDir, "apiserver.crt"))
+	require.NoError(t, err, "Error while reading the generated cert")
+	require.Len(t, certs, 2, "Expected the serving cert followed by its CA")
+	for _, cert := range certs {
+		assert.Equal(t, []string{"example"}, cert.Subject.Organization)
+		assert.Equal(t, 720*time.Hour, cert.NotAfter.Sub(cert.NotBefore))
+	}
+	assert.Contains(t, certs[0].DNSNames, "localhost")
+	assert.Equal(t, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}, certs[0].ExtKeyUsage)
+	assert.NoError(t, certs[0].CheckSignatureFrom(certs[1]), "Expected the serving cert to be signed by its CA")
+}
+
+// newFrontProxyCerts returns the certificate of a front-proxy CA, and the ones
+// it signs for the client of each of the given names.
+func newFrontProxyCerts(t *testing.T, names ...string) (*x509.Certificate, []*x509.Certificate) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), cryptorand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "front-proxy-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(cryptorand.Reader, caTemplate, caTemplate, caKey.Public(), caKey)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	clients := []*x509.Certificate{}
+	for i, name := range names {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), cryptorand.Reader)
+		require.NoError(t, err)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(int64(i + 2)),
+			Subject:      pkix.Name{CommonName: name},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}
+		der, err := x509.CreateCertificate(cryptorand.Reader, template, ca, key.Public(), caKey)
+		require.NoError(t, err)
+		client, err := x509.ParseCertificate(der)
+		require.NoError(t, err)
+		clients = append(clients, client)
+	}
+	return ca, clients
+}
+
+func TestRequestHeaderAuthentication(t *testing.T) {
+	ca, clients := newFrontProxyCerts(t, "front-proxy-client", "other-client")
+	caFile := filepath.Join(t.TempDir(), "front-proxy-ca.crt")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: certutil.CertificateBlockType, Bytes: ca.Raw}), 0600))
+
+	o := NewCustomMetricsAdapterServerOptions()
+	o.Authentication.RemoteKubeConfigFileOptional = true
+	o.Authorization.RemoteKubeConfigFileOptional = true
+	o.SecureServing.BindPort = 0
+	o.SecureServing.ServerCert.CertDirectory = t.TempDir()
+
+	flagSet := pflag.NewFlagSet("", pflag.PanicOnError)
+	o.AddFlags(flagSet)
+	err := flagSet.Parse([]string{
+		"--requestheader-client-ca-file=" + caFile,
+		"--requestheader-allowed-names=front-proxy-client",
+		"--require-requestheader-authentication",
+	})
+	require.NoError(t, err, "Error while parsing flags")
+	require.Empty(t, o.Validate(), "Error while validating options")
+
+	serverConfig := genericapiserver.NewConfig(apiserver.Codecs)
+	require.NoError(t, o.ApplyTo(serverConfig), "Error while applying options")
+	require.NotNil(t, serverConfig.Authentication.Authenticator, "should have configured an authenticator")
+
+	// a request relayed by the aggregation layer, with the user in front-proxy headers
+	relayed := func(client *x509.Certificate) *http.Request {
+		req, err := http.NewRequest(http.MethodGet, "https://localhost/apis/custom.metrics.k8s.io/v1beta2", nil)
+		require.NoError(t, err)
+		req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{client}}
+		req.Header.Set("X-Remote-User", "jane")
+		req.Header.Set("X-Remote-Group", "autoscalers")
+		return req
+	}
+
+	resp, ok, err := serverConfig.Authentication.Authenticator.AuthenticateRequest(relayed(clients[0]))
+	require.NoError(t, err)
+	require.True(t, ok, "should have authenticated the request of the front proxy")
+	assert.Equal(t, "jane", resp.User.GetName())
+	assert.Contains(t, resp.User.GetGroups(), "autoscalers")
+
+	// the headers of other clients of the CA are not trusted
+	_, ok, err = serverConfig.Authentication.Authenticator.AuthenticateRequest(relayed(clients[1]))
+	assert.ErrorContains(t, err, "not in the allowed list")
+	assert.False(t, ok, "should not have authenticated the request of a client not allowed")
+}
+
+func TestRequireRequestHeaderAuthentication(t *testing.T) {
+	o := NewCustomMetricsAdapterServerOptions()
+	// without cluster, the front-proxy CA cannot be looked up
+	o.Authentication.RemoteKubeConfigFileOptional = true
+	o.Authorization.RemoteKubeConfigFileOptional = true
+	o.SecureServing.BindPort = 0
+	o.SecureServing.ServerCert.CertDirectory = t.TempDir()
+	o.RequireRequestHeaderAuthentication = true
+
+	require.Empty(t, o.Validate(), "Error while validating options")
+	err := o.ApplyTo(genericapiserver.NewConfig(apiserver.Codecs))
+	assert.ErrorContains(t, err, "--requestheader-client-ca-file", "should have failed for lack of front-proxy CA")
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package options
+
+import (
+	"fmt"
+
+	genericapiserver "k8s.io/apiserver/pkg/server"
+	certutil "k8s.io/client-go/util/cert"
+)
+
+// validateRequestHeader validates the options authenticating the requests relayed
+// by the aggregation layer, whose misconfiguration gets them rejected with 401
+// Unauthorized instead of failing at startup.
+func (o *CustomMetricsAdapterServerOptions) validateRequestHeader() []error {
+	requestHeader := o.Authentication.RequestHeader
+	errors := []error{}
+	if requestHeader.ClientCAFile != "" {
+		if _, err := certutil.CertsFromFile(requestHeader.ClientCAFile); err != nil {
+			errors = append(errors, fmt.Errorf("invalid --requestheader-client-ca-file: %v", err))
+		}
+		if len(requestHeader.UsernameHeaders) == 0 {
+			errors = append(errors, fmt.Errorf("--requestheader-client-ca-file requires --requestheader-username-headers, since no user can be authenticated otherwise"))
+		}
+	} else if len(requestHeader.AllowedNames) > 0 {
+		// the allowed names are looked up in the cluster with the CA
+		errors = append(errors, fmt.Errorf("--requestheader-allowed-names requires --requestheader-client-ca-file, since it is ignored otherwise"))
+	}
+	if o.RequireRequestHeaderAuthentication && requestHeader.ClientCAFile == "" && o.Authentication.SkipInClusterLookup {
+		errors = append(errors, fmt.Errorf("--require-requestheader-authentication requires --requestheader-client-ca-file, since --authentication-skip-lookup disables the lookup of the front-proxy CA in the cluster"))
+	}
+	return errors
+}
+
+// checkRequestHeaderConfig fails when the requests relayed by the aggregation layer
+// are required to be authenticated, but no front-proxy CA was configured nor found
+// in the cluster.
+func (o *CustomMetricsAdapterServerOptions) checkRequestHeaderConfig(serverConfig *genericapiserver.Config) error {
+	if o.RequireRequestHeaderAuthentication && serverConfig.Authentication.RequestHeaderConfig == nil {
+		return fmt.Errorf("the requests relayed by the aggregation layer cannot be authenticated: set --requestheader-client-ca-file, " +
+			"or --authentication-kubeconfig to look up the front-proxy CA in the extension-apiserver-authentication configmap of kube-system")
+	}
+	return nil
+}
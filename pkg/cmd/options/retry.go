@@ -0,0 +1,71 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package options
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+// defaultStartupRetryTimeout bounds the time spent retrying the startup steps
+// which depend on the cluster.
+const defaultStartupRetryTimeout = 30 * time.Second
+
+// startupBackoff is the backoff between retries of startup steps.
+var startupBackoff = wait.Backoff{
+	Duration: 500 * time.Millisecond,
+	Factor:   2,
+	Jitter:   0.2,
+	Cap:      5 * time.Second,
+}
+
+// RetryStartupStep calls fn until it succeeds, with backoff, for up to
+// StartupRetryTimeout, for startup steps of the adapter which depend on the
+// cluster, such as building the initial REST mappings from discovery.  The step is
+// described by what in the logs of the failed attempts.
+func (o *CustomMetricsAdapterServerOptions) RetryStartupStep(what string, fn func() error) error {
+	return retryWithBackoff(o.StartupRetryTimeout, what, fn)
+}
+
+// retryWithBackoff calls fn until it succeeds, with a jittered exponential backoff,
+// for up to the given timeout, so that a briefly unavailable API server, e.g. during
+// a control plane upgrade, does not make the adapter exit.  It returns the last
+// error if fn never succeeds.  With a zero timeout, fn is called only once.
+func retryWithBackoff(timeout time.Duration, what string, fn func() error) error {
+	err := fn()
+	if err == nil || timeout <= 0 {
+		return err
+	}
+
+	deadline := time.Now().Add(timeout)
+	backoff := startupBackoff
+	backoff.Steps = int(^uint(0) >> 1)
+	for {
+		delay := backoff.Step()
+		if time.Now().Add(delay).After(deadline) {
+			return err
+		}
+		klog.Warningf("Unable to %s, retrying in %v: %v", what, delay.Round(time.Millisecond), err)
+		time.Sleep(delay)
+
+		if err = fn(); err == nil {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package options
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func withFastBackoff(t *testing.T) {
+	saved := startupBackoff
+	startupBackoff = wait.Backoff{Duration: time.Millisecond, Factor: 2, Jitter: 0.2, Cap: 10 * time.Millisecond}
+	t.Cleanup(func() { startupBackoff = saved })
+}
+
+func TestRetryWithBackoff(t *testing.T) {
+	withFastBackoff(t)
+	errUnavailable := errors.New("the server is currently unable to handle the request")
+
+	t.Run("transient failures", func(t *testing.T) {
+		calls := 0
+		err := retryWithBackoff(time.Second, "test", func() error {
+			calls++
+			if calls < 3 {
+				return errUnavailable
+			}
+			return nil
+		})
+		assert.NoError(t, err, "should have succeeded once the failures stopped")
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("persistent failures", func(t *testing.T) {
+		calls := 0
+		start := time.Now()
+		err := retryWithBackoff(50*time.Millisecond, "test", func() error {
+			calls++
+			return errUnavailable
+		})
+		assert.ErrorIs(t, err, errUnavailable, "should have returned the last error")
+		assert.Greater(t, calls, 1, "should have retried")
+		assert.Less(t, time.Since(start), time.Second, "should have given up after the timeout")
+	})
+
+	t.Run("no retries", func(t *testing.T) {
+		calls := 0
+		err := retryWithBackoff(0, "test", func() error {
+			calls++
+			return errUnavailable
+		})
+		assert.ErrorIs(t, err, errUnavailable)
+		assert.Equal(t, 1, calls, "should not have retried without timeout")
+	})
+}
@@ -0,0 +1,201 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package options
+
+import (
+	"bytes"
+	cryptorand "crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"path"
+	"time"
+
+	"k8s.io/apiserver/pkg/server/dynamiccertificates"
+	certutil "k8s.io/client-go/util/cert"
+	"k8s.io/client-go/util/keyutil"
+	"k8s.io/klog/v2"
+	netutils "k8s.io/utils/net"
+)
+
+// defaultSelfSignedCertValidity is the validity of generated self-signed
+// certificates, matching the one of the generic API server.
+const defaultSelfSignedCertValidity = 365 * 24 * time.Hour
+
+// maybeDefaultWithSelfSignedCerts generates a self-signed serving certificate if none
+// is provided, like SecureServingOptions.MaybeDefaultWithSelfSignedCerts, except that
+// the organization and validity of the certificate can be customized.
+func (o *CustomMetricsAdapterServerOptions) maybeDefaultWithSelfSignedCerts(publicAddress string, alternateDNS []string, alternateIPs []net.IP) error {
+	if o.SelfSignedCertOrganization == "" && o.SelfSignedCertValidity == defaultSelfSignedCertValidity {
+		return o.SecureServing.MaybeDefaultWithSelfSignedCerts(publicAddress, alternateDNS, alternateIPs)
+	}
+
+	s := o.SecureServing
+	if s == nil || (s.BindPort == 0 && s.Listener == nil) {
+		return nil
+	}
+	keyCert := &s.ServerCert.CertKey
+	if len(keyCert.CertFile) != 0 || len(keyCert.KeyFile) != 0 {
+		return nil
+	}
+
+	if len(s.ServerCert.CertDirectory) > 0 {
+		if len(s.ServerCert.PairName) == 0 {
+			return fmt.Errorf("PairName is required if CertDirectory is set")
+		}
+		keyCert.CertFile = path.Join(s.ServerCert.CertDirectory, s.ServerCert.PairName+".crt")
+		keyCert.KeyFile = path.Join(s.ServerCert.CertDirectory, s.ServerCert.PairName+".key")
+		canRead, err := certutil.CanReadCertAndKey(keyCert.CertFile, keyCert.KeyFile)
+		if err != nil {
+			return err
+		}
+		if canRead {
+			return nil
+		}
+	}
+
+	// add either the bind address or localhost to the valid alternates
+	if s.BindAddress.IsUnspecified() {
+		alternateDNS = append(alternateDNS, "localhost")
+	} else {
+		alternateIPs = append(alternateIPs, s.BindAddress)
+	}
+
+	cert, key, err := generateSelfSignedCertKey(publicAddress, alternateIPs, alternateDNS, o.SelfSignedCertOrganization, o.SelfSignedCertValidity)
+	if err != nil {
+		return fmt.Errorf("unable to generate self signed cert: %v", err)
+	}
+	if len(keyCert.CertFile) > 0 && len(keyCert.KeyFile) > 0 {
+		if err := certutil.WriteCert(keyCert.CertFile, cert); err != nil {
+			return err
+		}
+		if err := keyutil.WriteKey(keyCert.KeyFile, key); err != nil {
+			return err
+		}
+		klog.Infof("Generated self-signed cert (%s, %s)", keyCert.CertFile, keyCert.KeyFile)
+		return nil
+	}
+
+	s.ServerCert.GeneratedCert, err = dynamiccertificates.NewStaticCertKeyContent("Generated self signed cert", cert, key)
+	if err != nil {
+		return err
+	}
+	klog.Infof("Generated self-signed cert in-memory")
+	return nil
+}
+
+// generateSelfSignedCertKey generates a serving certificate for the given host and
+// alternate names, signed by a new CA, both with the given organization and validity.
+// It returns the PEM-encoded certificate followed by the CA, and the PEM-encoded key.
+func generateSelfSignedCertKey(host string, alternateIPs []net.IP, alternateDNS []string, organization string, validity time.Duration) ([]byte, []byte, error) {
+	validFrom := time.Now().Add(-time.Hour) // valid an hour earlier to avoid flakes due to clock skew
+	var organizations []string
+	if organization != "" {
+		organizations = []string{organization}
+	}
+
+	caKey, err := rsa.GenerateKey(cryptorand.Reader, 2048)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, nil, err
+	}
+	caTemplate := x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName:   fmt.Sprintf("%s-ca@%d", host, time.Now().Unix()),
+			Organization: organizations,
+		},
+		NotBefore: validFrom,
+		NotAfter:  validFrom.Add(validity),
+
+		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDERBytes, err := x509.CreateCertificate(cryptorand.Reader, &caTemplate, &caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	caCertificate, err := x509.ParseCertificate(caDERBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	priv, err := rsa.GenerateKey(cryptorand.Reader, 2048)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err = randomSerial()
+	if err != nil {
+		return nil, nil, err
+	}
+	template := x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName:   fmt.Sprintf("%s@%d", host, time.Now().Unix()),
+			Organization: organizations,
+		},
+		NotBefore: validFrom,
+		NotAfter:  validFrom.Add(validity),
+
+		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	if ip := netutils.ParseIPSloppy(host); ip != nil {
+		template.IPAddresses = append(template.IPAddresses, ip)
+	} else {
+		template.DNSNames = append(template.DNSNames, host)
+	}
+	template.IPAddresses = append(template.IPAddresses, alternateIPs...)
+	template.DNSNames = append(template.DNSNames, alternateDNS...)
+
+	derBytes, err := x509.CreateCertificate(cryptorand.Reader, &template, caCertificate, &priv.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certBuffer := bytes.Buffer{}
+	if err := pem.Encode(&certBuffer, &pem.Block{Type: certutil.CertificateBlockType, Bytes: derBytes}); err != nil {
+		return nil, nil, err
+	}
+	if err := pem.Encode(&certBuffer, &pem.Block{Type: certutil.CertificateBlockType, Bytes: caDERBytes}); err != nil {
+		return nil, nil, err
+	}
+	keyBuffer := bytes.Buffer{}
+	if err := pem.Encode(&keyBuffer, &pem.Block{Type: keyutil.RSAPrivateKeyBlockType, Bytes: x509.MarshalPKCS1PrivateKey(priv)}); err != nil {
+		return nil, nil, err
+	}
+	return certBuffer.Bytes(), keyBuffer.Bytes(), nil
+}
+
+// randomSerial returns a uniform random serial number in [1, MaxInt64).
+func randomSerial() (*big.Int, error) {
+	serial, err := cryptorand.Int(cryptorand.Reader, new(big.Int).SetInt64(math.MaxInt64-1))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).Add(serial, big.NewInt(1)), nil
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	authorizationclient "k8s.io/client-go/kubernetes/typed/authorization/v1"
+	"k8s.io/klog/v2"
+)
+
+// rbacCheckTimeout bounds the time spent checking the permissions of the adapter.
+const rbacCheckTimeout = 30 * time.Second
+
+// permission is a permission required by the adapter, along with what it is for.
+type permission struct {
+	resource    *authorizationv1.ResourceAttributes
+	nonResource *authorizationv1.NonResourceAttributes
+	reason      string
+}
+
+func (p permission) String() string {
+	if p.nonResource != nil {
+		return fmt.Sprintf("%s %s (%s)", p.nonResource.Verb, p.nonResource.Path, p.reason)
+	}
+	res := schema.GroupResource{Group: p.resource.Group, Resource: p.resource.Resource}.String()
+	if p.resource.Name != "" {
+		res += " " + p.resource.Name
+	}
+	if p.resource.Namespace != "" {
+		res += " in namespace " + p.resource.Namespace
+	}
+	return fmt.Sprintf("%s %s (%s)", p.resource.Verb, res, p.reason)
+}
+
+// requiredPermissions returns the permissions the adapter needs to serve requests.
+// The permissions needed by the providers to compute metrics are not included.
+func (b *AdapterBase) requiredPermissions() []permission {
+	permissions := []permission{
+		{
+			resource: &authorizationv1.ResourceAttributes{Verb: "create", Group: "authentication.k8s.io", Resource: "tokenreviews"},
+			reason:   "to authenticate requests, granted by the system:auth-delegator cluster role",
+		},
+		{
+			resource: &authorizationv1.ResourceAttributes{Verb: "create", Group: "authorization.k8s.io", Resource: "subjectaccessreviews"},
+			reason:   "to authorize requests, granted by the system:auth-delegator cluster role",
+		},
+		{
+			resource: &authorizationv1.ResourceAttributes{Verb: "get", Resource: "configmaps", Namespace: metav1.NamespaceSystem, Name: "extension-apiserver-authentication"},
+			reason:   "to look up the authentication configuration, granted by the extension-apiserver-authentication-reader role",
+		},
+	}
+	if b.RESTMapperMode != RESTMapperModeStatic {
+		permissions = append(permissions, permission{
+			nonResource: &authorizationv1.NonResourceAttributes{Verb: "get", Path: "/apis"},
+			reason:      "to discover the resources described by metrics, unless --rest-mapper-mode=static",
+		})
+	}
+	if b.LeaderElection.LeaderElect {
+		namespace, name := b.LeaderElection.ResourceNamespace, b.LeaderElection.ResourceName
+		permissions = append(permissions,
+			permission{
+				resource: &authorizationv1.ResourceAttributes{Verb: "create", Group: "coordination.k8s.io", Resource: "leases", Namespace: namespace},
+				reason:   "to create the leader election lease, with --leader-elect",
+			},
+			permission{
+				resource: &authorizationv1.ResourceAttributes{Verb: "get", Group: "coordination.k8s.io", Resource: "leases", Namespace: namespace, Name: name},
+				reason:   "to look up the leader, with --leader-elect",
+			},
+			permission{
+				resource: &authorizationv1.ResourceAttributes{Verb: "update", Group: "coordination.k8s.io", Resource: "leases", Namespace: namespace, Name: name},
+				reason:   "to acquire and renew the leadership, with --leader-elect",
+			},
+		)
+	}
+	return permissions
+}
+
+// checkRBAC checks that the adapter has the permissions it needs, so that missing
+// ones are reported at startup rather than as failing requests.
+func (b *AdapterBase) checkRBAC() error {
+	clientConfig, err := b.ClientConfig()
+	if err != nil {
+		return err
+	}
+	client, err := kubernetes.NewForConfig(clientConfig)
+	if err != nil {
+		return fmt.Errorf("unable to construct client to check permissions: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rbacCheckTimeout)
+	defer cancel()
+	return checkPermissions(ctx, client.AuthorizationV1().SelfSubjectAccessReviews(), b.requiredPermissions())
+}
+
+// checkPermissions reviews the given permissions, returning an error listing the
+// missing ones.
+func checkPermissions(ctx context.Context, client authorizationclient.SelfSubjectAccessReviewInterface, permissions []permission) error {
+	var missing []string
+	for _, p := range permissions {
+		review := &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes:    p.resource,
+				NonResourceAttributes: p.nonResource,
+			},
+		}
+		review, err := client.Create(ctx, review, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("unable to check permission to %s: %v", p, err)
+		}
+		if !review.Status.Allowed {
+			missing = append(missing, p.String())
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("the adapter is missing permissions, grant them or run without --check-rbac: %s", strings.Join(missing, "; "))
+	}
+	klog.V(2).Info("The adapter has the permissions it needs")
+	return nil
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+// fakeReviewClient returns a client allowing the reviewed resources for which
+// allowed returns true.
+func fakeReviewClient(allowed func(review *authorizationv1.SelfSubjectAccessReview) bool) *fake.Clientset {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "selfsubjectaccessreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		review := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview).DeepCopy()
+		review.Status.Allowed = allowed(review)
+		return true, review, nil
+	})
+	return client
+}
+
+func TestCheckPermissions(t *testing.T) {
+	adapter := &AdapterBase{}
+
+	t.Run("allowed", func(t *testing.T) {
+		client := fakeReviewClient(func(*authorizationv1.SelfSubjectAccessReview) bool { return true })
+		assert.NoError(t, checkPermissions(context.Background(), client.AuthorizationV1().SelfSubjectAccessReviews(), adapter.requiredPermissions()))
+	})
+
+	t.Run("denied", func(t *testing.T) {
+		client := fakeReviewClient(func(review *authorizationv1.SelfSubjectAccessReview) bool {
+			attributes := review.Spec.ResourceAttributes
+			return attributes == nil || (attributes.Resource != "subjectaccessreviews" && attributes.Resource != "configmaps")
+		})
+		err := checkPermissions(context.Background(), client.AuthorizationV1().SelfSubjectAccessReviews(), adapter.requiredPermissions())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "create subjectaccessreviews.authorization.k8s.io", "should have listed the missing permissions")
+		assert.Contains(t, err.Error(), "get configmaps extension-apiserver-authentication in namespace kube-system", "should have listed the missing permissions")
+		assert.NotContains(t, err.Error(), "tokenreviews", "should not have listed the granted permissions")
+	})
+
+	t.Run("review failure", func(t *testing.T) {
+		client := fake.NewSimpleClientset()
+		client.PrependReactor("create", "selfsubjectaccessreviews", func(clienttesting.Action) (bool, runtime.Object, error) {
+			return true, nil, errors.New("connection refused")
+		})
+		assert.ErrorContains(t, checkPermissions(context.Background(), client.AuthorizationV1().SelfSubjectAccessReviews(), adapter.requiredPermissions()), "connection refused")
+	})
+}
+
+func TestRequiredPermissionsWithStaticRESTMapper(t *testing.T) {
+	for _, p := range (&AdapterBase{RESTMapperMode: RESTMapperModeStatic}).requiredPermissions() {
+		assert.Nil(t, p.nonResource, "should not have required discovery permissions with a static RESTMapper")
+	}
+}
+
+func TestRequiredPermissionsWithLeaderElection(t *testing.T) {
+	adapter := &AdapterBase{LeaderElection: defaultLeaderElection("my-adapter")}
+	adapter.LeaderElection.LeaderElect = true
+	var leases []string
+	for _, p := range adapter.requiredPermissions() {
+		if p.resource != nil && p.resource.Resource == "leases" {
+			leases = append(leases, p.String())
+		}
+	}
+	assert.Len(t, leases, 3, "should have required the permissions to manage the lease")
+	assert.Contains(t, leases[2], "update leases.coordination.k8s.io my-adapter in namespace kube-system")
+}
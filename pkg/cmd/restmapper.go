@@ -0,0 +1,115 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// RESTMapperModeDynamic makes RESTMapper return a mapper populated with discovery
+	// information, which requires the adapter to be allowed to use discovery.
+	RESTMapperModeDynamic = "dynamic"
+	// RESTMapperModeStatic makes RESTMapper return a mapper loaded from RESTMapperFile.
+	RESTMapperModeStatic = "static"
+)
+
+// staticMapping is an entry of the file of the static RESTMapper.
+type staticMapping struct {
+	Group   string `json:"group"`
+	Version string `json:"version"`
+	Kind    string `json:"kind"`
+	// Resource is the plural name of the resource.  It defaults to the
+	// lowercase, pluralized kind.
+	Resource string `json:"resource"`
+	// Singular is the singular name of the resource.  It defaults to the
+	// lowercase kind.
+	Singular   string `json:"singular"`
+	Namespaced bool   `json:"namespaced"`
+}
+
+// loadStaticRESTMapper creates a RESTMapper from a YAML or JSON file listing the
+// kinds to map, e.g.:
+//
+//	# static-rest-mapper.yaml
+//	- group: apps
+//	  version: v1
+//	  kind: Deployment
+//	  namespaced: true
+func loadStaticRESTMapper(path string) (apimeta.RESTMapper, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var mappings []staticMapping
+	if err := yaml.UnmarshalStrict(data, &mappings); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %v", path, err)
+	}
+
+	// the group versions are preferred in the order of the file
+	var groupVersions []schema.GroupVersion
+	seen := map[schema.GroupVersion]bool{}
+	for i, mapping := range mappings {
+		if mapping.Version == "" || mapping.Kind == "" {
+			return nil, fmt.Errorf("invalid mapping %d in %s: version and kind are required", i, path)
+		}
+		if gv := (schema.GroupVersion{Group: mapping.Group, Version: mapping.Version}); !seen[gv] {
+			seen[gv] = true
+			groupVersions = append(groupVersions, gv)
+		}
+	}
+
+	mapper := apimeta.NewDefaultRESTMapper(groupVersions)
+	for _, mapping := range mappings {
+		gvk := schema.GroupVersionKind{Group: mapping.Group, Version: mapping.Version, Kind: mapping.Kind}
+		plural, singular := apimeta.UnsafeGuessKindToResource(gvk)
+		if mapping.Resource != "" {
+			plural = gvk.GroupVersion().WithResource(mapping.Resource)
+		}
+		if mapping.Singular != "" {
+			singular = gvk.GroupVersion().WithResource(mapping.Singular)
+		}
+		scope := apimeta.RESTScopeRoot
+		if mapping.Namespaced {
+			scope = apimeta.RESTScopeNamespace
+		}
+		mapper.AddSpecific(gvk, plural, singular, scope)
+	}
+	return mapper, nil
+}
+
+// validateRESTMapper validates the RESTMapper mode, and the file of the static one.
+func (b *AdapterBase) validateRESTMapper() []error {
+	switch b.RESTMapperMode {
+	case "", RESTMapperModeDynamic:
+	case RESTMapperModeStatic:
+		if b.RESTMapperFile == "" {
+			return []error{fmt.Errorf("--rest-mapper-file is required with --rest-mapper-mode=%s", RESTMapperModeStatic)}
+		}
+		if _, err := loadStaticRESTMapper(b.RESTMapperFile); err != nil {
+			return []error{fmt.Errorf("invalid --rest-mapper-file: %v", err)}
+		}
+	default:
+		return []error{fmt.Errorf("--rest-mapper-mode must be %s or %s", RESTMapperModeDynamic, RESTMapperModeStatic)}
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/cmd/options"
+)
+
+const staticMappings = `
+- version: v1
+  kind: Pod
+  namespaced: true
+- version: v1
+  kind: Node
+- group: apps
+  version: v1
+  kind: Deployment
+  resource: deployments
+  singular: deployment
+  namespaced: true
+`
+
+func writeFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "mappings.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestStaticRESTMapper(t *testing.T) {
+	adapter := &AdapterBase{FlagSet: pflag.NewFlagSet("", pflag.ContinueOnError)}
+	require.NoError(t, adapter.Flags().Parse([]string{"--rest-mapper-mode=static", "--rest-mapper-file=" + writeFile(t, staticMappings)}))
+	assert.Empty(t, adapter.Validate())
+
+	mapper, err := adapter.RESTMapper()
+	require.NoError(t, err, "should have loaded the static mapper")
+
+	gvr, err := mapper.ResourceFor(schema.GroupVersionResource{Resource: "pods"})
+	require.NoError(t, err, "should have resolved pods")
+	assert.Equal(t, schema.GroupVersionResource{Version: "v1", Resource: "pods"}, gvr)
+
+	mapping, err := mapper.RESTMapping(schema.GroupKind{Group: "apps", Kind: "Deployment"})
+	require.NoError(t, err, "should have mapped deployments")
+	assert.Equal(t, schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}, mapping.Resource)
+	assert.Equal(t, apimeta.RESTScopeNameNamespace, mapping.Scope.Name())
+
+	mapping, err = mapper.RESTMapping(schema.GroupKind{Kind: "Node"})
+	require.NoError(t, err, "should have mapped nodes")
+	assert.Equal(t, apimeta.RESTScopeNameRoot, mapping.Scope.Name())
+
+	singular, err := mapper.ResourceSingularizer("deployments")
+	require.NoError(t, err)
+	assert.Equal(t, "deployment", singular)
+}
+
+func TestValidateRESTMapper(t *testing.T) {
+	cases := []struct {
+		testName  string
+		args      []string
+		shouldErr bool
+	}{
+		{
+			testName: "dynamic",
+			args:     []string{"--rest-mapper-mode=dynamic"},
+		},
+		{
+			testName:  "unknown-mode",
+			args:      []string{"--rest-mapper-mode=discovery"},
+			shouldErr: true,
+		},
+		{
+			testName:  "static-without-file",
+			args:      []string{"--rest-mapper-mode=static"},
+			shouldErr: true,
+		},
+		{
+			testName:  "static-missing-file",
+			args:      []string{"--rest-mapper-mode=static", "--rest-mapper-file=" + filepath.Join(t.TempDir(), "missing.yaml")},
+			shouldErr: true,
+		},
+		{
+			testName:  "static-invalid-file",
+			args:      []string{"--rest-mapper-mode=static", "--rest-mapper-file=" + writeFile(t, "- version: v1\n  knd: Pod\n")},
+			shouldErr: true,
+		},
+		{
+			testName:  "static-mapping-without-kind",
+			args:      []string{"--rest-mapper-mode=static", "--rest-mapper-file=" + writeFile(t, "- version: v1\n")},
+			shouldErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.testName, func(t *testing.T) {
+			adapter := &AdapterBase{FlagSet: pflag.NewFlagSet("", pflag.ContinueOnError)}
+			require.NoError(t, adapter.Flags().Parse(append([]string{"--secure-port=6443"}, c.args...)))
+
+			errs := adapter.Validate()
+			if c.shouldErr {
+				assert.NotEmpty(t, errs)
+			} else {
+				assert.Empty(t, errs)
+			}
+		})
+	}
+}
+
+// failingDiscovery is fake discovery, listing pods, which fails a number of times
+// first, and counts the attempts.
+type failingDiscovery struct {
+	*fakediscovery.FakeDiscovery
+	failures int
+	attempts int
+}
+
+func newFailingDiscovery(failures int) *failingDiscovery {
+	discovery := &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{}}
+	discovery.Resources = []*metav1.APIResourceList{
+		{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "pods", Namespaced: true, Kind: "Pod"}}},
+	}
+	return &failingDiscovery{FakeDiscovery: discovery, failures: failures}
+}
+
+func (d *failingDiscovery) ServerGroupsAndResources() ([]*metav1.APIGroup, []*metav1.APIResourceList, error) {
+	d.attempts++
+	if d.attempts <= d.failures {
+		return nil, nil, fmt.Errorf("connection refused")
+	}
+	return d.FakeDiscovery.ServerGroupsAndResources()
+}
+
+func TestDynamicRESTMapperRetry(t *testing.T) {
+	discovery := newFailingDiscovery(2)
+	serverOptions := options.NewCustomMetricsAdapterServerOptions()
+	serverOptions.StartupRetryTimeout = 30 * time.Second
+	adapter := &AdapterBase{
+		CustomMetricsAdapterServerOptions: serverOptions,
+		FlagSet:                           pflag.NewFlagSet("", pflag.ContinueOnError),
+		discoveryClient:                   discovery,
+	}
+
+	mapper, err := adapter.RESTMapper()
+	require.NoError(t, err, "should have retried the initial discovery")
+	assert.Equal(t, 3, discovery.attempts, "should have retried until discovery succeeded")
+	gvk, err := mapper.KindFor(schema.GroupVersionResource{Resource: "pods"})
+	require.NoError(t, err)
+	assert.Equal(t, "Pod", gvk.Kind, "should have built the mappings of the successful discovery")
+}
+
+func TestDynamicRESTMapperRetryExhausted(t *testing.T) {
+	discovery := newFailingDiscovery(1)
+	serverOptions := options.NewCustomMetricsAdapterServerOptions()
+	serverOptions.StartupRetryTimeout = 0
+	adapter := &AdapterBase{
+		CustomMetricsAdapterServerOptions: serverOptions,
+		FlagSet:                           pflag.NewFlagSet("", pflag.ContinueOnError),
+		discoveryClient:                   discovery,
+	}
+
+	_, err := adapter.RESTMapper()
+	assert.Error(t, err, "should have failed once the retries are exhausted")
+	assert.Equal(t, 1, discovery.attempts, "should not have retried without retry timeout")
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import "runtime/debug"
+
+// gitVersion and gitCommit are the defaults of the GitVersion and GitCommit of
+// adapters, which can be set when building them, with
+//
+//	-ldflags "-X sigs.k8s.io/custom-metrics-apiserver/pkg/cmd.gitVersion=v1.2.3 -X sigs.k8s.io/custom-metrics-apiserver/pkg/cmd.gitCommit=abcdef"
+var (
+	gitVersion = "v0.0.0-unknown"
+	gitCommit  = ""
+)
+
+// version returns the version and commit of the adapter reported by /version:
+// the ones set on the AdapterBase, or else at build time.  Without either, the
+// commit comes from the VCS information recorded by the Go toolchain.
+func (b *AdapterBase) version() (string, string) {
+	version, commit := b.GitVersion, b.GitCommit
+	if version == "" {
+		version = gitVersion
+	}
+	if commit == "" {
+		commit = gitCommit
+	}
+	if commit == "" {
+		if info, ok := debug.ReadBuildInfo(); ok {
+			for _, setting := range info.Settings {
+				if setting.Key == "vcs.revision" {
+					commit = setting.Value
+				}
+			}
+		}
+	}
+	return version, commit
+}
@@ -27,6 +27,14 @@ import (
 	"k8s.io/klog/v2"
 )
 
+// loggerName is the name of the logger used by the mapper.
+// Its verbosity can be raised on its own with --vmodule=mapper=4.
+const loggerName = "mapper"
+
+func logger() klog.Logger {
+	return klog.Background().WithName(loggerName)
+}
+
 // RengeneratingDiscoveryRESTMapper is a RESTMapper which Regenerates its cache of mappings periodically.
 // It functions by recreating a normal discovery RESTMapper at the specified interval.
 // We don't refresh automatically on cache misses, since we get called on every label, plenty of which will
@@ -57,7 +65,7 @@ func NewRESTMapper(discoveryClient discovery.DiscoveryInterface, refreshInterval
 func (m *RegeneratingDiscoveryRESTMapper) RunUntil(stop <-chan struct{}) {
 	go wait.Until(func() {
 		if err := m.RegenerateMappings(); err != nil {
-			klog.Errorf("error regenerating REST mappings from discovery: %v", err)
+			logger().Error(err, "error regenerating REST mappings from discovery")
 		}
 	}, m.refreshInterval, stop)
 }
@@ -68,6 +76,7 @@ func (m *RegeneratingDiscoveryRESTMapper) RegenerateMappings() error {
 		return err
 	}
 	newDelegate := restmapper.NewDiscoveryRESTMapper(resources)
+	logger().V(4).Info("regenerated REST mappings from discovery", "groups", len(resources))
 
 	// don't lock until we're ready to replace
 	m.mu.Lock()
@@ -20,6 +20,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/go-logr/logr/funcr"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -27,6 +28,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/discovery/fake"
 	core "k8s.io/client-go/testing"
+	"k8s.io/klog/v2"
 )
 
 const testingMapperRefreshInterval = 1 * time.Second
@@ -92,3 +94,19 @@ func TestRegeneratingUpdatesMapper(t *testing.T) {
 		assert.Equal(t, schema.GroupVersionKind{Version: "v1alpha1", Kind: "Flunder", Group: "wardle"}, flundersGVK, "should have correctly fetched the kind for 'flunders.wardle' the second time")
 	}
 }
+
+func TestRegenerateMappingsLogsWithMapperName(t *testing.T) {
+	var names []string
+	klog.SetLoggerWithOptions(funcr.New(func(prefix, _ string) {
+		names = append(names, prefix)
+	}, funcr.Options{Verbosity: 4}), klog.ContextualLogger(true))
+	defer klog.ClearLogger()
+
+	mapper, _ := setupMapper(t, nil)
+	require.NoError(t, mapper.RegenerateMappings(), "regenerating the mappings should not have yielded an error")
+
+	require.NotEmpty(t, names, "regenerating the mappings should have been logged")
+	for _, name := range names {
+		assert.Equal(t, loggerName, name, "the mapper should only log with its own logger")
+	}
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/metrics/pkg/apis/external_metrics"
+)
+
+// AggregationOp is an operation combining several external metric values into one.
+type AggregationOp string
+
+const (
+	AggregationSum AggregationOp = "sum"
+	AggregationAvg AggregationOp = "avg"
+	AggregationMax AggregationOp = "max"
+	AggregationMin AggregationOp = "min"
+)
+
+// AggregateExternalValues combines the given values of an external metric, for instance
+// several backend series matching a selector, into a single value.
+//
+// The aggregated value keeps the labels shared by all the values, and the timestamp of
+// the oldest one, so that it is never reported as fresher than its inputs.  Its window
+// is only kept when all the values have the same.
+//
+// Providers are not required to aggregate values: the external metrics API serves
+// lists, and the HPA sums the values it receives for AverageValue and Value targets.
+// The API server returns the values as the provider returns them.
+func AggregateExternalValues(values []external_metrics.ExternalMetricValue, op AggregationOp) (external_metrics.ExternalMetricValue, error) {
+	switch op {
+	case AggregationSum, AggregationAvg, AggregationMax, AggregationMin:
+	default:
+		return external_metrics.ExternalMetricValue{}, fmt.Errorf("unknown aggregation operation %q", op)
+	}
+	if len(values) == 0 {
+		return external_metrics.ExternalMetricValue{}, fmt.Errorf("unable to aggregate empty list of values")
+	}
+
+	res := *values[0].DeepCopy()
+	for i := 1; i < len(values); i++ {
+		value := &values[i]
+		switch op {
+		case AggregationSum, AggregationAvg:
+			res.Value.Add(value.Value)
+		case AggregationMax:
+			if value.Value.Cmp(res.Value) > 0 {
+				res.Value = value.Value.DeepCopy()
+			}
+		case AggregationMin:
+			if value.Value.Cmp(res.Value) < 0 {
+				res.Value = value.Value.DeepCopy()
+			}
+		}
+
+		for name, labelValue := range res.MetricLabels {
+			if value.MetricLabels[name] != labelValue {
+				delete(res.MetricLabels, name)
+			}
+		}
+		if value.Timestamp.Before(&res.Timestamp) {
+			res.Timestamp = value.Timestamp
+		}
+		if res.WindowSeconds != nil && (value.WindowSeconds == nil || *value.WindowSeconds != *res.WindowSeconds) {
+			res.WindowSeconds = nil
+		}
+	}
+
+	if op == AggregationAvg {
+		res.Value = *resource.NewMilliQuantity(res.Value.MilliValue()/int64(len(values)), res.Value.Format)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/metrics/pkg/apis/external_metrics"
+)
+
+func externalValues() []external_metrics.ExternalMetricValue {
+	now := time.Now()
+	window := int64(60)
+	return []external_metrics.ExternalMetricValue{
+		{
+			MetricName:    "queue-length",
+			MetricLabels:  map[string]string{"queue": "a", "region": "eu"},
+			Timestamp:     metav1.NewTime(now),
+			WindowSeconds: &window,
+			Value:         resource.MustParse("4"),
+		},
+		{
+			MetricName:    "queue-length",
+			MetricLabels:  map[string]string{"queue": "b", "region": "eu"},
+			Timestamp:     metav1.NewTime(now.Add(-time.Minute)),
+			WindowSeconds: &window,
+			Value:         resource.MustParse("1"),
+		},
+		{
+			MetricName:    "queue-length",
+			MetricLabels:  map[string]string{"queue": "c", "region": "eu"},
+			Timestamp:     metav1.NewTime(now),
+			WindowSeconds: &window,
+			Value:         resource.MustParse("7"),
+		},
+	}
+}
+
+func TestAggregateExternalValues(t *testing.T) {
+	for op, expected := range map[AggregationOp]string{
+		AggregationSum: "12",
+		AggregationAvg: "4",
+		AggregationMax: "7",
+		AggregationMin: "1",
+	} {
+		t.Run(string(op), func(t *testing.T) {
+			values := externalValues()
+			res, err := AggregateExternalValues(values, op)
+			require.NoError(t, err)
+
+			assert.Equal(t, expected, res.Value.String(), "should have aggregated the values")
+			assert.Equal(t, "queue-length", res.MetricName)
+			assert.Equal(t, map[string]string{"region": "eu"}, res.MetricLabels, "should have only kept the shared labels")
+			assert.Equal(t, values[1].Timestamp, res.Timestamp, "should have kept the oldest timestamp")
+			if assert.NotNil(t, res.WindowSeconds, "should have kept the shared window") {
+				assert.Equal(t, int64(60), *res.WindowSeconds)
+			}
+			assert.Equal(t, "4", values[0].Value.String(), "should not have modified the values")
+			assert.Len(t, values[0].MetricLabels, 2, "should not have modified the labels of the values")
+		})
+	}
+}
+
+func TestAggregateExternalValuesAvgFraction(t *testing.T) {
+	values := externalValues()[:2]
+	res, err := AggregateExternalValues(values, AggregationAvg)
+	require.NoError(t, err)
+	assert.Equal(t, "2500m", res.Value.String(), "should have kept the fractional part of the average")
+}
+
+func TestAggregateExternalValuesErrors(t *testing.T) {
+	_, err := AggregateExternalValues(nil, AggregationSum)
+	assert.Error(t, err, "should not have been able to aggregate no values")
+	_, err = AggregateExternalValues(externalValues(), "median")
+	assert.Error(t, err, "should not have been able to aggregate with an unknown operation")
+}
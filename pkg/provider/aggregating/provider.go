@@ -0,0 +1,113 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package aggregating provides an external metrics provider aggregating, on the fly,
+// custom metrics over the objects matching a selector.
+package aggregating
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/metrics/pkg/apis/external_metrics"
+
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
+)
+
+// Metric defines an external metric aggregating a custom metric over the objects
+// matching the metric selector of queries, e.g. the average CPU of the pods
+// labelled app=web in a namespace.
+type Metric struct {
+	// Name is the name of the external metric.
+	Name string
+	// Base is the aggregated custom metric.  The namespace of queries is ignored
+	// when it is root-scoped.
+	Base provider.CustomMetricInfo
+	// Op combines the values of the base metric.
+	Op provider.AggregationOp
+}
+
+type aggregatingProvider struct {
+	base    provider.CustomMetricsProvider
+	metrics map[string]Metric
+	infos   []provider.ExternalMetricInfo
+}
+
+// NewProvider creates an ExternalMetricsProvider serving the given aggregates of
+// the custom metrics of the base provider.  It fails if a metric is defined twice,
+// or has an unknown aggregation operation.
+func NewProvider(base provider.CustomMetricsProvider, metrics []Metric) (provider.ExternalMetricsProvider, error) {
+	p := &aggregatingProvider{
+		base:    base,
+		metrics: make(map[string]Metric, len(metrics)),
+		infos:   make([]provider.ExternalMetricInfo, 0, len(metrics)),
+	}
+	for _, metric := range metrics {
+		if _, ok := p.metrics[metric.Name]; ok {
+			return nil, fmt.Errorf("aggregated metric %s is defined more than once", metric.Name)
+		}
+		switch metric.Op {
+		case provider.AggregationSum, provider.AggregationAvg, provider.AggregationMax, provider.AggregationMin:
+		default:
+			return nil, fmt.Errorf("unknown aggregation operation %q for aggregated metric %s", metric.Op, metric.Name)
+		}
+		p.metrics[metric.Name] = metric
+		p.infos = append(p.infos, provider.ExternalMetricInfo{Metric: metric.Name})
+	}
+	return p, nil
+}
+
+// GetExternalMetric aggregates the values of the base metric for the objects matching
+// the metric selector.  It returns an empty list when no objects match.
+func (p *aggregatingProvider) GetExternalMetric(ctx context.Context, namespace string, metricSelector labels.Selector, info provider.ExternalMetricInfo) (*external_metrics.ExternalMetricValueList, error) {
+	metric, ok := p.metrics[info.Metric]
+	if !ok {
+		return nil, provider.NewMetricNotFoundError(external_metrics.Resource(info.Metric), info.Metric)
+	}
+	if !metric.Base.Namespaced {
+		namespace = ""
+	}
+
+	values, err := p.base.GetMetricBySelector(ctx, namespace, metricSelector, metric.Base, labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	res := &external_metrics.ExternalMetricValueList{}
+	if values == nil || len(values.Items) == 0 {
+		return res, nil
+	}
+
+	externalValues := make([]external_metrics.ExternalMetricValue, 0, len(values.Items))
+	for _, value := range values.Items {
+		externalValues = append(externalValues, external_metrics.ExternalMetricValue{
+			MetricName:    metric.Name,
+			Timestamp:     value.Timestamp,
+			WindowSeconds: value.WindowSeconds,
+			Value:         value.Value,
+		})
+	}
+	aggregate, err := provider.AggregateExternalValues(externalValues, metric.Op)
+	if err != nil {
+		return nil, err
+	}
+	res.Items = append(res.Items, aggregate)
+	return res, nil
+}
+
+func (p *aggregatingProvider) ListAllExternalMetrics() []provider.ExternalMetricInfo {
+	return p.infos
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregating
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/metrics/pkg/apis/custom_metrics"
+
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider/defaults"
+)
+
+var cpuInfo = provider.CustomMetricInfo{
+	GroupResource: schema.GroupResource{Resource: "pods"},
+	Namespaced:    true,
+	Metric:        "cpu",
+}
+
+type fakePod struct {
+	namespace, name string
+	labels          labels.Set
+	cpu             string
+}
+
+// fakeBaseProvider serves the CPU of a fixed set of pods.
+type fakeBaseProvider struct {
+	defaults.DefaultCustomMetricsProvider
+	pods []fakePod
+}
+
+func (p *fakeBaseProvider) GetMetricBySelector(_ context.Context, namespace string, selector labels.Selector, info provider.CustomMetricInfo, _ labels.Selector) (*custom_metrics.MetricValueList, error) {
+	if info != cpuInfo {
+		return nil, provider.NewMetricNotFoundError(info.GroupResource, info.Metric)
+	}
+	res := &custom_metrics.MetricValueList{}
+	for _, pod := range p.pods {
+		if pod.namespace != namespace || !selector.Matches(pod.labels) {
+			continue
+		}
+		res.Items = append(res.Items, custom_metrics.MetricValue{
+			DescribedObject: custom_metrics.ObjectReference{Kind: "Pod", Namespace: pod.namespace, Name: pod.name},
+			Metric:          custom_metrics.MetricIdentifier{Name: info.Metric},
+			Value:           resource.MustParse(pod.cpu),
+		})
+	}
+	return res, nil
+}
+
+func (p *fakeBaseProvider) GetMetricByName(_ context.Context, _ types.NamespacedName, info provider.CustomMetricInfo, _ labels.Selector) (*custom_metrics.MetricValue, error) {
+	return nil, provider.NewMetricNotFoundError(info.GroupResource, info.Metric)
+}
+
+func setupProvider(t *testing.T) provider.ExternalMetricsProvider {
+	base := &fakeBaseProvider{pods: []fakePod{
+		{"default", "web-1", labels.Set{"app": "web"}, "100m"},
+		{"default", "web-2", labels.Set{"app": "web"}, "300m"},
+		{"default", "db-1", labels.Set{"app": "db"}, "1"},
+		{"other", "web-3", labels.Set{"app": "web"}, "2"},
+	}}
+	prov, err := NewProvider(base, []Metric{
+		{Name: "pods_cpu_avg", Base: cpuInfo, Op: provider.AggregationAvg},
+		{Name: "pods_cpu_max", Base: cpuInfo, Op: provider.AggregationMax},
+	})
+	require.NoError(t, err)
+	return prov
+}
+
+func TestGetExternalMetric(t *testing.T) {
+	prov := setupProvider(t)
+	web := labels.SelectorFromSet(labels.Set{"app": "web"})
+
+	values, err := prov.GetExternalMetric(context.Background(), "default", web, provider.ExternalMetricInfo{Metric: "pods_cpu_avg"})
+	require.NoError(t, err)
+	require.Len(t, values.Items, 1, "should have aggregated the values into one")
+	assert.Equal(t, "pods_cpu_avg", values.Items[0].MetricName)
+	assert.Equal(t, "200m", values.Items[0].Value.String(), "should have averaged the pods matching the selector in the namespace")
+
+	values, err = prov.GetExternalMetric(context.Background(), "default", labels.Everything(), provider.ExternalMetricInfo{Metric: "pods_cpu_max"})
+	require.NoError(t, err)
+	require.Len(t, values.Items, 1)
+	assert.Equal(t, "1", values.Items[0].Value.String(), "should have taken the maximum of all the pods in the namespace")
+
+	values, err = prov.GetExternalMetric(context.Background(), "empty", web, provider.ExternalMetricInfo{Metric: "pods_cpu_avg"})
+	require.NoError(t, err)
+	assert.Empty(t, values.Items, "should have returned an empty list when no pods match")
+
+	_, err = prov.GetExternalMetric(context.Background(), "default", web, provider.ExternalMetricInfo{Metric: "unknown"})
+	assert.True(t, apierr.IsNotFound(err), "should have returned a not found error for an unknown metric, got %v", err)
+}
+
+func TestListAllExternalMetrics(t *testing.T) {
+	prov := setupProvider(t)
+
+	assert.Equal(t, []provider.ExternalMetricInfo{{Metric: "pods_cpu_avg"}, {Metric: "pods_cpu_max"}}, prov.ListAllExternalMetrics())
+}
+
+func TestNewProviderValidation(t *testing.T) {
+	_, err := NewProvider(&fakeBaseProvider{}, []Metric{{Name: "foo", Base: cpuInfo, Op: "median"}})
+	assert.Error(t, err, "should have rejected an unknown aggregation operation")
+
+	_, err = NewProvider(&fakeBaseProvider{}, []Metric{
+		{Name: "foo", Base: cpuInfo, Op: provider.AggregationSum},
+		{Name: "foo", Base: cpuInfo, Op: provider.AggregationMax},
+	})
+	assert.Error(t, err, "should have rejected a metric defined twice")
+}
@@ -0,0 +1,537 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package caching provides metrics providers caching the values returned by
+// other providers for a fixed time.  They record the provenance of the values
+// they return as either cache or, unless the other providers recorded one,
+// backend.
+//
+// Optionally, values older than a soft TTL are refreshed in the background
+// while they keep being served, until they expire at the hard TTL, and the number
+// of cached queries is bounded, the least recently used ones being evicted first.
+// The number of cached queries is reported by the metrics_apiserver_cached_queries
+// gauge.
+package caching
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+	"k8s.io/metrics/pkg/apis/custom_metrics"
+	"k8s.io/metrics/pkg/apis/external_metrics"
+	"k8s.io/utils/clock"
+
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
+)
+
+var (
+	cachedQueries = metrics.NewGaugeVec(&metrics.GaugeOpts{
+		Namespace:      "metrics_apiserver",
+		Name:           "cached_queries",
+		Help:           "Number of queries whose values are cached by caching providers, by cache",
+		StabilityLevel: metrics.ALPHA,
+	}, []string{"cache"})
+
+	registerCachingMetrics sync.Once
+)
+
+type entry[V any] struct {
+	value   V
+	stale   time.Time
+	expires time.Time
+	// used is the element of the key in the list of recently used keys
+	used *list.Element
+}
+
+// cache stores values until their TTL expires, or until they are evicted.
+// Values are keyed by the query they were returned for, and tagged with
+// what they describe, so that they can be evicted selectively.  Values older
+// than the soft TTL are stale: they are still returned, but should be refreshed.
+// When the cache is full, the least recently used values are evicted first.
+type cache[T comparable, V any] struct {
+	softTTL time.Duration
+	ttl     time.Duration
+	clock   clock.PassiveClock
+	// maxEntries bounds the number of entries.  Zero means no bound.
+	maxEntries int
+	// size counts the entries, along with the ones of the other caches of the
+	// same kind, so it is only ever incremented or decremented
+	size metrics.GaugeMetric
+
+	mu      sync.Mutex
+	entries map[string]entry[V]
+	tags    map[string]T
+	// recent lists the keys of the entries, from the most recently used one
+	recent     *list.List
+	refreshing map[string]bool
+	// fetching tracks the tags with fetches in flight, so that the values fetched
+	// before the tags are evicted are not cached after the eviction
+	fetching map[T]*fetches
+}
+
+// fetches counts the fetches in flight for a tag, and the evictions of the tag
+// since the first of them started.
+type fetches struct {
+	count      int
+	generation uint64
+}
+
+func newCache[T comparable, V any](name string, softTTL, ttl time.Duration, clock clock.PassiveClock) *cache[T, V] {
+	registerCachingMetrics.Do(func() {
+		legacyregistry.MustRegister(cachedQueries)
+	})
+	return &cache[T, V]{
+		softTTL:    softTTL,
+		ttl:        ttl,
+		clock:      clock,
+		size:       cachedQueries.WithLabelValues(name),
+		entries:    make(map[string]entry[V]),
+		tags:       make(map[string]T),
+		recent:     list.New(),
+		refreshing: make(map[string]bool),
+		fetching:   make(map[T]*fetches),
+	}
+}
+
+// get returns the value cached for the key, if any, and whether it is stale.
+func (c *cache[T, V]) get(key string) (V, bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	now := c.clock.Now()
+	if ok && !now.Before(e.expires) {
+		c.remove(key)
+		ok = false
+	}
+	if ok {
+		c.recent.MoveToFront(e.used)
+	}
+	return e.value, ok, ok && !now.Before(e.stale)
+}
+
+// fetch calls fetch, and caches the value it returns for the key, unless the tag
+// was evicted in the meantime, since the value may then predate the eviction.
+func (c *cache[T, V]) fetch(ctx context.Context, key string, tag T, fetch func(ctx context.Context) (V, error)) (V, error) {
+	c.mu.Lock()
+	f, ok := c.fetching[tag]
+	if !ok {
+		f = &fetches{}
+		c.fetching[tag] = f
+	}
+	f.count++
+	generation := f.generation
+	c.mu.Unlock()
+
+	value, err := fetch(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if f.count--; f.count == 0 {
+		delete(c.fetching, tag)
+	}
+	if err == nil && f.generation == generation {
+		c.set(key, tag, value)
+	}
+	return value, err
+}
+
+// set caches the value for the key, evicting the least recently used entries
+// if the cache is full.  It must be called with the lock held.
+func (c *cache[T, V]) set(key string, tag T, value V) {
+	now := c.clock.Now()
+	// drop the expired entries, so that they do not accumulate
+	for k, e := range c.entries {
+		if !now.Before(e.expires) {
+			c.remove(k)
+		}
+	}
+
+	softTTL := c.softTTL
+	if softTTL <= 0 || softTTL > c.ttl {
+		softTTL = c.ttl
+	}
+	e, ok := c.entries[key]
+	if ok {
+		c.recent.MoveToFront(e.used)
+	} else {
+		e.used = c.recent.PushFront(key)
+		c.size.Inc()
+	}
+	e.value, e.stale, e.expires = value, now.Add(softTTL), now.Add(c.ttl)
+	c.entries[key] = e
+	c.tags[key] = tag
+
+	for c.maxEntries > 0 && len(c.entries) > c.maxEntries {
+		c.remove(c.recent.Back().Value.(string))
+	}
+}
+
+// remove removes the entry of the key.  It must be called with the lock held.
+func (c *cache[T, V]) remove(key string) {
+	e, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	c.recent.Remove(e.used)
+	delete(c.entries, key)
+	delete(c.tags, key)
+	c.size.Dec()
+}
+
+// refresh refreshes the value of the key in the background with fetch, unless
+// it is already being refreshed.  When fetch fails, the cached value is kept
+// until it expires.
+func (c *cache[T, V]) refresh(key string, tag T, fetch func(ctx context.Context) (V, error)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.refreshing[key] {
+		return
+	}
+	c.refreshing[key] = true
+
+	go func() {
+		defer func() {
+			c.mu.Lock()
+			delete(c.refreshing, key)
+			c.mu.Unlock()
+		}()
+
+		// the query which found the value stale does not wait for the refresh
+		ctx, cancel := context.WithTimeout(context.Background(), c.ttl)
+		defer cancel()
+		if _, err := c.fetch(ctx, key, tag, fetch); err != nil {
+			klog.V(4).InfoS("Unable to refresh cached metric values, keeping them until they expire", "query", key, "err", err)
+		}
+	}()
+}
+
+// snapshot calls f for each entry which has not expired, in key order.
+func (c *cache[T, V]) snapshot(f func(key string, expires time.Time, value V)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keys := make([]string, 0, len(c.entries))
+	now := c.clock.Now()
+	for key, e := range c.entries {
+		if now.Before(e.expires) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		e := c.entries[key]
+		f(key, e.expires, e.value)
+	}
+}
+
+// evict removes the entries whose tag matches, and discards the values of the
+// fetches in flight for matching tags.
+func (c *cache[T, V]) evict(matches func(tag T) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, tag := range c.tags {
+		if matches(tag) {
+			c.remove(key)
+		}
+	}
+	for tag, f := range c.fetching {
+		if matches(tag) {
+			f.generation++
+		}
+	}
+}
+
+// CachedQuery describes the values cached for a query, for debugging.
+type CachedQuery struct {
+	// Query identifies the query the values were returned for.
+	Query string `json:"query"`
+	// Expires is when the values expire.
+	Expires time.Time     `json:"expires"`
+	Values  []CachedValue `json:"values"`
+}
+
+// CachedValue describes a cached value.  The labels of the metric, which may come
+// from the backend, are left out.
+type CachedValue struct {
+	// Object is the object described by custom metric values.
+	Object        string      `json:"object,omitempty"`
+	Metric        string      `json:"metric"`
+	Value         string      `json:"value"`
+	Timestamp     metav1.Time `json:"timestamp"`
+	WindowSeconds *int64      `json:"windowSeconds,omitempty"`
+}
+
+func customCachedValue(value *custom_metrics.MetricValue) CachedValue {
+	object := value.DescribedObject.Kind + "/" + value.DescribedObject.Name
+	if value.DescribedObject.Namespace != "" {
+		object = value.DescribedObject.Namespace + "/" + object
+	}
+	return CachedValue{
+		Object:        object,
+		Metric:        value.Metric.Name,
+		Value:         value.Value.String(),
+		Timestamp:     value.Timestamp,
+		WindowSeconds: value.WindowSeconds,
+	}
+}
+
+// customTag describes the objects a cached custom metric value is for.
+// The name is empty for values returned for a selector.
+type customTag struct {
+	info      provider.CustomMetricInfo
+	namespace string
+	name      string
+}
+
+// CustomMetricsProvider is a CustomMetricsProvider caching the values returned
+// by another one.  It's a WrappingCustomMetricsProvider: it keeps the extensions
+// describing the metrics of the other provider, but queries it as a plain
+// CustomMetricsProvider.
+type CustomMetricsProvider struct {
+	provider.CustomMetricsProvider
+
+	byName     *cache[customTag, *custom_metrics.MetricValue]
+	bySelector *cache[customTag, *custom_metrics.MetricValueList]
+}
+
+var _ provider.WrappingCustomMetricsProvider = &CustomMetricsProvider{}
+
+// NewCustomMetricsProvider creates a CustomMetricsProvider caching the values
+// returned by the given provider for the given TTL.  Errors are not cached.
+func NewCustomMetricsProvider(delegate provider.CustomMetricsProvider, ttl time.Duration) *CustomMetricsProvider {
+	return newCustomMetricsProvider(delegate, ttl, ttl, clock.RealClock{})
+}
+
+// NewRefreshingCustomMetricsProvider creates a CustomMetricsProvider caching the
+// values returned by the given provider for the given hard TTL.  Once older than
+// the soft TTL, cached values are still returned, but refreshed in the background,
+// with one refresh at a time per query, so that queries seldom wait for the given
+// provider.  Errors are not cached, and failed refreshes keep the cached values.
+func NewRefreshingCustomMetricsProvider(delegate provider.CustomMetricsProvider, softTTL, hardTTL time.Duration) *CustomMetricsProvider {
+	return newCustomMetricsProvider(delegate, softTTL, hardTTL, clock.RealClock{})
+}
+
+func newCustomMetricsProvider(delegate provider.CustomMetricsProvider, softTTL, hardTTL time.Duration, clock clock.PassiveClock) *CustomMetricsProvider {
+	return &CustomMetricsProvider{
+		CustomMetricsProvider: delegate,
+		byName:                newCache[customTag, *custom_metrics.MetricValue]("custom_by_name", softTTL, hardTTL, clock),
+		bySelector:            newCache[customTag, *custom_metrics.MetricValueList]("custom_by_selector", softTTL, hardTTL, clock),
+	}
+}
+
+// WithMaxEntries bounds the number of queries whose values are cached, in each of
+// the caches of the provider, for queries by name and by selector, so that queries
+// for many distinct objects or selectors cannot grow them without bound.  When a
+// cache is full, the least recently used values are evicted first.  It must be
+// called before the provider is used, and returns the provider.
+func (p *CustomMetricsProvider) WithMaxEntries(maxEntries int) *CustomMetricsProvider {
+	p.byName.maxEntries = maxEntries
+	p.bySelector.maxEntries = maxEntries
+	return p
+}
+
+// Unwrap returns the provider whose values are cached.
+func (p *CustomMetricsProvider) Unwrap() provider.CustomMetricsProvider {
+	return p.CustomMetricsProvider
+}
+
+func (p *CustomMetricsProvider) GetMetricByName(ctx context.Context, name types.NamespacedName, info provider.CustomMetricInfo, metricSelector labels.Selector) (*custom_metrics.MetricValue, error) {
+	key := fmt.Sprintf("%s/%s?metricSelector=%s", info.String(), name.String(), metricSelector.String())
+	tag := customTag{info: info, namespace: name.Namespace, name: name.Name}
+	if value, ok, stale := p.byName.get(key); ok {
+		if stale {
+			p.byName.refresh(key, tag, func(ctx context.Context) (*custom_metrics.MetricValue, error) {
+				return p.CustomMetricsProvider.GetMetricByName(ctx, name, info, metricSelector)
+			})
+		}
+		provider.SetProvenance(ctx, provider.ProvenanceCache)
+		return value.DeepCopy(), nil
+	}
+
+	value, err := p.byName.fetch(ctx, key, tag, func(ctx context.Context) (*custom_metrics.MetricValue, error) {
+		return p.CustomMetricsProvider.GetMetricByName(ctx, name, info, metricSelector)
+	})
+	if err != nil {
+		return nil, err
+	}
+	provider.SetProvenance(ctx, provider.ProvenanceBackend)
+	return value.DeepCopy(), nil
+}
+
+func (p *CustomMetricsProvider) GetMetricBySelector(ctx context.Context, namespace string, selector labels.Selector, info provider.CustomMetricInfo, metricSelector labels.Selector) (*custom_metrics.MetricValueList, error) {
+	key := fmt.Sprintf("%s/%s?selector=%s&metricSelector=%s", info.String(), namespace, selector.String(), metricSelector.String())
+	tag := customTag{info: info, namespace: namespace}
+	if values, ok, stale := p.bySelector.get(key); ok {
+		if stale {
+			p.bySelector.refresh(key, tag, func(ctx context.Context) (*custom_metrics.MetricValueList, error) {
+				return p.CustomMetricsProvider.GetMetricBySelector(ctx, namespace, selector, info, metricSelector)
+			})
+		}
+		provider.SetProvenance(ctx, provider.ProvenanceCache)
+		return values.DeepCopy(), nil
+	}
+
+	values, err := p.bySelector.fetch(ctx, key, tag, func(ctx context.Context) (*custom_metrics.MetricValueList, error) {
+		return p.CustomMetricsProvider.GetMetricBySelector(ctx, namespace, selector, info, metricSelector)
+	})
+	if err != nil {
+		return nil, err
+	}
+	provider.SetProvenance(ctx, provider.ProvenanceBackend)
+	return values.DeepCopy(), nil
+}
+
+// CachedQueries lists the values currently cached, for debugging.
+func (p *CustomMetricsProvider) CachedQueries() []CachedQuery {
+	queries := []CachedQuery{}
+	p.byName.snapshot(func(key string, expires time.Time, value *custom_metrics.MetricValue) {
+		queries = append(queries, CachedQuery{Query: key, Expires: expires, Values: []CachedValue{customCachedValue(value)}})
+	})
+	p.bySelector.snapshot(func(key string, expires time.Time, values *custom_metrics.MetricValueList) {
+		query := CachedQuery{Query: key, Expires: expires, Values: []CachedValue{}}
+		if values == nil {
+			values = &custom_metrics.MetricValueList{}
+		}
+		for i := range values.Items {
+			query.Values = append(query.Values, customCachedValue(&values.Items[i]))
+		}
+		queries = append(queries, query)
+	})
+	return queries
+}
+
+// Invalidate evicts the cached values of the given metric for the given object,
+// so that the next query for them is passed to the underlying provider.
+// Since values returned for selectors may include the object, they are evicted
+// for the whole namespace.  Providers can call it when they know a value changed.
+func (p *CustomMetricsProvider) Invalidate(info provider.CustomMetricInfo, name types.NamespacedName) {
+	p.byName.evict(func(tag customTag) bool {
+		return tag.info == info && tag.namespace == name.Namespace && tag.name == name.Name
+	})
+	p.bySelector.evict(func(tag customTag) bool {
+		return tag.info == info && tag.namespace == name.Namespace
+	})
+}
+
+// ExternalMetricsProvider is an ExternalMetricsProvider caching the values returned
+// by another one.  It's a WrappingExternalMetricsProvider, which does not stream
+// the values of a StreamingExternalMetricsProvider.
+type ExternalMetricsProvider struct {
+	provider.ExternalMetricsProvider
+
+	values *cache[provider.ExternalMetricInfo, *external_metrics.ExternalMetricValueList]
+}
+
+var _ provider.WrappingExternalMetricsProvider = &ExternalMetricsProvider{}
+
+// NewExternalMetricsProvider creates an ExternalMetricsProvider caching the values
+// returned by the given provider for the given TTL.  Errors are not cached.
+func NewExternalMetricsProvider(delegate provider.ExternalMetricsProvider, ttl time.Duration) *ExternalMetricsProvider {
+	return newExternalMetricsProvider(delegate, ttl, ttl, clock.RealClock{})
+}
+
+// NewRefreshingExternalMetricsProvider creates an ExternalMetricsProvider caching
+// the values returned by the given provider for the given hard TTL, and refreshing
+// them in the background once older than the soft TTL, like
+// NewRefreshingCustomMetricsProvider.
+func NewRefreshingExternalMetricsProvider(delegate provider.ExternalMetricsProvider, softTTL, hardTTL time.Duration) *ExternalMetricsProvider {
+	return newExternalMetricsProvider(delegate, softTTL, hardTTL, clock.RealClock{})
+}
+
+func newExternalMetricsProvider(delegate provider.ExternalMetricsProvider, softTTL, hardTTL time.Duration, clock clock.PassiveClock) *ExternalMetricsProvider {
+	return &ExternalMetricsProvider{
+		ExternalMetricsProvider: delegate,
+		values:                  newCache[provider.ExternalMetricInfo, *external_metrics.ExternalMetricValueList]("external", softTTL, hardTTL, clock),
+	}
+}
+
+// WithMaxEntries bounds the number of queries whose values are cached, like
+// CustomMetricsProvider.WithMaxEntries.  It must be called before the provider is
+// used, and returns the provider.
+func (p *ExternalMetricsProvider) WithMaxEntries(maxEntries int) *ExternalMetricsProvider {
+	p.values.maxEntries = maxEntries
+	return p
+}
+
+// Unwrap returns the provider whose values are cached.
+func (p *ExternalMetricsProvider) Unwrap() provider.ExternalMetricsProvider {
+	return p.ExternalMetricsProvider
+}
+
+func (p *ExternalMetricsProvider) GetExternalMetric(ctx context.Context, namespace string, metricSelector labels.Selector, info provider.ExternalMetricInfo) (*external_metrics.ExternalMetricValueList, error) {
+	key := fmt.Sprintf("%s/%s?metricSelector=%s", namespace, info.Metric, metricSelector.String())
+	if values, ok, stale := p.values.get(key); ok {
+		if stale {
+			p.values.refresh(key, info, func(ctx context.Context) (*external_metrics.ExternalMetricValueList, error) {
+				return p.ExternalMetricsProvider.GetExternalMetric(ctx, namespace, metricSelector, info)
+			})
+		}
+		provider.SetProvenance(ctx, provider.ProvenanceCache)
+		return values.DeepCopy(), nil
+	}
+
+	values, err := p.values.fetch(ctx, key, info, func(ctx context.Context) (*external_metrics.ExternalMetricValueList, error) {
+		return p.ExternalMetricsProvider.GetExternalMetric(ctx, namespace, metricSelector, info)
+	})
+	if err != nil {
+		return nil, err
+	}
+	provider.SetProvenance(ctx, provider.ProvenanceBackend)
+	return values.DeepCopy(), nil
+}
+
+// CachedQueries lists the values currently cached, for debugging.
+func (p *ExternalMetricsProvider) CachedQueries() []CachedQuery {
+	queries := []CachedQuery{}
+	p.values.snapshot(func(key string, expires time.Time, values *external_metrics.ExternalMetricValueList) {
+		query := CachedQuery{Query: key, Expires: expires, Values: []CachedValue{}}
+		if values == nil {
+			values = &external_metrics.ExternalMetricValueList{}
+		}
+		for _, value := range values.Items {
+			query.Values = append(query.Values, CachedValue{
+				Metric:        value.MetricName,
+				Value:         value.Value.String(),
+				Timestamp:     value.Timestamp,
+				WindowSeconds: value.WindowSeconds,
+			})
+		}
+		queries = append(queries, query)
+	})
+	return queries
+}
+
+// Invalidate evicts all the cached values of the given metric, so that the next
+// query for it is passed to the underlying provider.  Providers can call it when
+// they know a value changed.
+func (p *ExternalMetricsProvider) Invalidate(info provider.ExternalMetricInfo) {
+	p.values.evict(func(tag provider.ExternalMetricInfo) bool {
+		return tag == info
+	})
+}
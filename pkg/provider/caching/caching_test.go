@@ -0,0 +1,425 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package caching
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/component-base/metrics/testutil"
+	"k8s.io/metrics/pkg/apis/custom_metrics"
+	"k8s.io/metrics/pkg/apis/external_metrics"
+	testingclock "k8s.io/utils/clock/testing"
+
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider/defaults"
+)
+
+const testTTL = time.Minute
+
+var podsInfo = provider.CustomMetricInfo{
+	GroupResource: schema.GroupResource{Resource: "pods"},
+	Namespaced:    true,
+	Metric:        "some-metric",
+}
+
+// valueProvider returns its current value for any object, and counts its queries.
+type valueProvider struct {
+	defaults.DefaultCustomMetricsProvider
+	defaults.DefaultExternalMetricsProvider
+
+	value   resource.Quantity
+	queries int
+}
+
+func (p *valueProvider) GetMetricByName(_ context.Context, name types.NamespacedName, _ provider.CustomMetricInfo, _ labels.Selector) (*custom_metrics.MetricValue, error) {
+	p.queries++
+	return &custom_metrics.MetricValue{
+		DescribedObject: custom_metrics.ObjectReference{Namespace: name.Namespace, Name: name.Name},
+		Value:           p.value,
+	}, nil
+}
+
+func (p *valueProvider) GetMetricBySelector(_ context.Context, namespace string, _ labels.Selector, _ provider.CustomMetricInfo, _ labels.Selector) (*custom_metrics.MetricValueList, error) {
+	p.queries++
+	return &custom_metrics.MetricValueList{Items: []custom_metrics.MetricValue{
+		{DescribedObject: custom_metrics.ObjectReference{Namespace: namespace, Name: "foo"}, Value: p.value},
+	}}, nil
+}
+
+func (p *valueProvider) GetExternalMetric(_ context.Context, _ string, _ labels.Selector, info provider.ExternalMetricInfo) (*external_metrics.ExternalMetricValueList, error) {
+	p.queries++
+	return &external_metrics.ExternalMetricValueList{Items: []external_metrics.ExternalMetricValue{
+		{MetricName: info.Metric, Value: p.value},
+	}}, nil
+}
+
+func TestCustomMetricsProviderCaches(t *testing.T) {
+	delegate := &valueProvider{value: resource.MustParse("1")}
+	clock := testingclock.NewFakePassiveClock(time.Now())
+	prov := newCustomMetricsProvider(delegate, testTTL, testTTL, clock)
+	name := types.NamespacedName{Namespace: "default", Name: "foo"}
+
+	value, err := prov.GetMetricByName(context.Background(), name, podsInfo, labels.Everything())
+	require.NoError(t, err)
+	assert.Equal(t, "1", value.Value.String())
+
+	delegate.value = resource.MustParse("2")
+	value, err = prov.GetMetricByName(context.Background(), name, podsInfo, labels.Everything())
+	require.NoError(t, err)
+	assert.Equal(t, "1", value.Value.String(), "should have returned the cached value")
+	assert.Equal(t, 1, delegate.queries, "should not have queried the underlying provider again")
+
+	clock.SetTime(clock.Now().Add(testTTL))
+	value, err = prov.GetMetricByName(context.Background(), name, podsInfo, labels.Everything())
+	require.NoError(t, err)
+	assert.Equal(t, "2", value.Value.String(), "should have queried the underlying provider once the TTL expired")
+}
+
+func TestCustomMetricsProviderInvalidate(t *testing.T) {
+	delegate := &valueProvider{value: resource.MustParse("1")}
+	prov := newCustomMetricsProvider(delegate, testTTL, testTTL, testingclock.NewFakePassiveClock(time.Now()))
+	name := types.NamespacedName{Namespace: "default", Name: "foo"}
+	other := types.NamespacedName{Namespace: "default", Name: "bar"}
+
+	_, err := prov.GetMetricByName(context.Background(), name, podsInfo, labels.Everything())
+	require.NoError(t, err)
+	_, err = prov.GetMetricByName(context.Background(), other, podsInfo, labels.Everything())
+	require.NoError(t, err)
+	_, err = prov.GetMetricBySelector(context.Background(), "default", labels.Everything(), podsInfo, labels.Everything())
+	require.NoError(t, err)
+
+	delegate.value = resource.MustParse("2")
+	prov.Invalidate(podsInfo, name)
+
+	value, err := prov.GetMetricByName(context.Background(), name, podsInfo, labels.Everything())
+	require.NoError(t, err)
+	assert.Equal(t, "2", value.Value.String(), "should have bypassed the cache for the invalidated object")
+
+	values, err := prov.GetMetricBySelector(context.Background(), "default", labels.Everything(), podsInfo, labels.Everything())
+	require.NoError(t, err)
+	assert.Equal(t, "2", values.Items[0].Value.String(), "should have bypassed the cache for selectors in the namespace of the invalidated object")
+
+	value, err = prov.GetMetricByName(context.Background(), other, podsInfo, labels.Everything())
+	require.NoError(t, err)
+	assert.Equal(t, "1", value.Value.String(), "should have kept the cached values of other objects")
+}
+
+func TestCustomMetricsProviderDropsExpiredValues(t *testing.T) {
+	delegate := &valueProvider{value: resource.MustParse("1")}
+	clock := testingclock.NewFakePassiveClock(time.Now())
+	prov := newCustomMetricsProvider(delegate, testTTL, testTTL, clock)
+
+	for _, name := range []string{"foo", "bar"} {
+		_, err := prov.GetMetricByName(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, podsInfo, labels.Everything())
+		require.NoError(t, err)
+	}
+	clock.SetTime(clock.Now().Add(testTTL))
+	_, err := prov.GetMetricByName(context.Background(), types.NamespacedName{Namespace: "default", Name: "baz"}, podsInfo, labels.Everything())
+	require.NoError(t, err)
+
+	assert.Len(t, prov.byName.entries, 1, "should have dropped the expired values which were not queried again")
+	assert.Len(t, prov.byName.tags, 1, "should have dropped the tags of the expired values")
+}
+
+func TestCustomMetricsProviderMaxEntries(t *testing.T) {
+	delegate := &valueProvider{value: resource.MustParse("1")}
+	prov := newCustomMetricsProvider(delegate, testTTL, testTTL, testingclock.NewFakePassiveClock(time.Now())).WithMaxEntries(2)
+	size := func() float64 {
+		value, err := testutil.GetGaugeMetricValue(cachedQueries.WithLabelValues("custom_by_name"))
+		require.NoError(t, err)
+		return value
+	}
+	sizeBefore := size()
+	get := func(name string) {
+		_, err := prov.GetMetricByName(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, podsInfo, labels.Everything())
+		require.NoError(t, err)
+	}
+
+	get("foo")
+	get("bar")
+	assert.Equal(t, 2.0, size()-sizeBefore, "should have counted the cached queries")
+	// foo is now more recently used than bar
+	get("foo")
+	assert.Equal(t, 2, delegate.queries, "should have cached the values up to the capacity")
+
+	get("baz")
+	assert.Len(t, prov.byName.entries, 2, "should have evicted a value at capacity")
+	assert.Equal(t, 2.0, size()-sizeBefore, "should have counted the evicted query out")
+
+	get("foo")
+	assert.Equal(t, 3, delegate.queries, "should have kept the recently used value")
+	get("bar")
+	assert.Equal(t, 4, delegate.queries, "should have evicted the least recently used value")
+}
+
+func TestExternalMetricsProviderMaxEntries(t *testing.T) {
+	delegate := &valueProvider{value: resource.MustParse("1")}
+	prov := newExternalMetricsProvider(delegate, testTTL, testTTL, testingclock.NewFakePassiveClock(time.Now())).WithMaxEntries(1)
+
+	for _, metric := range []string{"foo", "bar", "foo"} {
+		_, err := prov.GetExternalMetric(context.Background(), "default", labels.Everything(), provider.ExternalMetricInfo{Metric: metric})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 3, delegate.queries, "should have evicted the previous value at capacity")
+	assert.Len(t, prov.values.entries, 1)
+}
+
+func TestCustomMetricsProviderReturnsCopies(t *testing.T) {
+	delegate := &valueProvider{value: resource.MustParse("1")}
+	prov := newCustomMetricsProvider(delegate, testTTL, testTTL, testingclock.NewFakePassiveClock(time.Now()))
+
+	values, err := prov.GetMetricBySelector(context.Background(), "default", labels.Everything(), podsInfo, labels.Everything())
+	require.NoError(t, err)
+	values.Items[0].Value = resource.MustParse("3")
+
+	values, err = prov.GetMetricBySelector(context.Background(), "default", labels.Everything(), podsInfo, labels.Everything())
+	require.NoError(t, err)
+	assert.Equal(t, "1", values.Items[0].Value.String(), "should not have been affected by changes to returned values")
+}
+
+func TestExternalMetricsProviderInvalidate(t *testing.T) {
+	delegate := &valueProvider{value: resource.MustParse("1")}
+	prov := newExternalMetricsProvider(delegate, testTTL, testTTL, testingclock.NewFakePassiveClock(time.Now()))
+	info := provider.ExternalMetricInfo{Metric: "queue-length"}
+
+	_, err := prov.GetExternalMetric(context.Background(), "default", labels.Everything(), info)
+	require.NoError(t, err)
+
+	delegate.value = resource.MustParse("2")
+	values, err := prov.GetExternalMetric(context.Background(), "default", labels.Everything(), info)
+	require.NoError(t, err)
+	assert.Equal(t, "1", values.Items[0].Value.String(), "should have returned the cached value")
+
+	prov.Invalidate(info)
+	values, err = prov.GetExternalMetric(context.Background(), "default", labels.Everything(), info)
+	require.NoError(t, err)
+	assert.Equal(t, "2", values.Items[0].Value.String(), "should have bypassed the cache once invalidated")
+}
+
+func TestCustomMetricsProviderCachedQueries(t *testing.T) {
+	delegate := &valueProvider{value: resource.MustParse("1")}
+	clock := testingclock.NewFakePassiveClock(time.Now())
+	prov := newCustomMetricsProvider(delegate, testTTL, testTTL, clock)
+
+	_, err := prov.GetMetricByName(context.Background(), types.NamespacedName{Namespace: "default", Name: "foo"}, podsInfo, labels.Everything())
+	require.NoError(t, err)
+	_, err = prov.GetMetricBySelector(context.Background(), "default", labels.Everything(), podsInfo, labels.Everything())
+	require.NoError(t, err)
+
+	queries := prov.CachedQueries()
+	require.Len(t, queries, 2, "should have listed the cached queries by name and by selector")
+	for _, query := range queries {
+		require.Len(t, query.Values, 1)
+		assert.Equal(t, "default//foo", query.Values[0].Object)
+		assert.Equal(t, "1", query.Values[0].Value)
+		assert.Equal(t, clock.Now().Add(testTTL), query.Expires)
+	}
+
+	clock.SetTime(clock.Now().Add(testTTL))
+	assert.Empty(t, prov.CachedQueries(), "should not have listed expired queries")
+}
+
+// refreshedProvider returns its current value for any object, and counts its
+// queries.  Unlike valueProvider, it can be queried concurrently, and queries
+// wait for release when it is set.
+type refreshedProvider struct {
+	defaults.DefaultCustomMetricsProvider
+	defaults.DefaultExternalMetricsProvider
+
+	mu      sync.Mutex
+	value   resource.Quantity
+	queries int
+	release chan struct{}
+}
+
+func (p *refreshedProvider) query() resource.Quantity {
+	p.mu.Lock()
+	p.queries++
+	release := p.release
+	p.mu.Unlock()
+
+	if release != nil {
+		<-release
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.value
+}
+
+func (p *refreshedProvider) setValue(value string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.value = resource.MustParse(value)
+}
+
+func (p *refreshedProvider) queryCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.queries
+}
+
+func (p *refreshedProvider) GetMetricByName(_ context.Context, name types.NamespacedName, _ provider.CustomMetricInfo, _ labels.Selector) (*custom_metrics.MetricValue, error) {
+	return &custom_metrics.MetricValue{
+		DescribedObject: custom_metrics.ObjectReference{Namespace: name.Namespace, Name: name.Name},
+		Value:           p.query(),
+	}, nil
+}
+
+func (p *refreshedProvider) GetMetricBySelector(_ context.Context, namespace string, _ labels.Selector, _ provider.CustomMetricInfo, _ labels.Selector) (*custom_metrics.MetricValueList, error) {
+	return &custom_metrics.MetricValueList{Items: []custom_metrics.MetricValue{
+		{DescribedObject: custom_metrics.ObjectReference{Namespace: namespace, Name: "foo"}, Value: p.query()},
+	}}, nil
+}
+
+func (p *refreshedProvider) GetExternalMetric(_ context.Context, _ string, _ labels.Selector, info provider.ExternalMetricInfo) (*external_metrics.ExternalMetricValueList, error) {
+	return &external_metrics.ExternalMetricValueList{Items: []external_metrics.ExternalMetricValue{
+		{MetricName: info.Metric, Value: p.query()},
+	}}, nil
+}
+
+func TestCustomMetricsProviderRefreshes(t *testing.T) {
+	delegate := &refreshedProvider{value: resource.MustParse("1")}
+	clock := testingclock.NewFakePassiveClock(time.Now())
+	prov := newCustomMetricsProvider(delegate, testTTL/2, testTTL, clock)
+	name := types.NamespacedName{Namespace: "default", Name: "foo"}
+	get := func() string {
+		value, err := prov.GetMetricByName(context.Background(), name, podsInfo, labels.Everything())
+		require.NoError(t, err)
+		return value.Value.String()
+	}
+
+	assert.Equal(t, "1", get())
+	delegate.setValue("2")
+	delegate.release = make(chan struct{})
+
+	clock.SetTime(clock.Now().Add(testTTL / 4))
+	assert.Equal(t, "1", get(), "should have returned the fresh cached value")
+	assert.Equal(t, 1, delegate.queryCount(), "should not have refreshed a fresh value")
+
+	clock.SetTime(clock.Now().Add(testTTL / 4))
+	assert.Equal(t, "1", get(), "should have returned the stale cached value without waiting for the refresh")
+	assert.Equal(t, "1", get(), "should have returned the stale cached value while it is refreshed")
+	assert.Eventually(t, func() bool { return delegate.queryCount() == 2 }, 5*time.Second, time.Millisecond, "should have refreshed the stale value")
+	close(delegate.release)
+	assert.Eventually(t, func() bool { return get() == "2" }, 5*time.Second, time.Millisecond, "should have cached the refreshed value")
+	assert.Equal(t, 2, delegate.queryCount(), "should have refreshed the stale value only once")
+
+	delegate.setValue("3")
+	clock.SetTime(clock.Now().Add(testTTL))
+	assert.Equal(t, "3", get(), "should have waited for the underlying provider once the hard TTL expired")
+}
+
+func TestCustomMetricsProviderInvalidateDuringRefresh(t *testing.T) {
+	delegate := &refreshedProvider{value: resource.MustParse("1")}
+	clock := testingclock.NewFakePassiveClock(time.Now())
+	prov := newCustomMetricsProvider(delegate, testTTL/2, testTTL, clock)
+	name := types.NamespacedName{Namespace: "default", Name: "foo"}
+	get := func() string {
+		value, err := prov.GetMetricByName(context.Background(), name, podsInfo, labels.Everything())
+		require.NoError(t, err)
+		return value.Value.String()
+	}
+
+	assert.Equal(t, "1", get())
+	delegate.setValue("2")
+	delegate.release = make(chan struct{})
+	clock.SetTime(clock.Now().Add(testTTL / 2))
+	assert.Equal(t, "1", get(), "should have returned the stale cached value")
+	assert.Eventually(t, func() bool { return delegate.queryCount() == 2 }, 5*time.Second, time.Millisecond, "should have started refreshing the stale value")
+
+	// the refresh started before the invalidation, so its value may predate it
+	prov.Invalidate(podsInfo, name)
+	close(delegate.release)
+	assert.Eventually(t, func() bool {
+		prov.byName.mu.Lock()
+		defer prov.byName.mu.Unlock()
+		return len(prov.byName.refreshing) == 0
+	}, 5*time.Second, time.Millisecond, "should have finished refreshing")
+
+	delegate.setValue("3")
+	assert.Equal(t, "3", get(), "should not have cached the value of the refresh started before the invalidation")
+}
+
+func TestExternalMetricsProviderRefreshes(t *testing.T) {
+	delegate := &refreshedProvider{value: resource.MustParse("1")}
+	clock := testingclock.NewFakePassiveClock(time.Now())
+	prov := newExternalMetricsProvider(delegate, testTTL/2, testTTL, clock)
+	info := provider.ExternalMetricInfo{Metric: "some-metric"}
+	get := func() string {
+		values, err := prov.GetExternalMetric(context.Background(), "default", labels.Everything(), info)
+		require.NoError(t, err)
+		require.Len(t, values.Items, 1)
+		return values.Items[0].Value.String()
+	}
+
+	assert.Equal(t, "1", get())
+	delegate.setValue("2")
+	clock.SetTime(clock.Now().Add(testTTL / 2))
+	assert.Equal(t, "1", get(), "should have returned the stale cached value")
+	assert.Eventually(t, func() bool { return get() == "2" }, 5*time.Second, time.Millisecond, "should have cached the refreshed value")
+}
+
+// extendedProvider implements extensions describing its metrics, and for queries.
+type extendedProvider struct {
+	valueProvider
+}
+
+func (p *extendedProvider) MetricType(provider.CustomMetricInfo) provider.MetricType {
+	return provider.MetricTypeGauge
+}
+
+func (p *extendedProvider) MetricDiagnostics() []provider.MetricDiagnostics {
+	return nil
+}
+
+func (p *extendedProvider) GetMetricByUID(ctx context.Context, name types.NamespacedName, _ types.UID, info provider.CustomMetricInfo, metricSelector labels.Selector) (*custom_metrics.MetricValue, error) {
+	return p.GetMetricByName(ctx, name, info, metricSelector)
+}
+
+func (p *extendedProvider) StreamExternalMetric(context.Context, string, labels.Selector, provider.ExternalMetricInfo) (<-chan external_metrics.ExternalMetricValue, error) {
+	return nil, nil
+}
+
+func TestProvidersWrapExtensions(t *testing.T) {
+	inner := &extendedProvider{}
+
+	custom := NewCustomMetricsProvider(inner, testTTL)
+	_, ok := provider.As[provider.TypedCustomMetricsProvider](custom)
+	assert.True(t, ok, "should have kept the type of the metrics")
+	_, ok = provider.As[provider.DiagnosticsProvider](custom)
+	assert.True(t, ok, "should have kept the diagnostics")
+	// queries by UID would not be cached
+	_, ok = interface{}(custom).(provider.UIDCustomMetricsProvider)
+	assert.False(t, ok, "should have queried the values by name instead of UID")
+
+	external := NewExternalMetricsProvider(inner, testTTL)
+	_, ok = provider.As[provider.DiagnosticsProvider](external)
+	assert.True(t, ok, "should have kept the diagnostics")
+	// streamed values would not be cached
+	_, ok = interface{}(external).(provider.StreamingExternalMetricsProvider)
+	assert.False(t, ok, "should have buffered the values instead of streaming them")
+}
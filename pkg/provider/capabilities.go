@@ -0,0 +1,57 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AllCapabilities are the capabilities of providers which support all queries.
+var AllCapabilities = MetricCapabilities{ByName: true, BySelector: true}
+
+// CapabilitiesFor returns the queries the given provider supports for the given
+// metric: all of them, unless it is, or wraps, a CapableCustomMetricsProvider.
+func CapabilitiesFor(p CustomMetricsProvider, info CustomMetricInfo) MetricCapabilities {
+	if capable, ok := As[CapableCustomMetricsProvider](p); ok {
+		return capable.Capabilities(info)
+	}
+	return AllCapabilities
+}
+
+// discoveryVerbs returns the verbs advertised in discovery for the given metric:
+// the ones of its capabilities for a CapableCustomMetricsProvider, or a provider
+// wrapping one, and "get" for other providers, as before capabilities were
+// introduced.
+func discoveryVerbs(p CustomMetricsProvider, info CustomMetricInfo) metav1.Verbs {
+	if capable, ok := As[CapableCustomMetricsProvider](p); ok {
+		return capable.Capabilities(info).Verbs()
+	}
+	return metav1.Verbs{"get"}
+}
+
+// Verbs returns the verbs advertised in discovery for the capabilities: "get"
+// for queries by name, and "list" for queries by selector.
+func (c MetricCapabilities) Verbs() metav1.Verbs {
+	verbs := metav1.Verbs{}
+	if c.ByName {
+		verbs = append(verbs, "get")
+	}
+	if c.BySelector {
+		verbs = append(verbs, "list")
+	}
+	return verbs
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/metrics/pkg/apis/custom_metrics"
+)
+
+type listingProvider struct {
+	metrics []CustomMetricInfo
+}
+
+func (p *listingProvider) GetMetricByName(context.Context, types.NamespacedName, CustomMetricInfo, labels.Selector) (*custom_metrics.MetricValue, error) {
+	return nil, nil
+}
+
+func (p *listingProvider) GetMetricBySelector(context.Context, string, labels.Selector, CustomMetricInfo, labels.Selector) (*custom_metrics.MetricValueList, error) {
+	return nil, nil
+}
+
+func (p *listingProvider) ListAllMetrics() []CustomMetricInfo {
+	return p.metrics
+}
+
+// capableProvider only supports queries by selector for the metrics named "by-selector".
+type capableProvider struct {
+	listingProvider
+}
+
+func (p *capableProvider) Capabilities(info CustomMetricInfo) MetricCapabilities {
+	if info.Metric == "by-selector" {
+		return MetricCapabilities{BySelector: true}
+	}
+	return AllCapabilities
+}
+
+func TestMetricCapabilitiesVerbs(t *testing.T) {
+	assert.Equal(t, metav1.Verbs{"get", "list"}, AllCapabilities.Verbs())
+	assert.Equal(t, metav1.Verbs{"get"}, MetricCapabilities{ByName: true}.Verbs())
+	assert.Equal(t, metav1.Verbs{"list"}, MetricCapabilities{BySelector: true}.Verbs())
+	assert.Empty(t, MetricCapabilities{}.Verbs())
+}
+
+func TestCustomMetricResourceListerVerbs(t *testing.T) {
+	metrics := []CustomMetricInfo{
+		{GroupResource: schema.GroupResource{Resource: "pods"}, Namespaced: true, Metric: "all"},
+		{GroupResource: schema.GroupResource{Resource: "pods"}, Namespaced: true, Metric: "by-selector"},
+	}
+
+	resources := NewCustomMetricResourceLister(&listingProvider{metrics: metrics}).ListAPIResources()
+	if assert.Len(t, resources, 2) {
+		assert.Equal(t, metav1.Verbs{"get"}, resources[0].Verbs, "should have kept the baseline verbs without capabilities")
+		assert.Equal(t, metav1.Verbs{"get"}, resources[1].Verbs, "should have kept the baseline verbs without capabilities")
+	}
+
+	resources = NewCustomMetricResourceLister(&capableProvider{listingProvider{metrics: metrics}}).ListAPIResources()
+	if assert.Len(t, resources, 2) {
+		assert.Equal(t, metav1.Verbs{"get", "list"}, resources[0].Verbs, "should have advertised all verbs for a metric supporting all queries")
+		assert.Equal(t, metav1.Verbs{"list"}, resources[1].Verbs, "should only have advertised the verbs of the supported queries")
+	}
+}
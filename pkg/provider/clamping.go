@@ -0,0 +1,115 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+	"k8s.io/metrics/pkg/apis/custom_metrics"
+)
+
+var (
+	clampedValues = metrics.NewCounterVec(&metrics.CounterOpts{
+		Namespace:      "metrics_apiserver",
+		Name:           "clamped_metric_values_total",
+		Help:           "Number of custom metric values clamped to the range of a clamping provider, by metric and bound",
+		StabilityLevel: metrics.ALPHA,
+	}, []string{"metric", "bound"})
+
+	registerClampingMetrics sync.Once
+)
+
+type clampingProvider struct {
+	CustomMetricsProvider
+
+	min, max *resource.Quantity
+}
+
+// NewClampingProvider creates a CustomMetricsProvider clamping the values returned
+// by the inner one to the given range, so that a glitch of the backend does not
+// make the HPA scale to extremes.  A nil bound leaves the values unbounded on that
+// side, and min must not exceed max.  Each clamping is logged
+// and counted by the metrics_apiserver_clamped_metric_values_total metric.
+// The values fetched with the extensions for queries of the inner provider would
+// not be clamped, so it's queried as a plain CustomMetricsProvider: see
+// WrappingCustomMetricsProvider.
+func NewClampingProvider(inner CustomMetricsProvider, min, max *resource.Quantity) CustomMetricsProvider {
+	registerClampingMetrics.Do(func() {
+		legacyregistry.MustRegister(clampedValues)
+	})
+	return &clampingProvider{
+		CustomMetricsProvider: inner,
+		min:                   min,
+		max:                   max,
+	}
+}
+
+// Unwrap returns the provider whose values are clamped.
+func (p *clampingProvider) Unwrap() CustomMetricsProvider {
+	return p.CustomMetricsProvider
+}
+
+func (p *clampingProvider) GetMetricByName(ctx context.Context, name types.NamespacedName, info CustomMetricInfo, metricSelector labels.Selector) (*custom_metrics.MetricValue, error) {
+	value, err := p.CustomMetricsProvider.GetMetricByName(ctx, name, info, metricSelector)
+	if err != nil || value == nil {
+		return value, err
+	}
+	return p.clamp(info, value), nil
+}
+
+func (p *clampingProvider) GetMetricBySelector(ctx context.Context, namespace string, selector labels.Selector, info CustomMetricInfo, metricSelector labels.Selector) (*custom_metrics.MetricValueList, error) {
+	values, err := p.CustomMetricsProvider.GetMetricBySelector(ctx, namespace, selector, info, metricSelector)
+	if err != nil || values == nil {
+		return values, err
+	}
+
+	res := &custom_metrics.MetricValueList{ListMeta: values.ListMeta, Items: make([]custom_metrics.MetricValue, len(values.Items))}
+	for i := range values.Items {
+		res.Items[i] = *p.clamp(info, &values.Items[i])
+	}
+	return res, nil
+}
+
+// clamp returns the value clamped to the range, copying it rather than modifying
+// the one of the inner provider, which may be shared, for instance by a cache.
+func (p *clampingProvider) clamp(info CustomMetricInfo, value *custom_metrics.MetricValue) *custom_metrics.MetricValue {
+	var bound *resource.Quantity
+	var boundName string
+	switch {
+	case p.max != nil && value.Value.Cmp(*p.max) > 0:
+		bound, boundName = p.max, "max"
+	case p.min != nil && value.Value.Cmp(*p.min) < 0:
+		bound, boundName = p.min, "min"
+	default:
+		return value
+	}
+
+	klog.V(2).InfoS("Clamped custom metric value", "metric", info.Metric, "object", value.DescribedObject.Name,
+		"namespace", value.DescribedObject.Namespace, "value", value.Value.String(), "bound", boundName)
+	clampedValues.WithLabelValues(info.Metric, boundName).Inc()
+
+	res := value.DeepCopy()
+	res.Value = bound.DeepCopy()
+	return res
+}
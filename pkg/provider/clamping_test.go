@@ -0,0 +1,116 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/component-base/metrics/testutil"
+)
+
+func clampingTestProvider() CustomMetricsProvider {
+	low, high := resource.MustParse("-5"), resource.MustParse("1M")
+	inner := NewStaticMetricsProvider(StaticMetricsSpec{
+		CustomMetrics: []StaticCustomMetric{
+			{
+				Info:       CustomMetricInfo{GroupResource: schema.GroupResource{Resource: "pods"}, Namespaced: true, Metric: "queue_length"},
+				APIVersion: "v1",
+				Kind:       "Pod",
+				Value:      resource.MustParse("50"),
+				Objects: []StaticObject{
+					{Namespace: "default", Name: "in-range"},
+					{Namespace: "default", Name: "below-min", Value: &low},
+					{Namespace: "default", Name: "above-max", Value: &high},
+				},
+			},
+		},
+	})
+	min, max := resource.MustParse("0"), resource.MustParse("1k")
+	return NewClampingProvider(inner, &min, &max)
+}
+
+var queueLengthInfo = CustomMetricInfo{GroupResource: schema.GroupResource{Resource: "pods"}, Namespaced: true, Metric: "queue_length"}
+
+func TestClampingProviderByName(t *testing.T) {
+	prov := clampingTestProvider()
+	clampedBefore := func(bound string) float64 {
+		count, err := testutil.GetCounterMetricValue(clampedValues.WithLabelValues("queue_length", bound))
+		require.NoError(t, err)
+		return count
+	}
+	minBefore, maxBefore := clampedBefore("min"), clampedBefore("max")
+
+	cases := []struct {
+		name     string
+		expected string
+	}{
+		{name: "in-range", expected: "50"},
+		{name: "below-min", expected: "0"},
+		{name: "above-max", expected: "1k"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			value, err := prov.GetMetricByName(context.Background(), types.NamespacedName{Namespace: "default", Name: c.name}, queueLengthInfo, labels.Everything())
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, value.Value.String())
+			assert.Equal(t, c.name, value.DescribedObject.Name, "should have kept the rest of the value")
+		})
+	}
+
+	assert.Equal(t, minBefore+1, clampedBefore("min"), "should have counted the value clamped to the min")
+	assert.Equal(t, maxBefore+1, clampedBefore("max"), "should have counted the value clamped to the max")
+}
+
+func TestClampingProviderBySelector(t *testing.T) {
+	prov := clampingTestProvider()
+
+	values, err := prov.GetMetricBySelector(context.Background(), "default", labels.Everything(), queueLengthInfo, labels.Everything())
+	require.NoError(t, err)
+	byName := map[string]string{}
+	for _, value := range values.Items {
+		byName[value.DescribedObject.Name] = value.Value.String()
+	}
+	assert.Equal(t, map[string]string{"in-range": "50", "below-min": "0", "above-max": "1k"}, byName)
+}
+
+func TestClampingProviderUnbounded(t *testing.T) {
+	high := resource.MustParse("1M")
+	inner := NewStaticMetricsProvider(StaticMetricsSpec{
+		CustomMetrics: []StaticCustomMetric{
+			{Info: queueLengthInfo, Kind: "Pod", Value: high},
+		},
+	})
+	min := resource.MustParse("0")
+	prov := NewClampingProvider(inner, &min, nil)
+
+	value, err := prov.GetMetricByName(context.Background(), types.NamespacedName{Namespace: "default", Name: "foo"}, queueLengthInfo, labels.Everything())
+	require.NoError(t, err)
+	assert.Equal(t, "1M", value.Value.String(), "should not have clamped values without max")
+}
+
+func TestClampingProviderWrapsExtensions(t *testing.T) {
+	min, max := resource.MustParse("0"), resource.MustParse("1k")
+	assertWrapsExtensions(t, NewClampingProvider(&extendedProvider{}, &min, &max))
+}
@@ -0,0 +1,234 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package configmap provides a metrics provider serving the static metrics
+// declared in a ConfigMap.  The ConfigMap is watched, so that metrics can be
+// added or changed, e.g. by GitOps tools, without changing the adapter.
+package configmap
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"k8s.io/metrics/pkg/apis/custom_metrics"
+	"k8s.io/metrics/pkg/apis/external_metrics"
+	"sigs.k8s.io/yaml"
+
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
+)
+
+// MetricsKey is the key of the ConfigMap data holding the metric definitions.
+const MetricsKey = "metrics.yaml"
+
+// Definitions is the content of the MetricsKey of the ConfigMap, in YAML or JSON, e.g.:
+//
+//	customMetrics:
+//	- resource: pods
+//	  namespaced: true
+//	  metric: queue_length_target
+//	  apiVersion: v1
+//	  kind: Pod
+//	  value: "10"
+//	  objects:
+//	  - namespace: default
+//	    name: worker-1
+//	    labels:
+//	      app: worker
+//	externalMetrics:
+//	- name: queue_depth_target
+//	  labels:
+//	    queue: jobs
+//	  value: "100"
+type Definitions struct {
+	CustomMetrics   []CustomMetric   `json:"customMetrics,omitempty"`
+	ExternalMetrics []ExternalMetric `json:"externalMetrics,omitempty"`
+}
+
+// CustomMetric defines a custom metric with a constant value, as
+// provider.StaticCustomMetric does.
+type CustomMetric struct {
+	Group      string `json:"group,omitempty"`
+	Resource   string `json:"resource"`
+	Namespaced bool   `json:"namespaced,omitempty"`
+	Metric     string `json:"metric"`
+	// APIVersion and Kind of the objects described by the metric.
+	APIVersion string            `json:"apiVersion"`
+	Kind       string            `json:"kind"`
+	Value      resource.Quantity `json:"value"`
+	// Objects lists the objects returned when the metric is queried with a label selector.
+	Objects []Object `json:"objects,omitempty"`
+}
+
+// Object is an object described by a custom metric.
+type Object struct {
+	// Namespace is empty for root-scoped objects.
+	Namespace string            `json:"namespace,omitempty"`
+	Name      string            `json:"name"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	// Value overrides the value of the metric for this object, when set.
+	Value *resource.Quantity `json:"value,omitempty"`
+}
+
+// ExternalMetric defines a series of an external metric with a constant value.
+type ExternalMetric struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Value  resource.Quantity `json:"value"`
+}
+
+// parseDefinitions parses the definitions held in a ConfigMap.
+func parseDefinitions(data string) (provider.StaticMetricsSpec, error) {
+	definitions := Definitions{}
+	if err := yaml.UnmarshalStrict([]byte(data), &definitions); err != nil {
+		return provider.StaticMetricsSpec{}, err
+	}
+
+	spec := provider.StaticMetricsSpec{}
+	for i, metric := range definitions.CustomMetrics {
+		if metric.Resource == "" || metric.Metric == "" {
+			return provider.StaticMetricsSpec{}, fmt.Errorf("custom metric %d has no resource or metric name", i)
+		}
+		static := provider.StaticCustomMetric{
+			Info: provider.CustomMetricInfo{
+				GroupResource: schema.GroupResource{Group: metric.Group, Resource: metric.Resource},
+				Namespaced:    metric.Namespaced,
+				Metric:        metric.Metric,
+			},
+			APIVersion: metric.APIVersion,
+			Kind:       metric.Kind,
+			Value:      metric.Value,
+		}
+		for _, object := range metric.Objects {
+			static.Objects = append(static.Objects, provider.StaticObject(object))
+		}
+		spec.CustomMetrics = append(spec.CustomMetrics, static)
+	}
+	for i, metric := range definitions.ExternalMetrics {
+		if metric.Name == "" {
+			return provider.StaticMetricsSpec{}, fmt.Errorf("external metric %d has no name", i)
+		}
+		spec.ExternalMetrics = append(spec.ExternalMetrics, provider.StaticExternalMetric(metric))
+	}
+	return spec, nil
+}
+
+// Provider is a MetricsProvider serving the metrics defined in a ConfigMap.  It
+// notifies discovery whenever the ConfigMap changes.
+type Provider struct {
+	namespace string
+	name      string
+	changed   chan struct{}
+
+	mu      sync.RWMutex
+	current provider.MetricsProvider
+}
+
+var _ provider.MetricsProvider = &Provider{}
+var _ provider.NotifyingCustomMetricsProvider = &Provider{}
+
+// NewProvider creates a Provider serving the metrics defined in the given ConfigMap,
+// as received by the given ConfigMap informer, which the caller must start.  The
+// informer is ideally restricted to the ConfigMap, e.g. with a field selector.
+// Until the ConfigMap is received, and once it is deleted, no metrics are served.
+// When the definitions of the ConfigMap are invalid, the previous ones are kept.
+func NewProvider(informer cache.SharedIndexInformer, namespace, name string) (*Provider, error) {
+	p := &Provider{
+		namespace: namespace,
+		name:      name,
+		changed:   make(chan struct{}, 1),
+		current:   provider.NewStaticMetricsProvider(provider.StaticMetricsSpec{}),
+	}
+	_, err := informer.AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: p.isConfigMap,
+		Handler: cache.ResourceEventHandlerFuncs{
+			AddFunc:    p.update,
+			UpdateFunc: func(_, obj interface{}) { p.update(obj) },
+			DeleteFunc: func(interface{}) { p.set(provider.StaticMetricsSpec{}) },
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to watch ConfigMap %s/%s: %v", namespace, name, err)
+	}
+	return p, nil
+}
+
+func (p *Provider) isConfigMap(obj interface{}) bool {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	configMap, ok := obj.(*corev1.ConfigMap)
+	return ok && configMap.Namespace == p.namespace && configMap.Name == p.name
+}
+
+func (p *Provider) update(obj interface{}) {
+	configMap := obj.(*corev1.ConfigMap)
+	spec, err := parseDefinitions(configMap.Data[MetricsKey])
+	if err != nil {
+		klog.ErrorS(err, "Ignoring invalid metric definitions, keeping the previous ones", "configMap", klog.KObj(configMap))
+		return
+	}
+	p.set(spec)
+}
+
+func (p *Provider) set(spec provider.StaticMetricsSpec) {
+	p.mu.Lock()
+	p.current = provider.NewStaticMetricsProvider(spec)
+	p.mu.Unlock()
+
+	select {
+	case p.changed <- struct{}{}:
+	default:
+	}
+}
+
+func (p *Provider) metrics() provider.MetricsProvider {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.current
+}
+
+func (p *Provider) GetMetricByName(ctx context.Context, name types.NamespacedName, info provider.CustomMetricInfo, metricSelector labels.Selector) (*custom_metrics.MetricValue, error) {
+	return p.metrics().GetMetricByName(ctx, name, info, metricSelector)
+}
+
+func (p *Provider) GetMetricBySelector(ctx context.Context, namespace string, selector labels.Selector, info provider.CustomMetricInfo, metricSelector labels.Selector) (*custom_metrics.MetricValueList, error) {
+	return p.metrics().GetMetricBySelector(ctx, namespace, selector, info, metricSelector)
+}
+
+func (p *Provider) ListAllMetrics() []provider.CustomMetricInfo {
+	return p.metrics().ListAllMetrics()
+}
+
+// MetricsChanged signals the changes of the ConfigMap.
+func (p *Provider) MetricsChanged() <-chan struct{} {
+	return p.changed
+}
+
+func (p *Provider) GetExternalMetric(ctx context.Context, namespace string, metricSelector labels.Selector, info provider.ExternalMetricInfo) (*external_metrics.ExternalMetricValueList, error) {
+	return p.metrics().GetExternalMetric(ctx, namespace, metricSelector, info)
+}
+
+func (p *Provider) ListAllExternalMetrics() []provider.ExternalMetricInfo {
+	return p.metrics().ListAllExternalMetrics()
+}
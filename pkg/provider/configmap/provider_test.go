@@ -0,0 +1,159 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configmap
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
+)
+
+const podsDefinitions = `
+customMetrics:
+- resource: pods
+  namespaced: true
+  metric: queue_length_target
+  apiVersion: v1
+  kind: Pod
+  value: "10"
+  objects:
+  - namespace: default
+    name: worker-1
+    labels:
+      app: worker
+    value: "20"
+  - namespace: default
+    name: web-1
+    labels:
+      app: web
+`
+
+const externalDefinitions = `
+externalMetrics:
+- name: queue_depth_target
+  labels:
+    queue: jobs
+  value: "100"
+`
+
+var podsInfo = provider.CustomMetricInfo{GroupResource: schema.GroupResource{Resource: "pods"}, Namespaced: true, Metric: "queue_length_target"}
+
+func config(definitions string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "monitoring", Name: "metrics"},
+		Data:       map[string]string{MetricsKey: definitions},
+	}
+}
+
+func TestParseDefinitions(t *testing.T) {
+	spec, err := parseDefinitions(podsDefinitions + externalDefinitions)
+	require.NoError(t, err)
+	require.Len(t, spec.CustomMetrics, 1)
+	assert.Equal(t, podsInfo, spec.CustomMetrics[0].Info)
+	assert.Equal(t, "10", spec.CustomMetrics[0].Value.String())
+	require.Len(t, spec.CustomMetrics[0].Objects, 2)
+	if assert.NotNil(t, spec.CustomMetrics[0].Objects[0].Value) {
+		assert.Equal(t, "20", spec.CustomMetrics[0].Objects[0].Value.String())
+	}
+	require.Len(t, spec.ExternalMetrics, 1)
+	assert.Equal(t, map[string]string{"queue": "jobs"}, spec.ExternalMetrics[0].Labels)
+
+	for name, data := range map[string]string{
+		"unknown field":    "customMetrics:\n- resource: pods\n  metric: m\n  valeu: \"1\"\n",
+		"invalid quantity": "externalMetrics:\n- name: m\n  value: lots\n",
+		"no metric name":   "customMetrics:\n- resource: pods\n",
+		"no external name": "externalMetrics:\n- value: \"1\"\n",
+	} {
+		_, err := parseDefinitions(data)
+		assert.Error(t, err, "should have rejected definitions with %s", name)
+	}
+}
+
+func TestProviderWatchesConfigMap(t *testing.T) {
+	client := fake.NewSimpleClientset(config(podsDefinitions))
+	factory := informers.NewSharedInformerFactoryWithOptions(client, 0, informers.WithNamespace("monitoring"))
+	prov, err := NewProvider(factory.Core().V1().ConfigMaps().Informer(), "monitoring", "metrics")
+	require.NoError(t, err)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	factory.Start(stopCh)
+	factory.WaitForCacheSync(stopCh)
+	<-prov.MetricsChanged()
+
+	assert.Equal(t, []provider.CustomMetricInfo{podsInfo}, prov.ListAllMetrics(), "should have served the metrics of the ConfigMap")
+	value, err := prov.GetMetricByName(context.Background(), types.NamespacedName{Namespace: "default", Name: "worker-1"}, podsInfo, labels.Everything())
+	require.NoError(t, err)
+	assert.Equal(t, "20", value.Value.String())
+	selector, err := labels.Parse("app=web")
+	require.NoError(t, err)
+	values, err := prov.GetMetricBySelector(context.Background(), "default", selector, podsInfo, labels.Everything())
+	require.NoError(t, err)
+	if assert.Len(t, values.Items, 1) {
+		assert.Equal(t, "10", values.Items[0].Value.String(), "should have used the default value of the metric")
+	}
+
+	// update the definitions
+	_, err = client.CoreV1().ConfigMaps("monitoring").Update(context.Background(), config(externalDefinitions), metav1.UpdateOptions{})
+	require.NoError(t, err)
+	select {
+	case <-prov.MetricsChanged():
+	case <-time.After(10 * time.Second):
+		t.Fatal("should have signaled the change of the metrics")
+	}
+	assert.Empty(t, prov.ListAllMetrics(), "should have dropped the metrics removed from the ConfigMap")
+	assert.Equal(t, []provider.ExternalMetricInfo{{Metric: "queue_depth_target"}}, prov.ListAllExternalMetrics())
+	externalValues, err := prov.GetExternalMetric(context.Background(), "default", labels.Everything(), provider.ExternalMetricInfo{Metric: "queue_depth_target"})
+	require.NoError(t, err)
+	assert.Len(t, externalValues.Items, 1)
+	_, err = prov.GetMetricByName(context.Background(), types.NamespacedName{Namespace: "default", Name: "worker-1"}, podsInfo, labels.Everything())
+	assert.True(t, apierr.IsNotFound(err), "should have returned a not found error for a removed metric, got %v", err)
+
+	// invalid definitions keep the previous ones, and valid ones apply again
+	_, err = client.CoreV1().ConfigMaps("monitoring").Update(context.Background(), config("externalMetrics: {"), metav1.UpdateOptions{})
+	require.NoError(t, err)
+	_, err = client.CoreV1().ConfigMaps("monitoring").Update(context.Background(), config(podsDefinitions+externalDefinitions), metav1.UpdateOptions{})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return len(prov.ListAllMetrics()) == 1
+	}, 10*time.Second, 10*time.Millisecond, "should have applied valid definitions following invalid ones")
+	assert.Len(t, prov.ListAllExternalMetrics(), 1)
+
+	// other ConfigMaps are ignored
+	other := config("")
+	other.Name = "other"
+	assert.False(t, prov.isConfigMap(other), "should have ignored other ConfigMaps")
+
+	require.NoError(t, client.CoreV1().ConfigMaps("monitoring").Delete(context.Background(), "metrics", metav1.DeleteOptions{}))
+	assert.Eventually(t, func() bool {
+		return len(prov.ListAllMetrics()) == 0 && len(prov.ListAllExternalMetrics()) == 0
+	}, 10*time.Second, 10*time.Millisecond, "should have stopped serving metrics once the ConfigMap is deleted")
+}
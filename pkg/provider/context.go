@@ -0,0 +1,84 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/metrics/pkg/apis/custom_metrics"
+)
+
+// requestIDKey is the type of the keys of the request identifiers
+// stored in the contexts passed to providers.
+type requestIDKey int
+
+const (
+	auditIDKey requestIDKey = iota
+	traceIDKey
+	objectUIDKey
+)
+
+// WithAuditID returns a copy of ctx carrying the given audit ID.
+// The API server sets it in the context passed to providers, so that
+// they can log with the same ID as the audit log.
+func WithAuditID(ctx context.Context, auditID string) context.Context {
+	return context.WithValue(ctx, auditIDKey, auditID)
+}
+
+// AuditIDFromContext returns the audit ID of the request a provider is queried for,
+// if any.
+func AuditIDFromContext(ctx context.Context) (string, bool) {
+	auditID, ok := ctx.Value(auditIDKey).(string)
+	return auditID, ok && auditID != ""
+}
+
+// WithTraceID returns a copy of ctx carrying the given trace ID.
+// The API server sets it in the context passed to providers when the
+// request is traced, so that they can correlate their logs with the trace.
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceIDKey, traceID)
+}
+
+// TraceIDFromContext returns the ID of the trace of the request a provider is
+// queried for, if it is traced.
+func TraceIDFromContext(ctx context.Context) (string, bool) {
+	traceID, ok := ctx.Value(traceIDKey).(string)
+	return traceID, ok && traceID != ""
+}
+
+// WithObjectUID returns a copy of ctx carrying the given UID of the described object.
+// The API server sets it in the context passed to providers when the client selects
+// the object by UID too, with the metadata.uid field selector, since names are reused:
+// a value for a former object of the same name is not for the requested one.
+func WithObjectUID(ctx context.Context, uid types.UID) context.Context {
+	return context.WithValue(ctx, objectUIDKey, uid)
+}
+
+// ObjectUIDFromContext returns the UID of the object a provider is queried for, if
+// the client selected it.
+func ObjectUIDFromContext(ctx context.Context) (types.UID, bool) {
+	uid, ok := ctx.Value(objectUIDKey).(types.UID)
+	return uid, ok && uid != ""
+}
+
+// MatchesUID returns whether a value describing the given object may be for the
+// object with the given UID.  Values without UID, as returned by providers which
+// do not know them, match any UID, and any value matches an empty UID.
+func MatchesUID(object custom_metrics.ObjectReference, uid types.UID) bool {
+	return uid == "" || object.UID == "" || object.UID == uid
+}
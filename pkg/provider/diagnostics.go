@@ -0,0 +1,95 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MetricDiagnostics is the view of a provider of the collection of one of its
+// metrics, e.g. from the scrapes of its backend.
+type MetricDiagnostics struct {
+	Metric string `json:"metric"`
+	// LastSuccess is when the metric was last collected successfully.  It's nil
+	// when it never was.
+	LastSuccess *metav1.Time `json:"lastSuccess,omitempty"`
+	// LastError is the error of the last failed collection of the metric, if any,
+	// at LastErrorTime.  It's kept after later successful collections.
+	LastError     string       `json:"lastError,omitempty"`
+	LastErrorTime *metav1.Time `json:"lastErrorTime,omitempty"`
+}
+
+// DiagnosticsProvider is an optional extension of the providers which track the
+// collection of their metrics, for instance with a DiagnosticsRecorder.  When a
+// provider implements it, the debug endpoints serve its diagnostics, to find out
+// why a metric is stale.
+type DiagnosticsProvider interface {
+	// MetricDiagnostics returns the diagnostics of the metrics of the provider.
+	MetricDiagnostics() []MetricDiagnostics
+}
+
+// DiagnosticsRecorder records the collections of metrics, and implements
+// DiagnosticsProvider for the providers embedding it.  It's safe for concurrent use.
+type DiagnosticsRecorder struct {
+	mu      sync.Mutex
+	metrics map[string]*MetricDiagnostics
+}
+
+var _ DiagnosticsProvider = &DiagnosticsRecorder{}
+
+// NewDiagnosticsRecorder returns a DiagnosticsRecorder without collections.
+func NewDiagnosticsRecorder() *DiagnosticsRecorder {
+	return &DiagnosticsRecorder{metrics: make(map[string]*MetricDiagnostics)}
+}
+
+// Record records a collection of the given metric at the given time, which
+// succeeded when err is nil.
+func (r *DiagnosticsRecorder) Record(metric string, err error, at time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	diagnostics, ok := r.metrics[metric]
+	if !ok {
+		diagnostics = &MetricDiagnostics{Metric: metric}
+		r.metrics[metric] = diagnostics
+	}
+	timestamp := metav1.NewTime(at)
+	if err != nil {
+		diagnostics.LastError = err.Error()
+		diagnostics.LastErrorTime = &timestamp
+		return
+	}
+	diagnostics.LastSuccess = &timestamp
+}
+
+// MetricDiagnostics implements DiagnosticsProvider, returning the metrics sorted
+// by name.
+func (r *DiagnosticsRecorder) MetricDiagnostics() []MetricDiagnostics {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	diagnostics := make([]MetricDiagnostics, 0, len(r.metrics))
+	for _, metric := range r.metrics {
+		diagnostics = append(diagnostics, *metric)
+	}
+	sort.Slice(diagnostics, func(i, j int) bool {
+		return diagnostics[i].Metric < diagnostics[j].Metric
+	})
+	return diagnostics
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiagnosticsRecorder(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	recorder := NewDiagnosticsRecorder()
+	recorder.Record("requests", nil, start)
+	recorder.Record("requests", fmt.Errorf("scrape timed out"), start.Add(time.Minute))
+	recorder.Record("requests", nil, start.Add(2*time.Minute))
+	recorder.Record("latency", fmt.Errorf("connection refused"), start)
+
+	diagnostics := recorder.MetricDiagnostics()
+	require.Len(t, diagnostics, 2)
+
+	latency := diagnostics[0]
+	assert.Equal(t, "latency", latency.Metric, "should have sorted the metrics by name")
+	assert.Nil(t, latency.LastSuccess)
+	assert.Equal(t, "connection refused", latency.LastError)
+
+	requests := diagnostics[1]
+	require.NotNil(t, requests.LastSuccess)
+	assert.Equal(t, start.Add(2*time.Minute), requests.LastSuccess.Time)
+	assert.Equal(t, "scrape timed out", requests.LastError, "should have kept the last error after a later success")
+	require.NotNil(t, requests.LastErrorTime)
+	assert.Equal(t, start.Add(time.Minute), requests.LastErrorTime.Time)
+}
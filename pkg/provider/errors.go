@@ -18,7 +18,9 @@ package provider
 
 import (
 	"fmt"
+	"math"
 	"net/http"
+	"time"
 
 	apierr "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -59,3 +61,64 @@ func NewMetricNotFoundForSelectorError(resource schema.GroupResource, metricName
 		Message: fmt.Sprintf("the server could not find the metric %s for %s %s with selector %s", metricName, resource.String(), resourceName, selector.String()),
 	}}
 }
+
+// RetryableError is an error which the provider expects to be resolved after a delay,
+// for instance while its backend is starting.  It is reported to clients as
+// 503 Service Unavailable, with a Retry-After header set to the delay.
+type RetryableError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+// NewRetryableError returns a RetryableError indicating that the request failed
+// with the given error, and should be retried after the given delay.
+func NewRetryableError(err error, after time.Duration) *RetryableError {
+	return &RetryableError{Err: err, RetryAfter: after}
+}
+
+func (e *RetryableError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryableError) Unwrap() error {
+	return e.Err
+}
+
+// Status implements the APIStatus interface of "k8s.io/apimachinery/pkg/api/errors".
+func (e *RetryableError) Status() metav1.Status {
+	seconds := int32(math.Ceil(e.RetryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    int32(http.StatusServiceUnavailable),
+		Reason:  metav1.StatusReasonServiceUnavailable,
+		Message: e.Error(),
+		Details: &metav1.StatusDetails{
+			RetryAfterSeconds: seconds,
+		},
+	}
+}
+
+// NoDataError is an error which the provider returns for a metric which exists, but
+// has currently no data, for instance when nothing was reported in the last window.
+// It is reported to clients as an empty list of values, with a warning saying so,
+// rather than as a failure, so that they can tell it apart from a missing metric.
+type NoDataError struct {
+	Metric string
+	Reason string
+}
+
+// NewNoDataError returns a NoDataError indicating that the given metric has no data,
+// for the given reason, if any.
+func NewNoDataError(metric string, reason string) *NoDataError {
+	return &NoDataError{Metric: metric, Reason: reason}
+}
+
+func (e *NoDataError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("the metric %s has no data", e.Metric)
+	}
+	return fmt.Sprintf("the metric %s has no data: %s", e.Metric, e.Reason)
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package events provides a custom metrics provider recording Kubernetes events
+// when another provider repeatedly fails to collect metrics, so that operators
+// see the failures in kubectl describe.
+package events
+
+import (
+	"context"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/metrics/pkg/apis/custom_metrics"
+
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
+)
+
+// ReasonMetricCollectionFailed is the reason of the events recorded on failures.
+const ReasonMetricCollectionFailed = "MetricCollectionFailed"
+
+// CustomMetricsProvider is a CustomMetricsProvider recording a warning event each
+// time another one fails to get a metric for an object a number of times in a row.
+// It's a WrappingCustomMetricsProvider: the failures of queries made with the
+// extensions for queries of the other provider would not be recorded, so it's
+// queried as a plain CustomMetricsProvider.
+type CustomMetricsProvider struct {
+	provider.CustomMetricsProvider
+
+	recorder  record.EventRecorder
+	object    runtime.Object
+	threshold int
+
+	mu       sync.Mutex
+	failures map[string]int
+}
+
+var _ provider.WrappingCustomMetricsProvider = &CustomMetricsProvider{}
+
+// NewCustomMetricsProvider creates a CustomMetricsProvider recording, with the given
+// recorder, an event on the given object, e.g. the adapter's Deployment or
+// ConfigMap, whenever the given provider fails to get a metric for an object
+// threshold times in a row.  A threshold below 1 records every failure.  Not found
+// errors, which are reported to clients, are not collection failures.
+func NewCustomMetricsProvider(delegate provider.CustomMetricsProvider, recorder record.EventRecorder, object runtime.Object, threshold int) *CustomMetricsProvider {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &CustomMetricsProvider{
+		CustomMetricsProvider: delegate,
+		recorder:              recorder,
+		object:                object,
+		threshold:             threshold,
+		failures:              make(map[string]int),
+	}
+}
+
+// Unwrap returns the provider whose failures are recorded.
+func (p *CustomMetricsProvider) Unwrap() provider.CustomMetricsProvider {
+	return p.CustomMetricsProvider
+}
+
+func (p *CustomMetricsProvider) GetMetricByName(ctx context.Context, name types.NamespacedName, info provider.CustomMetricInfo, metricSelector labels.Selector) (*custom_metrics.MetricValue, error) {
+	value, err := p.CustomMetricsProvider.GetMetricByName(ctx, name, info, metricSelector)
+	key := info.String() + "/" + name.String()
+	if err == nil || apierr.IsNotFound(err) {
+		p.mu.Lock()
+		delete(p.failures, key)
+		p.mu.Unlock()
+		return value, err
+	}
+
+	p.mu.Lock()
+	p.failures[key]++
+	failures := p.failures[key]
+	p.mu.Unlock()
+
+	if failures%p.threshold == 0 {
+		p.recorder.Eventf(p.object, corev1.EventTypeWarning, ReasonMetricCollectionFailed,
+			"Failed %d times in a row to collect metric %s for %s %s: %v", failures, info.Metric, info.GroupResource.String(), name.String(), err)
+	}
+	return nil, err
+}
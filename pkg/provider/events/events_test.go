@@ -0,0 +1,134 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/metrics/pkg/apis/custom_metrics"
+
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
+)
+
+var podsInfo = provider.CustomMetricInfo{GroupResource: schema.GroupResource{Resource: "pods"}, Namespaced: true, Metric: "queue_length"}
+
+// failingProvider fails to get metrics while err is set.
+type failingProvider struct {
+	provider.CustomMetricsProvider
+	err error
+}
+
+func (p *failingProvider) GetMetricByName(_ context.Context, name types.NamespacedName, info provider.CustomMetricInfo, _ labels.Selector) (*custom_metrics.MetricValue, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &custom_metrics.MetricValue{
+		DescribedObject: custom_metrics.ObjectReference{Kind: "Pod", Namespace: name.Namespace, Name: name.Name},
+		Metric:          custom_metrics.MetricIdentifier{Name: info.Metric},
+	}, nil
+}
+
+// events returns the events recorded so far.
+func events(recorder *record.FakeRecorder) []string {
+	var recorded []string
+	for {
+		select {
+		case event := <-recorder.Events:
+			recorded = append(recorded, event)
+		default:
+			return recorded
+		}
+	}
+}
+
+func TestCustomMetricsProviderRecordsRepeatedFailures(t *testing.T) {
+	delegate := &failingProvider{err: errors.New("backend unavailable")}
+	recorder := record.NewFakeRecorder(10)
+	object := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "monitoring", Name: "adapter"}}
+	prov := NewCustomMetricsProvider(delegate, recorder, object, 3)
+	name := types.NamespacedName{Namespace: "default", Name: "worker-1"}
+
+	for i := 0; i < 2; i++ {
+		_, err := prov.GetMetricByName(context.Background(), name, podsInfo, labels.Everything())
+		assert.Error(t, err, "should have returned the error of the provider")
+	}
+	assert.Empty(t, events(recorder), "should not have recorded an event below the threshold")
+
+	_, err := prov.GetMetricByName(context.Background(), name, podsInfo, labels.Everything())
+	require.Error(t, err)
+	recorded := events(recorder)
+	if assert.Len(t, recorded, 1, "should have recorded an event at the threshold") {
+		assert.Equal(t, "Warning MetricCollectionFailed Failed 3 times in a row to collect metric queue_length for pods default/worker-1: backend unavailable", recorded[0])
+	}
+
+	// failures for other objects are counted separately
+	_, _ = prov.GetMetricByName(context.Background(), types.NamespacedName{Namespace: "default", Name: "worker-2"}, podsInfo, labels.Everything())
+	assert.Empty(t, events(recorder))
+
+	// a success resets the count
+	delegate.err = nil
+	_, err = prov.GetMetricByName(context.Background(), name, podsInfo, labels.Everything())
+	require.NoError(t, err)
+	delegate.err = errors.New("backend unavailable")
+	for i := 0; i < 2; i++ {
+		_, _ = prov.GetMetricByName(context.Background(), name, podsInfo, labels.Everything())
+	}
+	assert.Empty(t, events(recorder), "should have started counting failures again after a success")
+}
+
+func TestCustomMetricsProviderIgnoresNotFound(t *testing.T) {
+	delegate := &failingProvider{err: provider.NewMetricNotFoundForError(podsInfo.GroupResource, podsInfo.Metric, "worker-1")}
+	recorder := record.NewFakeRecorder(10)
+	prov := NewCustomMetricsProvider(delegate, recorder, &corev1.ConfigMap{}, 1)
+
+	_, err := prov.GetMetricByName(context.Background(), types.NamespacedName{Namespace: "default", Name: "worker-1"}, podsInfo, labels.Everything())
+	assert.Error(t, err)
+	assert.Empty(t, events(recorder), "should not have recorded an event for a missing metric")
+}
+
+// typedProvider knows the type of its metrics, and keys their values on UIDs.
+type typedProvider struct {
+	failingProvider
+}
+
+func (p *typedProvider) MetricType(provider.CustomMetricInfo) provider.MetricType {
+	return provider.MetricTypeGauge
+}
+
+func (p *typedProvider) GetMetricByUID(ctx context.Context, name types.NamespacedName, _ types.UID, info provider.CustomMetricInfo, metricSelector labels.Selector) (*custom_metrics.MetricValue, error) {
+	return p.GetMetricByName(ctx, name, info, metricSelector)
+}
+
+func TestCustomMetricsProviderWrapsExtensions(t *testing.T) {
+	p := NewCustomMetricsProvider(&typedProvider{}, record.NewFakeRecorder(10), &corev1.ConfigMap{}, 1)
+	_, ok := provider.As[provider.TypedCustomMetricsProvider](p)
+	assert.True(t, ok, "should have kept the type of the metrics")
+	// the failures of queries by UID would not be recorded
+	_, ok = interface{}(p).(provider.UIDCustomMetricsProvider)
+	assert.False(t, ok, "should have queried the values by name instead of UID")
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/metrics/pkg/apis/custom_metrics"
+)
+
+// EWMASmoother smooths metric values with exponentially weighted moving averages,
+// so that spiky metrics do not make the HPA scale up and down repeatedly.  It keeps
+// the average of each series, identified by a key such as the name of the described
+// object, and updates it with each newer value:
+//
+//	average = alpha*value + (1-alpha)*average
+//
+// The higher alpha, the faster the average follows the values: with samples
+// every interval, the average is as smooth as the mean of the last N samples,
+// i.e. over a window of N intervals, for alpha = 2/(N+1), which EWMAAlpha returns.
+// It weighs recent samples more than the mean does, so it follows changes sooner.
+// It is safe for concurrent use.
+type EWMASmoother struct {
+	alpha float64
+
+	mu     sync.Mutex
+	series map[string]ewmaState
+}
+
+type ewmaState struct {
+	average   float64
+	timestamp time.Time
+}
+
+// NewEWMASmoother returns an EWMASmoother with the given alpha, in (0, 1].  Other
+// alphas are replaced by 1, which does not smooth values.
+func NewEWMASmoother(alpha float64) *EWMASmoother {
+	if alpha <= 0 || alpha > 1 || math.IsNaN(alpha) {
+		alpha = 1
+	}
+	return &EWMASmoother{alpha: alpha, series: map[string]ewmaState{}}
+}
+
+// EWMAAlpha returns the alpha of an EWMASmoother as smooth as the mean of the
+// samples of the given window, for samples every interval.
+func EWMAAlpha(window, interval time.Duration) float64 {
+	if window <= interval || interval <= 0 {
+		return 1
+	}
+	samples := float64(window) / float64(interval)
+	return 2 / (samples + 1)
+}
+
+// Smooth updates the average of the series of the given key with the value, and
+// returns a copy of the value with the average as its value, in the format of the
+// value, with a precision of a thousandth.  The first value of a series is its
+// average.  Values which are not newer than the last one of the series, e.g. the
+// same one returned again by the backend, leave the average unchanged.
+func (s *EWMASmoother) Smooth(key string, value custom_metrics.MetricValue) custom_metrics.MetricValue {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sample := value.Value.AsApproximateFloat64()
+	state, ok := s.series[key]
+	switch {
+	case !ok:
+		state = ewmaState{average: sample, timestamp: value.Timestamp.Time}
+	case value.Timestamp.Time.After(state.timestamp):
+		state = ewmaState{average: s.alpha*sample + (1-s.alpha)*state.average, timestamp: value.Timestamp.Time}
+	}
+	s.series[key] = state
+
+	smoothed := *value.DeepCopy()
+	smoothed.Value = *resource.NewMilliQuantity(int64(math.Round(state.average*1000)), value.Value.Format)
+	return smoothed
+}
+
+// Reset drops the average of the series of the given key, whose next value starts
+// a new average, for instance once the described object is replaced, or deleted,
+// since averages are kept until they are reset.
+func (s *EWMASmoother) Reset(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.series, key)
+}
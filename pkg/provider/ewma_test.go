@@ -0,0 +1,110 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/metrics/pkg/apis/custom_metrics"
+)
+
+func TestEWMASmoother(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	value := func(v int64, offset time.Duration) custom_metrics.MetricValue {
+		return custom_metrics.MetricValue{
+			Metric:    custom_metrics.MetricIdentifier{Name: "queue-length"},
+			Timestamp: metav1.NewTime(start.Add(offset)),
+			Value:     *resource.NewQuantity(v, resource.DecimalSI),
+		}
+	}
+
+	for _, tc := range []struct {
+		name     string
+		alpha    float64
+		values   []custom_metrics.MetricValue
+		expected []int64
+	}{
+		{
+			name:     "sequence",
+			alpha:    0.5,
+			values:   []custom_metrics.MetricValue{value(10, 0), value(20, 15*time.Second), value(40, 30*time.Second), value(0, 45*time.Second)},
+			expected: []int64{10000, 15000, 27500, 13750},
+		},
+		{
+			name:     "low alpha",
+			alpha:    0.2,
+			values:   []custom_metrics.MetricValue{value(100, 0), value(0, 15*time.Second), value(0, 30*time.Second)},
+			expected: []int64{100000, 80000, 64000},
+		},
+		{
+			name:     "values which are not newer",
+			alpha:    0.5,
+			values:   []custom_metrics.MetricValue{value(10, 15*time.Second), value(30, 15*time.Second), value(50, 0), value(30, 30*time.Second)},
+			expected: []int64{10000, 10000, 10000, 20000},
+		},
+		{
+			name:     "invalid alpha",
+			alpha:    1.5,
+			values:   []custom_metrics.MetricValue{value(10, 0), value(30, 15*time.Second)},
+			expected: []int64{10000, 30000},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			smoother := NewEWMASmoother(tc.alpha)
+			for i, v := range tc.values {
+				smoothed := smoother.Smooth("pod-1", v)
+				assert.Equal(t, tc.expected[i], smoothed.Value.MilliValue(), "value %d", i)
+				assert.Equal(t, v.Timestamp, smoothed.Timestamp)
+				assert.Equal(t, v.Metric, smoothed.Metric)
+			}
+		})
+	}
+}
+
+func TestEWMASmootherSeries(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	value := func(v int64, offset time.Duration) custom_metrics.MetricValue {
+		return custom_metrics.MetricValue{
+			Timestamp: metav1.NewTime(start.Add(offset)),
+			Value:     *resource.NewQuantity(v, resource.DecimalSI),
+		}
+	}
+
+	smoother := NewEWMASmoother(0.5)
+	smoother.Smooth("pod-1", value(10, 0))
+	smoother.Smooth("pod-2", value(100, 0))
+
+	smoothed := smoother.Smooth("pod-1", value(30, 15*time.Second))
+	assert.Equal(t, int64(20000), smoothed.Value.MilliValue())
+	smoothed = smoother.Smooth("pod-2", value(0, 15*time.Second))
+	assert.Equal(t, int64(50000), smoothed.Value.MilliValue())
+
+	smoother.Reset("pod-1")
+	smoothed = smoother.Smooth("pod-1", value(70, 30*time.Second))
+	assert.Equal(t, int64(70000), smoothed.Value.MilliValue())
+}
+
+func TestEWMAAlpha(t *testing.T) {
+	assert.InDelta(t, 0.5, EWMAAlpha(45*time.Second, 15*time.Second), 1e-9)
+	assert.InDelta(t, 2.0/11, EWMAAlpha(10*time.Minute, time.Minute), 1e-9)
+	assert.Equal(t, 1.0, EWMAAlpha(15*time.Second, 15*time.Second))
+	assert.Equal(t, 1.0, EWMAAlpha(time.Minute, 0))
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+
+	"golang.org/x/sync/errgroup"
+
+	"k8s.io/metrics/pkg/apis/custom_metrics"
+)
+
+// BoundedFanout runs the per-object fetches of providers which fan out selector
+// queries into one backend call per object, with a bounded concurrency.  The bound
+// is shared by all the queries run with the same BoundedFanout, so that the backend
+// is protected from concurrent queries as well as from large ones.
+type BoundedFanout struct {
+	slots chan struct{}
+}
+
+// NewBoundedFanout creates a BoundedFanout running at most maxConcurrency fetches
+// at a time.  A maxConcurrency lower than 1 is treated as 1.
+func NewBoundedFanout(maxConcurrency int) *BoundedFanout {
+	if maxConcurrency < 1 {
+		maxConcurrency = 1
+	}
+	return &BoundedFanout{slots: make(chan struct{}, maxConcurrency)}
+}
+
+// GetMetricValues calls fetch for each of the given object names, and returns the
+// fetched values in the order of the names.  fetch may return a nil value to skip
+// an object, for instance one without the metric.
+//
+// The first error returned by fetch is returned, and cancels the context passed to
+// the other fetches; fetches which have not started yet are not started.  Likewise,
+// no fetch is started once ctx is done, in which case its error is returned.
+func (f *BoundedFanout) GetMetricValues(ctx context.Context, names []string, fetch func(ctx context.Context, name string) (*custom_metrics.MetricValue, error)) (*custom_metrics.MetricValueList, error) {
+	group, groupCtx := errgroup.WithContext(ctx)
+	values := make([]*custom_metrics.MetricValue, len(names))
+
+start:
+	for i, name := range names {
+		select {
+		case f.slots <- struct{}{}:
+		case <-groupCtx.Done():
+			break start
+		}
+		if groupCtx.Err() != nil {
+			// the slot may have been freed by a canceled fetch
+			<-f.slots
+			break
+		}
+
+		i, name := i, name
+		group.Go(func() error {
+			defer func() { <-f.slots }()
+			value, err := fetch(groupCtx, name)
+			if err != nil {
+				return err
+			}
+			values[i] = value
+			return nil
+		})
+	}
+
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	res := &custom_metrics.MetricValueList{Items: make([]custom_metrics.MetricValue, 0, len(values))}
+	for _, value := range values {
+		if value != nil {
+			res.Items = append(res.Items, *value)
+		}
+	}
+	return res, nil
+}
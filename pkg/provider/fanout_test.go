@@ -0,0 +1,114 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/metrics/pkg/apis/custom_metrics"
+)
+
+func objectNames(n int) []string {
+	names := make([]string, n)
+	for i := range names {
+		names[i] = fmt.Sprintf("pod-%d", i)
+	}
+	return names
+}
+
+func TestBoundedFanoutConcurrency(t *testing.T) {
+	fanout := NewBoundedFanout(2)
+	var running, maxRunning atomic.Int32
+	fetch := func(_ context.Context, name string) (*custom_metrics.MetricValue, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			max := maxRunning.Load()
+			if n <= max || maxRunning.CompareAndSwap(max, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		if name == "pod-3" {
+			return nil, nil
+		}
+		return &custom_metrics.MetricValue{DescribedObject: custom_metrics.ObjectReference{Name: name}}, nil
+	}
+
+	// the bound is shared by concurrent queries
+	var wg sync.WaitGroup
+	for query := 0; query < 3; query++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			values, err := fanout.GetMetricValues(context.Background(), objectNames(10), fetch)
+			if assert.NoError(t, err) && assert.Len(t, values.Items, 9, "should have skipped the nil value") {
+				assert.Equal(t, "pod-0", values.Items[0].DescribedObject.Name)
+				assert.Equal(t, "pod-4", values.Items[3].DescribedObject.Name, "should have kept the values in the order of the names")
+			}
+		}()
+	}
+	wg.Wait()
+	assert.LessOrEqual(t, maxRunning.Load(), int32(2), "should have run at most 2 fetches at a time")
+}
+
+func TestBoundedFanoutError(t *testing.T) {
+	fanout := NewBoundedFanout(3)
+	failure := errors.New("backend unavailable")
+	var started, canceled atomic.Int32
+	fetch := func(ctx context.Context, name string) (*custom_metrics.MetricValue, error) {
+		started.Add(1)
+		if name == "pod-2" {
+			return nil, failure
+		}
+		<-ctx.Done()
+		canceled.Add(1)
+		return nil, ctx.Err()
+	}
+
+	_, err := fanout.GetMetricValues(context.Background(), objectNames(10), fetch)
+	assert.Equal(t, failure, err, "should have returned the first error")
+	assert.Equal(t, started.Load()-1, canceled.Load(), "should have canceled the other fetches")
+	assert.Less(t, started.Load(), int32(10), "should not have started fetches after the error")
+}
+
+func TestBoundedFanoutCancellation(t *testing.T) {
+	fanout := NewBoundedFanout(2)
+	ctx, cancel := context.WithCancel(context.Background())
+	var started atomic.Int32
+	fetch := func(ctx context.Context, _ string) (*custom_metrics.MetricValue, error) {
+		if started.Add(1) == 2 {
+			cancel()
+		}
+		<-ctx.Done()
+		return &custom_metrics.MetricValue{}, nil
+	}
+
+	_, err := fanout.GetMetricValues(ctx, objectNames(10), fetch)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.Canceled), "should have returned the cancellation, got %v", err)
+	assert.Equal(t, int32(2), started.Load(), "should not have started fetches once canceled")
+}
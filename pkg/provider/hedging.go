@@ -0,0 +1,135 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"time"
+
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"k8s.io/metrics/pkg/apis/custom_metrics"
+)
+
+type hedgingProvider struct {
+	CustomMetricsProvider
+
+	delay       time.Duration
+	maxAttempts int
+}
+
+// NewHedgingProvider creates a CustomMetricsProvider hedging the calls of the inner
+// one, to cut the tail latency of backends with occasional slow queries: when an
+// attempt takes longer than delay, another one is started, up to maxAttempts in all,
+// and the first value returned wins.  The context of the other attempts is then
+// canceled.  An attempt failing starts the next one right away, and the error of the
+// last one is returned once all failed, except for not found errors, which are
+// returned as is, since another attempt would not find the metric either.  With a
+// maxAttempts lower than 2, or a delay which is not positive, calls are not hedged.
+//
+// Since a call may run several times concurrently, it is only safe for providers
+// whose queries have no side effects, which is the case of the read-only queries of
+// most backends.  Hedging also multiplies the load of the backend when it is slow
+// for all queries, so delay should be set well above its usual latency, e.g. to its
+// 95th percentile.
+//
+// Only the queries of CustomMetricsProvider are hedged, so the inner provider is
+// queried as such, but keeps the extensions describing its metrics, as documented
+// by WrappingCustomMetricsProvider.
+func NewHedgingProvider(inner CustomMetricsProvider, delay time.Duration, maxAttempts int) CustomMetricsProvider {
+	return &hedgingProvider{
+		CustomMetricsProvider: inner,
+		delay:                 delay,
+		maxAttempts:           maxAttempts,
+	}
+}
+
+// Unwrap returns the provider whose calls are hedged.
+func (p *hedgingProvider) Unwrap() CustomMetricsProvider {
+	return p.CustomMetricsProvider
+}
+
+func (p *hedgingProvider) GetMetricByName(ctx context.Context, name types.NamespacedName, info CustomMetricInfo, metricSelector labels.Selector) (*custom_metrics.MetricValue, error) {
+	return hedge(ctx, p.delay, p.maxAttempts, info, func(ctx context.Context) (*custom_metrics.MetricValue, error) {
+		return p.CustomMetricsProvider.GetMetricByName(ctx, name, info, metricSelector)
+	})
+}
+
+func (p *hedgingProvider) GetMetricBySelector(ctx context.Context, namespace string, selector labels.Selector, info CustomMetricInfo, metricSelector labels.Selector) (*custom_metrics.MetricValueList, error) {
+	return hedge(ctx, p.delay, p.maxAttempts, info, func(ctx context.Context) (*custom_metrics.MetricValueList, error) {
+		return p.CustomMetricsProvider.GetMetricBySelector(ctx, namespace, selector, info, metricSelector)
+	})
+}
+
+// hedge runs call, and runs it again after each delay while it did not return,
+// up to maxAttempts times, returning the first result.
+func hedge[T any](ctx context.Context, delay time.Duration, maxAttempts int, info CustomMetricInfo, call func(ctx context.Context) (T, error)) (T, error) {
+	if maxAttempts < 2 || delay <= 0 {
+		return call(ctx)
+	}
+
+	// the attempts which lose are canceled once the first one returns
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		value T
+		err   error
+	}
+	// buffered so that the attempts which lose do not block
+	results := make(chan result, maxAttempts)
+	started := 0
+	var next <-chan time.Time
+	start := func() {
+		started++
+		if started > 1 {
+			klog.V(4).InfoS("Hedging provider call", "metric", info.Metric, "attempt", started)
+		}
+		go func() {
+			value, err := call(ctx)
+			results <- result{value: value, err: err}
+		}()
+		next = nil
+		if started < maxAttempts {
+			next = time.After(delay)
+		}
+	}
+
+	start()
+	var zero T
+	for failed := 0; ; {
+		select {
+		case res := <-results:
+			if res.err == nil || apierr.IsNotFound(res.err) {
+				return res.value, res.err
+			}
+			failed++
+			if failed == maxAttempts {
+				return zero, res.err
+			}
+			if started < maxAttempts {
+				start()
+			}
+		case <-next:
+			start()
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/metrics/pkg/apis/custom_metrics"
+)
+
+// hedgingTestProvider runs the attempt of the given index for each call.
+type hedgingTestProvider struct {
+	CustomMetricsProvider
+
+	mu       sync.Mutex
+	calls    int
+	attempts []func(ctx context.Context) (*custom_metrics.MetricValue, error)
+}
+
+func (p *hedgingTestProvider) GetMetricByName(ctx context.Context, name types.NamespacedName, info CustomMetricInfo, metricSelector labels.Selector) (*custom_metrics.MetricValue, error) {
+	p.mu.Lock()
+	attempt := p.attempts[p.calls]
+	p.calls++
+	p.mu.Unlock()
+	return attempt(ctx)
+}
+
+func (p *hedgingTestProvider) GetMetricBySelector(ctx context.Context, namespace string, selector labels.Selector, info CustomMetricInfo, metricSelector labels.Selector) (*custom_metrics.MetricValueList, error) {
+	value, err := p.GetMetricByName(ctx, types.NamespacedName{Namespace: namespace}, info, metricSelector)
+	if err != nil {
+		return nil, err
+	}
+	return &custom_metrics.MetricValueList{Items: []custom_metrics.MetricValue{*value}}, nil
+}
+
+func (p *hedgingTestProvider) callCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls
+}
+
+func returning(value string) func(ctx context.Context) (*custom_metrics.MetricValue, error) {
+	return func(ctx context.Context) (*custom_metrics.MetricValue, error) {
+		return &custom_metrics.MetricValue{Value: resource.MustParse(value)}, nil
+	}
+}
+
+func failing(err error) func(ctx context.Context) (*custom_metrics.MetricValue, error) {
+	return func(ctx context.Context) (*custom_metrics.MetricValue, error) {
+		return nil, err
+	}
+}
+
+func TestHedgingProviderSlowAttempt(t *testing.T) {
+	canceled := make(chan struct{})
+	inner := &hedgingTestProvider{attempts: []func(ctx context.Context) (*custom_metrics.MetricValue, error){
+		func(ctx context.Context) (*custom_metrics.MetricValue, error) {
+			<-ctx.Done()
+			close(canceled)
+			return nil, ctx.Err()
+		},
+		returning("2"),
+	}}
+	prov := NewHedgingProvider(inner, 10*time.Millisecond, 3)
+
+	value, err := prov.GetMetricByName(context.Background(), types.NamespacedName{Namespace: "default", Name: "foo"}, queueLengthInfo, labels.Everything())
+	require.NoError(t, err)
+	assert.Equal(t, "2", value.Value.String(), "should have returned the value of the second attempt")
+	select {
+	case <-canceled:
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("should have canceled the slow attempt")
+	}
+	assert.Equal(t, 2, inner.callCount(), "should not have started a third attempt")
+}
+
+func TestHedgingProviderSelector(t *testing.T) {
+	inner := &hedgingTestProvider{attempts: []func(ctx context.Context) (*custom_metrics.MetricValue, error){
+		func(ctx context.Context) (*custom_metrics.MetricValue, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+		returning("2"),
+	}}
+	prov := NewHedgingProvider(inner, 10*time.Millisecond, 2)
+
+	values, err := prov.GetMetricBySelector(context.Background(), "default", labels.Everything(), queueLengthInfo, labels.Everything())
+	require.NoError(t, err)
+	require.Len(t, values.Items, 1)
+	assert.Equal(t, "2", values.Items[0].Value.String(), "should have returned the values of the second attempt")
+}
+
+func TestHedgingProviderAttempts(t *testing.T) {
+	backendErr := fmt.Errorf("backend unavailable")
+	notFound := NewMetricNotFoundError(queueLengthInfo.GroupResource, queueLengthInfo.Metric)
+
+	cases := []struct {
+		name          string
+		maxAttempts   int
+		attempts      []func(ctx context.Context) (*custom_metrics.MetricValue, error)
+		expectedValue string
+		expectedErr   error
+		expectedCalls int
+	}{
+		{
+			name:          "fast first attempt",
+			maxAttempts:   3,
+			attempts:      []func(ctx context.Context) (*custom_metrics.MetricValue, error){returning("1")},
+			expectedValue: "1",
+			expectedCalls: 1,
+		},
+		{
+			name:          "failed first attempt",
+			maxAttempts:   3,
+			attempts:      []func(ctx context.Context) (*custom_metrics.MetricValue, error){failing(backendErr), returning("2")},
+			expectedValue: "2",
+			expectedCalls: 2,
+		},
+		{
+			name:          "all attempts failed",
+			maxAttempts:   2,
+			attempts:      []func(ctx context.Context) (*custom_metrics.MetricValue, error){failing(fmt.Errorf("first")), failing(backendErr)},
+			expectedErr:   backendErr,
+			expectedCalls: 2,
+		},
+		{
+			name:          "not found",
+			maxAttempts:   3,
+			attempts:      []func(ctx context.Context) (*custom_metrics.MetricValue, error){failing(notFound)},
+			expectedErr:   notFound,
+			expectedCalls: 1,
+		},
+		{
+			name:          "not hedged",
+			maxAttempts:   1,
+			attempts:      []func(ctx context.Context) (*custom_metrics.MetricValue, error){failing(backendErr)},
+			expectedErr:   backendErr,
+			expectedCalls: 1,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			inner := &hedgingTestProvider{attempts: c.attempts}
+			// long enough for the attempts to only be started by failures
+			prov := NewHedgingProvider(inner, time.Hour, c.maxAttempts)
+
+			value, err := prov.GetMetricByName(context.Background(), types.NamespacedName{Namespace: "default", Name: "foo"}, queueLengthInfo, labels.Everything())
+			if c.expectedErr != nil {
+				assert.Equal(t, c.expectedErr, err)
+			} else if assert.NoError(t, err) {
+				assert.Equal(t, c.expectedValue, value.Value.String())
+			}
+			assert.Equal(t, c.expectedCalls, inner.callCount())
+		})
+	}
+}
+
+func TestHedgingProviderWrapsExtensions(t *testing.T) {
+	assertWrapsExtensions(t, NewHedgingProvider(&extendedProvider{}, time.Second, 2))
+}
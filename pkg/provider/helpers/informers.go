@@ -0,0 +1,42 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/client-go/tools/cache"
+
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
+)
+
+// cacheSyncRetryAfter is the delay after which clients are asked to retry
+// requests which timed out waiting for informer caches to sync.
+const cacheSyncRetryAfter = 5 * time.Second
+
+// WaitForCacheSync waits for the given informer caches to sync, for no longer than
+// the request context allows, so that requests on cold informers do not outlive the
+// deadline of their client.  If the context is done first, it returns a RetryableError,
+// which is reported to the client as 503 Service Unavailable.
+func WaitForCacheSync(ctx context.Context, cacheSyncs ...cache.InformerSynced) error {
+	if !cache.WaitForCacheSync(ctx.Done(), cacheSyncs...) {
+		return provider.NewRetryableError(fmt.Errorf("informer caches did not sync in time: %w", ctx.Err()), cacheSyncRetryAfter)
+	}
+	return nil
+}
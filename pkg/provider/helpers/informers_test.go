@@ -0,0 +1,49 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+)
+
+func TestWaitForCacheSync(t *testing.T) {
+	t.Run("synced", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		err := WaitForCacheSync(ctx, func() bool { return true })
+		assert.NoError(t, err, "should have returned once the caches synced")
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		err := WaitForCacheSync(ctx, func() bool { return true }, func() bool { return false })
+		assert.Less(t, time.Since(start), 2*time.Second, "should have returned once the deadline fired")
+		assert.True(t, apierr.IsServiceUnavailable(err), "should have returned a service unavailable error, got %v", err)
+		assert.True(t, errors.Is(err, context.DeadlineExceeded), "should have wrapped the context error, got %v", err)
+	})
+}
@@ -20,7 +20,10 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/prometheus/client_golang/prometheus"
+
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
@@ -32,8 +35,11 @@ import (
 // fully-qualified group resource.
 type CustomMetricInfo struct {
 	GroupResource schema.GroupResource
-	Namespaced    bool
-	Metric        string
+	// Namespaced is false for metrics requested outside of any namespace, as
+	// for root-scoped (cluster-scoped) objects such as nodes, e.g. at
+	// /nodes/node-1/cpu_usage.  Their namespace is then always empty.
+	Namespaced bool
+	Metric     string
 }
 
 // ExternalMetricInfo describes a metric.
@@ -83,11 +89,16 @@ func (i CustomMetricInfo) Normalized(mapper apimeta.RESTMapper) (normalizedInfo
 // wish to simply make use of stored information in their TSDB.
 type CustomMetricsProvider interface {
 	// GetMetricByName fetches a particular metric for a particular object.
-	// The namespace will be empty if the metric is root-scoped.
+	// The namespace will be empty if the metric is root-scoped, in which case
+	// info.Namespaced is false: root-scoped objects, such as nodes, are identified
+	// by their name alone.
 	GetMetricByName(ctx context.Context, name types.NamespacedName, info CustomMetricInfo, metricSelector labels.Selector) (*custom_metrics.MetricValue, error)
 
 	// GetMetricBySelector fetches a particular metric for a set of objects matching
 	// the given label selector.  The namespace will be empty if the metric is root-scoped.
+	// When the selector matches no objects, it should return an empty list (a nil list
+	// is treated as such), rather than an error: errors are for failed queries, and
+	// are reported to the client as such.
 	GetMetricBySelector(ctx context.Context, namespace string, selector labels.Selector, info CustomMetricInfo, metricSelector labels.Selector) (*custom_metrics.MetricValueList, error)
 
 	// ListAllMetrics provides a list of all available metrics at
@@ -102,6 +113,11 @@ type CustomMetricsProvider interface {
 // implementation how to translate metricSelector to a filter for metric values.
 // Namespace can be used by the implemetation for metric identification, access control or ignored.
 type ExternalMetricsProvider interface {
+	// GetExternalMetric fetches the values of an external metric matching the given
+	// metric selector.  When no values match, it should return an empty list (a nil list
+	// is treated as such), rather than an error.
+	// The values are returned to the client as is, one per matching series: providers
+	// which want to combine them can use AggregateExternalValues.
 	GetExternalMetric(ctx context.Context, namespace string, metricSelector labels.Selector, info ExternalMetricInfo) (*external_metrics.ExternalMetricValueList, error)
 
 	// ListAllExternalMetrics provides a list of all available
@@ -112,6 +128,239 @@ type ExternalMetricsProvider interface {
 	ListAllExternalMetrics() []ExternalMetricInfo
 }
 
+// StreamingExternalMetricsProvider is an optional extension of ExternalMetricsProvider
+// for metrics producing very large lists of values.  When a provider implements it,
+// external metric values are sent to the client incrementally as they are received,
+// instead of being buffered into a single list first.
+type StreamingExternalMetricsProvider interface {
+	ExternalMetricsProvider
+
+	// StreamExternalMetric fetches the values of an external metric incrementally.
+	// Values are sent on the returned channel, which the implementor must close once
+	// all values have been sent, or once ctx is done.  Errors can only be reported
+	// before streaming starts, since the response status has been sent by then.
+	StreamExternalMetric(ctx context.Context, namespace string, metricSelector labels.Selector, info ExternalMetricInfo) (<-chan external_metrics.ExternalMetricValue, error)
+}
+
+// NotifyingCustomMetricsProvider is an optional extension of CustomMetricsProvider
+// for providers whose set of metrics changes over time.  When a provider implements
+// it, discovery caches the metrics returned by ListAllMetrics, and lists them again
+// only once the provider signals a change, refreshing the OpenAPI documents of the
+// server, which list the metrics, along with it.
+type NotifyingCustomMetricsProvider interface {
+	CustomMetricsProvider
+
+	// MetricsChanged returns a channel on which the implementor sends a value each
+	// time the set of metrics returned by ListAllMetrics changes.  Signals are
+	// coalesced, so implementors should send without blocking, for instance on a
+	// channel buffered with a capacity of 1.  Closing the channel disables caching.
+	MetricsChanged() <-chan struct{}
+}
+
+// UIDCustomMetricsProvider is an optional extension of CustomMetricsProvider for
+// providers keying the values of objects on their UID, which unlike names are never
+// reused.  When a provider implements it, and the client selects the object by UID
+// too, with the metadata.uid field selector, values for single objects are fetched
+// with GetMetricByUID instead of GetMetricByName.
+type UIDCustomMetricsProvider interface {
+	CustomMetricsProvider
+
+	// GetMetricByUID fetches a particular metric for the object with the given name
+	// and UID.  Values for former objects of the same name must not be returned.
+	GetMetricByUID(ctx context.Context, name types.NamespacedName, uid types.UID, info CustomMetricInfo, metricSelector labels.Selector) (*custom_metrics.MetricValue, error)
+}
+
+// ObjectCustomMetricsProvider is an optional extension of CustomMetricsProvider for
+// providers serving metrics for objects of several kinds, which need the kind of the
+// described object.  When a provider implements it, and the adapter has a RESTMapper,
+// values for single objects are fetched with GetMetricByObject instead of
+// GetMetricByName.
+type ObjectCustomMetricsProvider interface {
+	CustomMetricsProvider
+
+	// GetMetricByObject fetches a particular metric for a particular object, whose
+	// reference holds its API version, kind, namespace and name.  The reference can be
+	// used as is as the described object of the returned value.
+	GetMetricByObject(ctx context.Context, object custom_metrics.ObjectReference, info CustomMetricInfo, metricSelector labels.Selector) (*custom_metrics.MetricValue, error)
+}
+
+// MultiMetricCustomMetricsProvider is an optional extension of CustomMetricsProvider
+// for providers able to fetch several metrics of the same objects in one backend
+// query.  Clients can request several metrics at once by separating their names
+// with commas, e.g. pods/*/cpu_usage,memory_usage.  When a provider implements it,
+// such requests are passed to it in one call; otherwise, the metrics are fetched
+// one after the other.
+type MultiMetricCustomMetricsProvider interface {
+	CustomMetricsProvider
+
+	// GetMetricsByName fetches the given metrics for a particular object.  The infos
+	// only differ by their metric.
+	GetMetricsByName(ctx context.Context, name types.NamespacedName, infos []CustomMetricInfo, metricSelector labels.Selector) (*custom_metrics.MetricValueList, error)
+
+	// GetMetricsBySelector fetches the given metrics for a set of objects matching
+	// the given label selector, like GetMetricBySelector.  The infos only differ by
+	// their metric.
+	GetMetricsBySelector(ctx context.Context, namespace string, selector labels.Selector, infos []CustomMetricInfo, metricSelector labels.Selector) (*custom_metrics.MetricValueList, error)
+}
+
+// MetricCapabilities describes the queries a provider supports for a custom metric.
+type MetricCapabilities struct {
+	// ByName is whether values can be fetched for single objects, with GetMetricByName.
+	ByName bool
+	// BySelector is whether values can be fetched for the objects matching a label
+	// selector, with GetMetricBySelector.
+	BySelector bool
+}
+
+// CapableCustomMetricsProvider is an optional extension of CustomMetricsProvider
+// for providers which only support some queries for some metrics.  When a provider
+// implements it, discovery only advertises the verbs matching the supported queries,
+// and the other queries are rejected without calling the provider.
+type CapableCustomMetricsProvider interface {
+	CustomMetricsProvider
+
+	// Capabilities returns the queries supported for the given metric, one of the
+	// metrics returned by ListAllMetrics.
+	Capabilities(info CustomMetricInfo) MetricCapabilities
+}
+
+// MetricType hints how the values of a metric evolve, for consumers which treat
+// them differently.
+type MetricType string
+
+const (
+	// MetricTypeUnspecified is the type of metrics without hint.
+	MetricTypeUnspecified MetricType = ""
+	// MetricTypeGauge is the type of metrics whose values go up and down.
+	MetricTypeGauge MetricType = "gauge"
+	// MetricTypeCounter is the type of metrics whose values only increase,
+	// except when they are reset.
+	MetricTypeCounter MetricType = "counter"
+)
+
+// TypedCustomMetricsProvider is an optional extension of CustomMetricsProvider
+// for providers which know the type of their metrics.  When a provider implements
+// it, discovery advertises the type of each metric as its category.
+type TypedCustomMetricsProvider interface {
+	CustomMetricsProvider
+
+	// MetricType returns the type of the given metric, one of the metrics returned
+	// by ListAllMetrics, or MetricTypeUnspecified if it is unknown.
+	MetricType(info CustomMetricInfo) MetricType
+}
+
+// MetricDescription documents a custom metric for its consumers.
+type MetricDescription struct {
+	// Description explains what the metric measures.
+	Description string
+	// Unit is the unit of the values of the metric, such as "requests/s".
+	Unit string
+	// ExampleSelector is an example of a metric label selector for the metric.
+	ExampleSelector string
+}
+
+// DescribedCustomMetricsProvider is an optional extension of CustomMetricsProvider
+// for providers which document their metrics.  When a provider implements it,
+// the metrics catalog includes their description.
+type DescribedCustomMetricsProvider interface {
+	CustomMetricsProvider
+
+	// DescribeMetric returns the description of the given metric, one of the
+	// metrics returned by ListAllMetrics.
+	DescribeMetric(info CustomMetricInfo) MetricDescription
+}
+
+// InstrumentedProvider is an optional extension of the providers which expose
+// their own Prometheus collectors, for instance to report the latency of their
+// backend.  When a provider implements it, or implements prometheus.Collector
+// itself, the adapter registers its collectors on its metrics endpoint.
+type InstrumentedProvider interface {
+	// Collectors returns the collectors of the provider.
+	Collectors() []prometheus.Collector
+}
+
+// WrappingCustomMetricsProvider is an optional extension of CustomMetricsProvider
+// for decorators, such as the caching or retrying ones, passing the queries to the
+// provider they wrap.  The extensions describing the metrics or the provider, i.e.
+// NotifyingCustomMetricsProvider, CapableCustomMetricsProvider,
+// TypedCustomMetricsProvider, DescribedCustomMetricsProvider, DiagnosticsProvider
+// and InstrumentedProvider, are looked up on the wrapped providers with As, so that
+// decorators keep them.  The extensions for queries, i.e. UIDCustomMetricsProvider,
+// ObjectCustomMetricsProvider and MultiMetricCustomMetricsProvider, are not: queries
+// go through the methods of the decorator, and so use the wrapped provider as a
+// plain CustomMetricsProvider, unless the decorator implements them itself.
+type WrappingCustomMetricsProvider interface {
+	CustomMetricsProvider
+
+	// Unwrap returns the wrapped provider.
+	Unwrap() CustomMetricsProvider
+}
+
+// WrappingExternalMetricsProvider is an optional extension of ExternalMetricsProvider
+// for decorators, like WrappingCustomMetricsProvider.  StreamingExternalMetricsProvider
+// is not kept by decorators, unless they implement it themselves.
+type WrappingExternalMetricsProvider interface {
+	ExternalMetricsProvider
+
+	// Unwrap returns the wrapped provider.
+	Unwrap() ExternalMetricsProvider
+}
+
+// CustomMetricTransformFunc transforms a custom metric value before it is returned
+// to the client, for instance to convert its unit.  The info describes the requested
+// metric.  Returning an error fails the whole request.
+type CustomMetricTransformFunc func(info CustomMetricInfo, value custom_metrics.MetricValue) (custom_metrics.MetricValue, error)
+
+// ExternalMetricTransformFunc transforms an external metric value before it is returned
+// to the client, for instance to convert its unit.  The info describes the requested
+// metric.  Returning an error fails the whole request.
+type ExternalMetricTransformFunc func(info ExternalMetricInfo, value external_metrics.ExternalMetricValue) (external_metrics.ExternalMetricValue, error)
+
+// CustomMetricQuery describes a request for a custom metric, as parsed by the API
+// server before passing it to the provider.
+type CustomMetricQuery struct {
+	Info CustomMetricInfo
+	// Namespace is empty for metrics of root-scoped objects.
+	Namespace string
+	// Name is the name of the described object, or "*" for requests selecting the
+	// described objects with Selector.
+	Name           string
+	Selector       labels.Selector
+	MetricSelector labels.Selector
+}
+
+// ExternalMetricQuery describes a request for an external metric, as parsed by the
+// API server before passing it to the provider.
+type ExternalMetricQuery struct {
+	Info           ExternalMetricInfo
+	Namespace      string
+	MetricSelector labels.Selector
+}
+
+// CustomMetricQueryValidatorFunc validates a custom metric query before it is passed
+// to the provider, for instance to forbid selecting all the objects of a namespace
+// for an expensive metric.  Returning an error rejects the request: errors
+// implementing the APIStatus interface of "k8s.io/apimachinery/pkg/api/errors", such
+// as the ones built with its NewForbidden, are returned as is, and other errors as
+// 400 Bad Request.  Requests for several metrics have a query per metric.
+type CustomMetricQueryValidatorFunc func(ctx context.Context, query CustomMetricQuery) error
+
+// ExternalMetricQueryValidatorFunc validates an external metric query before it is
+// passed to the provider, for instance to require a selector for a metric with many
+// series.  Errors are returned like the ones of CustomMetricQueryValidatorFunc.
+type ExternalMetricQueryValidatorFunc func(ctx context.Context, query ExternalMetricQuery) error
+
+// CustomMetricDiscoveryFilterFunc filters the custom metrics listed in discovery,
+// for instance to hide deprecated metrics which are still served.  It returns
+// false to drop the metric, and may annotate the resource describing it, e.g.
+// with categories.  The info describes the listed metric.
+type CustomMetricDiscoveryFilterFunc func(info CustomMetricInfo, resource *metav1.APIResource) bool
+
+// ExternalMetricDiscoveryFilterFunc filters the external metrics listed in
+// discovery, for instance to hide deprecated metrics which are still served.  It
+// returns false to drop the metric, and may annotate the resource describing it.
+type ExternalMetricDiscoveryFilterFunc func(info ExternalMetricInfo, resource *metav1.APIResource) bool
+
 type MetricsProvider interface {
 	CustomMetricsProvider
 	ExternalMetricsProvider
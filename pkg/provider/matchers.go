@@ -0,0 +1,115 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+// MatchType is the operator of a Prometheus label matcher.
+type MatchType string
+
+const (
+	// MatchEqual matches the labels equal to the value.
+	MatchEqual MatchType = "="
+	// MatchNotEqual matches the labels not equal to the value.
+	MatchNotEqual MatchType = "!="
+	// MatchRegexp matches the labels fully matching the regular expression of the value.
+	MatchRegexp MatchType = "=~"
+	// MatchNotRegexp matches the labels not fully matching the regular expression of the value.
+	MatchNotRegexp MatchType = "!~"
+)
+
+// Matcher is a Prometheus label matcher, as in the selectors of PromQL.
+type Matcher struct {
+	Name  string
+	Type  MatchType
+	Value string
+}
+
+// String formats the matcher in PromQL, e.g. code!="500".
+func (m Matcher) String() string {
+	return m.Name + string(m.Type) + strconv.Quote(m.Value)
+}
+
+// labelNameRE matches the valid Prometheus label names.
+var labelNameRE = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// LabelsSelectorToMatchers converts a label selector, such as the metric selector
+// of a request, to the equivalent Prometheus label matchers, ordered by label
+// name.  Matchers on the absence of a label match the empty value, as in PromQL,
+// and set-based requirements on several values match regular expressions.  It
+// returns a BadRequest error for selectors which cannot be expressed with label
+// matchers, such as numeric comparisons, or labels with invalid names.
+func LabelsSelectorToMatchers(sel labels.Selector) ([]Matcher, error) {
+	requirements, selectable := sel.Requirements()
+	if !selectable {
+		return nil, apierr.NewBadRequest(fmt.Sprintf("unable to convert the selector %q, which selects nothing, to label matchers", sel.String()))
+	}
+
+	matchers := make([]Matcher, 0, len(requirements))
+	for _, requirement := range requirements {
+		name := requirement.Key()
+		if !labelNameRE.MatchString(name) {
+			return nil, apierr.NewBadRequest(fmt.Sprintf("unable to convert the selector on %q to a label matcher: invalid Prometheus label name", name))
+		}
+
+		// the values of requirements are sorted
+		values := requirement.Values().List()
+		var matcher Matcher
+		switch requirement.Operator() {
+		case selection.Equals, selection.DoubleEquals:
+			matcher = Matcher{Name: name, Type: MatchEqual, Value: values[0]}
+		case selection.NotEquals:
+			matcher = Matcher{Name: name, Type: MatchNotEqual, Value: values[0]}
+		case selection.In:
+			matcher = Matcher{Name: name, Type: MatchEqual, Value: values[0]}
+			if len(values) > 1 {
+				matcher = Matcher{Name: name, Type: MatchRegexp, Value: alternatives(values)}
+			}
+		case selection.NotIn:
+			matcher = Matcher{Name: name, Type: MatchNotEqual, Value: values[0]}
+			if len(values) > 1 {
+				matcher = Matcher{Name: name, Type: MatchNotRegexp, Value: alternatives(values)}
+			}
+		case selection.Exists:
+			matcher = Matcher{Name: name, Type: MatchNotEqual, Value: ""}
+		case selection.DoesNotExist:
+			matcher = Matcher{Name: name, Type: MatchEqual, Value: ""}
+		default:
+			return nil, apierr.NewBadRequest(fmt.Sprintf("unable to convert the selector on %q to a label matcher: unsupported operator %q", name, requirement.Operator()))
+		}
+		matchers = append(matchers, matcher)
+	}
+	return matchers, nil
+}
+
+// alternatives returns the regular expression matching exactly one of the values.
+func alternatives(values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, value := range values {
+		quoted = append(quoted, regexp.QuoteMeta(value))
+	}
+	return strings.Join(quoted, "|")
+}
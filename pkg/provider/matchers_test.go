@@ -0,0 +1,87 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestLabelsSelectorToMatchers(t *testing.T) {
+	for _, tc := range []struct {
+		selector string
+		matchers []string
+	}{
+		{selector: "", matchers: []string{}},
+		{selector: "code=200", matchers: []string{`code="200"`}},
+		{selector: "code==200", matchers: []string{`code="200"`}},
+		{selector: "code!=500", matchers: []string{`code!="500"`}},
+		{selector: "code in (200)", matchers: []string{`code="200"`}},
+		{selector: "code in (201,200)", matchers: []string{`code=~"200|201"`}},
+		{selector: "code notin (500)", matchers: []string{`code!="500"`}},
+		{selector: "code notin (503,500)", matchers: []string{`code!~"500|503"`}},
+		{selector: "path in (a.b,c)", matchers: []string{`path=~"a\\.b|c"`}},
+		{selector: "code", matchers: []string{`code!=""`}},
+		{selector: "!code", matchers: []string{`code=""`}},
+		{selector: "verb=GET,code!=500,app", matchers: []string{`app!=""`, `code!="500"`, `verb="GET"`}},
+	} {
+		t.Run(tc.selector, func(t *testing.T) {
+			selector, err := labels.Parse(tc.selector)
+			require.NoError(t, err)
+
+			matchers, err := LabelsSelectorToMatchers(selector)
+			require.NoError(t, err, "should have been able to convert the selector")
+			formatted := []string{}
+			for _, matcher := range matchers {
+				formatted = append(formatted, matcher.String())
+			}
+			assert.Equal(t, tc.matchers, formatted)
+		})
+	}
+}
+
+func TestLabelsSelectorToMatchersUnsupported(t *testing.T) {
+	for _, selector := range []string{"size>10", "size<10", "app.kubernetes.io/name=web"} {
+		t.Run(selector, func(t *testing.T) {
+			sel, err := labels.Parse(selector)
+			require.NoError(t, err)
+
+			_, err = LabelsSelectorToMatchers(sel)
+			assert.True(t, apierr.IsBadRequest(err), "should have rejected the selector, got %v", err)
+		})
+	}
+
+	_, err := LabelsSelectorToMatchers(labels.Nothing())
+	assert.True(t, apierr.IsBadRequest(err), "should have rejected the selector selecting nothing, got %v", err)
+}
+
+func TestLabelsSelectorToMatchersTypes(t *testing.T) {
+	selector, err := labels.Parse("code in (200,201),verb=GET")
+	require.NoError(t, err)
+
+	matchers, err := LabelsSelectorToMatchers(selector)
+	require.NoError(t, err)
+	assert.Equal(t, []Matcher{
+		{Name: "code", Type: MatchRegexp, Value: "200|201"},
+		{Name: "verb", Type: MatchEqual, Value: "GET"},
+	}, matchers)
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"sync"
+)
+
+// Provenance describes where the metric values returned for a query come from.
+// It is reported to clients in the X-Metrics-Provenance header of responses.
+type Provenance string
+
+const (
+	// ProvenanceBackend is the provenance of values queried from the backend.
+	ProvenanceBackend Provenance = "backend"
+	// ProvenanceCache is the provenance of values served from a cache.
+	ProvenanceCache Provenance = "cache"
+	// ProvenanceFallback is the provenance of values served in place of the ones
+	// which could not be queried, such as the last known ones.
+	ProvenanceFallback Provenance = "fallback"
+)
+
+type provenanceKey struct{}
+
+type provenanceRecorder struct {
+	mu         sync.Mutex
+	provenance Provenance
+}
+
+// WithProvenanceRecorder returns a copy of ctx in which the provenance of the values
+// returned for a query can be recorded, and a function returning the recorded one,
+// which is empty if none was.
+func WithProvenanceRecorder(ctx context.Context) (context.Context, func() Provenance) {
+	recorder := &provenanceRecorder{}
+	return context.WithValue(ctx, provenanceKey{}, recorder), func() Provenance {
+		recorder.mu.Lock()
+		defer recorder.mu.Unlock()
+		return recorder.provenance
+	}
+}
+
+// SetProvenance records the provenance of the values returned for the query ctx
+// was passed for, unless one was already recorded.  Decorators call it once they
+// get the values, so that the provenance is the one recorded by the decorator
+// closest to their source.
+func SetProvenance(ctx context.Context, provenance Provenance) {
+	recorder, ok := ctx.Value(provenanceKey{}).(*provenanceRecorder)
+	if !ok {
+		return
+	}
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	if recorder.provenance == "" {
+		recorder.provenance = provenance
+	}
+}
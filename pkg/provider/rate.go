@@ -0,0 +1,120 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/metrics/pkg/apis/custom_metrics"
+)
+
+// RateCalculator computes per-second rates of counters from their samples, for
+// providers serving as metrics the rates of counters exposed by their backend.  It
+// keeps the samples of each counter, identified by a key such as the name of the
+// series, over a window, and computes the rate over the samples in the window.
+//
+// A counter decreasing between two samples is assumed to have been reset, e.g. by
+// the restart of the process exposing it, and to have counted from zero since, as
+// done by Prometheus.  It is safe for concurrent use.
+type RateCalculator struct {
+	window time.Duration
+
+	mu        sync.Mutex
+	samples   map[string][]counterSample
+	lastPrune time.Time
+}
+
+type counterSample struct {
+	value     float64
+	timestamp time.Time
+}
+
+// NewRateCalculator returns a RateCalculator computing rates over the given window.
+func NewRateCalculator(window time.Duration) *RateCalculator {
+	return &RateCalculator{window: window, samples: map[string][]counterSample{}}
+}
+
+// Observe records a sample of the counter of the given key, and returns its rate
+// over the samples of the window ending at the sample, as a metric value with the
+// timestamp of the sample, and the window of the samples the rate is computed over.
+// The caller sets the described object and the metric of the value.  It returns
+// false until the window holds two samples of the counter.  Samples older than the
+// last one of the counter are ignored.
+func (c *RateCalculator) Observe(key string, value float64, timestamp time.Time) (*custom_metrics.MetricValue, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.prune(timestamp)
+	samples := c.samples[key]
+	if len(samples) > 0 && !timestamp.After(samples[len(samples)-1].timestamp) {
+		return nil, false
+	}
+	samples = append(samples, counterSample{value: value, timestamp: timestamp})
+	start := 0
+	for start < len(samples)-1 && timestamp.Sub(samples[start].timestamp) > c.window {
+		start++
+	}
+	samples = samples[start:]
+	c.samples[key] = samples
+	if len(samples) < 2 {
+		return nil, false
+	}
+
+	increase := 0.0
+	for i := 1; i < len(samples); i++ {
+		if delta := samples[i].value - samples[i-1].value; delta >= 0 {
+			increase += delta
+		} else {
+			// reset, the counter counted from zero since
+			increase += samples[i].value
+		}
+	}
+	elapsed := timestamp.Sub(samples[0].timestamp)
+	rate := increase / elapsed.Seconds()
+	windowSeconds := int64(math.Round(elapsed.Seconds()))
+	return &custom_metrics.MetricValue{
+		Timestamp:     metav1.NewTime(timestamp),
+		WindowSeconds: &windowSeconds,
+		Value:         *resource.NewMilliQuantity(int64(math.Round(rate*1000)), resource.DecimalSI),
+	}, true
+}
+
+// Forget drops the samples of the counter of the given key, for instance once the
+// series is deleted.  Counters which are not observed for a window are forgotten.
+func (c *RateCalculator) Forget(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.samples, key)
+}
+
+// prune forgets the counters whose last sample is older than the window, at most
+// once per window.
+func (c *RateCalculator) prune(now time.Time) {
+	if now.Sub(c.lastPrune) < c.window {
+		return
+	}
+	c.lastPrune = now
+	for key, samples := range c.samples {
+		if now.Sub(samples[len(samples)-1].timestamp) > c.window {
+			delete(c.samples, key)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateCalculator(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	type sample struct {
+		value  float64
+		offset time.Duration
+	}
+
+	for _, tc := range []struct {
+		name           string
+		samples        []sample
+		expectedRate   int64
+		expectedWindow int64
+	}{
+		{
+			name:           "increase",
+			samples:        []sample{{10, 0}, {40, 15 * time.Second}, {70, 30 * time.Second}},
+			expectedRate:   2000,
+			expectedWindow: 30,
+		},
+		{
+			name:           "reset",
+			samples:        []sample{{100, 0}, {130, 15 * time.Second}, {30, 30 * time.Second}},
+			expectedRate:   2000,
+			expectedWindow: 30,
+		},
+		{
+			name:           "samples out of the window",
+			samples:        []sample{{0, 0}, {1000, 30 * time.Second}, {1060, 60 * time.Second}, {1120, 90 * time.Second}},
+			expectedRate:   2000,
+			expectedWindow: 60,
+		},
+		{
+			name:           "fractional rate",
+			samples:        []sample{{0, 0}, {1, 4 * time.Second}},
+			expectedRate:   250,
+			expectedWindow: 4,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			calculator := NewRateCalculator(time.Minute)
+			for i, s := range tc.samples {
+				value, ok := calculator.Observe("requests", s.value, start.Add(s.offset))
+				if i == len(tc.samples)-1 {
+					require.True(t, ok, "should have computed the rate")
+					assert.Equal(t, tc.expectedRate, value.Value.MilliValue(), "should have computed the rate per second")
+					require.NotNil(t, value.WindowSeconds)
+					assert.Equal(t, tc.expectedWindow, *value.WindowSeconds, "should have reported the window of the samples")
+					assert.True(t, start.Add(s.offset).Equal(value.Timestamp.Time), "should have reported the time of the last sample")
+				}
+			}
+		})
+	}
+}
+
+func TestRateCalculatorSamples(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	calculator := NewRateCalculator(time.Minute)
+
+	_, ok := calculator.Observe("requests", 10, start)
+	assert.False(t, ok, "should not have computed a rate from a single sample")
+	_, ok = calculator.Observe("requests", 20, start)
+	assert.False(t, ok, "should have ignored a sample at the time of the last one")
+
+	_, ok = calculator.Observe("errors", 10, start.Add(10*time.Second))
+	assert.False(t, ok, "should have kept the samples of each counter apart")
+	_, ok = calculator.Observe("requests", 20, start.Add(10*time.Second))
+	assert.True(t, ok, "should have computed the rate from two samples")
+
+	calculator.Forget("requests")
+	_, ok = calculator.Observe("requests", 30, start.Add(20*time.Second))
+	assert.False(t, ok, "should have forgotten the samples of the counter")
+
+	_, ok = calculator.Observe("requests", 40, start.Add(2*time.Minute))
+	assert.False(t, ok, "should have dropped the samples older than the window")
+}
@@ -17,36 +17,166 @@ limitations under the License.
 package provider
 
 import (
+	"strings"
+	"sync"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apiserver/pkg/endpoints/discovery"
+	"k8s.io/klog/v2"
 )
 
 type customMetricsResourceLister struct {
 	provider CustomMetricsProvider
+
+	// changed signals changes to the metrics of a NotifyingCustomMetricsProvider,
+	// in which case the resources are cached until it does.
+	changed   <-chan struct{}
+	mu        sync.Mutex
+	closed    bool
+	resources []metav1.APIResource
+
+	duplicates duplicateReporter
 }
 
 type externalMetricsResourceLister struct {
 	provider ExternalMetricsProvider
+
+	duplicates duplicateReporter
+}
+
+// duplicateReporter warns about the duplicate metrics returned by a provider once
+// per metric, since the resources are listed for each discovery request.
+type duplicateReporter struct {
+	mu       sync.Mutex
+	reported sets.Set[string]
+}
+
+func (r *duplicateReporter) warn(kind, metric string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.reported.Has(metric) {
+		return
+	}
+	if r.reported == nil {
+		r.reported = sets.New[string]()
+	}
+	r.reported.Insert(metric)
+	klog.Warningf("ignoring duplicate %s metric %s returned by provider", kind, metric)
+}
+
+type filteredCustomMetricsResourceLister struct {
+	lister discovery.APIResourceLister
+	filter CustomMetricDiscoveryFilterFunc
+}
+
+type filteredExternalMetricsResourceLister struct {
+	lister discovery.APIResourceLister
+	filter ExternalMetricDiscoveryFilterFunc
 }
 
 // NewCustomMetricResourceLister creates APIResourceLister for provided CustomMetricsProvider.
+// If the provider is a NotifyingCustomMetricsProvider, the resources are only listed
+// again when it signals a change.  If the provider is a CapableCustomMetricsProvider,
+// the verbs of each resource are the ones of the queries it supports for the metric;
+// otherwise, they are "get".  If the provider is a
+// TypedCustomMetricsProvider, the category of each resource is the type of the metric.
+// These extensions are also looked up on the providers it wraps, with As.
 func NewCustomMetricResourceLister(provider CustomMetricsProvider) discovery.APIResourceLister {
+	l := &customMetricsResourceLister{
+		provider: provider,
+	}
+	if notifying, ok := As[NotifyingCustomMetricsProvider](provider); ok {
+		l.changed = notifying.MetricsChanged()
+	}
+	return l
+}
+
+// NewCustomMetricResourceListerWithChanges creates an APIResourceLister for the given
+// provider, like NewCustomMetricResourceLister, caching the resources until a change
+// is signaled on the given channel, or until Refresh is called.  It is meant for
+// servers consuming the signals of a NotifyingCustomMetricsProvider themselves, to
+// update other documents, which pass the lister a channel they relay the signals to.
+func NewCustomMetricResourceListerWithChanges(provider CustomMetricsProvider, changed <-chan struct{}) discovery.APIResourceLister {
 	return &customMetricsResourceLister{
 		provider: provider,
+		changed:  changed,
 	}
 }
 
+// ListAPIResources lists all supported custom metrics.
+// Duplicate metrics returned by the provider are dropped, keeping the first occurrence.
 func (l *customMetricsResourceLister) ListAPIResources() []metav1.APIResource {
+	if l.changed == nil {
+		return l.listAPIResources()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stale := l.closed || l.resources == nil
+	for !l.closed && l.changeSignaled() {
+		stale = true
+	}
+	if stale {
+		l.resources = l.listAPIResources()
+	}
+	return l.resources
+}
+
+// Refresh lists the resources again, when they are cached, as when the provider
+// signals a change.  Resources which are not cached are listed for each request.
+func (l *customMetricsResourceLister) Refresh() {
+	if l.changed == nil {
+		return
+	}
+
+	resources := l.listAPIResources()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.resources = resources
+}
+
+// changeSignaled reports, without blocking, whether the provider signaled a change.
+func (l *customMetricsResourceLister) changeSignaled() bool {
+	select {
+	case _, ok := <-l.changed:
+		l.closed = !ok
+		return true
+	default:
+		return false
+	}
+}
+
+func (l *customMetricsResourceLister) listAPIResources() []metav1.APIResource {
 	metrics := l.provider.ListAllMetrics()
-	resources := make([]metav1.APIResource, len(metrics))
+	resources := make([]metav1.APIResource, 0, len(metrics))
+	seen := make(map[string]struct{}, len(metrics))
+	typed, _ := As[TypedCustomMetricsProvider](l.provider)
+
+	for _, metric := range metrics {
+		// the namespaced and root-scoped variants of a metric are distinct resources
+		if _, ok := seen[metric.String()]; ok {
+			l.duplicates.warn("custom", metric.String())
+			continue
+		}
+		seen[metric.String()] = struct{}{}
 
-	for i, metric := range metrics {
-		resources[i] = metav1.APIResource{
-			Name:       metric.GroupResource.String() + "/" + metric.Metric,
+		name := metric.GroupResource.String() + "/" + metric.Metric
+
+		resource := metav1.APIResource{
+			Name:       name,
 			Namespaced: metric.Namespaced,
 			Kind:       "MetricValueList",
-			Verbs:      metav1.Verbs{"get"}, // TODO: support "watch"
+			Verbs:      discoveryVerbs(l.provider, metric), // TODO: support "watch"
+		}
+		if typed != nil {
+			if metricType := typed.MetricType(metric); metricType != MetricTypeUnspecified {
+				resource.Categories = []string{string(metricType)}
+			}
 		}
+		resources = append(resources, resource)
 	}
 
 	return resources
@@ -59,19 +189,82 @@ func NewExternalMetricResourceLister(provider ExternalMetricsProvider) discovery
 	}
 }
 
-// ListAPIResources lists all supported custom metrics.
+// ListAPIResources lists all supported external metrics.
+// Duplicate metrics returned by the provider are dropped, keeping the first occurrence.
 func (l *externalMetricsResourceLister) ListAPIResources() []metav1.APIResource {
 	metrics := l.provider.ListAllExternalMetrics()
-	resources := make([]metav1.APIResource, len(metrics))
+	resources := make([]metav1.APIResource, 0, len(metrics))
+	seen := make(map[string]struct{}, len(metrics))
 
-	for i, metric := range metrics {
-		resources[i] = metav1.APIResource{
+	for _, metric := range metrics {
+		if _, ok := seen[metric.Metric]; ok {
+			l.duplicates.warn("external", metric.Metric)
+			continue
+		}
+		seen[metric.Metric] = struct{}{}
+
+		resources = append(resources, metav1.APIResource{
 			Name:       metric.Metric,
 			Namespaced: true,
 			Kind:       "ExternalMetricValueList",
 			Verbs:      metav1.Verbs{"get"},
-		}
+		})
 	}
 
 	return resources
 }
+
+// NewFilteredCustomMetricResourceLister creates an APIResourceLister listing the
+// custom metrics of the given lister, as created by NewCustomMetricResourceLister,
+// which pass the filter.  The filter is applied on each listing, to copies of the
+// resources.
+func NewFilteredCustomMetricResourceLister(lister discovery.APIResourceLister, filter CustomMetricDiscoveryFilterFunc) discovery.APIResourceLister {
+	return &filteredCustomMetricsResourceLister{
+		lister: lister,
+		filter: filter,
+	}
+}
+
+// ListAPIResources lists the custom metrics passing the filter.
+func (l *filteredCustomMetricsResourceLister) ListAPIResources() []metav1.APIResource {
+	resources := l.lister.ListAPIResources()
+	filtered := make([]metav1.APIResource, 0, len(resources))
+	for _, resource := range resources {
+		// resources are named after the path of the metrics, e.g. deployments.apps/cpu_usage
+		groupResource, metric, _ := strings.Cut(resource.Name, "/")
+		info := CustomMetricInfo{
+			GroupResource: schema.ParseGroupResource(groupResource),
+			Namespaced:    resource.Namespaced,
+			Metric:        metric,
+		}
+		resource := *resource.DeepCopy()
+		if l.filter(info, &resource) {
+			filtered = append(filtered, resource)
+		}
+	}
+	return filtered
+}
+
+// NewFilteredExternalMetricResourceLister creates an APIResourceLister listing the
+// external metrics of the given lister, as created by NewExternalMetricResourceLister,
+// which pass the filter.  The filter is applied on each listing, to copies of the
+// resources.
+func NewFilteredExternalMetricResourceLister(lister discovery.APIResourceLister, filter ExternalMetricDiscoveryFilterFunc) discovery.APIResourceLister {
+	return &filteredExternalMetricsResourceLister{
+		lister: lister,
+		filter: filter,
+	}
+}
+
+// ListAPIResources lists the external metrics passing the filter.
+func (l *filteredExternalMetricsResourceLister) ListAPIResources() []metav1.APIResource {
+	resources := l.lister.ListAPIResources()
+	filtered := make([]metav1.APIResource, 0, len(resources))
+	for _, resource := range resources {
+		resource := *resource.DeepCopy()
+		if l.filter(ExternalMetricInfo{Metric: resource.Name}, &resource) {
+			filtered = append(filtered, resource)
+		}
+	}
+	return filtered
+}
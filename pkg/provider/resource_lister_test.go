@@ -0,0 +1,74 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+	"k8s.io/metrics/pkg/apis/external_metrics"
+)
+
+type listingExternalProvider struct {
+	metrics []ExternalMetricInfo
+}
+
+func (p *listingExternalProvider) GetExternalMetric(context.Context, string, labels.Selector, ExternalMetricInfo) (*external_metrics.ExternalMetricValueList, error) {
+	return nil, nil
+}
+
+func (p *listingExternalProvider) ListAllExternalMetrics() []ExternalMetricInfo {
+	return p.metrics
+}
+
+func TestResourceListersWarnOnceAboutDuplicates(t *testing.T) {
+	var logs bytes.Buffer
+	klog.LogToStderr(false)
+	// warnings are also written to the info log
+	klog.SetOutputBySeverity("INFO", io.Discard)
+	klog.SetOutputBySeverity("WARNING", &logs)
+	defer klog.LogToStderr(true)
+
+	pods := schema.GroupResource{Resource: "pods"}
+	customLister := NewCustomMetricResourceLister(&listingProvider{metrics: []CustomMetricInfo{
+		{GroupResource: pods, Namespaced: true, Metric: "some-metric"},
+		{GroupResource: pods, Namespaced: true, Metric: "some-metric"},
+		{GroupResource: pods, Namespaced: false, Metric: "some-metric"},
+	}})
+	externalLister := NewExternalMetricResourceLister(&listingExternalProvider{metrics: []ExternalMetricInfo{
+		{Metric: "queue-length"},
+		{Metric: "queue-length"},
+	}})
+
+	// the resources are listed for each discovery request
+	for i := 0; i < 3; i++ {
+		assert.Len(t, customLister.ListAPIResources(), 2, "should have kept both scopes of the metric")
+		assert.Len(t, externalLister.ListAPIResources(), 1)
+	}
+
+	klog.Flush()
+	assert.Equal(t, 1, strings.Count(logs.String(), "ignoring duplicate custom metric"), "should have warned once about the duplicate custom metric")
+	assert.Equal(t, 1, strings.Count(logs.String(), "ignoring duplicate external metric"), "should have warned once about the duplicate external metric")
+}
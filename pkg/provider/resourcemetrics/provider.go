@@ -0,0 +1,204 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package resourcemetrics provides a custom metrics provider deriving its
+// metrics from the resource metrics API (metrics.k8s.io).
+package resourcemetrics
+
+import (
+	"context"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/metrics/pkg/apis/custom_metrics"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsclient "k8s.io/metrics/pkg/client/clientset/versioned/typed/metrics/v1beta1"
+
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
+)
+
+var (
+	podsResource  = schema.GroupResource{Resource: "pods"}
+	nodesResource = schema.GroupResource{Resource: "nodes"}
+)
+
+// MetricFunc computes the value of a custom metric from the resource usage of
+// an object.  For pods, the usage is the sum of the usage of their containers.
+type MetricFunc func(info provider.CustomMetricInfo, usage corev1.ResourceList) (resource.Quantity, error)
+
+type resourceMetricsProvider struct {
+	client  metricsclient.MetricsV1beta1Interface
+	metrics map[string]MetricFunc
+}
+
+// NewProvider creates a CustomMetricsProvider serving, for pods and nodes, the
+// custom metrics computed by the given functions from their resource metrics,
+// which are fetched with the given client.  The functions are keyed by metric name.
+func NewProvider(client metricsclient.MetricsV1beta1Interface, metrics map[string]MetricFunc) provider.CustomMetricsProvider {
+	return &resourceMetricsProvider{
+		client:  client,
+		metrics: metrics,
+	}
+}
+
+func (p *resourceMetricsProvider) metricFor(info provider.CustomMetricInfo) (MetricFunc, error) {
+	metric, ok := p.metrics[info.Metric]
+	if !ok {
+		return nil, provider.NewMetricNotFoundError(info.GroupResource, info.Metric)
+	}
+	switch {
+	case info.GroupResource == podsResource && info.Namespaced:
+	case info.GroupResource == nodesResource && !info.Namespaced:
+	default:
+		return nil, provider.NewMetricNotFoundError(info.GroupResource, info.Metric)
+	}
+	return metric, nil
+}
+
+func (p *resourceMetricsProvider) GetMetricByName(ctx context.Context, name types.NamespacedName, info provider.CustomMetricInfo, _ labels.Selector) (*custom_metrics.MetricValue, error) {
+	metric, err := p.metricFor(info)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.GroupResource == podsResource {
+		podMetrics, err := p.client.PodMetricses(name.Namespace).Get(ctx, name.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, notFoundFor(err, info, name.Name)
+		}
+		return podMetricValue(info, metric, podMetrics)
+	}
+
+	nodeMetrics, err := p.client.NodeMetricses().Get(ctx, name.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, notFoundFor(err, info, name.Name)
+	}
+	return nodeMetricValue(info, metric, nodeMetrics)
+}
+
+func (p *resourceMetricsProvider) GetMetricBySelector(ctx context.Context, namespace string, selector labels.Selector, info provider.CustomMetricInfo, _ labels.Selector) (*custom_metrics.MetricValueList, error) {
+	metric, err := p.metricFor(info)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &custom_metrics.MetricValueList{}
+	opts := metav1.ListOptions{LabelSelector: selector.String()}
+	if info.GroupResource == podsResource {
+		podMetricsList, err := p.client.PodMetricses(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for i := range podMetricsList.Items {
+			value, err := podMetricValue(info, metric, &podMetricsList.Items[i])
+			if err != nil {
+				return nil, err
+			}
+			res.Items = append(res.Items, *value)
+		}
+		return res, nil
+	}
+
+	nodeMetricsList, err := p.client.NodeMetricses().List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for i := range nodeMetricsList.Items {
+		value, err := nodeMetricValue(info, metric, &nodeMetricsList.Items[i])
+		if err != nil {
+			return nil, err
+		}
+		res.Items = append(res.Items, *value)
+	}
+	return res, nil
+}
+
+func (p *resourceMetricsProvider) ListAllMetrics() []provider.CustomMetricInfo {
+	names := make([]string, 0, len(p.metrics))
+	for name := range p.metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	infos := make([]provider.CustomMetricInfo, 0, 2*len(names))
+	for _, name := range names {
+		infos = append(infos,
+			provider.CustomMetricInfo{GroupResource: podsResource, Namespaced: true, Metric: name},
+			provider.CustomMetricInfo{GroupResource: nodesResource, Namespaced: false, Metric: name},
+		)
+	}
+	return infos
+}
+
+// notFoundFor reports missing resource metrics as a missing metric for the object.
+func notFoundFor(err error, info provider.CustomMetricInfo, name string) error {
+	if apierr.IsNotFound(err) {
+		return provider.NewMetricNotFoundForError(info.GroupResource, info.Metric, name)
+	}
+	return err
+}
+
+func podMetricValue(info provider.CustomMetricInfo, metric MetricFunc, podMetrics *metricsv1beta1.PodMetrics) (*custom_metrics.MetricValue, error) {
+	usage := corev1.ResourceList{}
+	for _, container := range podMetrics.Containers {
+		for name, quantity := range container.Usage {
+			total := usage[name]
+			total.Add(quantity)
+			usage[name] = total
+		}
+	}
+
+	ref := custom_metrics.ObjectReference{
+		APIVersion: "v1",
+		Kind:       "Pod",
+		Namespace:  podMetrics.Namespace,
+		Name:       podMetrics.Name,
+	}
+	return metricValue(info, metric, ref, usage, podMetrics.Timestamp, podMetrics.Window)
+}
+
+func nodeMetricValue(info provider.CustomMetricInfo, metric MetricFunc, nodeMetrics *metricsv1beta1.NodeMetrics) (*custom_metrics.MetricValue, error) {
+	ref := custom_metrics.ObjectReference{
+		APIVersion: "v1",
+		Kind:       "Node",
+		Name:       nodeMetrics.Name,
+	}
+	return metricValue(info, metric, ref, nodeMetrics.Usage, nodeMetrics.Timestamp, nodeMetrics.Window)
+}
+
+func metricValue(info provider.CustomMetricInfo, metric MetricFunc, ref custom_metrics.ObjectReference, usage corev1.ResourceList, timestamp metav1.Time, window metav1.Duration) (*custom_metrics.MetricValue, error) {
+	value, err := metric(info, usage)
+	if err != nil {
+		return nil, err
+	}
+
+	windowSeconds := int64(window.Seconds())
+	return &custom_metrics.MetricValue{
+		DescribedObject: ref,
+		Metric: custom_metrics.MetricIdentifier{
+			Name: info.Metric,
+		},
+		Timestamp:     timestamp,
+		WindowSeconds: &windowSeconds,
+		Value:         value,
+	}, nil
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcemetrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	"k8s.io/metrics/pkg/client/clientset/versioned/fake"
+
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
+)
+
+// cpuMillicores exposes the CPU usage in millicores.
+func cpuMillicores(_ provider.CustomMetricInfo, usage corev1.ResourceList) (resource.Quantity, error) {
+	return *resource.NewQuantity(usage.Cpu().MilliValue(), resource.DecimalSI), nil
+}
+
+func setupProvider(t *testing.T) provider.CustomMetricsProvider {
+	client := fake.NewSimpleClientset()
+	// the fake clientset guesses the wrong resources for metrics, so objects are added explicitly
+	pods := metricsv1beta1.SchemeGroupVersion.WithResource("pods")
+	nodes := metricsv1beta1.SchemeGroupVersion.WithResource("nodes")
+	window := metav1.Duration{Duration: 30 * time.Second}
+
+	for _, pod := range []*metricsv1beta1.PodMetrics{
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web-1", Labels: map[string]string{"app": "web"}},
+			Window:     window,
+			Containers: []metricsv1beta1.ContainerMetrics{
+				{Name: "a", Usage: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")}},
+				{Name: "b", Usage: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("50m")}},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "db-1", Labels: map[string]string{"app": "db"}},
+			Window:     window,
+			Containers: []metricsv1beta1.ContainerMetrics{
+				{Name: "a", Usage: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}},
+			},
+		},
+	} {
+		require.NoError(t, client.Tracker().Create(pods, pod, pod.Namespace))
+	}
+	require.NoError(t, client.Tracker().Create(nodes, &metricsv1beta1.NodeMetrics{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Window:     window,
+		Usage:      corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")},
+	}, ""))
+
+	return NewProvider(client.MetricsV1beta1(), map[string]MetricFunc{"cpu_millicores": cpuMillicores})
+}
+
+var (
+	podsInfo  = provider.CustomMetricInfo{GroupResource: podsResource, Namespaced: true, Metric: "cpu_millicores"}
+	nodesInfo = provider.CustomMetricInfo{GroupResource: nodesResource, Namespaced: false, Metric: "cpu_millicores"}
+)
+
+func TestGetMetricByName(t *testing.T) {
+	prov := setupProvider(t)
+
+	value, err := prov.GetMetricByName(context.Background(), types.NamespacedName{Namespace: "default", Name: "web-1"}, podsInfo, labels.Everything())
+	require.NoError(t, err, "should have been able to get the metric for a pod")
+	assert.Equal(t, "150", value.Value.String(), "should have summed the usage of the pod's containers")
+	assert.Equal(t, "Pod", value.DescribedObject.Kind)
+	if assert.NotNil(t, value.WindowSeconds) {
+		assert.Equal(t, int64(30), *value.WindowSeconds, "should have used the window of the resource metrics")
+	}
+
+	value, err = prov.GetMetricByName(context.Background(), types.NamespacedName{Name: "node-1"}, nodesInfo, labels.Everything())
+	require.NoError(t, err, "should have been able to get the metric for a node")
+	assert.Equal(t, "2k", value.Value.String())
+	assert.Equal(t, "Node", value.DescribedObject.Kind)
+
+	_, err = prov.GetMetricByName(context.Background(), types.NamespacedName{Namespace: "default", Name: "missing"}, podsInfo, labels.Everything())
+	assert.True(t, apierr.IsNotFound(err), "should have returned a not found error for a pod without metrics, got %v", err)
+
+	unknownInfo := podsInfo
+	unknownInfo.Metric = "unknown"
+	_, err = prov.GetMetricByName(context.Background(), types.NamespacedName{Namespace: "default", Name: "web-1"}, unknownInfo, labels.Everything())
+	assert.True(t, apierr.IsNotFound(err), "should have returned a not found error for an unknown metric, got %v", err)
+}
+
+func TestGetMetricBySelector(t *testing.T) {
+	prov := setupProvider(t)
+
+	selector, err := labels.Parse("app=web")
+	require.NoError(t, err)
+	values, err := prov.GetMetricBySelector(context.Background(), "default", selector, podsInfo, labels.Everything())
+	require.NoError(t, err, "should have been able to get the metric by selector")
+	require.Len(t, values.Items, 1, "should have only returned the matching pods")
+	assert.Equal(t, "web-1", values.Items[0].DescribedObject.Name)
+	assert.Equal(t, "150", values.Items[0].Value.String())
+
+	values, err = prov.GetMetricBySelector(context.Background(), "", labels.Everything(), nodesInfo, labels.Everything())
+	require.NoError(t, err, "should have been able to get the metric for all nodes")
+	assert.Len(t, values.Items, 1)
+}
+
+func TestListAllMetrics(t *testing.T) {
+	prov := setupProvider(t)
+
+	assert.ElementsMatch(t, []provider.CustomMetricInfo{podsInfo, nodesInfo}, prov.ListAllMetrics())
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	"k8s.io/metrics/pkg/apis/custom_metrics"
+	"k8s.io/metrics/pkg/apis/external_metrics"
+)
+
+// RetryClassifierFunc returns whether a provider call which failed with the given
+// error may succeed when it's retried.
+type RetryClassifierFunc func(err error) bool
+
+// IsRetryableError is the default RetryClassifierFunc.  It retries RetryableErrors,
+// API errors reporting a transient failure of the server, such as 503 Service
+// Unavailable or 429 Too Many Requests, and errors which are not API errors, such
+// as network errors.  It does not retry other API errors, in particular not found
+// errors, since another call would not find the metric either, nor NoDataErrors
+// and context errors.
+func IsRetryableError(err error) bool {
+	var retryable *RetryableError
+	var noData *NoDataError
+	switch {
+	case errors.As(err, &retryable):
+		return true
+	case errors.As(err, &noData), errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return false
+	case apierr.IsServiceUnavailable(err), apierr.IsTooManyRequests(err), apierr.IsServerTimeout(err),
+		apierr.IsTimeout(err), apierr.IsInternalError(err), apierr.IsUnexpectedServerError(err):
+		return true
+	}
+	var status apierr.APIStatus
+	return !errors.As(err, &status)
+}
+
+type retryingProvider struct {
+	CustomMetricsProvider
+
+	maxRetries int
+	backoff    wait.Backoff
+	retryable  RetryClassifierFunc
+}
+
+// NewRetryingProvider creates a CustomMetricsProvider retrying the calls of the inner
+// one failing with errors classified as retryable by IsRetryableError, up to
+// maxRetries times, waiting for the next step of backoff before each retry, or for
+// the delay of a RetryableError when it's longer.  As for other users of
+// wait.Backoff, the delay stops growing once its Steps are taken.  A call is not retried when the deadline of
+// its context would pass before the retry, so that the error is still returned to
+// the client in time, and the wait is abandoned when it's canceled.  It's a
+// WrappingCustomMetricsProvider, which only retries the queries of
+// CustomMetricsProvider.
+func NewRetryingProvider(inner CustomMetricsProvider, maxRetries int, backoff wait.Backoff) CustomMetricsProvider {
+	return NewRetryingProviderWithClassifier(inner, maxRetries, backoff, IsRetryableError)
+}
+
+// NewRetryingProviderWithClassifier is like NewRetryingProvider, but retries the
+// calls failing with errors classified as retryable by the given classifier.  Not
+// found errors are never retried.
+func NewRetryingProviderWithClassifier(inner CustomMetricsProvider, maxRetries int, backoff wait.Backoff, retryable RetryClassifierFunc) CustomMetricsProvider {
+	return &retryingProvider{
+		CustomMetricsProvider: inner,
+		maxRetries:            maxRetries,
+		backoff:               backoff,
+		retryable:             retryable,
+	}
+}
+
+// Unwrap returns the provider whose calls are retried.
+func (p *retryingProvider) Unwrap() CustomMetricsProvider {
+	return p.CustomMetricsProvider
+}
+
+func (p *retryingProvider) GetMetricByName(ctx context.Context, name types.NamespacedName, info CustomMetricInfo, metricSelector labels.Selector) (*custom_metrics.MetricValue, error) {
+	return retry(ctx, p.maxRetries, p.backoff, p.retryable, info.Metric, func() (*custom_metrics.MetricValue, error) {
+		return p.CustomMetricsProvider.GetMetricByName(ctx, name, info, metricSelector)
+	})
+}
+
+func (p *retryingProvider) GetMetricBySelector(ctx context.Context, namespace string, selector labels.Selector, info CustomMetricInfo, metricSelector labels.Selector) (*custom_metrics.MetricValueList, error) {
+	return retry(ctx, p.maxRetries, p.backoff, p.retryable, info.Metric, func() (*custom_metrics.MetricValueList, error) {
+		return p.CustomMetricsProvider.GetMetricBySelector(ctx, namespace, selector, info, metricSelector)
+	})
+}
+
+type retryingExternalProvider struct {
+	ExternalMetricsProvider
+
+	maxRetries int
+	backoff    wait.Backoff
+	retryable  RetryClassifierFunc
+}
+
+// NewRetryingExternalMetricsProvider creates an ExternalMetricsProvider retrying the
+// calls of the inner one like NewRetryingProvider.  It's a
+// WrappingExternalMetricsProvider, which does not stream values.
+func NewRetryingExternalMetricsProvider(inner ExternalMetricsProvider, maxRetries int, backoff wait.Backoff) ExternalMetricsProvider {
+	return &retryingExternalProvider{
+		ExternalMetricsProvider: inner,
+		maxRetries:              maxRetries,
+		backoff:                 backoff,
+		retryable:               IsRetryableError,
+	}
+}
+
+// Unwrap returns the provider whose calls are retried.
+func (p *retryingExternalProvider) Unwrap() ExternalMetricsProvider {
+	return p.ExternalMetricsProvider
+}
+
+func (p *retryingExternalProvider) GetExternalMetric(ctx context.Context, namespace string, metricSelector labels.Selector, info ExternalMetricInfo) (*external_metrics.ExternalMetricValueList, error) {
+	return retry(ctx, p.maxRetries, p.backoff, p.retryable, info.Metric, func() (*external_metrics.ExternalMetricValueList, error) {
+		return p.ExternalMetricsProvider.GetExternalMetric(ctx, namespace, metricSelector, info)
+	})
+}
+
+// retry runs call, and runs it again after the steps of backoff while it fails
+// with retryable errors, up to maxRetries times, within the deadline of ctx.
+func retry[T any](ctx context.Context, maxRetries int, backoff wait.Backoff, retryable RetryClassifierFunc, metric string, call func() (T, error)) (T, error) {
+	for retries := 0; ; retries++ {
+		value, err := call()
+		if err == nil || retries == maxRetries || apierr.IsNotFound(err) || !retryable(err) {
+			return value, err
+		}
+
+		delay := backoff.Step()
+		var retryableErr *RetryableError
+		if errors.As(err, &retryableErr) && retryableErr.RetryAfter > delay {
+			delay = retryableErr.RetryAfter
+		}
+		// the retry would not return before the client gives up
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+			return value, err
+		}
+
+		klog.V(4).InfoS("Retrying provider call", "metric", metric, "retry", retries+1, "delay", delay, "err", err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			var zero T
+			return zero, ctx.Err()
+		}
+	}
+}
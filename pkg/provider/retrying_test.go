@@ -0,0 +1,174 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/metrics/pkg/apis/custom_metrics"
+	"k8s.io/metrics/pkg/apis/external_metrics"
+)
+
+// flakyProvider fails with the given errors, one per call, before returning a value.
+type flakyProvider struct {
+	CustomMetricsProvider
+	ExternalMetricsProvider
+
+	errs  []error
+	calls int
+}
+
+func (p *flakyProvider) GetMetricByName(_ context.Context, _ types.NamespacedName, _ CustomMetricInfo, _ labels.Selector) (*custom_metrics.MetricValue, error) {
+	p.calls++
+	if p.calls <= len(p.errs) {
+		return nil, p.errs[p.calls-1]
+	}
+	return &custom_metrics.MetricValue{Value: resource.MustParse("42")}, nil
+}
+
+func (p *flakyProvider) GetExternalMetric(_ context.Context, _ string, _ labels.Selector, _ ExternalMetricInfo) (*external_metrics.ExternalMetricValueList, error) {
+	p.calls++
+	if p.calls <= len(p.errs) {
+		return nil, p.errs[p.calls-1]
+	}
+	return &external_metrics.ExternalMetricValueList{Items: []external_metrics.ExternalMetricValue{{Value: resource.MustParse("42")}}}, nil
+}
+
+func TestRetryingProvider(t *testing.T) {
+	info := CustomMetricInfo{GroupResource: schema.GroupResource{Resource: "pods"}, Namespaced: true, Metric: "some-metric"}
+	name := types.NamespacedName{Namespace: "default", Name: "foo"}
+	backoff := wait.Backoff{Duration: time.Millisecond, Factor: 2, Steps: 3}
+	unavailable := apierr.NewServiceUnavailable("backend is restarting")
+	notFound := NewMetricNotFoundForError(info.GroupResource, info.Metric, name.Name)
+	forbidden := apierr.NewForbidden(info.GroupResource, name.Name, fmt.Errorf("denied by the backend"))
+
+	for _, tc := range []struct {
+		name          string
+		errs          []error
+		expectedErr   error
+		expectedCalls int
+	}{
+		{name: "retryable errors", errs: []error{unavailable, fmt.Errorf("connection reset")}, expectedCalls: 3},
+		{name: "retryable error", errs: []error{NewRetryableError(fmt.Errorf("warming up"), time.Millisecond)}, expectedCalls: 2},
+		{name: "too many retryable errors", errs: []error{unavailable, unavailable, unavailable, unavailable}, expectedErr: unavailable, expectedCalls: 4},
+		{name: "not found", errs: []error{notFound}, expectedErr: notFound, expectedCalls: 1},
+		{name: "non-retryable error", errs: []error{forbidden}, expectedErr: forbidden, expectedCalls: 1},
+		{name: "no data", errs: []error{NewNoDataError(info.Metric, "")}, expectedErr: NewNoDataError(info.Metric, ""), expectedCalls: 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			inner := &flakyProvider{errs: tc.errs}
+			value, err := NewRetryingProvider(inner, 3, backoff).GetMetricByName(context.Background(), name, info, labels.Everything())
+			assert.Equal(t, tc.expectedCalls, inner.calls)
+			if tc.expectedErr != nil {
+				assert.Equal(t, tc.expectedErr, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, resource.MustParse("42"), value.Value)
+		})
+	}
+
+	t.Run("external metrics", func(t *testing.T) {
+		inner := &flakyProvider{errs: []error{unavailable}}
+		values, err := NewRetryingExternalMetricsProvider(inner, 3, backoff).GetExternalMetric(context.Background(), "default", labels.Everything(), ExternalMetricInfo{Metric: "queue-length"})
+		require.NoError(t, err)
+		assert.Len(t, values.Items, 1)
+		assert.Equal(t, 2, inner.calls)
+	})
+
+	t.Run("classifier", func(t *testing.T) {
+		inner := &flakyProvider{errs: []error{forbidden, notFound}}
+		retryAll := func(error) bool { return true }
+		_, err := NewRetryingProviderWithClassifier(inner, 3, backoff, retryAll).GetMetricByName(context.Background(), name, info, labels.Everything())
+		assert.Equal(t, notFound, err, "should not have retried the not found error")
+		assert.Equal(t, 2, inner.calls)
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		inner := &flakyProvider{errs: []error{unavailable}}
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		slow := wait.Backoff{Duration: time.Minute}
+		start := time.Now()
+		_, err := NewRetryingProvider(inner, 3, slow).GetMetricByName(ctx, name, info, labels.Everything())
+		assert.Equal(t, unavailable, err, "should have returned the error rather than retrying after the deadline")
+		assert.Equal(t, 1, inner.calls)
+		assert.Less(t, time.Since(start), time.Minute)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		inner := &flakyProvider{errs: []error{unavailable}}
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(10*time.Millisecond, cancel)
+		_, err := NewRetryingProvider(inner, 3, wait.Backoff{Duration: time.Minute}).GetMetricByName(ctx, name, info, labels.Everything())
+		assert.ErrorIs(t, err, context.Canceled, "should have abandoned the wait once canceled")
+		assert.Equal(t, 1, inner.calls)
+	})
+}
+
+func TestIsRetryableError(t *testing.T) {
+	gr := schema.GroupResource{Resource: "pods"}
+	for _, tc := range []struct {
+		err       error
+		retryable bool
+	}{
+		{err: fmt.Errorf("dial tcp: connection refused"), retryable: true},
+		{err: NewRetryableError(fmt.Errorf("warming up"), time.Second), retryable: true},
+		{err: apierr.NewServiceUnavailable("restarting"), retryable: true},
+		{err: apierr.NewTooManyRequests("slow down", 1), retryable: true},
+		{err: apierr.NewInternalError(fmt.Errorf("oops")), retryable: true},
+		{err: NewMetricNotFoundError(gr, "some-metric"), retryable: false},
+		{err: apierr.NewBadRequest("invalid selector"), retryable: false},
+		{err: fmt.Errorf("querying backend: %w", NewNoDataError("some-metric", "")), retryable: false},
+		{err: context.DeadlineExceeded, retryable: false},
+		{err: fmt.Errorf("querying backend: %w", context.Canceled), retryable: false},
+	} {
+		assert.Equal(t, tc.retryable, IsRetryableError(tc.err), "unexpected classification of %v", tc.err)
+	}
+}
+
+// streamingProvider streams its external metrics, and records its diagnostics.
+type streamingProvider struct {
+	ExternalMetricsProvider
+	DiagnosticsRecorder
+}
+
+func (p *streamingProvider) StreamExternalMetric(context.Context, string, labels.Selector, ExternalMetricInfo) (<-chan external_metrics.ExternalMetricValue, error) {
+	return nil, nil
+}
+
+func TestRetryingProviderWrapsExtensions(t *testing.T) {
+	assertWrapsExtensions(t, NewRetryingProvider(&extendedProvider{}, 3, wait.Backoff{}))
+
+	external := NewRetryingExternalMetricsProvider(&streamingProvider{}, 3, wait.Backoff{})
+	_, ok := As[DiagnosticsProvider](external)
+	assert.True(t, ok, "should have kept DiagnosticsProvider")
+	_, ok = external.(StreamingExternalMetricsProvider)
+	assert.False(t, ok, "should not have implemented StreamingExternalMetricsProvider")
+}
@@ -0,0 +1,192 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/metrics/pkg/apis/custom_metrics"
+	"k8s.io/metrics/pkg/apis/external_metrics"
+	"k8s.io/utils/clock"
+)
+
+// StaticMetricsSpec declares the metrics served by a static metrics provider,
+// and their constant values.
+type StaticMetricsSpec struct {
+	CustomMetrics   []StaticCustomMetric
+	ExternalMetrics []StaticExternalMetric
+}
+
+// StaticCustomMetric declares a custom metric with a constant value.
+type StaticCustomMetric struct {
+	Info CustomMetricInfo
+	// APIVersion and Kind of the objects described by the metric.
+	APIVersion string
+	Kind       string
+	// Value is the value of the metric for any object without an override.
+	Value resource.Quantity
+	// Objects lists the objects returned when the metric is queried with a label selector.
+	Objects []StaticObject
+}
+
+// StaticObject is an object described by a static custom metric.
+type StaticObject struct {
+	// Namespace is empty for root-scoped objects.
+	Namespace string
+	Name      string
+	Labels    map[string]string
+	// Value overrides the value of the metric for this object, when set.
+	Value *resource.Quantity
+}
+
+// StaticExternalMetric declares a series of an external metric with a constant value.
+// There may be several series of the same metric, with different labels.
+type StaticExternalMetric struct {
+	Name   string
+	Labels map[string]string
+	Value  resource.Quantity
+}
+
+type staticMetricsProvider struct {
+	spec  StaticMetricsSpec
+	clock clock.PassiveClock
+}
+
+// NewStaticMetricsProvider creates a MetricsProvider serving the constant values
+// declared in the given spec.  This is useful for tests, and for metrics which
+// are fixed targets rather than measurements.
+func NewStaticMetricsProvider(spec StaticMetricsSpec) MetricsProvider {
+	return &staticMetricsProvider{spec: spec, clock: clock.RealClock{}}
+}
+
+func (p *staticMetricsProvider) customMetricFor(info CustomMetricInfo) (*StaticCustomMetric, error) {
+	for i := range p.spec.CustomMetrics {
+		if p.spec.CustomMetrics[i].Info == info {
+			return &p.spec.CustomMetrics[i], nil
+		}
+	}
+	return nil, NewMetricNotFoundError(info.GroupResource, info.Metric)
+}
+
+func (p *staticMetricsProvider) GetMetricByName(_ context.Context, name types.NamespacedName, info CustomMetricInfo, metricSelector labels.Selector) (*custom_metrics.MetricValue, error) {
+	metric, err := p.customMetricFor(info)
+	if err != nil {
+		return nil, err
+	}
+
+	value := metric.Value
+	for _, object := range metric.Objects {
+		if object.Namespace == name.Namespace && object.Name == name.Name && object.Value != nil {
+			value = *object.Value
+			break
+		}
+	}
+	return staticMetricValue(metric, name, value, metav1.NewTime(p.clock.Now()), metricSelector)
+}
+
+func (p *staticMetricsProvider) GetMetricBySelector(_ context.Context, namespace string, selector labels.Selector, info CustomMetricInfo, metricSelector labels.Selector) (*custom_metrics.MetricValueList, error) {
+	metric, err := p.customMetricFor(info)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &custom_metrics.MetricValueList{}
+	timestamp := metav1.NewTime(p.clock.Now())
+	for _, object := range metric.Objects {
+		if object.Namespace != namespace || !selector.Matches(labels.Set(object.Labels)) {
+			continue
+		}
+		value := metric.Value
+		if object.Value != nil {
+			value = *object.Value
+		}
+		metricValue, err := staticMetricValue(metric, types.NamespacedName{Namespace: object.Namespace, Name: object.Name}, value, timestamp, metricSelector)
+		if err != nil {
+			return nil, err
+		}
+		res.Items = append(res.Items, *metricValue)
+	}
+	return res, nil
+}
+
+func staticMetricValue(metric *StaticCustomMetric, name types.NamespacedName, value resource.Quantity, timestamp metav1.Time, metricSelector labels.Selector) (*custom_metrics.MetricValue, error) {
+	metricValue := &custom_metrics.MetricValue{
+		DescribedObject: custom_metrics.ObjectReference{
+			APIVersion: metric.APIVersion,
+			Kind:       metric.Kind,
+			Namespace:  name.Namespace,
+			Name:       name.Name,
+		},
+		Metric: custom_metrics.MetricIdentifier{
+			Name: metric.Info.Metric,
+		},
+		Timestamp: timestamp,
+		Value:     value,
+	}
+
+	if len(metricSelector.String()) > 0 {
+		sel, err := metav1.ParseToLabelSelector(metricSelector.String())
+		if err != nil {
+			return nil, err
+		}
+		metricValue.Metric.Selector = sel
+	}
+
+	return metricValue, nil
+}
+
+func (p *staticMetricsProvider) ListAllMetrics() []CustomMetricInfo {
+	infos := make([]CustomMetricInfo, 0, len(p.spec.CustomMetrics))
+	for _, metric := range p.spec.CustomMetrics {
+		infos = append(infos, metric.Info)
+	}
+	return infos
+}
+
+func (p *staticMetricsProvider) GetExternalMetric(_ context.Context, _ string, metricSelector labels.Selector, info ExternalMetricInfo) (*external_metrics.ExternalMetricValueList, error) {
+	res := &external_metrics.ExternalMetricValueList{}
+	timestamp := metav1.NewTime(p.clock.Now())
+	for _, metric := range p.spec.ExternalMetrics {
+		if metric.Name != info.Metric || !metricSelector.Matches(labels.Set(metric.Labels)) {
+			continue
+		}
+		res.Items = append(res.Items, external_metrics.ExternalMetricValue{
+			MetricName:   metric.Name,
+			MetricLabels: metric.Labels,
+			Timestamp:    timestamp,
+			Value:        metric.Value,
+		})
+	}
+	return res, nil
+}
+
+func (p *staticMetricsProvider) ListAllExternalMetrics() []ExternalMetricInfo {
+	seen := make(map[string]bool, len(p.spec.ExternalMetrics))
+	infos := make([]ExternalMetricInfo, 0, len(p.spec.ExternalMetrics))
+	for _, metric := range p.spec.ExternalMetrics {
+		if seen[metric.Name] {
+			continue
+		}
+		seen[metric.Name] = true
+		infos = append(infos, ExternalMetricInfo{Metric: metric.Name})
+	}
+	return infos
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+var podsMetricInfo = CustomMetricInfo{
+	GroupResource: schema.GroupResource{Resource: "pods"},
+	Namespaced:    true,
+	Metric:        "target",
+}
+
+func staticProvider() MetricsProvider {
+	override := resource.MustParse("20")
+	return NewStaticMetricsProvider(StaticMetricsSpec{
+		CustomMetrics: []StaticCustomMetric{
+			{
+				Info:       podsMetricInfo,
+				APIVersion: "v1",
+				Kind:       "Pod",
+				Value:      resource.MustParse("10"),
+				Objects: []StaticObject{
+					{Namespace: "default", Name: "a", Labels: map[string]string{"app": "web"}},
+					{Namespace: "default", Name: "b", Labels: map[string]string{"app": "web"}, Value: &override},
+					{Namespace: "default", Name: "c", Labels: map[string]string{"app": "db"}},
+					{Namespace: "other", Name: "d", Labels: map[string]string{"app": "web"}},
+				},
+			},
+		},
+		ExternalMetrics: []StaticExternalMetric{
+			{Name: "queue-length", Labels: map[string]string{"queue": "a"}, Value: resource.MustParse("1")},
+			{Name: "queue-length", Labels: map[string]string{"queue": "b"}, Value: resource.MustParse("2")},
+			{Name: "slo", Value: resource.MustParse("500m")},
+		},
+	})
+}
+
+func TestStaticMetricsProviderByName(t *testing.T) {
+	prov := staticProvider()
+
+	value, err := prov.GetMetricByName(context.Background(), types.NamespacedName{Namespace: "default", Name: "a"}, podsMetricInfo, labels.Everything())
+	require.NoError(t, err, "should have been able to get the metric for a listed object")
+	assert.Equal(t, "10", value.Value.String(), "should have returned the metric's value")
+	assert.Equal(t, "Pod", value.DescribedObject.Kind, "should have described the object with the metric's kind")
+	assert.Equal(t, "a", value.DescribedObject.Name, "should have described the requested object")
+
+	value, err = prov.GetMetricByName(context.Background(), types.NamespacedName{Namespace: "default", Name: "b"}, podsMetricInfo, labels.Everything())
+	require.NoError(t, err, "should have been able to get the metric for an object with an override")
+	assert.Equal(t, "20", value.Value.String(), "should have returned the object's override")
+
+	value, err = prov.GetMetricByName(context.Background(), types.NamespacedName{Namespace: "default", Name: "unlisted"}, podsMetricInfo, labels.Everything())
+	require.NoError(t, err, "should have been able to get the metric for an unlisted object")
+	assert.Equal(t, "10", value.Value.String(), "should have returned the metric's value for an unlisted object")
+
+	unknownInfo := podsMetricInfo
+	unknownInfo.Metric = "unknown"
+	_, err = prov.GetMetricByName(context.Background(), types.NamespacedName{Namespace: "default", Name: "a"}, unknownInfo, labels.Everything())
+	assert.True(t, apierr.IsNotFound(err), "should have returned a not found error for an unknown metric, got %v", err)
+}
+
+func TestStaticMetricsProviderBySelector(t *testing.T) {
+	prov := staticProvider()
+
+	selector, err := labels.Parse("app=web")
+	require.NoError(t, err)
+	values, err := prov.GetMetricBySelector(context.Background(), "default", selector, podsMetricInfo, labels.Everything())
+	require.NoError(t, err, "should have been able to get the metric by selector")
+	require.Len(t, values.Items, 2, "should have only returned the matching objects in the namespace")
+	assert.Equal(t, "a", values.Items[0].DescribedObject.Name)
+	assert.Equal(t, "10", values.Items[0].Value.String())
+	assert.Equal(t, "b", values.Items[1].DescribedObject.Name)
+	assert.Equal(t, "20", values.Items[1].Value.String())
+
+	assert.Equal(t, []CustomMetricInfo{podsMetricInfo}, prov.ListAllMetrics(), "should have listed the declared metrics")
+}
+
+func TestStaticMetricsProviderExternal(t *testing.T) {
+	prov := staticProvider()
+
+	values, err := prov.GetExternalMetric(context.Background(), "default", labels.Everything(), ExternalMetricInfo{Metric: "queue-length"})
+	require.NoError(t, err, "should have been able to get the external metric")
+	assert.Len(t, values.Items, 2, "should have returned all the series of the metric")
+
+	selector, err := labels.Parse("queue=b")
+	require.NoError(t, err)
+	values, err = prov.GetExternalMetric(context.Background(), "default", selector, ExternalMetricInfo{Metric: "queue-length"})
+	require.NoError(t, err, "should have been able to get the external metric by selector")
+	require.Len(t, values.Items, 1, "should have only returned the matching series")
+	assert.Equal(t, "2", values.Items[0].Value.String())
+
+	assert.Equal(t, []ExternalMetricInfo{{Metric: "queue-length"}, {Metric: "slo"}}, prov.ListAllExternalMetrics(), "should have listed each external metric once")
+}
+
+func TestStaticMetricsProviderTimestamps(t *testing.T) {
+	now := time.Date(2026, time.January, 1, 12, 0, 0, 0, time.UTC)
+	clock := testingclock.NewFakePassiveClock(now)
+	prov := staticProvider().(*staticMetricsProvider)
+	prov.clock = clock
+
+	value, err := prov.GetMetricByName(context.Background(), types.NamespacedName{Namespace: "default", Name: "a"}, podsMetricInfo, labels.Everything())
+	require.NoError(t, err)
+	assert.True(t, value.Timestamp.Time.Equal(now), "should have timestamped the value with the current time")
+
+	clock.SetTime(now.Add(time.Minute))
+	values, err := prov.GetMetricBySelector(context.Background(), "default", labels.Everything(), podsMetricInfo, labels.Everything())
+	require.NoError(t, err)
+	for _, value := range values.Items {
+		assert.True(t, value.Timestamp.Time.Equal(now.Add(time.Minute)), "should have timestamped the values with the current time")
+	}
+
+	externalValues, err := prov.GetExternalMetric(context.Background(), "default", labels.Everything(), ExternalMetricInfo{Metric: "slo"})
+	require.NoError(t, err)
+	if assert.Len(t, externalValues.Items, 1) {
+		assert.True(t, externalValues.Items[0].Timestamp.Time.Equal(now.Add(time.Minute)), "should have timestamped the external values with the current time")
+	}
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"k8s.io/metrics/pkg/apis/custom_metrics"
+)
+
+// TenantMapping maps the namespace of a request to the namespace in which the
+// inner provider of a tenant-scoped provider serves the metrics of the tenant.
+// It returns false for the namespaces of no tenant.
+type TenantMapping func(namespace string) (string, bool)
+
+// NewTenantPrefixMapping creates a TenantMapping mapping each namespace to the
+// namespace with the given prefix, e.g. web to tenant-a-web for tenant-a-.
+func NewTenantPrefixMapping(prefix string) TenantMapping {
+	return func(namespace string) (string, bool) {
+		return prefix + namespace, true
+	}
+}
+
+// NewStaticTenantMapping creates a TenantMapping mapping the namespaces with the
+// given map, keyed by the namespaces of the requests.  Other namespaces are not
+// served.
+func NewStaticTenantMapping(namespaces map[string]string) TenantMapping {
+	return func(namespace string) (string, bool) {
+		tenantNamespace, ok := namespaces[namespace]
+		return tenantNamespace, ok
+	}
+}
+
+type tenantScopedProvider struct {
+	CustomMetricsProvider
+
+	mapping TenantMapping
+}
+
+// NewTenantScopedProvider creates a CustomMetricsProvider serving, for each
+// namespace, the metrics the inner one serves in the namespace of the tenant the
+// mapping maps it to, for instance in a hub cluster aggregating the metrics of
+// several tenants.  The values are returned in the namespace of the request, and
+// the values the inner provider returns in other namespaces are dropped, so that
+// a query never returns the metrics of another tenant.  Metrics of root-scoped
+// objects are passed through, except for values in a namespace.  The extensions for
+// queries of the inner provider would bypass the mapping, so it's only queried as a
+// plain CustomMetricsProvider: see WrappingCustomMetricsProvider.
+func NewTenantScopedProvider(inner CustomMetricsProvider, mapping TenantMapping) CustomMetricsProvider {
+	return &tenantScopedProvider{
+		CustomMetricsProvider: inner,
+		mapping:               mapping,
+	}
+}
+
+// Unwrap returns the provider serving the metrics of the tenants.
+func (p *tenantScopedProvider) Unwrap() CustomMetricsProvider {
+	return p.CustomMetricsProvider
+}
+
+func (p *tenantScopedProvider) GetMetricByName(ctx context.Context, name types.NamespacedName, info CustomMetricInfo, metricSelector labels.Selector) (*custom_metrics.MetricValue, error) {
+	tenantNamespace, ok := p.tenantNamespace(name.Namespace, info)
+	if !ok {
+		return nil, NewMetricNotFoundForError(info.GroupResource, info.Metric, name.Name)
+	}
+
+	value, err := p.CustomMetricsProvider.GetMetricByName(ctx, types.NamespacedName{Namespace: tenantNamespace, Name: name.Name}, info, metricSelector)
+	if err != nil || value == nil {
+		return value, err
+	}
+	res, ok := p.scope(info, name.Namespace, tenantNamespace, value)
+	if !ok {
+		return nil, NewMetricNotFoundForError(info.GroupResource, info.Metric, name.Name)
+	}
+	return res, nil
+}
+
+func (p *tenantScopedProvider) GetMetricBySelector(ctx context.Context, namespace string, selector labels.Selector, info CustomMetricInfo, metricSelector labels.Selector) (*custom_metrics.MetricValueList, error) {
+	tenantNamespace, ok := p.tenantNamespace(namespace, info)
+	if !ok {
+		return &custom_metrics.MetricValueList{}, nil
+	}
+
+	values, err := p.CustomMetricsProvider.GetMetricBySelector(ctx, tenantNamespace, selector, info, metricSelector)
+	if err != nil || values == nil {
+		return values, err
+	}
+
+	res := &custom_metrics.MetricValueList{ListMeta: values.ListMeta, Items: make([]custom_metrics.MetricValue, 0, len(values.Items))}
+	for i := range values.Items {
+		if value, ok := p.scope(info, namespace, tenantNamespace, &values.Items[i]); ok {
+			res.Items = append(res.Items, *value)
+		}
+	}
+	return res, nil
+}
+
+// tenantNamespace returns the namespace of the inner provider for the namespace of
+// a request, which is empty for root-scoped objects.
+func (p *tenantScopedProvider) tenantNamespace(namespace string, info CustomMetricInfo) (string, bool) {
+	if !info.Namespaced {
+		return "", true
+	}
+	tenantNamespace, ok := p.mapping(namespace)
+	if !ok {
+		klog.V(4).InfoS("Not serving custom metric for namespace of no tenant", "metric", info.Metric, "namespace", namespace)
+	}
+	return tenantNamespace, ok
+}
+
+// scope returns the value in the namespace of the request, or false if the inner
+// provider returned it in another namespace than the one of the tenant.  The value
+// is copied rather than modified, since the one of the inner provider may be shared,
+// for instance by a cache.
+func (p *tenantScopedProvider) scope(info CustomMetricInfo, namespace, tenantNamespace string, value *custom_metrics.MetricValue) (*custom_metrics.MetricValue, bool) {
+	if value.DescribedObject.Namespace != tenantNamespace {
+		klog.InfoS("Dropped custom metric value outside of the namespace of the tenant", "metric", info.Metric, "object", value.DescribedObject.Name,
+			"namespace", value.DescribedObject.Namespace, "tenantNamespace", tenantNamespace)
+		return nil, false
+	}
+	if namespace == tenantNamespace {
+		return value, true
+	}
+
+	res := value.DeepCopy()
+	res.DescribedObject.Namespace = namespace
+	return res, true
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/metrics/pkg/apis/custom_metrics"
+)
+
+// hubProvider serves the metrics of the pods of all tenants, whatever the
+// namespace of the query, to check that they do not leak to other tenants.
+type hubProvider struct {
+	namespaces []string
+}
+
+func (p *hubProvider) ListAllMetrics() []CustomMetricInfo {
+	return []CustomMetricInfo{tenantPodsInfo, tenantNodesInfo}
+}
+
+func (p *hubProvider) value(namespace, name string) custom_metrics.MetricValue {
+	return custom_metrics.MetricValue{
+		DescribedObject: custom_metrics.ObjectReference{Kind: "Pod", Namespace: namespace, Name: name},
+		Value:           resource.MustParse("1"),
+	}
+}
+
+func (p *hubProvider) GetMetricByName(_ context.Context, name types.NamespacedName, _ CustomMetricInfo, _ labels.Selector) (*custom_metrics.MetricValue, error) {
+	// a buggy backend returning the value of the first tenant
+	value := p.value(p.namespaces[0], name.Name)
+	return &value, nil
+}
+
+func (p *hubProvider) GetMetricBySelector(_ context.Context, _ string, _ labels.Selector, _ CustomMetricInfo, _ labels.Selector) (*custom_metrics.MetricValueList, error) {
+	res := &custom_metrics.MetricValueList{}
+	for _, namespace := range p.namespaces {
+		res.Items = append(res.Items, p.value(namespace, "web"))
+	}
+	res.Items = append(res.Items, p.value("", "node-1"))
+	return res, nil
+}
+
+var (
+	tenantPodsInfo  = CustomMetricInfo{GroupResource: schema.GroupResource{Resource: "pods"}, Namespaced: true, Metric: "requests"}
+	tenantNodesInfo = CustomMetricInfo{GroupResource: schema.GroupResource{Resource: "nodes"}, Metric: "requests"}
+)
+
+func TestTenantScopedProviderBySelector(t *testing.T) {
+	inner := &hubProvider{namespaces: []string{"tenant-a-web", "tenant-b-web"}}
+	prov := NewTenantScopedProvider(inner, NewTenantPrefixMapping("tenant-a-"))
+
+	values, err := prov.GetMetricBySelector(context.Background(), "web", labels.Everything(), tenantPodsInfo, labels.Everything())
+	require.NoError(t, err)
+	if assert.Len(t, values.Items, 1, "should only have returned the values of the tenant") {
+		assert.Equal(t, "web", values.Items[0].DescribedObject.Namespace, "should have returned the value in the namespace of the request")
+	}
+
+	values, err = prov.GetMetricBySelector(context.Background(), "", labels.Everything(), tenantNodesInfo, labels.Everything())
+	require.NoError(t, err)
+	if assert.Len(t, values.Items, 1, "should only have returned the values of root-scoped objects") {
+		assert.Equal(t, "node-1", values.Items[0].DescribedObject.Name)
+	}
+}
+
+func TestTenantScopedProviderByName(t *testing.T) {
+	inner := &hubProvider{namespaces: []string{"hub-a"}}
+	prov := NewTenantScopedProvider(inner, NewStaticTenantMapping(map[string]string{"a": "hub-a", "b": "hub-b"}))
+
+	value, err := prov.GetMetricByName(context.Background(), types.NamespacedName{Namespace: "a", Name: "web"}, tenantPodsInfo, labels.Everything())
+	require.NoError(t, err, "should have returned the value of the tenant")
+	assert.Equal(t, "a", value.DescribedObject.Namespace)
+
+	_, err = prov.GetMetricByName(context.Background(), types.NamespacedName{Namespace: "b", Name: "web"}, tenantPodsInfo, labels.Everything())
+	assert.True(t, apierr.IsNotFound(err), "should not have returned the value of another tenant, got %v", err)
+
+	_, err = prov.GetMetricByName(context.Background(), types.NamespacedName{Namespace: "c", Name: "web"}, tenantPodsInfo, labels.Everything())
+	assert.True(t, apierr.IsNotFound(err), "should not have served a namespace of no tenant, got %v", err)
+
+	values, err := prov.GetMetricBySelector(context.Background(), "c", labels.Everything(), tenantPodsInfo, labels.Everything())
+	require.NoError(t, err)
+	assert.Empty(t, values.Items, "should not have served a namespace of no tenant")
+}
+
+func TestTenantScopedProviderWrapsExtensions(t *testing.T) {
+	assertWrapsExtensions(t, NewTenantScopedProvider(&extendedProvider{}, NewTenantPrefixMapping("tenant-a-")))
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+// Unwrap returns the provider wrapped by the given one, if it is a
+// WrappingCustomMetricsProvider or a WrappingExternalMetricsProvider, and nil
+// otherwise.
+func Unwrap(p interface{}) interface{} {
+	switch wrapping := p.(type) {
+	case WrappingCustomMetricsProvider:
+		if inner := wrapping.Unwrap(); inner != nil {
+			return inner
+		}
+	case WrappingExternalMetricsProvider:
+		if inner := wrapping.Unwrap(); inner != nil {
+			return inner
+		}
+	}
+	return nil
+}
+
+// As returns the first provider implementing T among the given one and the ones it
+// wraps, found with Unwrap, like errors.As does for errors.  It's meant to look up
+// the optional extensions describing the metrics or the provider, which decorators
+// do not implement themselves.
+func As[T any](p interface{}) (T, bool) {
+	for ; p != nil; p = Unwrap(p) {
+		if t, ok := p.(T); ok {
+			return t, true
+		}
+	}
+	var zero T
+	return zero, false
+}
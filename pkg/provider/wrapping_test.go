@@ -0,0 +1,135 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/metrics/pkg/apis/custom_metrics"
+)
+
+// extendedProvider implements all the optional extensions of CustomMetricsProvider.
+type extendedProvider struct {
+	capableProvider
+}
+
+func (p *extendedProvider) MetricsChanged() <-chan struct{} {
+	return make(chan struct{})
+}
+
+func (p *extendedProvider) MetricType(CustomMetricInfo) MetricType {
+	return MetricTypeCounter
+}
+
+func (p *extendedProvider) DescribeMetric(CustomMetricInfo) MetricDescription {
+	return MetricDescription{Description: "Requests served"}
+}
+
+func (p *extendedProvider) MetricDiagnostics() []MetricDiagnostics {
+	return nil
+}
+
+func (p *extendedProvider) Collectors() []prometheus.Collector {
+	return nil
+}
+
+func (p *extendedProvider) GetMetricByUID(context.Context, types.NamespacedName, types.UID, CustomMetricInfo, labels.Selector) (*custom_metrics.MetricValue, error) {
+	return nil, nil
+}
+
+func (p *extendedProvider) GetMetricByObject(context.Context, custom_metrics.ObjectReference, CustomMetricInfo, labels.Selector) (*custom_metrics.MetricValue, error) {
+	return nil, nil
+}
+
+func (p *extendedProvider) GetMetricsByName(context.Context, types.NamespacedName, []CustomMetricInfo, labels.Selector) (*custom_metrics.MetricValueList, error) {
+	return nil, nil
+}
+
+func (p *extendedProvider) GetMetricsBySelector(context.Context, string, labels.Selector, []CustomMetricInfo, labels.Selector) (*custom_metrics.MetricValueList, error) {
+	return nil, nil
+}
+
+// wrappingProvider is a decorator implementing no extension itself.
+type wrappingProvider struct {
+	CustomMetricsProvider
+}
+
+func (p *wrappingProvider) Unwrap() CustomMetricsProvider {
+	return p.CustomMetricsProvider
+}
+
+// assertWrapsExtensions asserts that the extensions describing the metrics of the
+// provider wrapped by the given decorator are found on it with As, and that it does
+// not implement the extensions for queries, which would bypass it.
+func assertWrapsExtensions(t *testing.T, decorator CustomMetricsProvider) {
+	t.Helper()
+
+	_, ok := As[NotifyingCustomMetricsProvider](decorator)
+	assert.True(t, ok, "should have kept NotifyingCustomMetricsProvider")
+	_, ok = As[CapableCustomMetricsProvider](decorator)
+	assert.True(t, ok, "should have kept CapableCustomMetricsProvider")
+	_, ok = As[TypedCustomMetricsProvider](decorator)
+	assert.True(t, ok, "should have kept TypedCustomMetricsProvider")
+	_, ok = As[DescribedCustomMetricsProvider](decorator)
+	assert.True(t, ok, "should have kept DescribedCustomMetricsProvider")
+	_, ok = As[DiagnosticsProvider](decorator)
+	assert.True(t, ok, "should have kept DiagnosticsProvider")
+	_, ok = As[InstrumentedProvider](decorator)
+	assert.True(t, ok, "should have kept InstrumentedProvider")
+
+	_, ok = decorator.(UIDCustomMetricsProvider)
+	assert.False(t, ok, "should not have implemented UIDCustomMetricsProvider")
+	_, ok = decorator.(ObjectCustomMetricsProvider)
+	assert.False(t, ok, "should not have implemented ObjectCustomMetricsProvider")
+	_, ok = decorator.(MultiMetricCustomMetricsProvider)
+	assert.False(t, ok, "should not have implemented MultiMetricCustomMetricsProvider")
+}
+
+func TestAs(t *testing.T) {
+	inner := &extendedProvider{}
+	decorator := &wrappingProvider{&wrappingProvider{inner}}
+	assertWrapsExtensions(t, decorator)
+
+	typed, ok := As[TypedCustomMetricsProvider](decorator)
+	if assert.True(t, ok) {
+		assert.Same(t, inner, typed, "should have found the innermost provider")
+	}
+	_, ok = As[NotifyingCustomMetricsProvider](&wrappingProvider{&listingProvider{}})
+	assert.False(t, ok, "should not have found an extension no provider implements")
+	assert.Nil(t, Unwrap(inner), "should not have unwrapped a provider which is no decorator")
+}
+
+func TestCustomMetricResourceListerWrapped(t *testing.T) {
+	metrics := []CustomMetricInfo{
+		{GroupResource: schema.GroupResource{Resource: "pods"}, Namespaced: true, Metric: "by-selector"},
+	}
+	inner := &extendedProvider{capableProvider{listingProvider{metrics: metrics}}}
+
+	resources := NewCustomMetricResourceLister(&wrappingProvider{inner}).ListAPIResources()
+	if assert.Len(t, resources, 1) {
+		assert.Equal(t, metav1.Verbs{"list"}, resources[0].Verbs, "should have advertised the capabilities of the wrapped provider")
+		assert.Equal(t, []string{string(MetricTypeCounter)}, resources[0].Categories, "should have advertised the type known by the wrapped provider")
+	}
+}
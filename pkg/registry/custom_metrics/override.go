@@ -0,0 +1,69 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"context"
+	"fmt"
+
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/warning"
+	"k8s.io/metrics/pkg/apis/custom_metrics"
+
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/overrides"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
+)
+
+// overriddenValues returns the values forced by the request for the requested
+// objects, instead of querying the provider.  Objects selected by label must be
+// listed in the override, since the provider is not asked which objects match.
+func (r *REST) overriddenValues(ctx context.Context, override *overrides.Override, namespace, name string, infos []provider.CustomMetricInfo) (*custom_metrics.MetricValueList, error) {
+	names := []string{name}
+	if name == "*" {
+		if _, ok := override.Value(); ok {
+			return nil, apierr.NewBadRequest(fmt.Sprintf("the %s header must list the values of objects selected by label as name=quantity pairs", overrides.HeaderMetricOverride))
+		}
+		names = override.Names()
+	}
+
+	object := custom_metrics.ObjectReference{Namespace: namespace}
+	if r.RESTMapper != nil {
+		if kind, err := r.RESTMapper.KindFor(infos[0].GroupResource.WithVersion("")); err == nil {
+			object.APIVersion, object.Kind = kind.GroupVersion().String(), kind.Kind
+		}
+	}
+	timestamp := metav1.NewTime(r.Clock.Now())
+	res := &custom_metrics.MetricValueList{}
+	for _, info := range infos {
+		for _, name := range names {
+			value, ok := override.ValueFor(name)
+			if !ok {
+				return nil, provider.NewMetricNotFoundForError(info.GroupResource, info.Metric, name)
+			}
+			object.Name = name
+			res.Items = append(res.Items, custom_metrics.MetricValue{
+				DescribedObject: object,
+				Metric:          custom_metrics.MetricIdentifier{Name: info.Metric},
+				Timestamp:       timestamp,
+				Value:           value,
+			})
+		}
+	}
+	warning.AddWarning(ctx, "", fmt.Sprintf("the values of %s are forced by the %s header, not returned by the provider", infos[0].GroupResource.String(), overrides.HeaderMetricOverride))
+	return res, nil
+}
@@ -18,35 +18,110 @@ package apiserver
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metainternalversion "k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apiserver/pkg/endpoints/request"
 	"k8s.io/apiserver/pkg/registry/rest"
+	"k8s.io/apiserver/pkg/warning"
+	"k8s.io/klog/v2"
 	"k8s.io/metrics/pkg/apis/custom_metrics"
+	"k8s.io/utils/clock"
 
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/cachecontrol"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/coalesce"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/logsampling"
 	"sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/metrics"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/overrides"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/ratelimit"
 	cm_rest "sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/registry/rest"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/servertiming"
 	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/registry/internal"
 )
 
+// providerLoggerName is the name of the logger used for queries passed to the provider.
+// Their verbosity can be raised on its own with --vmodule=reststorage=5.
+const providerLoggerName = "provider"
+
 type REST struct {
 	cmProvider        provider.CustomMetricsProvider
 	freshnessObserver metrics.FreshnessObserver
+	inflight          coalesce.Group
+
+	// RateLimiter limits the rate of queries passed to the provider for each metric.
+	// It may be nil, in which case queries are not limited.
+	RateLimiter *ratelimit.MetricRateLimiter
+	// RequestCounter counts the requests for each metric.  It may be nil, in which
+	// case requests are not counted.
+	RequestCounter metrics.RequestCounter
+	// ValueAgeRecorder records the age of the most recent value served for each
+	// metric.  It may be nil, in which case ages are not recorded.
+	ValueAgeRecorder metrics.ValueAgeRecorder
+	// AllowedNamespaces restricts the namespaces for which metrics are served.
+	// When empty, metrics are served for all namespaces.
+	AllowedNamespaces sets.Set[string]
+	// DefaultNamespace is the namespace assumed for requests without namespace for
+	// metrics the provider only serves for namespaced objects, for legacy clients
+	// omitting it.  When empty, such requests are for root-scoped objects.
+	DefaultNamespace string
+	// MaxAges sets how long responses for each metric may be cached by clients.
+	// Responses for other metrics must not be cached.
+	MaxAges cachecontrol.MaxAges
+	// CoalesceWindow is how long the result of a query is shared with identical
+	// requests of the same user after it succeeded.  When zero, only concurrent
+	// requests share it.
+	CoalesceWindow time.Duration
+	// DefaultWindow is the window set on metric values for which the provider
+	// leaves it unset.  When zero, such values are returned without window.
+	DefaultWindow time.Duration
+	// SignificantDigits is the number of significant digits metric values are
+	// rounded to, before the transform.  When zero, values are not rounded.
+	SignificantDigits int
+	// Transform is applied to each metric value before it is returned.
+	// It may be nil, in which case values are returned as is.
+	Transform provider.CustomMetricTransformFunc
+	// Validator validates each query before it is passed to the provider.  It may be
+	// nil, in which case all queries are passed.
+	Validator provider.CustomMetricQueryValidatorFunc
+	// LogSampler samples the requests whose successful queries are logged.  It may
+	// be nil, in which case all of them are logged.  Failed queries are always logged.
+	LogSampler logsampling.Sampler
+	// MaxSelectorRequirements limits the number of requirements of each selector of
+	// the queries passed to the provider.  When zero, selectors are not limited.
+	MaxSelectorRequirements int
+	// Clock is used to compute the age of metric values printed in tables, and to
+	// timestamp the values forced by overrides.
+	// NewREST sets it to the real clock.
+	Clock clock.PassiveClock
+	// RESTMapper maps the resources of described objects to their kinds, for
+	// providers implementing provider.ObjectCustomMetricsProvider.  It may be nil,
+	// in which case they are passed names, like other providers.
+	RESTMapper apimeta.RESTMapper
 }
 
 var _ rest.Storage = &REST{}
 var _ cm_rest.ListerWithOptions = &REST{}
+var _ cm_rest.CacheControlled = &REST{}
+var _ rest.TableConvertor = &REST{}
 
 func NewREST(cmProvider provider.CustomMetricsProvider) *REST {
 	freshnessObserver := metrics.NewFreshnessObserver(custom_metrics.GroupName)
 	return &REST{
 		cmProvider:        cmProvider,
 		freshnessObserver: freshnessObserver,
+		Clock:             clock.RealClock{},
 	}
 }
 
@@ -89,6 +164,12 @@ func (r *REST) List(ctx context.Context, options *metainternalversion.ListOption
 		}
 		metricLabelSelector = sel
 	}
+	if err := internal.CheckSelectorRequirements("labelSelector", selector, r.MaxSelectorRequirements); err != nil {
+		return nil, err
+	}
+	if err := internal.CheckSelectorRequirements("metricLabelSelector", metricLabelSelector, r.MaxSelectorRequirements); err != nil {
+		return nil, err
+	}
 
 	// grab the name, if present, from the field selector list options
 	// (this is how the list handler logic injects it)
@@ -111,47 +192,346 @@ func (r *REST) List(ctx context.Context, options *metainternalversion.ListOption
 	metricName := requestInfo.Subresource
 
 	groupResource := schema.ParseGroupResource(resourceRaw)
+	if namespace == "" && r.DefaultNamespace != "" && r.onlyNamespaced(groupResource, metricName) {
+		namespace = r.DefaultNamespace
+		klog.V(2).InfoS("Assumed the default namespace for a request without namespace, which is deprecated", "resource", groupResource.String(), "metric", metricName, "namespace", namespace)
+		warning.AddWarning(ctx, "", fmt.Sprintf("custom metric %s of %s requested without namespace, assuming namespace %s: requests without namespace for namespaced objects are deprecated", metricName, groupResource.String(), namespace))
+	}
 
-	var res *custom_metrics.MetricValueList
-	var err error
+	info := provider.CustomMetricInfo{
+		GroupResource: groupResource,
+		Metric:        metricName,
+		Namespaced:    namespace != "",
+	}
+	// several metrics can be requested at once, separated by commas
+	infos := []provider.CustomMetricInfo{}
+	for _, metric := range strings.Split(metricName, ",") {
+		if metric == "" {
+			return nil, apierr.NewBadRequest(fmt.Sprintf("invalid list of metrics %q", metricName))
+		}
+		infos = append(infos, provider.CustomMetricInfo{GroupResource: groupResource, Metric: metric, Namespaced: info.Namespaced})
+	}
+	// root-scoped resources are not in any namespace, so they are always served
+	if namespace != "" && r.AllowedNamespaces.Len() > 0 && !r.AllowedNamespaces.Has(namespace) {
+		return nil, apierr.NewForbidden(groupResource, metricName, fmt.Errorf("metrics are not served for namespace %s", namespace))
+	}
+	for _, info := range infos {
+		capabilities := provider.CapabilitiesFor(r.cmProvider, info)
+		if name == "*" && !capabilities.BySelector {
+			return nil, apierr.NewMethodNotSupported(groupResource, "list")
+		}
+		if name != "*" && !capabilities.ByName {
+			return nil, apierr.NewMethodNotSupported(groupResource, "get")
+		}
+	}
+	if r.Validator != nil {
+		for _, info := range infos {
+			query := provider.CustomMetricQuery{Info: info, Namespace: namespace, Name: name, Selector: selector, MetricSelector: metricLabelSelector}
+			if err := r.Validator(ctx, query); err != nil {
+				return nil, internal.RejectedQueryError(err)
+			}
+		}
+	}
+	if r.RequestCounter != nil {
+		verb := "get"
+		if name == "*" {
+			verb = "list"
+		}
+		for _, info := range infos {
+			r.RequestCounter.Count(info.Metric, groupResource.String(), verb)
+		}
+	}
+	// the values forced by tests bypass the provider, and its rate limits
+	if override, ok := overrides.From(ctx); ok {
+		return r.overriddenValues(ctx, override, namespace, name, infos)
+	}
+	for _, info := range infos {
+		if accepted, retryAfter := r.RateLimiter.Accept(info.Metric, info.String()); !accepted {
+			return nil, ratelimit.NewTooManyRequestsError(info.Metric, retryAfter)
+		}
+	}
 
-	// handle namespaced and root metrics
-	if name == "*" {
-		res, err = r.handleWildcardOp(ctx, namespace, groupResource, selector, metricName, metricLabelSelector)
-	} else {
-		res, err = r.handleIndividualOp(ctx, namespace, groupResource, name, metricName, metricLabelSelector)
+	ctx = internal.RequestContext(ctx)
+
+	// identical requests, concurrent or within the coalescing window, are collapsed
+	// into a single provider query
+	key := fmt.Sprintf("%s/%s/%s?selector=%s&metricSelector=%s", namespace, info.String(), name, selector.String(), metricLabelSelector.String())
+	if uid, ok := provider.ObjectUIDFromContext(ctx); ok {
+		key += "&uid=" + string(uid)
+	}
+	// providers may serve each user differently, e.g. by impersonating them
+	if user, ok := request.UserFrom(ctx); ok {
+		key += "&user=" + user.GetName()
 	}
+	result, err, shared := r.inflight.Do(ctx, key, r.CoalesceWindow, func(ctx context.Context) (interface{}, error) {
+		var res *custom_metrics.MetricValueList
+		var err error
+
+		// the provenance is recorded for the query, and shared with identical requests
+		ctx, provenance := provider.WithProvenanceRecorder(ctx)
+		logger := klog.FromContext(ctx).WithName(providerLoggerName)
+		sampledLogger := r.LogSampler.Logger(logger)
+		sampledLogger.V(5).Info("querying custom metrics provider", "metric", info.String(), "namespace", namespace, "name", name, "selector", selector.String(), "metricSelector", metricLabelSelector.String())
+
+		// handle namespaced and root metrics
+		if len(infos) > 1 {
+			res, err = r.handleMultiOp(ctx, namespace, name, selector, infos, metricLabelSelector)
+		} else if name == "*" {
+			res, err = r.handleWildcardOp(ctx, namespace, groupResource, selector, metricName, metricLabelSelector)
+		} else {
+			res, err = r.handleIndividualOp(ctx, namespace, groupResource, name, metricName, metricLabelSelector)
+		}
+
+		var noData *provider.NoDataError
+		if errors.As(err, &noData) {
+			sampledLogger.V(5).Info("custom metrics provider returned no data", "metric", info.String(), "err", err)
+			return &internal.FlightResult[*custom_metrics.MetricValueList]{Values: &custom_metrics.MetricValueList{}, Provenance: provenance(), NoData: noData.Error()}, nil
+		}
+		if err != nil {
+			logger.V(5).Info("custom metrics provider returned an error", "metric", info.String(), "err", err)
+			return nil, internal.ProviderError(logger, "custom", err)
+		}
+		sampledLogger.V(5).Info("custom metrics provider returned values", "metric", info.String(), "count", len(res.Items))
+
+		// values for former objects of the same name are not for the requested one
+		if uid, ok := provider.ObjectUIDFromContext(ctx); ok {
+			items := make([]custom_metrics.MetricValue, 0, len(res.Items))
+			for _, item := range res.Items {
+				if provider.MatchesUID(item.DescribedObject, uid) {
+					items = append(items, item)
+				}
+			}
+			res.Items = items
+		}
+
+		if r.DefaultWindow > 0 {
+			windowSeconds := int64(r.DefaultWindow.Seconds())
+			for i := range res.Items {
+				if res.Items[i].WindowSeconds == nil {
+					res.Items[i].WindowSeconds = &windowSeconds
+				}
+			}
+		}
+
+		if r.SignificantDigits > 0 {
+			for i := range res.Items {
+				res.Items[i].Value = roundQuantity(res.Items[i].Value, r.SignificantDigits)
+			}
+		}
 
+		if r.Transform != nil {
+			for i := range res.Items {
+				if res.Items[i], err = r.Transform(infoFor(infos, &res.Items[i]), res.Items[i]); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return &internal.FlightResult[*custom_metrics.MetricValueList]{Values: res, Provenance: provenance()}, nil
+	})
 	if err != nil {
-		return nil, err
+		// the request may also have been canceled or timed out while waiting for an identical one
+		return nil, internal.ProviderError(klog.FromContext(ctx).WithName(providerLoggerName), "custom", err)
+	}
+	flight := result.(*internal.FlightResult[*custom_metrics.MetricValueList])
+	res := flight.Values
+	// the result is kept for the requests made within the coalescing window
+	if shared || r.CoalesceWindow > 0 {
+		res = res.DeepCopy()
+	}
+	if provenance := flight.Provenance; provenance != "" {
+		provider.SetProvenance(ctx, provenance)
+	}
+	// the warning lets clients tell a metric without data apart from a missing one
+	if noData := flight.NoData; noData != "" {
+		warning.AddWarning(ctx, "", noData)
 	}
 
 	for _, m := range res.Items {
 		r.freshnessObserver.Observe(m.Timestamp)
 	}
+	if r.ValueAgeRecorder != nil {
+		latest := map[string]metav1.Time{}
+		for i := range res.Items {
+			metric := infoFor(infos, &res.Items[i]).Metric
+			if timestamp, ok := latest[metric]; !ok || timestamp.Before(&res.Items[i].Timestamp) {
+				latest[metric] = res.Items[i].Timestamp
+			}
+		}
+		for metric, timestamp := range latest {
+			r.ValueAgeRecorder.Record(custom_metrics.GroupName, metric, timestamp)
+		}
+	}
 
 	return res, nil
 }
 
+// onlyNamespaced returns whether the provider serves the given metrics, which may
+// be a comma-separated list, for namespaced objects of the given resource but not
+// for root-scoped ones.
+func (r *REST) onlyNamespaced(groupResource schema.GroupResource, metricName string) bool {
+	metricNames := sets.New(strings.Split(metricName, ",")...)
+	namespaced, rootScoped := false, false
+	for _, info := range r.cmProvider.ListAllMetrics() {
+		if info.GroupResource != groupResource || !metricNames.Has(info.Metric) {
+			continue
+		}
+		if info.Namespaced {
+			namespaced = true
+		} else {
+			rootScoped = true
+		}
+	}
+	return namespaced && !rootScoped
+}
+
 func (r *REST) handleIndividualOp(ctx context.Context, namespace string, groupResource schema.GroupResource, name string, metricName string, metricLabelSelector labels.Selector) (*custom_metrics.MetricValueList, error) {
-	singleRes, err := r.cmProvider.GetMetricByName(ctx, types.NamespacedName{Namespace: namespace, Name: name}, provider.CustomMetricInfo{
+	info := provider.CustomMetricInfo{
 		GroupResource: groupResource,
 		Metric:        metricName,
 		Namespaced:    namespace != "",
-	}, metricLabelSelector)
+	}
+	uid, _ := provider.ObjectUIDFromContext(ctx)
+	uidProvider, keyedOnUID := r.cmProvider.(provider.UIDCustomMetricsProvider)
+	var singleRes *custom_metrics.MetricValue
+	var err error
+	if objectProvider, ok := r.cmProvider.(provider.ObjectCustomMetricsProvider); ok && r.RESTMapper != nil {
+		singleRes, err = r.getMetricByObject(ctx, objectProvider, namespace, name, uid, info, metricLabelSelector)
+	} else if keyedOnUID && uid != "" {
+		stop := servertiming.Start(ctx, servertiming.PhaseProvider)
+		singleRes, err = uidProvider.GetMetricByUID(ctx, types.NamespacedName{Namespace: namespace, Name: name}, uid, info, metricLabelSelector)
+		stop()
+	} else {
+		stop := servertiming.Start(ctx, servertiming.PhaseProvider)
+		singleRes, err = r.cmProvider.GetMetricByName(ctx, types.NamespacedName{Namespace: namespace, Name: name}, info, metricLabelSelector)
+		stop()
+	}
 	if err != nil {
 		return nil, err
 	}
+	// providers violating their contract must not crash the server
+	if singleRes == nil {
+		return nil, apierr.NewInternalError(fmt.Errorf("the custom metrics provider returned neither a value nor an error for metric %s of %s %s", metricName, groupResource.String(), name))
+	}
+	// a value for a former object of the same name is not for the requested one
+	if !provider.MatchesUID(singleRes.DescribedObject, uid) {
+		return nil, provider.NewMetricNotFoundForError(groupResource, metricName, name)
+	}
 
 	return &custom_metrics.MetricValueList{
 		Items: []custom_metrics.MetricValue{*singleRes},
 	}, nil
 }
 
+// handleMultiOp fetches several metrics for the same objects, in one call when the
+// provider supports it, and one metric after the other otherwise.
+func (r *REST) handleMultiOp(ctx context.Context, namespace string, name string, selector labels.Selector, infos []provider.CustomMetricInfo, metricLabelSelector labels.Selector) (*custom_metrics.MetricValueList, error) {
+	if multiProvider, ok := r.cmProvider.(provider.MultiMetricCustomMetricsProvider); ok {
+		var res *custom_metrics.MetricValueList
+		var err error
+		stop := servertiming.Start(ctx, servertiming.PhaseProvider)
+		if name == "*" {
+			res, err = multiProvider.GetMetricsBySelector(ctx, namespace, selector, infos, metricLabelSelector)
+		} else {
+			res, err = multiProvider.GetMetricsByName(ctx, types.NamespacedName{Namespace: namespace, Name: name}, infos, metricLabelSelector)
+		}
+		stop()
+		if err != nil {
+			return nil, err
+		}
+		if res == nil {
+			res = &custom_metrics.MetricValueList{}
+		}
+		return res, nil
+	}
+
+	res := &custom_metrics.MetricValueList{}
+	for _, info := range infos {
+		var values *custom_metrics.MetricValueList
+		var err error
+		if name == "*" {
+			values, err = r.handleWildcardOp(ctx, namespace, info.GroupResource, selector, info.Metric, metricLabelSelector)
+		} else {
+			values, err = r.handleIndividualOp(ctx, namespace, info.GroupResource, name, info.Metric, metricLabelSelector)
+		}
+		if err != nil {
+			return nil, err
+		}
+		res.Items = append(res.Items, values.Items...)
+	}
+	return res, nil
+}
+
+// infoFor returns the info of the requested metric the value is for.
+func infoFor(infos []provider.CustomMetricInfo, value *custom_metrics.MetricValue) provider.CustomMetricInfo {
+	for _, info := range infos {
+		if info.Metric == value.Metric.Name {
+			return info
+		}
+	}
+	return infos[0]
+}
+
+// getMetricByObject passes the reference of the described object, with its kind and
+// its UID, if selected, to the provider.  Objects of resources unknown to the RESTMapper have no metrics.
+func (r *REST) getMetricByObject(ctx context.Context, objectProvider provider.ObjectCustomMetricsProvider, namespace, name string, uid types.UID, info provider.CustomMetricInfo, metricLabelSelector labels.Selector) (*custom_metrics.MetricValue, error) {
+	stop := servertiming.Start(ctx, servertiming.PhaseMapper)
+	kind, err := r.RESTMapper.KindFor(info.GroupResource.WithVersion(""))
+	stop()
+	if err != nil {
+		klog.FromContext(ctx).V(4).Info("Unable to map the resource of the described object to its kind", "resource", info.GroupResource, "err", err)
+		return nil, provider.NewMetricNotFoundForError(info.GroupResource, info.Metric, name)
+	}
+
+	object := custom_metrics.ObjectReference{
+		APIVersion: kind.GroupVersion().String(),
+		Kind:       kind.Kind,
+		Namespace:  namespace,
+		Name:       name,
+		UID:        uid,
+	}
+	defer servertiming.Start(ctx, servertiming.PhaseProvider)()
+	return objectProvider.GetMetricByObject(ctx, object, info, metricLabelSelector)
+}
+
 func (r *REST) handleWildcardOp(ctx context.Context, namespace string, groupResource schema.GroupResource, selector labels.Selector, metricName string, metricLabelSelector labels.Selector) (*custom_metrics.MetricValueList, error) {
-	return r.cmProvider.GetMetricBySelector(ctx, namespace, selector, provider.CustomMetricInfo{
+	stop := servertiming.Start(ctx, servertiming.PhaseProvider)
+	res, err := r.cmProvider.GetMetricBySelector(ctx, namespace, selector, provider.CustomMetricInfo{
 		GroupResource: groupResource,
 		Metric:        metricName,
 		Namespaced:    namespace != "",
 	}, metricLabelSelector)
+	stop()
+	if err != nil {
+		return nil, err
+	}
+
+	// a selector matching no objects is not an error
+	if res == nil {
+		res = &custom_metrics.MetricValueList{}
+	}
+	return res, nil
+}
+
+// ConvertToTable converts metric values into a table, which is used by kubectl to
+// print them.
+func (r *REST) ConvertToTable(ctx context.Context, object runtime.Object, tableOptions runtime.Object) (*metav1.Table, error) {
+	return tableConvertor{clock: r.Clock}.ConvertToTable(ctx, object, tableOptions)
+}
+
+// CacheControl returns the Cache-Control header value of responses for the
+// requested metric, allowing them to be cached for its max age, if any.  When
+// several metrics are requested, the shortest of their max ages is used.
+func (r *REST) CacheControl(ctx context.Context) string {
+	requestInfo, ok := request.RequestInfoFrom(ctx)
+	if !ok {
+		return cachecontrol.NoCache
+	}
+	metricNames := strings.Split(requestInfo.Subresource, ",")
+	shortest := metricNames[0]
+	for _, metric := range metricNames[1:] {
+		if r.MaxAges[metric] < r.MaxAges[shortest] {
+			shortest = metric
+		}
+	}
+	return r.MaxAges.Header(shortest)
 }
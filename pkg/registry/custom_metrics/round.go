@@ -0,0 +1,37 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"math/big"
+
+	"gopkg.in/inf.v0"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// roundQuantity rounds the quantity to the given number of significant digits,
+// half away from zero, keeping its format.
+func roundQuantity(q resource.Quantity, digits int) resource.Quantity {
+	d := q.AsDec()
+	precision := len(new(big.Int).Abs(d.UnscaledBig()).String())
+	if precision <= digits {
+		return q
+	}
+	rounded := new(inf.Dec).Round(d, d.Scale()-inf.Scale(precision-digits), inf.RoundHalfUp)
+	return *resource.NewDecimalQuantity(*rounded, q.Format)
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/apiserver/pkg/registry/rest"
+	"k8s.io/metrics/pkg/apis/custom_metrics"
+	"k8s.io/utils/clock"
+)
+
+var tableColumnDefinitions = []metav1.TableColumnDefinition{
+	{Name: "Name", Type: "string", Format: "name", Description: "Name of the object described by the metric"},
+	{Name: "Metric", Type: "string", Description: "Name of the metric"},
+	{Name: "Value", Type: "string", Format: "quantity", Description: "Value of the metric"},
+	{Name: "Age", Type: "date", Description: "Time elapsed since the metric value was collected"},
+	{Name: "Kind", Type: "string", Priority: 1, Description: "Kind of the object described by the metric"},
+	{Name: "Window", Type: "string", Priority: 1, Description: "Window over which the metric value was calculated"},
+	{Name: "Selector", Type: "string", Priority: 1, Description: "Selector of the metric labels"},
+}
+
+// tableConvertor computes the age of metric values with its clock.
+type tableConvertor struct {
+	clock clock.PassiveClock
+}
+
+var _ rest.TableConvertor = tableConvertor{}
+
+// ConvertToTable converts metric values into a table, which is used by kubectl to
+// print them.  Additional details about the values are in priority 1 (wide) columns.
+func (c tableConvertor) ConvertToTable(_ context.Context, object runtime.Object, tableOptions runtime.Object) (*metav1.Table, error) {
+	var values []custom_metrics.MetricValue
+	switch t := object.(type) {
+	case *custom_metrics.MetricValueList:
+		values = t.Items
+	case *custom_metrics.MetricValue:
+		values = []custom_metrics.MetricValue{*t}
+	default:
+		return nil, fmt.Errorf("unable to convert %T to a table", object)
+	}
+
+	includeObject := false
+	if opts, ok := tableOptions.(*metav1.TableOptions); ok {
+		includeObject = opts.IncludeObject == metav1.IncludeObject
+	}
+
+	table := &metav1.Table{
+		ColumnDefinitions: tableColumnDefinitions,
+		Rows:              make([]metav1.TableRow, 0, len(values)),
+	}
+	for i := range values {
+		value := &values[i]
+
+		row := metav1.TableRow{
+			Cells: []interface{}{
+				value.DescribedObject.Name,
+				value.Metric.Name,
+				value.Value.String(),
+				translateTimestampSince(c.clock, value.Timestamp),
+				value.DescribedObject.Kind,
+				formatWindow(value.WindowSeconds),
+				metav1.FormatLabelSelector(value.Metric.Selector),
+			},
+		}
+		// metric values have no metadata of their own, so the described object's is used instead
+		if includeObject {
+			row.Object = runtime.RawExtension{Object: value}
+		} else {
+			row.Object = runtime.RawExtension{Object: &metav1.PartialObjectMetadata{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      value.DescribedObject.Name,
+					Namespace: value.DescribedObject.Namespace,
+				},
+			}}
+		}
+		table.Rows = append(table.Rows, row)
+	}
+
+	return table, nil
+}
+
+func translateTimestampSince(clock clock.PassiveClock, timestamp metav1.Time) string {
+	if timestamp.IsZero() {
+		return "<unknown>"
+	}
+	return duration.HumanDuration(clock.Since(timestamp.Time))
+}
+
+func formatWindow(windowSeconds *int64) string {
+	if windowSeconds == nil {
+		return "<none>"
+	}
+	return (time.Duration(*windowSeconds) * time.Second).String()
+}
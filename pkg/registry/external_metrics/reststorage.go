@@ -18,29 +18,84 @@ package apiserver
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
+	apierr "k8s.io/apimachinery/pkg/api/errors"
 	metainternalversion "k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apiserver/pkg/endpoints/request"
 	"k8s.io/apiserver/pkg/registry/rest"
+	"k8s.io/apiserver/pkg/warning"
+	"k8s.io/klog/v2"
 	"k8s.io/metrics/pkg/apis/external_metrics"
+	"k8s.io/utils/clock"
 
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/cachecontrol"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/coalesce"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/logsampling"
 	"sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/metrics"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/overrides"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/ratelimit"
+	cm_rest "sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/registry/rest"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/servertiming"
 	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/registry/internal"
 )
 
+// providerLoggerName is the name of the logger used for queries passed to the provider.
+// Their verbosity can be raised on its own with --vmodule=reststorage=5.
+const providerLoggerName = "provider"
+
 // REST is a wrapper for CustomMetricsProvider that provides implementation for Storage and Lister
 // interfaces.
 type REST struct {
 	emProvider        provider.ExternalMetricsProvider
 	freshnessObserver metrics.FreshnessObserver
-	rest.TableConvertor
+	inflight          coalesce.Group
+
+	// RateLimiter limits the rate of queries passed to the provider for each metric.
+	// It may be nil, in which case queries are not limited.
+	RateLimiter *ratelimit.MetricRateLimiter
+	// ValueAgeRecorder records the age of the most recent value served for each
+	// metric.  It may be nil, in which case ages are not recorded.
+	ValueAgeRecorder metrics.ValueAgeRecorder
+	// AllowedNamespaces restricts the namespaces for which metrics are served.
+	// When empty, metrics are served for all namespaces.
+	AllowedNamespaces sets.Set[string]
+	// MaxAges sets how long responses for each metric may be cached by clients.
+	// Responses for other metrics must not be cached.
+	MaxAges cachecontrol.MaxAges
+	// CoalesceWindow is how long the result of a query is shared with identical
+	// requests of the same user after it succeeded.  When zero, only concurrent
+	// requests share it.
+	CoalesceWindow time.Duration
+	// Transform is applied to each metric value before it is returned.
+	// It may be nil, in which case values are returned as is.
+	Transform provider.ExternalMetricTransformFunc
+	// Validator validates each query before it is passed to the provider.  It may be
+	// nil, in which case all queries are passed.
+	Validator provider.ExternalMetricQueryValidatorFunc
+	// LogSampler samples the requests whose successful queries are logged.  It may
+	// be nil, in which case all of them are logged.  Failed queries are always logged.
+	LogSampler logsampling.Sampler
+	// MaxSelectorRequirements limits the number of requirements of each selector of
+	// the queries passed to the provider.  When zero, selectors are not limited.
+	MaxSelectorRequirements int
+	// Clock is used to compute the age of metric values printed in tables, and to
+	// timestamp the values forced by overrides.
+	// NewREST sets it to the real clock.
+	Clock clock.PassiveClock
 }
 
 var _ rest.Storage = &REST{}
 var _ rest.Lister = &REST{}
+var _ cm_rest.CacheControlled = &REST{}
+var _ rest.TableConvertor = &REST{}
 
 // NewREST returns new REST object for provided CustomMetricsProvider.
 func NewREST(emProvider provider.ExternalMetricsProvider) *REST {
@@ -48,6 +103,7 @@ func NewREST(emProvider provider.ExternalMetricsProvider) *REST {
 	return &REST{
 		emProvider:        emProvider,
 		freshnessObserver: freshnessObserver,
+		Clock:             clock.RealClock{},
 	}
 }
 
@@ -75,6 +131,9 @@ func (r *REST) List(ctx context.Context, options *metainternalversion.ListOption
 	if options != nil && options.LabelSelector != nil {
 		metricSelector = options.LabelSelector
 	}
+	if err := internal.CheckSelectorRequirements("labelSelector", metricSelector, r.MaxSelectorRequirements); err != nil {
+		return nil, err
+	}
 
 	namespace := request.NamespaceValue(ctx)
 
@@ -84,14 +143,142 @@ func (r *REST) List(ctx context.Context, options *metainternalversion.ListOption
 	}
 	metricName := requestInfo.Resource
 
-	res, err := r.emProvider.GetExternalMetric(ctx, namespace, metricSelector, provider.ExternalMetricInfo{Metric: metricName})
+	if r.AllowedNamespaces.Len() > 0 && !r.AllowedNamespaces.Has(namespace) {
+		return nil, apierr.NewForbidden(external_metrics.Resource(metricName), "", fmt.Errorf("metrics are not served for namespace %s", namespace))
+	}
+	info := provider.ExternalMetricInfo{Metric: metricName}
+	if r.Validator != nil {
+		if err := r.Validator(ctx, provider.ExternalMetricQuery{Info: info, Namespace: namespace, MetricSelector: metricSelector}); err != nil {
+			return nil, internal.RejectedQueryError(err)
+		}
+	}
+	// the value forced by tests bypasses the provider, and its rate limits
+	if override, ok := overrides.From(ctx); ok {
+		value, ok := override.Value()
+		if !ok {
+			return nil, apierr.NewBadRequest(fmt.Sprintf("the %s header of external metrics must be a single quantity", overrides.HeaderMetricOverride))
+		}
+		warning.AddWarning(ctx, "", fmt.Sprintf("the value of %s is forced by the %s header, not returned by the provider", metricName, overrides.HeaderMetricOverride))
+		return &external_metrics.ExternalMetricValueList{Items: []external_metrics.ExternalMetricValue{{
+			MetricName: metricName,
+			Timestamp:  metav1.NewTime(r.Clock.Now()),
+			Value:      value,
+		}}}, nil
+	}
+	if accepted, retryAfter := r.RateLimiter.Accept(metricName, metricName); !accepted {
+		return nil, ratelimit.NewTooManyRequestsError(metricName, retryAfter)
+	}
+
+	ctx = internal.RequestContext(ctx)
+
+	logger := klog.FromContext(ctx).WithName(providerLoggerName)
+	sampledLogger := r.LogSampler.Logger(logger)
+	sampledLogger.V(5).Info("querying external metrics provider", "metric", metricName, "namespace", namespace, "selector", metricSelector.String())
+
+	if streamingProvider, ok := r.emProvider.(provider.StreamingExternalMetricsProvider); ok {
+		values, err := streamingProvider.StreamExternalMetric(ctx, namespace, metricSelector, info)
+		var noData *provider.NoDataError
+		if errors.As(err, &noData) {
+			sampledLogger.V(5).Info("external metrics provider returned no data", "metric", metricName, "err", err)
+			warning.AddWarning(ctx, "", noData.Error())
+			return &external_metrics.ExternalMetricValueList{}, nil
+		}
+		if err != nil {
+			logger.V(5).Info("external metrics provider returned an error", "metric", metricName, "err", err)
+			return nil, internal.ProviderError(logger, "external", err)
+		}
+		return &externalMetricValueStream{
+			info:              info,
+			values:            values,
+			transform:         r.Transform,
+			freshnessObserver: r.freshnessObserver,
+			valueAgeRecorder:  r.ValueAgeRecorder,
+		}, nil
+	}
+
+	// identical requests, concurrent or within the coalescing window, are collapsed
+	// into a single provider query
+	key := fmt.Sprintf("%s/%s?selector=%s", namespace, metricName, metricSelector.String())
+	// providers may serve each user differently, e.g. by impersonating them
+	if user, ok := request.UserFrom(ctx); ok {
+		key += "&user=" + user.GetName()
+	}
+	result, err, shared := r.inflight.Do(ctx, key, r.CoalesceWindow, func(ctx context.Context) (interface{}, error) {
+		// the provenance is recorded for the query, and shared with identical requests
+		ctx, provenance := provider.WithProvenanceRecorder(ctx)
+		stop := servertiming.Start(ctx, servertiming.PhaseProvider)
+		res, err := r.emProvider.GetExternalMetric(ctx, namespace, metricSelector, info)
+		stop()
+		var noData *provider.NoDataError
+		if errors.As(err, &noData) {
+			sampledLogger.V(5).Info("external metrics provider returned no data", "metric", metricName, "err", err)
+			return &internal.FlightResult[*external_metrics.ExternalMetricValueList]{Values: &external_metrics.ExternalMetricValueList{}, Provenance: provenance(), NoData: noData.Error()}, nil
+		}
+		if err != nil {
+			logger.V(5).Info("external metrics provider returned an error", "metric", metricName, "err", err)
+			return nil, internal.ProviderError(logger, "external", err)
+		}
+		// a selector matching no values is not an error
+		if res == nil {
+			res = &external_metrics.ExternalMetricValueList{}
+		}
+		sampledLogger.V(5).Info("external metrics provider returned values", "metric", metricName, "count", len(res.Items))
+
+		if r.Transform != nil {
+			for i := range res.Items {
+				if res.Items[i], err = r.Transform(info, res.Items[i]); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return &internal.FlightResult[*external_metrics.ExternalMetricValueList]{Values: res, Provenance: provenance()}, nil
+	})
 	if err != nil {
-		return nil, err
+		// the request may also have been canceled or timed out while waiting for an identical one
+		return nil, internal.ProviderError(klog.FromContext(ctx).WithName(providerLoggerName), "external", err)
+	}
+	flight := result.(*internal.FlightResult[*external_metrics.ExternalMetricValueList])
+	res := flight.Values
+	// the result is kept for the requests made within the coalescing window
+	if shared || r.CoalesceWindow > 0 {
+		res = res.DeepCopy()
+	}
+	if provenance := flight.Provenance; provenance != "" {
+		provider.SetProvenance(ctx, provenance)
+	}
+	// the warning lets clients tell a metric without data apart from a missing one
+	if noData := flight.NoData; noData != "" {
+		warning.AddWarning(ctx, "", noData)
 	}
 
 	for _, m := range res.Items {
 		r.freshnessObserver.Observe(m.Timestamp)
 	}
+	if r.ValueAgeRecorder != nil && len(res.Items) > 0 {
+		latest := res.Items[0].Timestamp
+		for i := range res.Items[1:] {
+			if latest.Before(&res.Items[i+1].Timestamp) {
+				latest = res.Items[i+1].Timestamp
+			}
+		}
+		r.ValueAgeRecorder.Record(external_metrics.GroupName, metricName, latest)
+	}
 
 	return res, nil
 }
+
+// ConvertToTable converts metric values into a table, which is used by kubectl to
+// print them.
+func (r *REST) ConvertToTable(ctx context.Context, object runtime.Object, tableOptions runtime.Object) (*metav1.Table, error) {
+	return tableConvertor{clock: r.Clock}.ConvertToTable(ctx, object, tableOptions)
+}
+
+// CacheControl returns the Cache-Control header value of responses for the
+// requested metric, allowing them to be cached for its max age, if any.
+func (r *REST) CacheControl(ctx context.Context) string {
+	requestInfo, ok := request.RequestInfoFrom(ctx)
+	if !ok {
+		return cachecontrol.NoCache
+	}
+	return r.MaxAges.Header(requestInfo.Resource)
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/registry/rest"
+	"k8s.io/metrics/pkg/apis/external_metrics"
+	"k8s.io/metrics/pkg/apis/external_metrics/v1beta1"
+
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/apiserver/metrics"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
+)
+
+// streamBufferSize is the amount of encoded values buffered before they are
+// written to the response.  Buffered values are also written whenever
+// the provider has no value ready to be sent.
+const streamBufferSize = 32 * 1024
+
+// externalMetricValueStream is a list of external metric values which is
+// encoded as an ExternalMetricValueList while values are received from
+// a StreamingExternalMetricsProvider.
+type externalMetricValueStream struct {
+	info              provider.ExternalMetricInfo
+	values            <-chan external_metrics.ExternalMetricValue
+	transform         provider.ExternalMetricTransformFunc
+	freshnessObserver metrics.FreshnessObserver
+	// valueAgeRecorder may be nil, in which case ages are not recorded
+	valueAgeRecorder metrics.ValueAgeRecorder
+}
+
+var _ runtime.Object = &externalMetricValueStream{}
+var _ rest.ResourceStreamer = &externalMetricValueStream{}
+
+func (s *externalMetricValueStream) GetObjectKind() schema.ObjectKind {
+	return schema.EmptyObjectKind
+}
+
+func (s *externalMetricValueStream) DeepCopyObject() runtime.Object {
+	return &externalMetricValueStream{
+		info:              s.info,
+		values:            s.values,
+		transform:         s.transform,
+		freshnessObserver: s.freshnessObserver,
+		valueAgeRecorder:  s.valueAgeRecorder,
+	}
+}
+
+// InputStream returns a reader producing the JSON encoding of the list.
+func (s *externalMetricValueStream) InputStream(ctx context.Context, apiVersion, _ string) (io.ReadCloser, bool, string, error) {
+	if apiVersion != v1beta1.SchemeGroupVersion.String() {
+		return nil, false, "", fmt.Errorf("unable to stream external metrics for version %s", apiVersion)
+	}
+
+	r, w := io.Pipe()
+	go func() {
+		w.CloseWithError(s.encode(ctx, w, apiVersion))
+	}()
+
+	return r, true, runtime.ContentTypeJSON, nil
+}
+
+func (s *externalMetricValueStream) encode(ctx context.Context, out io.Writer, apiVersion string) error {
+	w := bufio.NewWriterSize(out, streamBufferSize)
+	if _, err := fmt.Fprintf(w, `{"kind":"ExternalMetricValueList","apiVersion":%q,"metadata":{},"items":[`, apiVersion); err != nil {
+		return err
+	}
+
+	first := true
+	var latest metav1.Time
+	for {
+		var value external_metrics.ExternalMetricValue
+		var ok bool
+		select {
+		case value, ok = <-s.values:
+		default:
+			// nothing ready yet, so send what we have while waiting
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			select {
+			case value, ok = <-s.values:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if !ok {
+			break
+		}
+
+		if s.transform != nil {
+			var err error
+			if value, err = s.transform(s.info, value); err != nil {
+				return err
+			}
+		}
+
+		s.freshnessObserver.Observe(value.Timestamp)
+		// the age is only recorded for values more recent than the ones sent before
+		if s.valueAgeRecorder != nil && latest.Before(&value.Timestamp) {
+			latest = value.Timestamp
+			s.valueAgeRecorder.Record(external_metrics.GroupName, s.info.Metric, latest)
+		}
+
+		var versioned v1beta1.ExternalMetricValue
+		if err := v1beta1.Convert_external_metrics_ExternalMetricValue_To_v1beta1_ExternalMetricValue(&value, &versioned, nil); err != nil {
+			return err
+		}
+		data, err := json.Marshal(&versioned)
+		if err != nil {
+			return err
+		}
+
+		if !first {
+			if err := w.WriteByte(','); err != nil {
+				return err
+			}
+		}
+		first = false
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+
+	if _, err := w.WriteString("]}"); err != nil {
+		return err
+	}
+	return w.Flush()
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package internal holds the helpers shared by the REST storages of the custom
+// and external metrics APIs.
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
+)
+
+// StatusClientClosedRequest is the non-standard status, used by nginx among
+// others, of requests canceled by the client before the response is sent.
+const StatusClientClosedRequest = 499
+
+// ProviderError reports errors the provider expects to be transient as such, even when wrapped.
+// Errors caused by the request context are reported as a timeout when its deadline
+// is exceeded, and only logged when the client canceled the request, since nobody
+// reads the response then.  The API, e.g. custom, names the provider in the messages.
+func ProviderError(logger klog.Logger, api string, err error) error {
+	var retryable *provider.RetryableError
+	switch {
+	case errors.As(err, &retryable):
+		return retryable
+	case errors.Is(err, context.DeadlineExceeded):
+		return apierr.NewTimeoutError(fmt.Sprintf("the %s metrics provider did not answer in time: %v", api, err), 0)
+	case errors.Is(err, context.Canceled):
+		logger.V(4).Info("request canceled by the client while querying the provider", "err", err)
+		return &apierr.StatusError{ErrStatus: metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    StatusClientClosedRequest,
+			Reason:  metav1.StatusReasonUnknown,
+			Message: fmt.Sprintf("the request was canceled by the client: %v", err),
+		}}
+	}
+	return err
+}
+
+// CheckSelectorRequirements rejects the selector of the given query parameter with a
+// 400 Bad Request when it has more than limit requirements.  When limit is zero,
+// selectors are not limited.
+func CheckSelectorRequirements(param string, selector labels.Selector, limit int) error {
+	if limit <= 0 {
+		return nil
+	}
+	if requirements, _ := selector.Requirements(); len(requirements) > limit {
+		return apierr.NewBadRequest(fmt.Sprintf("%s has %d requirements, more than the limit of %d", param, len(requirements), limit))
+	}
+	return nil
+}
+
+// RejectedQueryError returns the error the validator rejected a query with, as a
+// bad request unless it has a status of its own.
+func RejectedQueryError(err error) error {
+	var status apierr.APIStatus
+	if errors.As(err, &status) {
+		return err
+	}
+	return apierr.NewBadRequest(err.Error())
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
+)
+
+func TestProviderError(t *testing.T) {
+	retryable := provider.NewRetryableError(errors.New("backend overloaded"), time.Second)
+	failure := errors.New("backend failure")
+
+	t.Run("retryable", func(t *testing.T) {
+		err := ProviderError(klog.Background(), "custom", fmt.Errorf("querying backend: %w", retryable))
+		assert.Same(t, retryable, err, "should have unwrapped the retryable error")
+	})
+
+	t.Run("deadline exceeded", func(t *testing.T) {
+		err := ProviderError(klog.Background(), "external", fmt.Errorf("querying backend: %w", context.DeadlineExceeded))
+		assert.True(t, apierr.IsTimeout(err), "should have reported a timeout, got %v", err)
+		assert.Contains(t, err.Error(), "the external metrics provider did not answer in time")
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		err := ProviderError(klog.Background(), "custom", fmt.Errorf("querying backend: %w", context.Canceled))
+		var status apierr.APIStatus
+		require.ErrorAs(t, err, &status)
+		assert.EqualValues(t, StatusClientClosedRequest, status.Status().Code)
+	})
+
+	t.Run("other", func(t *testing.T) {
+		assert.Same(t, failure, ProviderError(klog.Background(), "custom", failure), "should have kept other errors")
+	})
+}
+
+func TestCheckSelectorRequirements(t *testing.T) {
+	selector, err := labels.Parse("a=1,b=2,c")
+	require.NoError(t, err)
+
+	assert.NoError(t, CheckSelectorRequirements("labelSelector", selector, 0), "should not have limited selectors without limit")
+	assert.NoError(t, CheckSelectorRequirements("labelSelector", selector, 3))
+
+	err = CheckSelectorRequirements("metricLabelSelector", selector, 2)
+	assert.True(t, apierr.IsBadRequest(err), "should have rejected the selector as a bad request, got %v", err)
+	assert.Contains(t, err.Error(), "metricLabelSelector has 3 requirements")
+}
+
+func TestRejectedQueryError(t *testing.T) {
+	err := RejectedQueryError(errors.New("metric not allowed"))
+	assert.True(t, apierr.IsBadRequest(err), "should have rejected the query as a bad request, got %v", err)
+
+	forbidden := apierr.NewForbidden(schema.GroupResource{Resource: "pods"}, "some-metric", errors.New("not for you"))
+	err = RejectedQueryError(fmt.Errorf("validating: %w", forbidden))
+	var status apierr.APIStatus
+	require.ErrorAs(t, err, &status)
+	assert.EqualValues(t, http.StatusForbidden, status.Status().Code, "should have kept the status of the error")
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"k8s.io/apiserver/pkg/audit"
+
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
+)
+
+// RequestContext adds the identifiers of the request to ctx, so that providers
+// can correlate their logs with the ones of the API server.
+func RequestContext(ctx context.Context) context.Context {
+	if auditID, ok := audit.AuditIDFrom(ctx); ok {
+		ctx = provider.WithAuditID(ctx, string(auditID))
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+		ctx = provider.WithTraceID(ctx, spanContext.TraceID().String())
+	}
+	return ctx
+}
+
+// FlightResult is the result of a query, shared by identical requests.
+type FlightResult[T any] struct {
+	Values     T
+	Provenance provider.Provenance
+	// NoData is the reason why the provider returned no values, if it did so
+	// with a NoDataError
+	NoData string
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+
+	"k8s.io/apiserver/pkg/audit"
+
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
+)
+
+func TestRequestContext(t *testing.T) {
+	t.Run("without identifiers", func(t *testing.T) {
+		ctx := RequestContext(context.Background())
+		_, ok := provider.AuditIDFromContext(ctx)
+		assert.False(t, ok, "should not have set an audit ID")
+		_, ok = provider.TraceIDFromContext(ctx)
+		assert.False(t, ok, "should not have set a trace ID")
+	})
+
+	t.Run("with identifiers", func(t *testing.T) {
+		traceID := trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36}
+		ctx := audit.WithAuditContext(context.Background())
+		audit.WithAuditID(ctx, "some-audit-id")
+		ctx = trace.ContextWithSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: trace.SpanID{1}}))
+
+		ctx = RequestContext(ctx)
+		auditID, _ := provider.AuditIDFromContext(ctx)
+		assert.Equal(t, "some-audit-id", auditID)
+		actualTraceID, _ := provider.TraceIDFromContext(ctx)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", actualTraceID)
+	})
+}
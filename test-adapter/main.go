@@ -67,6 +67,17 @@ func main() {
 		klog.Fatalf("unable to parse flags: %v", err)
 	}
 
+	if cmd.PrintOpenAPI != "" {
+		// the OpenAPI document does not depend on the cluster, so don't connect to it
+		testProvider, _ := fakeprov.NewFakeProvider(nil, nil)
+		cmd.WithCustomMetrics(testProvider)
+		cmd.WithExternalMetrics(testProvider)
+		if err := cmd.Run(wait.NeverStop); err != nil {
+			klog.Fatalf("unable to print OpenAPI document: %v", err)
+		}
+		return
+	}
+
 	testProvider, webService := cmd.makeProviderOrDie()
 	cmd.WithCustomMetrics(testProvider)
 	cmd.WithExternalMetrics(testProvider)